@@ -9,6 +9,7 @@ import (
 
 	"github.com/adshao/go-binance/v2"
 	"github.com/drakos74/free-coin/client/binance/model"
+	"github.com/drakos74/free-coin/client/limit"
 	coinmodel "github.com/drakos74/free-coin/internal/model"
 )
 
@@ -39,57 +40,124 @@ type exchange interface {
 	GetExchangeInfo() (res *binance.ExchangeInfo, err error)
 	ListPrice() (res []*binance.SymbolPrice, err error)
 	CreateOrder(converter model.Converter, order coinmodel.TrackedOrder, volume string) (res *binance.CreateOrderResponse, err error)
+	GetOrder(converter model.Converter, order coinmodel.TrackedOrder) (res *binance.Order, err error)
 	GetAccount() (res *binance.Account, err error)
 	GetMarginAccount() (res *binance.MarginAccount, err error)
 	GetMarginPairs() (res []*binance.MarginAllPair, err error)
 }
 
 type binanceAPI struct {
-	client *binance.Client
+	client  *binance.Client
+	limiter *limit.Limiter
 }
 
 func newBinanceAPI(client *binance.Client) *binanceAPI {
-	return &binanceAPI{client: client}
+	return &binanceAPI{client: client, limiter: limiter}
+}
+
+// wait waits for the request weight of the call to be available.
+func (b *binanceAPI) wait(priority limit.Priority, weight float64) error {
+	if err := b.limiter.Wait(context.Background(), priority, weight); err != nil {
+		return fmt.Errorf("could not wait for rate limit: %w", err)
+	}
+	return nil
 }
 
 func (b *binanceAPI) GetExchangeInfo() (res *binance.ExchangeInfo, err error) {
-	return b.client.NewExchangeInfoService().Do(context.Background())
+	if err := b.wait(limit.Sync, exchangeInfoWeight); err != nil {
+		return nil, err
+	}
+	res, err = b.client.NewExchangeInfoService().Do(context.Background())
+	throttled(b.limiter, err)
+	return res, err
 }
 
 func (b *binanceAPI) ListPrice() (res []*binance.SymbolPrice, err error) {
-	return b.client.NewListPricesService().Do(context.Background())
+	if err := b.wait(limit.Sync, listPriceWeight); err != nil {
+		return nil, err
+	}
+	res, err = b.client.NewListPricesService().Do(context.Background())
+	throttled(b.limiter, err)
+	return res, err
 }
 
 func (b *binanceAPI) GetAccount() (res *binance.Account, err error) {
-	return b.client.NewGetAccountService().Do(context.Background())
+	if err := b.wait(limit.Sync, accountWeight); err != nil {
+		return nil, err
+	}
+	res, err = b.client.NewGetAccountService().Do(context.Background())
+	throttled(b.limiter, err)
+	return res, err
 }
 
 func (b *binanceAPI) GetMarginAccount() (res *binance.MarginAccount, err error) {
-	return b.client.NewGetMarginAccountService().Do(context.Background())
+	if err := b.wait(limit.Sync, marginAccountWeight); err != nil {
+		return nil, err
+	}
+	res, err = b.client.NewGetMarginAccountService().Do(context.Background())
+	throttled(b.limiter, err)
+	return res, err
 }
 
 func (b *binanceAPI) GetMarginPairs() (res []*binance.MarginAllPair, err error) {
-	return b.client.NewGetMarginAllPairsService().Do(context.Background())
+	if err := b.wait(limit.Sync, marginPairsWeight); err != nil {
+		return nil, err
+	}
+	res, err = b.client.NewGetMarginAllPairsService().Do(context.Background())
+	throttled(b.limiter, err)
+	return res, err
 }
 
 func (b *binanceAPI) CreateOrder(converter model.Converter, order coinmodel.TrackedOrder, volume string) (res *binance.CreateOrderResponse, err error) {
 	if order.Leverage > 0 {
-		return b.client.NewCreateMarginOrderService().
+		if err := b.wait(limit.Order, marginOrderWeight); err != nil {
+			return nil, err
+		}
+		res, err = b.client.NewCreateMarginOrderService().
 			Symbol(converter.Coin.Pair(order.Coin)).
 			Side(converter.Type.From(order.Type)).
 			Type(converter.OrderType.From(order.OType)).
 			//TimeInForce(binance.TimeInForceTypeGTC).
 			Quantity(volume).
 			//Price(coinmodel.Price.Format(order.Coin, order.Volume)).
+			NewClientOrderID(order.CID).
 			Do(context.Background())
 	} else {
-		return b.client.NewCreateOrderService().
+		if err := b.wait(limit.Order, orderWeight); err != nil {
+			return nil, err
+		}
+		res, err = b.client.NewCreateOrderService().
 			Symbol(converter.Coin.Pair(order.Coin)).
 			Side(converter.Type.From(order.Type)).
 			Type(converter.OrderType.From(order.OType)).
 			//TimeInForce(binance.TimeInForceTypeGTC).
 			Quantity(volume).
 			//Price(coinmodel.Price.Format(order.Coin, order.Volume)).
+			NewClientOrderID(order.CID).
+			Do(context.Background())
+	}
+	throttled(b.limiter, err)
+	return res, err
+}
+
+func (b *binanceAPI) GetOrder(converter model.Converter, order coinmodel.TrackedOrder) (res *binance.Order, err error) {
+	if order.Leverage > 0 {
+		if err := b.wait(limit.Order, getMarginOrderWeight); err != nil {
+			return nil, err
+		}
+		res, err = b.client.NewGetMarginOrderService().
+			Symbol(converter.Coin.Pair(order.Coin)).
+			OrigClientOrderID(order.CID).
+			Do(context.Background())
+	} else {
+		if err := b.wait(limit.Order, getOrderWeight); err != nil {
+			return nil, err
+		}
+		res, err = b.client.NewGetOrderService().
+			Symbol(converter.Coin.Pair(order.Coin)).
+			OrigClientOrderID(order.CID).
 			Do(context.Background())
 	}
+	throttled(b.limiter, err)
+	return res, err
 }
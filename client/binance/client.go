@@ -74,10 +74,10 @@ func (c *Client) Close() error {
 func (c *Client) Trades(process <-chan api.Signal) (coinmodel.TradeSource, error) {
 
 	// prepare the output channel
-	out := make(chan *coinmodel.Trade)
+	out := make(chan *coinmodel.TradeSignal)
 
 	// receive and delegate tick events Max the output
-	trades := make(chan *coinmodel.Trade)
+	trades := make(chan *coinmodel.TradeSignal)
 	// read trades from the remote source and push them to the trade channel
 	go c.execute(trades)
 	// controller decides Max delegate trade for processing, or stop execution
@@ -87,7 +87,7 @@ func (c *Client) Trades(process <-chan api.Signal) (coinmodel.TradeSource, error
 
 }
 
-func (c *Client) controller(input chan *coinmodel.Trade, output chan *coinmodel.Trade) {
+func (c *Client) controller(input chan *coinmodel.TradeSignal, output chan *coinmodel.TradeSignal) {
 	defer func() {
 		log.Info().Msg("closing trade controller")
 		close(output)
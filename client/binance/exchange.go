@@ -2,6 +2,7 @@ package binance
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"strconv"
 
@@ -12,7 +13,9 @@ import (
 	"github.com/rs/zerolog/log"
 
 	"github.com/adshao/go-binance/v2"
+	"github.com/adshao/go-binance/v2/common"
 	"github.com/drakos74/free-coin/client/binance/model"
+	"github.com/drakos74/free-coin/client/limit"
 	coinmodel "github.com/drakos74/free-coin/internal/model"
 )
 
@@ -33,6 +36,14 @@ func NewExchange(user account.Name) *Exchange {
 	return exchange
 }
 
+// WithLimiter sets the rate limiter for the exchange calls , by default it is shared with the other binance exchanges.
+func (c *Exchange) WithLimiter(limiter *limit.Limiter) *Exchange {
+	if remote, ok := c.api.(*binanceAPI); ok {
+		remote.limiter = limiter
+	}
+	return c
+}
+
 func (c *Exchange) getInfo() {
 	if c.info != nil {
 		return
@@ -112,6 +123,7 @@ func (c *Exchange) OpenOrder(order *coinmodel.TrackedOrder) (*coinmodel.TrackedO
 
 	price := 0.0
 	count := 0.0
+	fees := 0.0
 	for _, fill := range orderResponse.Fills {
 		if fill != nil {
 			p, err := strconv.ParseFloat(fill.Price, 64)
@@ -126,13 +138,49 @@ func (c *Exchange) OpenOrder(order *coinmodel.TrackedOrder) (*coinmodel.TrackedO
 			}
 			price += q * p
 			count += q
+			// only the commission in the quote asset adds up to the fees , the other assets are not priced here
+			if c, err := strconv.ParseFloat(fill.Commission, 64); err == nil && fill.CommissionAsset == s.QuoteAsset {
+				fees += c
+			}
 		}
 	}
 	order.Audit.Fills = int(count)
 	order.Price = price / count
+	if price > 0 {
+		order.Fill = &coinmodel.Fill{
+			Price:  order.Price,
+			Volume: count,
+			Fees:   fees,
+		}
+	}
 	return order, []string{orderResponse.ClientOrderID}, nil
 }
 
+// unknownOrder is the binance error code for an order that does not exist.
+const unknownOrder = -2013
+
+// QueryOrder looks up the order by its client order id.
+func (c *Exchange) QueryOrder(ctx context.Context, order *coinmodel.TrackedOrder) (*coinmodel.TrackedOrder, bool, error) {
+	if order.CID == "" {
+		return nil, false, fmt.Errorf("no client id for order '%s'", order.ID)
+	}
+	response, err := c.api.GetOrder(c.converter, *order)
+	if err != nil {
+		var apiErr *common.APIError
+		if errors.As(err, &apiErr) && apiErr.Code == unknownOrder {
+			return nil, false, nil
+		}
+		return nil, false, fmt.Errorf("could not query order: %w", err)
+	}
+	switch response.Status {
+	case binance.OrderStatusTypeCanceled, binance.OrderStatusTypeRejected, binance.OrderStatusTypeExpired:
+		return nil, false, nil
+	}
+	found := *order
+	found.TxIDs = []string{response.ClientOrderID}
+	return &found, true, nil
+}
+
 func (c *Exchange) ClosePosition(position *coinmodel.Position) error {
 	order := coinmodel.NewOrder(position.Coin).
 		Market().
@@ -70,6 +70,10 @@ func (t testAPI) CreateOrder(converter model.Converter, order coinmodel.TrackedO
 	panic("implement me")
 }
 
+func (t testAPI) GetOrder(converter model.Converter, order coinmodel.TrackedOrder) (res *binance.Order, err error) {
+	panic("implement me")
+}
+
 func (t testAPI) GetAccount() (res *binance.Account, err error) {
 	if t.account == "" {
 		return nil, fmt.Errorf("could not get account")
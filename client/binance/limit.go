@@ -0,0 +1,46 @@
+package binance
+
+import (
+	"errors"
+	"time"
+
+	"github.com/adshao/go-binance/v2/common"
+	"github.com/drakos74/free-coin/client/limit"
+)
+
+const (
+	// maxWeight is the request weight budget per minute.
+	maxWeight = 1200
+
+	exchangeInfoWeight   = 10
+	listPriceWeight      = 2
+	accountWeight        = 10
+	marginAccountWeight  = 10
+	marginPairsWeight    = 1
+	orderWeight          = 1
+	marginOrderWeight    = 6
+	getOrderWeight       = 2
+	getMarginOrderWeight = 10
+
+	tooManyRequests = -1003
+)
+
+// NewLimiter creates a limiter for the binance request weight ,
+// which keeps part of the budget free for the orders and the position sync.
+func NewLimiter() *limit.Limiter {
+	return limit.New(string(Name), limit.NewWindow(maxWeight, time.Minute)).
+		WithReserve(limit.Sync, 100).
+		WithReserve(limit.History, 300)
+}
+
+// limiter is the rate limiter shared by the binance exchanges of the process.
+var limiter = NewLimiter()
+
+// throttled checks if binance rejected the call for exceeding the request weight ,
+// in which case the limiter waits for the next window.
+func throttled(limiter *limit.Limiter, err error) {
+	var apiErr *common.APIError
+	if errors.As(err, &apiErr) && apiErr.Code == tooManyRequests {
+		limiter.Exceeded()
+	}
+}
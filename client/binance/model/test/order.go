@@ -25,7 +25,7 @@ func main() {
 	fmt.Printf("\nl = %+v", lotSize)
 
 	f := 16.7
-	v := lotSize.Adjust(f)
+	v := lotSize.Adjust(f, false)
 
 	if f != v {
 		fmt.Printf("\nf = %+v", f)
@@ -9,7 +9,8 @@ import (
 	cointime "github.com/drakos74/free-coin/internal/time"
 )
 
-func FromKLine(event *binance.WsKlineEvent) (*coinmodel.Trade, error) {
+// FromKLine converts a kline event into a trade signal , at the average of the open and close price.
+func FromKLine(event *binance.WsKlineEvent) (*coinmodel.TradeSignal, error) {
 	openPrice, err := strconv.ParseFloat(event.Kline.Open, 64)
 	if err != nil {
 		return nil, fmt.Errorf("could not parse openPrice price: %w", err)
@@ -22,17 +23,18 @@ func FromKLine(event *binance.WsKlineEvent) (*coinmodel.Trade, error) {
 	if err != nil {
 		return nil, fmt.Errorf("could not parse volume: %w", err)
 	}
-	trade := coinmodel.Trade{
-		SourceID: "binance",
-		ID:       strconv.FormatInt(event.Kline.LastTradeID, 10),
-		Coin:     coinmodel.Coin(event.Symbol),
-		Price:    (openPrice + closePrice) / float64(2),
-		Volume:   volume,
-		Time:     cointime.FromMilli(event.Kline.EndTime),
-		Type:     coinmodel.SignedType(closePrice - openPrice),
-		Active:   true,
-		Live:     true,
-		Signals:  make([]coinmodel.Signal, 0),
-	}
-	return &trade, nil
+	t := cointime.FromMilli(event.Kline.EndTime)
+	tick := coinmodel.NewTick((openPrice+closePrice)/float64(2), volume, coinmodel.SignedType(closePrice-openPrice), t)
+	tick.Active = true
+	return &coinmodel.TradeSignal{
+		Coin: coinmodel.Coin(event.Symbol),
+		Meta: coinmodel.Meta{
+			ID:       strconv.FormatInt(event.Kline.LastTradeID, 10),
+			Time:     t,
+			Unix:     t.Unix(),
+			Live:     true,
+			Exchange: "binance",
+		},
+		Tick: tick,
+	}, nil
 }
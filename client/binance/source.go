@@ -23,7 +23,7 @@ const (
 // This interface allows to abstract the remote exchange logic.
 type Source interface {
 	io.Closer
-	Serve(coin coinmodel.Coin, interval time.Duration, trades chan *coinmodel.Trade) (doneC, stopC chan struct{}, err error)
+	Serve(coin coinmodel.Coin, interval time.Duration, trades chan *coinmodel.TradeSignal) (doneC, stopC chan struct{}, err error)
 }
 
 // RemoteSource defines a remote api for interaction with kraken exchange.
@@ -34,7 +34,7 @@ type RemoteSource struct {
 
 // Serve opens a socket connection to the trade source.
 // TODO: add a proper error handler
-func (r *RemoteSource) Serve(coin coinmodel.Coin, interval time.Duration, trades chan *coinmodel.Trade) (doneC, stopC chan struct{}, err error) {
+func (r *RemoteSource) Serve(coin coinmodel.Coin, interval time.Duration, trades chan *coinmodel.TradeSignal) (doneC, stopC chan struct{}, err error) {
 	return binance.WsKlineServe(r.converter.Coin.Pair(coin), r.converter.Time.From(interval), r.handler(trades), nil)
 }
 
@@ -81,7 +81,7 @@ func (m *MockSource) Close() error {
 	return nil
 }
 
-func (m *MockSource) Serve(coin coinmodel.Coin, interval time.Duration, trades chan *coinmodel.Trade) (doneC, stopC chan struct{}, err error) {
+func (m *MockSource) Serve(coin coinmodel.Coin, interval time.Duration, trades chan *coinmodel.TradeSignal) (doneC, stopC chan struct{}, err error) {
 	done := make(chan struct{})
 	stop := make(chan struct{})
 	return done, stop, nil
@@ -92,7 +92,7 @@ type baseSource struct {
 	converter model.Converter
 }
 
-func (b *baseSource) handler(trades chan *coinmodel.Trade) func(event *binance.WsKlineEvent) {
+func (b *baseSource) handler(trades chan *coinmodel.TradeSignal) func(event *binance.WsKlineEvent) {
 	return func(event *binance.WsKlineEvent) {
 		trade, err := model.FromKLine(event)
 		if err != nil {
@@ -0,0 +1,253 @@
+package client
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/drakos74/free-coin/internal/api"
+	"github.com/drakos74/free-coin/internal/model"
+	"github.com/rs/zerolog/log"
+)
+
+// Subscriber subscribes and unsubscribes coins on a live trade source.
+type Subscriber interface {
+	Subscribe(coins ...model.Coin) error
+	Unsubscribe(coins ...model.Coin) error
+}
+
+// Pairs returns the coins that can be traded on the exchange.
+type Pairs func() ([]model.Coin, error)
+
+// CoinChange describes the coins added and removed at runtime.
+type CoinChange struct {
+	Added   []model.Coin
+	Removed []model.Coin
+}
+
+// Empty returns true if no coins were added or removed.
+func (c CoinChange) Empty() bool {
+	return len(c.Added) == 0 && len(c.Removed) == 0
+}
+
+func (c CoinChange) String() string {
+	if c.Empty() {
+		return "no changes"
+	}
+	changes := make([]string, 0)
+	for _, coin := range c.Added {
+		changes = append(changes, fmt.Sprintf("+%s", coin))
+	}
+	for _, coin := range c.Removed {
+		changes = append(changes, fmt.Sprintf("-%s", coin))
+	}
+	return strings.Join(changes, " ")
+}
+
+// Coins manages the coins the bot is trading at runtime.
+// It subscribes the added coins on the source and unsubscribes the removed ones ,
+// and notifies the listeners , so that the processors can spin their coin state up or down.
+type Coins struct {
+	lock       *sync.Mutex
+	active     map[model.Coin]bool
+	tradable   map[model.Coin]bool
+	pairs      Pairs
+	subscriber Subscriber
+	listeners  []func(change CoinChange)
+}
+
+// NewCoins creates a new coin manager for the given subscriber , with the coins it is already subscribed to.
+func NewCoins(subscriber Subscriber, coins ...model.Coin) *Coins {
+	active := make(map[model.Coin]bool)
+	for _, coin := range coins {
+		active[coin] = true
+	}
+	return &Coins{
+		lock:       new(sync.Mutex),
+		active:     active,
+		subscriber: subscriber,
+		listeners:  make([]func(change CoinChange), 0),
+	}
+}
+
+// WithPairs adds the source of the tradable coins , the coins are validated against them when added.
+func (c *Coins) WithPairs(pairs Pairs) *Coins {
+	c.pairs = pairs
+	return c
+}
+
+// OnChange adds a listener for the coin changes.
+func (c *Coins) OnChange(listener func(change CoinChange)) *Coins {
+	c.listeners = append(c.listeners, listener)
+	return c
+}
+
+// Load loads the tradable coins from the exchange.
+func (c *Coins) Load() error {
+	if c.pairs == nil {
+		return nil
+	}
+	coins, err := c.pairs()
+	if err != nil {
+		return fmt.Errorf("could not load tradable pairs: %w", err)
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.tradable = make(map[model.Coin]bool)
+	for _, coin := range coins {
+		c.tradable[coin] = true
+	}
+	return nil
+}
+
+// Active returns the active coins in a stable order.
+func (c *Coins) Active() []model.Coin {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return sorted(c.active)
+}
+
+// IsActive checks if the coin is active.
+func (c *Coins) IsActive(coin model.Coin) bool {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.active[coin]
+}
+
+// Add subscribes to the given coins , the ones already active are ignored.
+func (c *Coins) Add(coins ...model.Coin) (CoinChange, error) {
+	return c.apply(coins, nil)
+}
+
+// Remove unsubscribes from the given coins , the ones not active are ignored.
+func (c *Coins) Remove(coins ...model.Coin) (CoinChange, error) {
+	return c.apply(nil, coins)
+}
+
+// Set makes the given coins the active ones e.g. from the config file.
+func (c *Coins) Set(coins ...model.Coin) (CoinChange, error) {
+	next := make(map[model.Coin]bool)
+	for _, coin := range coins {
+		next[coin] = true
+	}
+	c.lock.Lock()
+	remove := make([]model.Coin, 0)
+	for coin := range c.active {
+		if !next[coin] {
+			remove = append(remove, coin)
+		}
+	}
+	c.lock.Unlock()
+	return c.apply(coins, remove)
+}
+
+// Check checks if the given coins can be added , without subscribing to them.
+func (c *Coins) Check(coins ...model.Coin) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.check(coins...)
+}
+
+// check checks if the coins are tradable , the caller must hold the lock.
+func (c *Coins) check(coins ...model.Coin) error {
+	for _, coin := range coins {
+		if model.IsAnyCoin(coin) {
+			return fmt.Errorf("invalid coin '%s'", coin)
+		}
+		if c.tradable != nil && !c.tradable[coin] {
+			return fmt.Errorf("coin '%s' is not tradable", coin)
+		}
+	}
+	return nil
+}
+
+func (c *Coins) apply(add, remove []model.Coin) (CoinChange, error) {
+	c.lock.Lock()
+	change := CoinChange{
+		Added:   make([]model.Coin, 0),
+		Removed: make([]model.Coin, 0),
+	}
+	if err := c.check(add...); err != nil {
+		c.lock.Unlock()
+		return CoinChange{}, err
+	}
+	for _, coin := range add {
+		if !c.active[coin] {
+			change.Added = append(change.Added, coin)
+		}
+	}
+	for _, coin := range remove {
+		if c.active[coin] {
+			change.Removed = append(change.Removed, coin)
+		}
+	}
+	if len(change.Added) > 0 {
+		if err := c.subscriber.Subscribe(change.Added...); err != nil {
+			c.lock.Unlock()
+			return CoinChange{}, fmt.Errorf("could not subscribe to %v: %w", change.Added, err)
+		}
+	}
+	if len(change.Removed) > 0 {
+		if err := c.subscriber.Unsubscribe(change.Removed...); err != nil {
+			log.Error().Err(err).Str("coins", fmt.Sprintf("%v", change.Removed)).Msg("could not unsubscribe")
+			// we are still subscribed to the removed coins
+			change.Removed = []model.Coin{}
+		}
+	}
+	for _, coin := range change.Added {
+		c.active[coin] = true
+	}
+	for _, coin := range change.Removed {
+		delete(c.active, coin)
+	}
+	c.lock.Unlock()
+
+	if !change.Empty() {
+		log.Info().Str("change", change.String()).Msg("coins changed")
+		for _, listener := range c.listeners {
+			listener(change)
+		}
+	}
+	return change, nil
+}
+
+// Run listens for the '?coin add/remove/list' user commands.
+func (c *Coins) Run(index api.Index, u api.User) {
+	for command := range u.Listen("coin", "?coin") {
+		var action string
+		var coin string
+		_, err := command.Validate(
+			api.AnyUser(),
+			api.Contains("?coin"),
+			api.OneOf(&action, "add", "remove", "list", ""),
+			api.Any(&coin),
+		)
+		if err != nil {
+			api.Reply(index, u, api.NewMessage("[cmd error]").ReplyTo(command.ID), err)
+			continue
+		}
+		msg := api.NewMessage("[coin]").ReplyTo(command.ID)
+		var change CoinChange
+		switch action {
+		case "add":
+			change, err = c.Add(model.Coin(strings.ToUpper(coin)))
+			msg.AddLine(change.String())
+		case "remove":
+			change, err = c.Remove(model.Coin(strings.ToUpper(coin)))
+			msg.AddLine(change.String())
+		}
+		api.Reply(index, u, msg.AddLine(fmt.Sprintf("%v", c.Active())), err)
+	}
+}
+
+func sorted(coins map[model.Coin]bool) []model.Coin {
+	cc := make([]model.Coin, 0, len(coins))
+	for coin := range coins {
+		cc = append(cc, coin)
+	}
+	sort.Slice(cc, func(i, j int) bool {
+		return cc[i] < cc[j]
+	})
+	return cc
+}
@@ -0,0 +1,157 @@
+package client
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/drakos74/free-coin/internal/model"
+	"github.com/stretchr/testify/assert"
+)
+
+// stubExchange emulates the exchange pairs and the socket subscriptions.
+type stubExchange struct {
+	pairs      []model.Coin
+	subscribed map[model.Coin]bool
+	fail       bool
+}
+
+func newStubExchange(coins ...model.Coin) *stubExchange {
+	subscribed := make(map[model.Coin]bool)
+	for _, coin := range coins {
+		subscribed[coin] = true
+	}
+	return &stubExchange{
+		pairs:      []model.Coin{model.BTC, model.ETH, model.SOL, model.DOT},
+		subscribed: subscribed,
+	}
+}
+
+func (s *stubExchange) Pairs() ([]model.Coin, error) {
+	return s.pairs, nil
+}
+
+func (s *stubExchange) Subscribe(coins ...model.Coin) error {
+	if s.fail {
+		return fmt.Errorf("socket closed")
+	}
+	for _, coin := range coins {
+		s.subscribed[coin] = true
+	}
+	return nil
+}
+
+func (s *stubExchange) Unsubscribe(coins ...model.Coin) error {
+	if s.fail {
+		return fmt.Errorf("socket closed")
+	}
+	for _, coin := range coins {
+		delete(s.subscribed, coin)
+	}
+	return nil
+}
+
+func TestCoins(t *testing.T) {
+
+	type test struct {
+		apply   func(c *Coins) (CoinChange, error)
+		fail    bool
+		err     string
+		change  CoinChange
+		active  []model.Coin
+		changes int
+	}
+
+	tests := map[string]test{
+		"add": {
+			apply: func(c *Coins) (CoinChange, error) {
+				return c.Add(model.SOL, model.BTC)
+			},
+			change:  CoinChange{Added: []model.Coin{model.SOL}, Removed: []model.Coin{}},
+			active:  []model.Coin{model.BTC, model.ETH, model.SOL},
+			changes: 1,
+		},
+		"add-not-tradable": {
+			apply: func(c *Coins) (CoinChange, error) {
+				return c.Add(model.KAVA)
+			},
+			err:    "not tradable",
+			active: []model.Coin{model.BTC, model.ETH},
+		},
+		"check-not-tradable": {
+			apply: func(c *Coins) (CoinChange, error) {
+				return CoinChange{}, c.Check(model.BTC, model.KAVA)
+			},
+			err:    "not tradable",
+			active: []model.Coin{model.BTC, model.ETH},
+		},
+		"add-fails": {
+			apply: func(c *Coins) (CoinChange, error) {
+				return c.Add(model.SOL)
+			},
+			fail:   true,
+			err:    "could not subscribe",
+			active: []model.Coin{model.BTC, model.ETH},
+		},
+		"remove": {
+			apply: func(c *Coins) (CoinChange, error) {
+				return c.Remove(model.ETH, model.DOT)
+			},
+			change:  CoinChange{Added: []model.Coin{}, Removed: []model.Coin{model.ETH}},
+			active:  []model.Coin{model.BTC},
+			changes: 1,
+		},
+		"remove-fails": {
+			apply: func(c *Coins) (CoinChange, error) {
+				return c.Remove(model.ETH)
+			},
+			fail:   true,
+			change: CoinChange{Added: []model.Coin{}, Removed: []model.Coin{}},
+			active: []model.Coin{model.BTC, model.ETH},
+		},
+		"set": {
+			apply: func(c *Coins) (CoinChange, error) {
+				return c.Set(model.BTC, model.DOT)
+			},
+			change:  CoinChange{Added: []model.Coin{model.DOT}, Removed: []model.Coin{model.ETH}},
+			active:  []model.Coin{model.BTC, model.DOT},
+			changes: 1,
+		},
+		"no-changes": {
+			apply: func(c *Coins) (CoinChange, error) {
+				return c.Set(model.ETH, model.BTC)
+			},
+			change: CoinChange{Added: []model.Coin{}, Removed: []model.Coin{}},
+			active: []model.Coin{model.BTC, model.ETH},
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			exchange := newStubExchange(model.BTC, model.ETH)
+			changes := make([]CoinChange, 0)
+			coins := NewCoins(exchange, model.BTC, model.ETH).
+				WithPairs(exchange.Pairs).
+				OnChange(func(change CoinChange) {
+					changes = append(changes, change)
+				})
+			assert.NoError(t, coins.Load())
+
+			exchange.fail = tt.fail
+			change, err := tt.apply(coins)
+			if tt.err != "" {
+				if assert.Error(t, err) {
+					assert.Contains(t, err.Error(), tt.err)
+				}
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.change, change)
+			}
+			assert.Equal(t, tt.active, coins.Active())
+			assert.Equal(t, len(tt.active), len(exchange.subscribed))
+			for _, coin := range tt.active {
+				assert.True(t, exchange.subscribed[coin])
+			}
+			assert.Equal(t, tt.changes, len(changes))
+		})
+	}
+}
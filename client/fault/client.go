@@ -0,0 +1,83 @@
+package fault
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/drakos74/free-coin/internal/api"
+	"github.com/drakos74/free-coin/internal/model"
+	"github.com/rs/zerolog/log"
+)
+
+// Client wraps a trade client and injects the configured faults on the trade stream.
+// Dropped trades are not emitted , duplicated ones are emitted twice ,
+// and stale ones carry the price of the previous trade for the coin.
+type Client struct {
+	*injector
+	client api.Client
+}
+
+// NewClient creates a new faulty client wrapping the given one , the seed reproduces the faults.
+func NewClient(client api.Client, seed int64) *Client {
+	return &Client{
+		injector: newInjector(seed),
+		client:   client,
+	}
+}
+
+// With sets the faults for the trades.
+func (c *Client) With(fault Fault) *Client {
+	c.injector.with(Trades, fault)
+	return c
+}
+
+// Trades returns the trade stream of the wrapped client with the injected faults.
+func (c *Client) Trades(process <-chan api.Signal) (model.TradeSource, error) {
+	if c.fail(Trades, c.fault(Trades)) {
+		return nil, fmt.Errorf("could not get trades: %w", ErrInjected)
+	}
+	// the wrapped client waits for each of its trades to be processed
+	upstream := make(chan api.Signal)
+	source, err := c.client.Trades(upstream)
+	if err != nil {
+		return nil, err
+	}
+	out := make(model.TradeSource)
+	go func() {
+		defer func() {
+			log.Info().Str("processor", "fault-source").Msg("closing processor")
+			close(out)
+		}()
+		prices := make(map[model.Coin]float64)
+		for trade := range source {
+			fault := c.call(Trades)
+			if err := c.delay(context.Background(), fault); err != nil {
+				log.Warn().Err(err).Msg("could not delay trade")
+			}
+			if trade == nil {
+				out <- trade
+				upstream <- <-process
+				continue
+			}
+			if c.drop(Trades, fault) {
+				upstream <- api.Signal{}
+				continue
+			}
+			price, ok := prices[trade.Coin]
+			prices[trade.Coin] = trade.Tick.Price
+			t := *trade
+			if ok && c.stale(Trades, fault) {
+				t.Tick.Price = price
+			}
+			if c.duplicate(Trades, fault) {
+				// emit a copy , so that the duplicate is not modified by the processors
+				d := t
+				out <- &d
+				<-process
+			}
+			out <- &t
+			upstream <- <-process
+		}
+	}()
+	return out, nil
+}
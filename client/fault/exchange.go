@@ -0,0 +1,230 @@
+package fault
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/drakos74/free-coin/internal/api"
+	"github.com/drakos74/free-coin/internal/model"
+	"github.com/rs/zerolog/log"
+)
+
+// Exchange wraps an exchange and injects the configured faults on its methods ,
+// to test how the trading logic copes with an unreliable exchange.
+type Exchange struct {
+	*injector
+	exchange api.Exchange
+	lock     *sync.Mutex
+	prices   map[model.Coin]model.CurrentPrice
+	balance  map[model.Coin]model.Balance
+	batch    *model.PositionBatch
+}
+
+// NewExchange creates a new faulty exchange wrapping the given one , the seed reproduces the faults.
+func NewExchange(exchange api.Exchange, seed int64) *Exchange {
+	return &Exchange{
+		injector: newInjector(seed),
+		exchange: exchange,
+		lock:     new(sync.Mutex),
+	}
+}
+
+// With sets the faults for the given method.
+func (e *Exchange) With(method string, fault Fault) *Exchange {
+	e.injector.with(method, fault)
+	return e
+}
+
+// OpenPositions returns the open positions of the exchange.
+// Partial responses miss one of the positions , and stale responses return the previous positions.
+func (e *Exchange) OpenPositions(ctx context.Context) (*model.PositionBatch, error) {
+	fault := e.call(OpenPositions)
+	if err := e.delay(ctx, fault); err != nil {
+		return nil, fmt.Errorf("could not get positions: %w", err)
+	}
+	if e.fail(OpenPositions, fault) {
+		return nil, fmt.Errorf("could not get positions: %w", ErrInjected)
+	}
+	batch, err := e.exchange.OpenPositions(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if e.drop(OpenPositions, fault) {
+		return nil, fmt.Errorf("could not get positions: %w", ErrDropped)
+	}
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	if e.batch != nil && e.stale(OpenPositions, fault) {
+		return e.batch, nil
+	}
+	e.batch = batch
+	if len(batch.Positions) > 0 && e.partial(OpenPositions, fault) {
+		miss := e.intn(len(batch.Positions))
+		positions := make([]model.Position, 0, len(batch.Positions)-1)
+		for i, p := range batch.Positions {
+			if i != miss {
+				positions = append(positions, p)
+			}
+		}
+		return &model.PositionBatch{
+			Positions: positions,
+			Index:     batch.Index,
+		}, nil
+	}
+	return batch, nil
+}
+
+// OpenOrder submits the order to the exchange.
+// Dropped responses return an error after the order is executed ,
+// and duplicated ones execute the order twice.
+func (e *Exchange) OpenOrder(order *model.TrackedOrder) (*model.TrackedOrder, []string, error) {
+	fault := e.call(OpenOrder)
+	if err := e.delay(context.Background(), fault); err != nil {
+		return nil, nil, fmt.Errorf("could not open order: %w", err)
+	}
+	if e.fail(OpenOrder, fault) {
+		return nil, nil, fmt.Errorf("could not open order: %w", ErrInjected)
+	}
+	o, txIDs, err := e.exchange.OpenOrder(order)
+	if err != nil {
+		return nil, nil, err
+	}
+	if e.duplicate(OpenOrder, fault) {
+		if _, _, err := e.exchange.OpenOrder(order); err != nil {
+			log.Warn().Err(err).Str("order", order.ID).Msg("could not duplicate order")
+		}
+	}
+	if e.drop(OpenOrder, fault) {
+		return nil, nil, fmt.Errorf("could not open order: %w", ErrDropped)
+	}
+	return o, txIDs, nil
+}
+
+// QueryOrder looks up the order on the exchange , if the wrapped exchange supports it.
+func (e *Exchange) QueryOrder(ctx context.Context, order *model.TrackedOrder) (*model.TrackedOrder, bool, error) {
+	fault := e.call(QueryOrder)
+	tracker, ok := e.exchange.(api.OrderTracker)
+	if !ok {
+		return nil, false, fmt.Errorf("could not query order: %w", ErrNotSupported)
+	}
+	if err := e.delay(ctx, fault); err != nil {
+		return nil, false, fmt.Errorf("could not query order: %w", err)
+	}
+	if e.fail(QueryOrder, fault) {
+		return nil, false, fmt.Errorf("could not query order: %w", ErrInjected)
+	}
+	found, exists, err := tracker.QueryOrder(ctx, order)
+	if err != nil {
+		return nil, false, err
+	}
+	if e.drop(QueryOrder, fault) {
+		return nil, false, fmt.Errorf("could not query order: %w", ErrDropped)
+	}
+	return found, exists, nil
+}
+
+// Balance returns the balance of the exchange.
+// Partial responses miss one of the coins , and stale responses return the previous balance.
+func (e *Exchange) Balance(ctx context.Context, priceMap map[model.Coin]model.CurrentPrice) (map[model.Coin]model.Balance, error) {
+	fault := e.call(Balance)
+	if err := e.delay(ctx, fault); err != nil {
+		return nil, fmt.Errorf("could not get balance: %w", err)
+	}
+	if e.fail(Balance, fault) {
+		return nil, fmt.Errorf("could not get balance: %w", ErrInjected)
+	}
+	balance, err := e.exchange.Balance(ctx, priceMap)
+	if err != nil {
+		return nil, err
+	}
+	if e.drop(Balance, fault) {
+		return nil, fmt.Errorf("could not get balance: %w", ErrDropped)
+	}
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	if e.balance != nil && e.stale(Balance, fault) {
+		return e.balance, nil
+	}
+	e.balance = balance
+	if len(balance) > 0 && e.partial(Balance, fault) {
+		coins := make([]model.Coin, 0, len(balance))
+		for coin := range balance {
+			coins = append(coins, coin)
+		}
+		miss := e.miss(coins)
+		partial := make(map[model.Coin]model.Balance)
+		for coin, b := range balance {
+			if coin != miss {
+				partial[coin] = b
+			}
+		}
+		return partial, nil
+	}
+	return balance, nil
+}
+
+// miss returns one of the coins , picked in a reproducible order.
+func (e *Exchange) miss(coins []model.Coin) model.Coin {
+	sort.Slice(coins, func(i, j int) bool {
+		return coins[i] < coins[j]
+	})
+	return coins[e.intn(len(coins))]
+}
+
+// Pairs returns the pairs of the exchange , the failed calls return no pairs.
+func (e *Exchange) Pairs(ctx context.Context) map[string]api.Pair {
+	fault := e.call(Pairs)
+	if err := e.delay(ctx, fault); err != nil {
+		return map[string]api.Pair{}
+	}
+	if e.fail(Pairs, fault) {
+		return map[string]api.Pair{}
+	}
+	pairs := e.exchange.Pairs(ctx)
+	if e.drop(Pairs, fault) {
+		return map[string]api.Pair{}
+	}
+	return pairs
+}
+
+// CurrentPrice returns the current prices of the exchange.
+// Partial responses miss one of the coins , and stale responses return the previous prices.
+func (e *Exchange) CurrentPrice(ctx context.Context) (map[model.Coin]model.CurrentPrice, error) {
+	fault := e.call(CurrentPrice)
+	if err := e.delay(ctx, fault); err != nil {
+		return nil, fmt.Errorf("could not get prices: %w", err)
+	}
+	if e.fail(CurrentPrice, fault) {
+		return nil, fmt.Errorf("could not get prices: %w", ErrInjected)
+	}
+	prices, err := e.exchange.CurrentPrice(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if e.drop(CurrentPrice, fault) {
+		return nil, fmt.Errorf("could not get prices: %w", ErrDropped)
+	}
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	if e.prices != nil && e.stale(CurrentPrice, fault) {
+		return e.prices, nil
+	}
+	e.prices = prices
+	if len(prices) > 0 && e.partial(CurrentPrice, fault) {
+		coins := make([]model.Coin, 0, len(prices))
+		for coin := range prices {
+			coins = append(coins, coin)
+		}
+		miss := e.miss(coins)
+		partial := make(map[model.Coin]model.CurrentPrice)
+		for coin, p := range prices {
+			if coin != miss {
+				partial[coin] = p
+			}
+		}
+		return partial, nil
+	}
+	return prices, nil
+}
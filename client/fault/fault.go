@@ -0,0 +1,172 @@
+package fault
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"sync"
+	"time"
+
+	cointime "github.com/drakos74/free-coin/internal/time"
+)
+
+const (
+	// OpenPositions is the exchange method returning the open positions.
+	OpenPositions = "open-positions"
+	// OpenOrder is the exchange method submitting an order.
+	OpenOrder = "open-order"
+	// Balance is the exchange method returning the account balance.
+	Balance = "balance"
+	// Pairs is the exchange method returning the traded pairs.
+	Pairs = "pairs"
+	// CurrentPrice is the exchange method returning the current prices.
+	CurrentPrice = "current-price"
+	// QueryOrder is the exchange method looking up an order by its client id.
+	QueryOrder = "query-order"
+	// Trades is the client method streaming the trades.
+	Trades = "trades"
+)
+
+// ErrInjected is the error of the injected faults.
+var ErrInjected = errors.New("injected fault")
+
+// ErrDropped is the error of a call , for which the exchange response was lost.
+var ErrDropped = errors.New("dropped response")
+
+// ErrNotSupported is the error of a method , that the wrapped exchange does not implement.
+var ErrNotSupported = errors.New("not supported")
+
+// Fault defines the faults injected on a method , the probabilities are from 0 to 1.
+// Not every fault applies to every method e.g. only orders are duplicated.
+type Fault struct {
+	// Error fails the call , without reaching the exchange.
+	Error float64
+	// Drop loses the response , after the exchange has executed the call.
+	Drop float64
+	// Duplicate executes the order twice on the exchange e.g. a duplicated fill ,
+	// or emits the trade twice.
+	Duplicate float64
+	// Partial removes part of the response e.g. some of the positions.
+	Partial float64
+	// Stale returns the previous response e.g. the previous prices.
+	Stale float64
+	// Latency delays the call for a random duration up to the given one.
+	Latency time.Duration
+}
+
+// Counts are the calls and the injected faults for a method.
+type Counts struct {
+	Calls      int
+	Errors     int
+	Drops      int
+	Duplicates int
+	Partials   int
+	Stale      int
+}
+
+// injector rolls the faults for each method with a seeded random source ,
+// so that a faulty run can be reproduced.
+type injector struct {
+	lock   *sync.Mutex
+	rnd    *rand.Rand
+	faults map[string]Fault
+	counts map[string]Counts
+}
+
+func newInjector(seed int64) *injector {
+	return &injector{
+		lock:   new(sync.Mutex),
+		rnd:    rand.New(rand.NewSource(seed)),
+		faults: make(map[string]Fault),
+		counts: make(map[string]Counts),
+	}
+}
+
+func (i *injector) with(method string, fault Fault) {
+	i.lock.Lock()
+	defer i.lock.Unlock()
+	i.faults[method] = fault
+}
+
+// Counts returns the calls and the injected faults for the method.
+func (i *injector) Counts(method string) Counts {
+	i.lock.Lock()
+	defer i.lock.Unlock()
+	return i.counts[method]
+}
+
+// fault returns the fault for the method.
+func (i *injector) fault(method string) Fault {
+	i.lock.Lock()
+	defer i.lock.Unlock()
+	return i.faults[method]
+}
+
+// call counts the call and returns the fault for the method.
+func (i *injector) call(method string) Fault {
+	i.lock.Lock()
+	defer i.lock.Unlock()
+	counts := i.counts[method]
+	counts.Calls++
+	i.counts[method] = counts
+	return i.faults[method]
+}
+
+// roll returns true with the given probability , and counts the injected fault.
+func (i *injector) roll(method string, p float64, count func(c *Counts)) bool {
+	if p <= 0 {
+		return false
+	}
+	i.lock.Lock()
+	defer i.lock.Unlock()
+	if i.rnd.Float64() >= p {
+		return false
+	}
+	counts := i.counts[method]
+	count(&counts)
+	i.counts[method] = counts
+	return true
+}
+
+func (i *injector) fail(method string, fault Fault) bool {
+	return i.roll(method, fault.Error, func(c *Counts) { c.Errors++ })
+}
+
+func (i *injector) drop(method string, fault Fault) bool {
+	return i.roll(method, fault.Drop, func(c *Counts) { c.Drops++ })
+}
+
+func (i *injector) duplicate(method string, fault Fault) bool {
+	return i.roll(method, fault.Duplicate, func(c *Counts) { c.Duplicates++ })
+}
+
+func (i *injector) partial(method string, fault Fault) bool {
+	return i.roll(method, fault.Partial, func(c *Counts) { c.Partials++ })
+}
+
+func (i *injector) stale(method string, fault Fault) bool {
+	return i.roll(method, fault.Stale, func(c *Counts) { c.Stale++ })
+}
+
+// intn returns a random index up to n.
+func (i *injector) intn(n int) int {
+	i.lock.Lock()
+	defer i.lock.Unlock()
+	return i.rnd.Intn(n)
+}
+
+// delay waits for a random duration up to the fault latency , or until the context is done.
+func (i *injector) delay(ctx context.Context, fault Fault) error {
+	if fault.Latency <= 0 {
+		return nil
+	}
+	i.lock.Lock()
+	d := time.Duration(i.rnd.Int63n(int64(fault.Latency)))
+	i.lock.Unlock()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-cointime.Current().After(d):
+		return nil
+	}
+}
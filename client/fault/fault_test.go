@@ -0,0 +1,214 @@
+package fault
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/drakos74/free-coin/client/local"
+	"github.com/drakos74/free-coin/client/synthetic"
+	"github.com/drakos74/free-coin/internal/api"
+	"github.com/drakos74/free-coin/internal/model"
+	"github.com/stretchr/testify/assert"
+)
+
+var _ api.Exchange = &Exchange{}
+var _ api.Client = &Client{}
+var _ api.OrderTracker = &Exchange{}
+
+func order() *model.TrackedOrder {
+	return model.NewOrder(model.BTC).
+		Market().
+		WithType(model.Buy).
+		WithVolume(1).
+		CreateTracked(model.Key{Coin: model.BTC}, time.Now(), "")
+}
+
+func TestExchange_OpenOrder(t *testing.T) {
+
+	type test struct {
+		fault  Fault
+		err    error
+		orders int
+		counts Counts
+	}
+
+	tests := map[string]test{
+		"no-fault": {
+			orders: 1,
+			counts: Counts{Calls: 1},
+		},
+		"error": {
+			fault:  Fault{Error: 1},
+			err:    ErrInjected,
+			counts: Counts{Calls: 1, Errors: 1},
+		},
+		"drop": {
+			fault:  Fault{Drop: 1},
+			err:    ErrDropped,
+			orders: 1,
+			counts: Counts{Calls: 1, Drops: 1},
+		},
+		"duplicate": {
+			fault:  Fault{Duplicate: 1},
+			orders: 2,
+			counts: Counts{Calls: 1, Duplicates: 1},
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			upstream := local.NewExchange(local.VoidLog)
+			exchange := NewExchange(upstream, 1).With(OpenOrder, tt.fault)
+			_, _, err := exchange.OpenOrder(order())
+			assert.True(t, errors.Is(err, tt.err))
+			assert.Equal(t, tt.orders, len(upstream.Orders()))
+			assert.Equal(t, tt.counts, exchange.Counts(OpenOrder))
+		})
+	}
+}
+
+func TestExchange_QueryOrder(t *testing.T) {
+	upstream := local.NewExchange(local.VoidLog)
+	exchange := NewExchange(upstream, 1).With(OpenOrder, Fault{Drop: 1})
+
+	submitted := order()
+	submitted.CID = model.NewClientID("test", "order")
+	_, _, err := exchange.OpenOrder(submitted)
+	assert.True(t, errors.Is(err, ErrDropped))
+
+	// the dropped order is found by its client id
+	found, ok, err := exchange.QueryOrder(context.Background(), submitted)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, submitted.CID, found.CID)
+
+	other := order()
+	other.CID = model.NewClientID("test", "other")
+	_, ok, err = exchange.QueryOrder(context.Background(), other)
+	assert.NoError(t, err)
+	assert.False(t, ok)
+
+	exchange.With(QueryOrder, Fault{Error: 1})
+	_, _, err = exchange.QueryOrder(context.Background(), submitted)
+	assert.True(t, errors.Is(err, ErrInjected))
+
+	// hide the order tracking of the wrapped exchange
+	untracked := struct{ api.Exchange }{upstream}
+	_, _, err = NewExchange(untracked, 1).QueryOrder(context.Background(), submitted)
+	assert.True(t, errors.Is(err, ErrNotSupported))
+}
+
+func TestExchange_Reproducible(t *testing.T) {
+	run := func(seed int64) []bool {
+		exchange := NewExchange(local.NewExchange(local.VoidLog), seed).With(OpenOrder, Fault{Error: 0.5})
+		results := make([]bool, 100)
+		for i := range results {
+			_, _, err := exchange.OpenOrder(order())
+			results[i] = err == nil
+		}
+		return results
+	}
+	assert.Equal(t, run(1), run(1))
+	assert.NotEqual(t, run(1), run(2))
+
+	exchange := NewExchange(local.NewExchange(local.VoidLog), 1).With(OpenOrder, Fault{Error: 0.2})
+	for i := 0; i < 1000; i++ {
+		_, _, _ = exchange.OpenOrder(order())
+	}
+	assert.InDelta(t, 200, exchange.Counts(OpenOrder).Errors, 40)
+}
+
+func TestExchange_Responses(t *testing.T) {
+	paper := local.NewPaper("paper", 1000)
+	paper.Process(&model.TradeSignal{Coin: model.BTC, Tick: model.NewTick(100, 1, model.Buy, time.Now())})
+	paper.Process(&model.TradeSignal{Coin: model.ETH, Tick: model.NewTick(10, 1, model.Buy, time.Now())})
+	exchange := NewExchange(paper, 1)
+
+	// a partial response misses one coin
+	exchange.With(CurrentPrice, Fault{Partial: 1})
+	prices, err := exchange.CurrentPrice(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(prices))
+
+	// a stale response returns the previous prices
+	exchange.With(CurrentPrice, Fault{Stale: 1})
+	paper.Process(&model.TradeSignal{Coin: model.BTC, Tick: model.NewTick(110, 1, model.Buy, time.Now())})
+	prices, err = exchange.CurrentPrice(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(prices))
+	assert.Equal(t, 100.0, prices[model.BTC].Price)
+	exchange.With(CurrentPrice, Fault{})
+	prices, err = exchange.CurrentPrice(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 110.0, prices[model.BTC].Price)
+
+	// the latency respects the context
+	exchange.With(Balance, Fault{Latency: time.Hour})
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err = exchange.Balance(ctx, nil)
+	assert.True(t, errors.Is(err, context.DeadlineExceeded))
+
+	exchange.With(Pairs, Fault{Error: 1})
+	assert.Equal(t, 0, len(exchange.Pairs(context.Background())))
+}
+
+func TestClient_Trades(t *testing.T) {
+
+	type test struct {
+		fault  Fault
+		trades int
+		stale  bool
+	}
+
+	tests := map[string]test{
+		"no-fault": {
+			trades: 100,
+		},
+		"drop": {
+			fault:  Fault{Drop: 1},
+			trades: 0,
+		},
+		"duplicate": {
+			fault:  Fault{Duplicate: 1},
+			trades: 200,
+		},
+		"stale": {
+			fault:  Fault{Stale: 1},
+			trades: 100,
+			stale:  true,
+		},
+	}
+
+	start := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			market := synthetic.New(1, start, time.Minute, 100).WithMarket(
+				synthetic.NewMarket(model.BTC, 100, synthetic.GBM(0, 0.1)),
+			)
+			client := NewClient(market, 1).With(tt.fault)
+			process := make(chan api.Signal)
+			source, err := client.Trades(process)
+			assert.NoError(t, err)
+			trades := make([]*model.TradeSignal, 0)
+			for trade := range source {
+				trades = append(trades, trade)
+				process <- api.Signal{}
+			}
+			assert.Equal(t, tt.trades, len(trades))
+			if tt.stale {
+				// every trade has the price of the previous one
+				path := market.Path(model.BTC)
+				for i := 1; i < len(trades); i++ {
+					assert.Equal(t, path[i].Time, trades[i].Tick.Time)
+					assert.Equal(t, path[i-1].Price, trades[i].Tick.Price)
+				}
+			}
+		})
+	}
+
+	_, err := NewClient(synthetic.New(1, start, time.Minute, 100), 1).With(Fault{Error: 1}).Trades(make(chan api.Signal))
+	assert.True(t, errors.Is(err, ErrInjected))
+}
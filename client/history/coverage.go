@@ -0,0 +1,56 @@
+package history
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/drakos74/free-coin/internal/algo/processor/quality"
+	"github.com/drakos74/free-coin/internal/model"
+	"github.com/drakos74/free-coin/internal/storage"
+)
+
+// Coverage reports the data quality of the stored history ranges of the coin for the given period ,
+// with one entry for each stored range and for each missing one.
+func (h *History) Coverage(coin model.Coin, from, to time.Time, config quality.Config) ([]quality.Coverage, error) {
+	first, err := h.deKey(h.key(from))
+	if err != nil {
+		return nil, fmt.Errorf("could not find range for '%s': %w", from, err)
+	}
+	ranges := h.Ranges(coin, first, to)
+	sort.Slice(ranges, func(i, j int) bool {
+		if !ranges[i].From.Equal(ranges[j].From) {
+			return ranges[i].From.Before(ranges[j].From)
+		}
+		return ranges[i].Path < ranges[j].Path
+	})
+
+	size := 4 * secondsInAnHour * time.Second
+	coverage := make([]quality.Coverage, 0)
+	next := first
+	for _, rg := range ranges {
+		for ; next.Before(rg.From); next = next.Add(size) {
+			coverage = append(coverage, quality.Coverage{From: next, To: next.Add(size)})
+		}
+		trades := []model.TradeSignal{{}}
+		err := h.registry.GetAll(storage.K{
+			Pair:  string(coin),
+			Label: rg.Path,
+		}, &trades)
+		if err != nil {
+			return nil, fmt.Errorf("could not load range '%s': %w", rg.Path, err)
+		}
+		valid := make([]model.TradeSignal, 0)
+		for _, trade := range trades {
+			if !trade.Tick.Time.IsZero() {
+				valid = append(valid, trade)
+			}
+		}
+		coverage = append(coverage, quality.NewCoverage(rg.Path, rg.From, rg.From.Add(size), valid, config))
+		next = rg.From.Add(size)
+	}
+	for ; !next.After(to); next = next.Add(size) {
+		coverage = append(coverage, quality.Coverage{From: next, To: next.Add(size)})
+	}
+	return coverage, nil
+}
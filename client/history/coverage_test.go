@@ -0,0 +1,40 @@
+package history
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/drakos74/free-coin/internal/algo/processor/quality"
+	"github.com/drakos74/free-coin/internal/model"
+	"github.com/drakos74/free-coin/internal/storage"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHistory_Coverage(t *testing.T) {
+	r := registry{MockRegistry: storage.NewMockRegistry()}
+	importer, err := NewImporter(KrakenFormat, model.BTC, r)
+	assert.NoError(t, err)
+	// 2021-01-01 00:00 , 00:00 again , 01:00 and 08:00 UTC
+	_, err = importer.Import(strings.NewReader(`1609459200,25000,0.5
+1609459200,25000,0.5
+1609462800,25100,0.25
+1609488000,25200,1`))
+	assert.NoError(t, err)
+
+	from := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	coverage, err := New(nil).WithRegistry(r).Coverage(model.BTC, from, from.Add(12*time.Hour), quality.DefaultConfig())
+	assert.NoError(t, err)
+	assert.Equal(t, 4, len(coverage))
+
+	assert.Equal(t, "kraken_BTC_111768", coverage[0].Path)
+	assert.Equal(t, 3, coverage[0].Counts.Trades)
+	assert.Equal(t, 1, coverage[0].Counts.Anomalies[quality.Duplicate])
+	assert.Equal(t, 1, coverage[0].Counts.Anomalies[quality.Gap])
+	assert.Equal(t, 0.25, coverage[0].Ratio())
+
+	assert.True(t, coverage[1].Missing())
+	assert.Equal(t, from.Add(4*time.Hour), coverage[1].From.UTC())
+	assert.Equal(t, 1, coverage[2].Counts.Trades)
+	assert.True(t, coverage[3].Missing())
+}
@@ -0,0 +1,247 @@
+package history
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/drakos74/free-coin/internal/model"
+	"github.com/drakos74/free-coin/internal/storage"
+	"github.com/rs/zerolog/log"
+)
+
+// Format is the csv format of an exchange trade archive.
+type Format string
+
+const (
+	// KrakenFormat is the kraken time and sales format i.e. 'timestamp,price,volume' ,
+	// with the timestamp in unix seconds.
+	KrakenFormat Format = "kraken"
+	// BinanceFormat is the binance aggTrades format i.e.
+	// 'agg_trade_id,price,quantity,first_trade_id,last_trade_id,transact_time,is_buyer_maker[,is_best_match]' ,
+	// with the transact time in unix milli or micro seconds.
+	BinanceFormat Format = "binance"
+)
+
+// Progress reports the progress of an import.
+// Skipped are the trades falling in ranges that existed before the import ,
+// and Invalid the lines that could not be parsed.
+type Progress struct {
+	Lines    int
+	Imported int
+	Skipped  int
+	Invalid  int
+	From     time.Time
+	To       time.Time
+}
+
+func (p Progress) String() string {
+	return fmt.Sprintf("lines = %d , imported = %d , skipped = %d , invalid = %d , from = %s , to = %s",
+		p.Lines, p.Imported, p.Skipped, p.Invalid, p.From.Format(time.RFC3339), p.To.Format(time.RFC3339))
+}
+
+// Verification reports the history ranges covering an imported period.
+// Missing are the ranges without any trades , within the period.
+type Verification struct {
+	Ranges  int
+	From    time.Time
+	To      time.Time
+	Missing []time.Time
+}
+
+// Importer imports the csv trade archives of an exchange into the history registry ,
+// with the same layout as the one the History writes i.e. '{Exchange}_{Coin}_{time-hash}'.
+type Importer struct {
+	history  *History
+	format   Format
+	coin     model.Coin
+	every    int
+	progress func(progress Progress)
+}
+
+// NewImporter creates a new importer for the trades of the coin in the given format.
+func NewImporter(format Format, coin model.Coin, registry storage.Registry) (*Importer, error) {
+	switch format {
+	case KrakenFormat, BinanceFormat:
+	default:
+		return nil, fmt.Errorf("unknown format '%s'", format)
+	}
+	if coin == model.NoCoin {
+		return nil, errors.New("no coin given")
+	}
+	return &Importer{
+		history:  New(nil).WithRegistry(registry),
+		format:   format,
+		coin:     coin,
+		progress: func(progress Progress) {},
+	}, nil
+}
+
+// WithProgress reports the progress of the import every given number of lines.
+func (i *Importer) WithProgress(every int, report func(progress Progress)) *Importer {
+	i.every = every
+	i.progress = report
+	return i
+}
+
+// Import imports the trades of the csv archive.
+// The ranges that already exist in the registry before the import are skipped ,
+// so that importing overlapping archives does not duplicate the trades.
+func (i *Importer) Import(r io.Reader) (Progress, error) {
+	existing := make(map[string]bool)
+	for _, rg := range i.history.Ranges(i.coin, time.Time{}, time.Date(3000, 1, 1, 0, 0, 0, 0, time.UTC)) {
+		existing[rg.Path] = true
+	}
+
+	progress := Progress{}
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.ReuseRecord = true
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		progress.Lines++
+		if err != nil {
+			progress.Invalid++
+			continue
+		}
+		trade, err := i.parse(record)
+		if err != nil {
+			// the archives might come with a header
+			if progress.Lines > 1 {
+				progress.Invalid++
+				log.Debug().Err(err).Int("line", progress.Lines).Msg("could not parse trade")
+			}
+			continue
+		}
+		key := Key{
+			Coin:     i.coin,
+			Exchange: trade.Meta.Exchange,
+			Key:      i.history.key(trade.Meta.Time),
+		}
+		if existing[key.String()] {
+			progress.Skipped++
+		} else {
+			err = i.history.registry.Add(storage.K{
+				Pair:  string(i.coin),
+				Label: key.String(),
+			}, trade)
+			if err != nil {
+				return progress, fmt.Errorf("could not store trade at line %d: %w", progress.Lines, err)
+			}
+			progress.Imported++
+		}
+		if progress.From.IsZero() || trade.Meta.Time.Before(progress.From) {
+			progress.From = trade.Meta.Time
+		}
+		if trade.Meta.Time.After(progress.To) {
+			progress.To = trade.Meta.Time
+		}
+		if i.every > 0 && progress.Lines%i.every == 0 {
+			i.progress(progress)
+		}
+	}
+	i.progress(progress)
+	return progress, nil
+}
+
+// Verify checks the history ranges covering the given period , through the History ranges.
+func (i *Importer) Verify(from, to time.Time) Verification {
+	first, err := i.history.deKey(i.history.key(from))
+	if err != nil {
+		return Verification{}
+	}
+	ranges := i.history.Ranges(i.coin, first, to)
+	exchange := string(i.format)
+	present := make(map[int64]bool)
+	for _, rg := range ranges {
+		if strings.HasPrefix(rg.Path, exchange) {
+			present[rg.From.Unix()] = true
+		}
+	}
+	verification := Verification{
+		Ranges:  len(present),
+		From:    from,
+		To:      to,
+		Missing: make([]time.Time, 0),
+	}
+	for t := first; !t.After(to); t = t.Add(4 * secondsInAnHour * time.Second) {
+		if !present[t.Unix()] {
+			verification.Missing = append(verification.Missing, t)
+		}
+	}
+	return verification
+}
+
+func (i *Importer) parse(record []string) (*model.TradeSignal, error) {
+	switch i.format {
+	case KrakenFormat:
+		if len(record) < 3 {
+			return nil, fmt.Errorf("expected 3 fields but got %d", len(record))
+		}
+		seconds, err := strconv.ParseFloat(record[0], 64)
+		if err != nil {
+			return nil, fmt.Errorf("could not parse time '%s': %w", record[0], err)
+		}
+		t := time.Unix(0, int64(seconds*float64(time.Second)))
+		return i.trade(t, record[1], record[2], model.NoType)
+	case BinanceFormat:
+		if len(record) < 7 {
+			return nil, fmt.Errorf("expected 7 fields but got %d", len(record))
+		}
+		stamp, err := strconv.ParseInt(record[5], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("could not parse time '%s': %w", record[5], err)
+		}
+		t := time.UnixMilli(stamp)
+		// the newer archives use micro seconds
+		if stamp > 1e15 {
+			t = time.UnixMicro(stamp)
+		}
+		maker, err := strconv.ParseBool(record[6])
+		if err != nil {
+			return nil, fmt.Errorf("could not parse buyer maker flag '%s': %w", record[6], err)
+		}
+		// if the buyer is the maker , the trade was initiated by the seller
+		tradeType := model.Buy
+		if maker {
+			tradeType = model.Sell
+		}
+		return i.trade(t, record[1], record[2], tradeType)
+	}
+	return nil, fmt.Errorf("unknown format '%s'", i.format)
+}
+
+func (i *Importer) trade(t time.Time, price, volume string, tradeType model.Type) (*model.TradeSignal, error) {
+	p, err := strconv.ParseFloat(price, 64)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse price '%s': %w", price, err)
+	}
+	v, err := strconv.ParseFloat(volume, 64)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse volume '%s': %w", volume, err)
+	}
+	t = t.UTC()
+	return &model.TradeSignal{
+		Coin: i.coin,
+		Tick: model.Tick{
+			Level: model.Level{
+				Price:  p,
+				Volume: v,
+			},
+			Type: tradeType,
+			Time: t,
+		},
+		Meta: model.Meta{
+			Time:     t,
+			Unix:     t.Unix(),
+			Exchange: string(i.format),
+		},
+	}, nil
+}
@@ -0,0 +1,122 @@
+package history
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/drakos74/free-coin/internal/model"
+	"github.com/drakos74/free-coin/internal/storage"
+	"github.com/stretchr/testify/assert"
+)
+
+// registry is an in-memory registry , exposing the labels of each pair like the file registry.
+type registry struct {
+	*storage.MockRegistry
+}
+
+func (r registry) GetAll(key storage.K, value interface{}) error {
+	b, err := json.Marshal(r.Events[key])
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, value)
+}
+
+func (r registry) Check(key storage.K) (map[string]storage.RegistryPath, error) {
+	paths := make(map[string]storage.RegistryPath)
+	for k := range r.Events {
+		if k.Pair == key.Pair {
+			paths[k.Label] = storage.RegistryPath{Name: k.Label, Files: []string{k.Label}}
+		}
+	}
+	return paths, nil
+}
+
+const (
+	// 2021-01-01 00:00 , 02:00 and 05:00 UTC
+	krakenArchive = `1609459200,25000.1,0.5
+1609466400.5,25100,0.25
+not,a,trade
+1609477200,25200,1`
+	binanceArchive = `agg_trade_id,price,quantity,first_trade_id,last_trade_id,transact_time,is_buyer_maker,is_best_match
+1,29000.5,0.1,1,1,1609459200000,true,true
+2,29001,0.2,2,3,1609466400000,false,true
+3,29002,0.3,4,4,1609477200000000,false,true`
+)
+
+func TestImporter_Import(t *testing.T) {
+
+	type test struct {
+		format   Format
+		archive  string
+		progress Progress
+		types    []model.Type
+	}
+
+	from := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2021, 1, 1, 5, 0, 0, 0, time.UTC)
+
+	tests := map[string]test{
+		"kraken": {
+			format:   KrakenFormat,
+			archive:  krakenArchive,
+			progress: Progress{Lines: 4, Imported: 3, Invalid: 1, From: from, To: to},
+			types:    []model.Type{model.NoType, model.NoType},
+		},
+		"binance": {
+			format:   BinanceFormat,
+			archive:  binanceArchive,
+			progress: Progress{Lines: 4, Imported: 3, From: from, To: to},
+			types:    []model.Type{model.Sell, model.Buy},
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			r := registry{MockRegistry: storage.NewMockRegistry()}
+			reports := 0
+			importer, err := NewImporter(tt.format, model.BTC, r)
+			assert.NoError(t, err)
+			importer.WithProgress(2, func(progress Progress) {
+				reports++
+			})
+
+			progress, err := importer.Import(strings.NewReader(tt.archive))
+			assert.NoError(t, err)
+			assert.Equal(t, tt.progress, progress)
+			assert.Equal(t, 3, reports)
+
+			// the trades are grouped in 4h ranges
+			first := storage.K{Pair: string(model.BTC), Label: string(tt.format) + "_BTC_111768"}
+			second := storage.K{Pair: string(model.BTC), Label: string(tt.format) + "_BTC_111769"}
+			assert.Equal(t, 2, len(r.Events))
+			assert.Equal(t, 2, len(r.Events[first]))
+			assert.Equal(t, 1, len(r.Events[second]))
+			for i, tp := range tt.types {
+				trade := r.Events[first][i].(*model.TradeSignal)
+				assert.Equal(t, tp, trade.Tick.Type)
+				assert.Equal(t, string(tt.format), trade.Meta.Exchange)
+			}
+
+			// importing again skips the existing ranges
+			progress, err = importer.Import(strings.NewReader(tt.archive))
+			assert.NoError(t, err)
+			assert.Equal(t, 0, progress.Imported)
+			assert.Equal(t, 3, progress.Skipped)
+			assert.Equal(t, 2, len(r.Events[first]))
+
+			verification := importer.Verify(from, to.Add(4*time.Hour))
+			assert.Equal(t, 2, verification.Ranges)
+			assert.Equal(t, []time.Time{time.Unix(to.Add(3*time.Hour).Unix(), 0)}, verification.Missing)
+		})
+	}
+}
+
+func TestNewImporter(t *testing.T) {
+	_, err := NewImporter("coinbase", model.BTC, storage.NewMockRegistry())
+	assert.Error(t, err)
+	_, err = NewImporter(KrakenFormat, model.NoCoin, storage.NewMockRegistry())
+	assert.Error(t, err)
+}
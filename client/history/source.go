@@ -52,7 +52,7 @@ func (s *source) Trades(process <-chan api.Signal) (model.TradeSource, error) {
 			close(out)
 		}()
 		for _, t := range trades {
-			if t.Time.After(s.request.From) && t.Time.Before(s.request.To) {
+			if t.Meta.Time.After(s.request.From) && t.Meta.Time.Before(s.request.To) {
 				out <- &t
 				<-process
 			}
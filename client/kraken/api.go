@@ -1,10 +1,15 @@
 package kraken
 
 import (
+	"context"
 	"fmt"
+	"hash/fnv"
+	"math"
 	"strconv"
+	"strings"
 
 	krakenapi "github.com/beldur/kraken-go-api-client"
+	"github.com/drakos74/free-coin/client/limit"
 	coinmodel "github.com/drakos74/free-coin/internal/model"
 	"github.com/rs/zerolog/log"
 )
@@ -14,12 +19,21 @@ type RemoteExchange struct {
 	*baseSource
 	private *krakenapi.KrakenAPI
 	info    map[coinmodel.Coin]krakenapi.AssetPairInfo
+	limiter *limit.Limiter
+	public  *limit.Limiter
 }
 
 func (r *RemoteExchange) getInfo() {
+	// the asset pairs are public
+	if err := r.public.Wait(context.Background(), limit.Sync, callCost); err != nil {
+		log.Error().Err(err).Str("exchange", "kraken").Msg("could not get exchange info")
+		return
+	}
 	pairs, err := r.private.AssetPairs()
+	throttled(r.public, err)
 	if err != nil {
-		log.Error().Str("exchange", "kraken").Msg("could not get exchange info")
+		log.Error().Err(err).Str("exchange", "kraken").Msg("could not get exchange info")
+		return
 	}
 
 	symbols := make(map[coinmodel.Coin]krakenapi.AssetPairInfo)
@@ -67,6 +81,10 @@ func (r *RemoteExchange) Order(order coinmodel.Order) (*coinmodel.Order, []strin
 		params["leverage"] = r.converter.Leverage.For(order)
 	}
 
+	if order.CID != "" {
+		params["userref"] = userRef(order.CID)
+	}
+
 	if order.Price > 0 {
 		// TODO : make per coin as for binance
 		params["price"] = strconv.FormatFloat(order.Price, 'f', 2, 64)
@@ -76,12 +94,16 @@ func (r *RemoteExchange) Order(order coinmodel.Order) (*coinmodel.Order, []strin
 	oType := r.converter.OrderType.From(order.OType)
 
 	vol := strconv.FormatFloat(order.Volume, 'f', s.LotDecimals, 64)
+	if err := r.limiter.Wait(context.Background(), limit.Order, orderCost); err != nil {
+		return nil, nil, fmt.Errorf("could not add order: %w", err)
+	}
 	response, err := r.private.AddOrder(
 		pair.Rest,
 		direction,
 		oType,
 		vol,
 		params)
+	throttled(r.limiter, err)
 	if err != nil {
 		return nil, nil, fmt.Errorf("could not add order '%+v' - (pair=%s,direction=%s,orderType=%s,volume=%s,decimals=%d)  : %w | %+v",
 			order,
@@ -91,3 +113,78 @@ func (r *RemoteExchange) Order(order coinmodel.Order) (*coinmodel.Order, []strin
 
 	return r.newOrder(response.Description), response.TransactionIds, nil
 }
+
+// Fill returns the execution of the orders with the given transaction ids ,
+// or false if they are not executed yet.
+func (r *RemoteExchange) Fill(ctx context.Context, txIDs []string) (*coinmodel.Fill, bool, error) {
+	if len(txIDs) == 0 {
+		return nil, false, nil
+	}
+	if err := r.limiter.Wait(ctx, limit.Order, callCost); err != nil {
+		return nil, false, fmt.Errorf("could not query orders: %w", err)
+	}
+	response, err := r.private.QueryOrders(strings.Join(txIDs, ","), map[string]string{})
+	throttled(r.limiter, err)
+	if err != nil {
+		return nil, false, fmt.Errorf("could not query orders: %w", err)
+	}
+	fill := new(coinmodel.Fill)
+	cost := 0.0
+	for _, order := range *response {
+		if order.Status != "closed" {
+			return nil, false, nil
+		}
+		fill.Volume += order.VolumeExecuted
+		fill.Fees += order.Fee
+		cost += order.Cost
+	}
+	if fill.Volume == 0 {
+		return nil, false, nil
+	}
+	fill.Price = cost / fill.Volume
+	return fill, true, nil
+}
+
+// userRef maps the client order id to the kraken user reference , which is a positive 32-bit integer.
+func userRef(cid string) string {
+	h := fnv.New32a()
+	h.Write([]byte(cid))
+	return strconv.FormatUint(uint64(h.Sum32()&math.MaxInt32), 10)
+}
+
+// Query returns the orders with the user reference of the client order id ,
+// that are open or closed without being canceled.
+func (r *RemoteExchange) Query(ctx context.Context, cid string) ([]string, error) {
+	ref := userRef(cid)
+	params := map[string]string{
+		"userref": ref,
+	}
+	if err := r.limiter.Wait(ctx, limit.Order, callCost); err != nil {
+		return nil, fmt.Errorf("could not get open orders: %w", err)
+	}
+	open, err := r.private.OpenOrders(params)
+	throttled(r.limiter, err)
+	if err != nil {
+		return nil, fmt.Errorf("could not get open orders: %w", err)
+	}
+	if err := r.limiter.Wait(ctx, limit.Order, historyCost); err != nil {
+		return nil, fmt.Errorf("could not get closed orders: %w", err)
+	}
+	closed, err := r.private.ClosedOrders(params)
+	throttled(r.limiter, err)
+	if err != nil {
+		return nil, fmt.Errorf("could not get closed orders: %w", err)
+	}
+	txIDs := make([]string, 0)
+	for txID, order := range open.Open {
+		if strconv.Itoa(order.UserRef) == ref {
+			txIDs = append(txIDs, txID)
+		}
+	}
+	for txID, order := range closed.Closed {
+		if strconv.Itoa(order.UserRef) == ref && order.Status == "closed" {
+			txIDs = append(txIDs, txID)
+		}
+	}
+	return txIDs, nil
+}
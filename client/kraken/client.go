@@ -2,10 +2,17 @@ package kraken
 
 import (
 	"context"
+	"fmt"
+	"math"
 	"strconv"
+	"sync"
 	"time"
 
 	krakenapi "github.com/beldur/kraken-go-api-client"
+	"github.com/drakos74/free-coin/client"
+	"github.com/drakos74/free-coin/client/kraken/model"
+	"github.com/drakos74/free-coin/client/limit"
+	"github.com/drakos74/free-coin/client/replay"
 	"github.com/drakos74/free-coin/internal/api"
 	"github.com/drakos74/free-coin/internal/metrics"
 	coinmodel "github.com/drakos74/free-coin/internal/model"
@@ -14,6 +21,7 @@ import (
 
 // Client is the exchange client used Max interact with the exchange methods.
 type Client struct {
+	lock          *sync.RWMutex
 	coins         []coinmodel.Coin
 	init          int64
 	since         map[coinmodel.Coin]int64
@@ -33,7 +41,8 @@ type Client struct {
 // i.e. client.WithRemote(NewMockSource("testdata/response-trades"))
 func NewClient(coin ...coinmodel.Coin) *Client {
 	interval := 60 * time.Second
-	client := &Client{
+	c := &Client{
+		lock:          new(sync.RWMutex),
 		coins:         coin,
 		since:         make(map[coinmodel.Coin]int64),
 		interval:      interval,
@@ -42,11 +51,13 @@ func NewClient(coin ...coinmodel.Coin) *Client {
 			baseSource: newSource(),
 			Interval:   interval,
 			public:     krakenapi.New("KEY", "SECRET"),
+			limiter:    limiters.public,
 		},
 		timer:  make(map[coinmodel.Coin]time.Time),
 		socket: NewSocket(coin...),
 	}
-	return client
+	c.socket.WithBackFill(c.backFill)
+	return c
 }
 
 // Since sets the starting point for consuming trades.
@@ -74,12 +85,119 @@ func (c *Client) WithRemote(source Source) *Client {
 	return c
 }
 
-// Live defines the trade pull strategy for the client
+// Live defines the trade pull strategy for the client.
+// If combined with Since , the client replays the history from the rest api before switching to the socket.
 func (c *Client) Live(live bool) *Client {
 	c.live = live
 	return c
 }
 
+// WithBook enables the level 2 order book subscription of the given depth for the live socket.
+func (c *Client) WithBook(depth int) *Client {
+	c.socket.WithBook(depth)
+	return c
+}
+
+// WithRecorder records the raw socket messages and rest responses of the session.
+func (c *Client) WithRecorder(recorder *replay.Recorder) *Client {
+	c.socket.WithRecorder(recorder)
+	if source, ok := c.Source.(*RemoteSource); ok {
+		source.WithRecorder(recorder)
+	}
+	return c
+}
+
+// WithReplay replays a recorded session instead of connecting to kraken ,
+// with the original timing accelerated by the given speed , or as fast as possible for zero speed.
+func (c *Client) WithReplay(messages []replay.Message, speed float64) *Client {
+	c.socket.WithReplay(replay.NewPlayer(replay.Filter(messages, replay.Socket)).WithSpeed(speed))
+	c.Source = NewReplaySource(messages)
+	return c
+}
+
+// WithLimiter sets the rate limiter for the trades polling , by default it is the public limiter shared by the kraken clients.
+func (c *Client) WithLimiter(limiter *limit.Limiter) *Client {
+	if source, ok := c.Source.(*RemoteSource); ok {
+		source.WithLimiter(limiter)
+	}
+	return c
+}
+
+// WithUser reports the live socket connection events to the user.
+func (c *Client) WithUser(index api.Index, user api.User) *Client {
+	c.socket.WithReport(func(event client.Event) {
+		user.Send(index, api.NewMessage(fmt.Sprintf("kraken socket %s", event.String())), nil)
+	})
+	return c
+}
+
+// Pairs loads the tradable pairs from kraken , so that they can be subscribed to at runtime.
+func (c *Client) Pairs() ([]coinmodel.Coin, error) {
+	source, ok := c.Source.(*RemoteSource)
+	if !ok {
+		return nil, fmt.Errorf("asset pairs are not supported by the source")
+	}
+	pairs, err := source.AssetPairs()
+	if err != nil {
+		return nil, fmt.Errorf("could not get asset pairs: %w", err)
+	}
+	return model.LoadPairs(*pairs), nil
+}
+
+// Subscribe adds the given coins to the live socket subscriptions.
+func (c *Client) Subscribe(coins ...coinmodel.Coin) error {
+	if !c.live {
+		return fmt.Errorf("coins can only be added to the live socket")
+	}
+	if err := c.socket.Subscribe(coins...); err != nil {
+		return fmt.Errorf("could not subscribe: %w", err)
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	for _, coin := range coins {
+		if !c.has(coin) {
+			c.coins = append(c.coins, coin)
+		}
+	}
+	return nil
+}
+
+// Unsubscribe removes the given coins from the live socket subscriptions.
+func (c *Client) Unsubscribe(coins ...coinmodel.Coin) error {
+	if !c.live {
+		return fmt.Errorf("coins can only be removed from the live socket")
+	}
+	if err := c.socket.Unsubscribe(coins...); err != nil {
+		return fmt.Errorf("could not unsubscribe: %w", err)
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	cc := make([]coinmodel.Coin, 0, len(c.coins))
+	for _, coin := range c.coins {
+		removed := false
+		for _, r := range coins {
+			if coin == r {
+				removed = true
+			}
+		}
+		if !removed {
+			cc = append(cc, coin)
+		}
+	}
+	c.coins = cc
+	return nil
+}
+
+// has checks if the client is subscribed to the coin , the caller must hold the lock.
+func (c *Client) has(coin coinmodel.Coin) bool {
+	for _, cc := range c.coins {
+		if cc == coin {
+			return true
+		}
+	}
+	return false
+}
+
 // Close closes the client.
 func (c *Client) Close() error {
 	return c.Source.Close()
@@ -91,15 +209,21 @@ func (c *Client) Close() error {
 // returns a channel for consumers to read the trades from.
 func (c *Client) Trades(process <-chan api.Signal) (out coinmodel.TradeSource, err error) {
 	// this is our first run ... so lets make sure we pass in the right since parameter
+	c.lock.Lock()
 	for _, coin := range c.coins {
 		c.since[coin] = c.init
 	}
+	coins := append([]coinmodel.Coin{}, c.coins...)
+	c.lock.Unlock()
 
 	// expose the trades to the outside world
 	out = make(chan *coinmodel.TradeSignal)
 
-	// TODO : load past trades to train the model and then switch to socket ...
 	if c.live {
+		if c.init > 0 {
+			// replay the history first , so that the processors are warmed up when we go live
+			c.socket.WithWarmUp(time.Unix(0, c.init), c.warmUp, coins...)
+		}
 		out, err = c.socket.Run(process)
 	} else {
 		// receive and delegate tick events Max the output
@@ -148,18 +272,7 @@ const (
 )
 
 func (c *Client) execute(i int, trades coinmodel.TradeSource) {
-	if i >= len(c.coins) {
-		i = 0
-	}
-
-	// track how often we make the call for each coin
 	now := time.Now()
-	last := c.timer[c.coins[i]]
-	duration := now.Sub(last).Seconds()
-	f, _ := strconv.ParseFloat(now.Format("20060102.1504"), 64)
-	metrics.Observer.NoteLag(f, string(c.coins[i]), krakenProcessor, sourceProcessor)
-	metrics.Observer.TrackDuration(duration, string(c.coins[i]), krakenProcessor, sourceProcessor)
-	c.timer[c.coins[i]] = now
 	// call itself after the processing finishes
 	defer time.AfterFunc(c.interval, func() {
 		interval := time.Now().Sub(now).Seconds()
@@ -167,9 +280,28 @@ func (c *Client) execute(i int, trades coinmodel.TradeSource) {
 		c.execute(i+1, trades)
 	})
 
-	// do the execution logic for this cycle
+	// the coins can change in the meantime
+	c.lock.Lock()
+	if len(c.coins) == 0 {
+		c.lock.Unlock()
+		return
+	}
+	if i >= len(c.coins) {
+		i = 0
+	}
 	coin := c.coins[i]
+	last := c.timer[coin]
+	c.timer[coin] = now
 	since := c.since[coin]
+	c.lock.Unlock()
+
+	// track how often we make the call for each coin
+	duration := now.Sub(last).Seconds()
+	f, _ := strconv.ParseFloat(now.Format("20060102.1504"), 64)
+	metrics.Observer.NoteLag(f, string(coin), krakenProcessor, sourceProcessor)
+	metrics.Observer.TrackDuration(duration, string(coin), krakenProcessor, sourceProcessor)
+
+	// do the execution logic for this cycle
 	log.Trace().
 		Str("coin", string(coin)).
 		Int64("since", since).
@@ -214,7 +346,57 @@ func (c *Client) execute(i int, trades coinmodel.TradeSource) {
 	//	// TODO : send a message instead and improve the tracker spamming
 	//	log.Info().Time("start", start).Str("coin", string(c.coin)).Str("percent", coinmath.Format(percent)).Msg("progress")
 	//}
+	c.lock.Lock()
 	c.since[coin] = tradeResponse.Index
+	c.lock.Unlock()
+}
+
+const (
+	// backFillBatch is the max number of trades returned by kraken for each call.
+	backFillBatch = 1000
+	// backFillCalls is the max number of calls for a single back-fill.
+	backFillCalls = 10
+)
+
+// backFill retrieves the trades missed by the socket since the given time from the rest api.
+func (c *Client) backFill(coin coinmodel.Coin, since time.Time) ([]coinmodel.TradeSignal, error) {
+	c.lock.RLock()
+	subscribed := c.has(coin)
+	c.lock.RUnlock()
+	if !subscribed {
+		// the coin has been removed in the meantime
+		return []coinmodel.TradeSignal{}, nil
+	}
+	return c.history(coin, since, backFillCalls)
+}
+
+// warmUp retrieves all the trades since the given time from the rest api.
+func (c *Client) warmUp(coin coinmodel.Coin, since time.Time) ([]coinmodel.TradeSignal, error) {
+	return c.history(coin, since, math.MaxInt)
+}
+
+// history retrieves the trades since the given time , paging through the results for up to the given number of calls.
+func (c *Client) history(coin coinmodel.Coin, since time.Time, calls int) ([]coinmodel.TradeSignal, error) {
+	index := since.UnixNano()
+	trades := make([]coinmodel.TradeSignal, 0)
+	for i := 0; i < calls; i++ {
+		batch, err := c.Source.Trades(coin, index)
+		if err != nil {
+			return trades, fmt.Errorf("could not get trades for %s: %w", coin, err)
+		}
+		for _, trade := range batch.Trades {
+			// align with the socket trades
+			trade.Tick.Active = true
+			trade.Meta.Live = true
+			trade.Meta.Exchange = "kraken"
+			trades = append(trades, trade)
+		}
+		if len(batch.Trades) < backFillBatch || batch.Index <= index {
+			break
+		}
+		index = batch.Index
+	}
+	return trades, nil
 }
 
 func (c *Client) CurrentPrice(ctx context.Context) (map[coinmodel.Coin]coinmodel.CurrentPrice, error) {
@@ -7,9 +7,11 @@ import (
 	"time"
 
 	krakenapi "github.com/beldur/kraken-go-api-client"
+	"github.com/drakos74/free-coin/client/limit"
 	"github.com/drakos74/free-coin/internal/account"
 	"github.com/drakos74/free-coin/internal/api"
 	coinmodel "github.com/drakos74/free-coin/internal/model"
+	"github.com/rs/zerolog/log"
 )
 
 // Exchange implements the exchange interface for kraken.
@@ -24,6 +26,8 @@ func Raw(key, secret string) *Exchange {
 		Api: &RemoteExchange{
 			baseSource: newSource(),
 			private:    krakenapi.New(key, secret),
+			limiter:    keyLimiter(key, "raw"),
+			public:     limiters.public,
 		},
 	}
 	exchange.Api.getInfo()
@@ -50,6 +54,8 @@ func NewExchange(user account.Name) api.Exchange {
 		Api: &RemoteExchange{
 			baseSource: newSource(),
 			private:    krakenapi.New(key, secret),
+			limiter:    keyLimiter(key, string(user)),
+			public:     limiters.public,
 		},
 	}
 	exchange.Api.getInfo()
@@ -57,6 +63,12 @@ func NewExchange(user account.Name) api.Exchange {
 	return client
 }
 
+// WithLimiter sets the rate limiter for the private exchange calls , by default it is shared with the exchanges of the same key.
+func (e *Exchange) WithLimiter(limiter *limit.Limiter) *Exchange {
+	e.Api.limiter = limiter
+	return e
+}
+
 func (e *Exchange) ClosePosition(position *coinmodel.Position) error {
 	order := coinmodel.NewOrder(position.Coin).
 		Market().
@@ -72,14 +84,38 @@ func (e *Exchange) ClosePosition(position *coinmodel.Position) error {
 	return nil
 }
 
+// OpenOrder opens the order , and reports its fill if the exchange executed it already.
 func (e *Exchange) OpenOrder(order *coinmodel.TrackedOrder) (*coinmodel.TrackedOrder, []string, error) {
 	_, txids, err := e.Api.Order(order.Order)
 	if err != nil {
 		return order, nil, fmt.Errorf("could not open order: %w", err)
 	}
+	fill, ok, err := e.Api.Fill(context.Background(), txids)
+	if err != nil {
+		log.Warn().Err(err).Strs("txids", txids).Msg("could not get order fill")
+	} else if ok {
+		order.Fill = fill
+	}
 	return order, txids, nil
 }
 
+// QueryOrder looks up the order by the user reference of its client order id.
+func (e *Exchange) QueryOrder(ctx context.Context, order *coinmodel.TrackedOrder) (*coinmodel.TrackedOrder, bool, error) {
+	if order.CID == "" {
+		return nil, false, fmt.Errorf("no client id for order '%s'", order.ID)
+	}
+	txIDs, err := e.Api.Query(ctx, order.CID)
+	if err != nil {
+		return nil, false, fmt.Errorf("could not query order: %w", err)
+	}
+	if len(txIDs) == 0 {
+		return nil, false, nil
+	}
+	found := *order
+	found.TxIDs = txIDs
+	return &found, true, nil
+}
+
 func (e *Exchange) CurrentPrice(ctx context.Context) (map[coinmodel.Coin]coinmodel.CurrentPrice, error) {
 	return make(map[coinmodel.Coin]coinmodel.CurrentPrice), nil
 }
@@ -91,7 +127,11 @@ func (e *Exchange) OpenPositions(ctx context.Context) (*coinmodel.PositionBatch,
 	params := map[string]string{
 		"docalcs": "true",
 	}
+	if err := e.Api.limiter.Wait(ctx, limit.Sync, callCost); err != nil {
+		return nil, fmt.Errorf("could not get positions: %w", err)
+	}
 	response, err := e.Api.private.OpenPositions(params)
+	throttled(e.Api.limiter, err)
 	if err != nil {
 		return nil, fmt.Errorf("could not get positions: %w", err)
 	}
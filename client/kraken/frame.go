@@ -0,0 +1,231 @@
+package kraken
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	ws "github.com/aopoltorzhicky/go_kraken/websocket"
+	"github.com/gorilla/websocket"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// readTimeout is the max time without a frame , kraken sends a heartbeat every second on a subscribed socket.
+	readTimeout = 15 * time.Second
+	// pingInterval is the interval of the pings that keep the connection alive.
+	pingInterval = 10 * time.Second
+)
+
+// frameConn is the websocket connection , reading and writing the raw frames.
+type frameConn interface {
+	ReadMessage() (int, []byte, error)
+	WriteMessage(messageType int, data []byte) error
+	SetReadDeadline(t time.Time) error
+	Close() error
+}
+
+// dialFrames opens the websocket connection to the given url.
+func dialFrames(url string) (frameConn, error) {
+	conn, resp, err := websocket.DefaultDialer.Dial(url, nil)
+	if err != nil {
+		return nil, err
+	}
+	_ = resp.Body.Close()
+	return conn, nil
+}
+
+// frameConnection is the live kraken socket connection , which reads the raw frames itself ,
+// so that they are recorded exactly as kraken sent them , before being decoded into the updates.
+// The updates channel closes when the connection fails , the reconnect is left to the socket supervisor.
+type frameConnection struct {
+	url     string
+	dial    func(url string) (frameConn, error)
+	conn    frameConn
+	lock    *sync.Mutex
+	record  func(update ws.Update, frame []byte)
+	updates chan ws.Update
+	done    chan struct{}
+	once    *sync.Once
+}
+
+func newFrameConnection(url string, record func(update ws.Update, frame []byte)) *frameConnection {
+	return &frameConnection{
+		url:     url,
+		dial:    dialFrames,
+		lock:    new(sync.Mutex),
+		record:  record,
+		updates: make(chan ws.Update, 1024),
+		done:    make(chan struct{}),
+		once:    new(sync.Once),
+	}
+}
+
+func (f *frameConnection) Connect() error {
+	conn, err := f.dial(f.url)
+	if err != nil {
+		return fmt.Errorf("could not dial '%s': %w", f.url, err)
+	}
+	f.conn = conn
+	go f.listen()
+	go f.ping()
+	return nil
+}
+
+// listen reads the frames until the connection fails or is closed.
+// The events e.g. the heartbeats and the subscription status are skipped , only the channel frames carry data.
+func (f *frameConnection) listen() {
+	defer close(f.updates)
+	for {
+		if err := f.conn.SetReadDeadline(time.Now().Add(readTimeout)); err != nil {
+			log.Error().Err(err).Msg("could not set socket read deadline")
+			return
+		}
+		_, frame, err := f.conn.ReadMessage()
+		if err != nil {
+			select {
+			case <-f.done:
+			default:
+				log.Error().Err(err).Msg("could not read socket frame")
+			}
+			return
+		}
+		if len(frame) == 0 || frame[0] != '[' {
+			continue
+		}
+		update, err := decodeFrame(frame)
+		if err != nil {
+			log.Warn().Err(err).Str("frame", string(frame)).Msg("could not decode socket frame")
+			continue
+		}
+		if f.record != nil {
+			f.record(update, frame)
+		}
+		select {
+		case <-f.done:
+			return
+		case f.updates <- update:
+		}
+	}
+}
+
+// ping keeps the connection alive , a failed ping is picked up by the read deadline.
+func (f *frameConnection) ping() {
+	ticker := time.NewTicker(pingInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-f.done:
+			return
+		case <-ticker.C:
+			if err := f.send(ws.PingRequest{Event: ws.EventPing}); err != nil {
+				log.Warn().Err(err).Msg("could not ping socket")
+			}
+		}
+	}
+}
+
+func (f *frameConnection) send(request interface{}) error {
+	data, err := json.Marshal(request)
+	if err != nil {
+		return fmt.Errorf("could not encode request: %w", err)
+	}
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	if f.conn == nil {
+		return fmt.Errorf("socket not connected")
+	}
+	return f.conn.WriteMessage(websocket.TextMessage, data)
+}
+
+func (f *frameConnection) subscribe(event string, pairs []string, subscription ws.Subscription) error {
+	return f.send(ws.SubscriptionRequest{
+		Event:        event,
+		Pairs:        pairs,
+		Subscription: subscription,
+	})
+}
+
+func (f *frameConnection) Close() error {
+	var err error
+	f.once.Do(func() {
+		close(f.done)
+		if f.conn != nil {
+			err = f.conn.Close()
+		}
+	})
+	return err
+}
+
+func (f *frameConnection) Listen() <-chan ws.Update {
+	return f.updates
+}
+
+func (f *frameConnection) SubscribeTicker(pairs []string) error {
+	return f.subscribe(ws.EventSubscribe, pairs, ws.Subscription{Name: ws.ChanTicker})
+}
+
+func (f *frameConnection) SubscribeSpread(pairs []string) error {
+	return f.subscribe(ws.EventSubscribe, pairs, ws.Subscription{Name: ws.ChanSpread})
+}
+
+func (f *frameConnection) SubscribeTrades(pairs []string) error {
+	return f.subscribe(ws.EventSubscribe, pairs, ws.Subscription{Name: ws.ChanTrades})
+}
+
+func (f *frameConnection) SubscribeBook(pairs []string, depth int64) error {
+	return f.subscribe(ws.EventSubscribe, pairs, ws.Subscription{Name: ws.ChanBook, Depth: depth})
+}
+
+func (f *frameConnection) Unsubscribe(channelType string, pairs []string) error {
+	return f.subscribe(ws.EventUnsubscribe, pairs, ws.Subscription{Name: channelType})
+}
+
+func (f *frameConnection) UnsubscribeBook(pairs []string, depth int64) error {
+	return f.subscribe(ws.EventUnsubscribe, pairs, ws.Subscription{Name: ws.ChanBook, Depth: depth})
+}
+
+// decodeFrame decodes a raw channel frame to the update , the same way for the live and the recorded frames.
+func decodeFrame(frame []byte) (ws.Update, error) {
+	var msg ws.Message
+	if err := json.Unmarshal(frame, &msg); err != nil {
+		return ws.Update{}, fmt.Errorf("could not decode frame: %w", err)
+	}
+	update := ws.Update{
+		ChannelID:   msg.ChannelID,
+		ChannelName: msg.ChannelName,
+		Pair:        msg.Pair,
+		Sequence:    msg.Sequence,
+	}
+	switch strings.Split(msg.ChannelName, "-")[0] {
+	case ws.ChanTicker:
+		var ticker ws.TickerUpdate
+		if err := json.Unmarshal(msg.Data, &ticker); err != nil {
+			return update, fmt.Errorf("could not decode ticker: %w", err)
+		}
+		update.Data = ticker
+	case ws.ChanTrades:
+		trades := make([]ws.Trade, 0)
+		if err := json.Unmarshal(msg.Data, &trades); err != nil {
+			return update, fmt.Errorf("could not decode trades: %w", err)
+		}
+		update.Data = trades
+	case ws.ChanSpread:
+		var spread ws.Spread
+		if err := json.Unmarshal(msg.Data, &spread); err != nil {
+			return update, fmt.Errorf("could not decode spread: %w", err)
+		}
+		update.Data = spread
+	case ws.ChanBook:
+		var book ws.OrderBookUpdate
+		if err := json.Unmarshal(msg.Data, &book); err != nil {
+			return update, fmt.Errorf("could not decode book: %w", err)
+		}
+		update.Data = book
+	default:
+		return update, fmt.Errorf("unknown channel '%s'", msg.ChannelName)
+	}
+	return update, nil
+}
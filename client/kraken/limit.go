@@ -0,0 +1,92 @@
+package kraken
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/drakos74/free-coin/client/limit"
+)
+
+// Tier is the kraken account verification tier , which defines the api counter limits.
+type Tier struct {
+	// Max is the maximum value of the api counter.
+	Max float64
+	// Decay is the decrease of the api counter per second.
+	Decay float64
+}
+
+var (
+	// Starter is the api counter of the starter tier.
+	Starter = Tier{Max: 15, Decay: 0.33}
+	// Intermediate is the api counter of the intermediate tier.
+	Intermediate = Tier{Max: 20, Decay: 0.5}
+	// Pro is the api counter of the pro tier.
+	Pro = Tier{Max: 20, Decay: 1}
+	// Public is the limit of the public api , which is counted per ip at about one call per second.
+	Public = Tier{Max: 15, Decay: 1}
+)
+
+const (
+	// callCost is the api counter increase of a call.
+	callCost = 1
+	// historyCost is the api counter increase of the ledger , trade and closed orders history calls.
+	historyCost = 2
+	// orderCost is the api counter increase of an order ,
+	// orders are limited separately by the trading engine , and do not increase the api counter.
+	orderCost = 0
+
+	rateLimitExceeded = "Rate limit exceeded"
+)
+
+// NewLimiter creates a limiter for the private api counter of a key of the given tier ,
+// which keeps part of the counter free for the orders.
+func NewLimiter(tier Tier) *limit.Limiter {
+	return newLimiter(string(Name), tier)
+}
+
+func newLimiter(name string, tier Tier) *limit.Limiter {
+	return limit.New(name, limit.NewDecay(tier.Max, tier.Decay)).
+		WithReserve(limit.Sync, 2)
+}
+
+// NewPublicLimiter creates a limiter for the public api calls , which kraken counts per ip and not per key.
+// The trades polling keeps part of the capacity free for the pair requests.
+func NewPublicLimiter() *limit.Limiter {
+	return limit.New(fmt.Sprintf("%s-public", Name), limit.NewDecay(Public.Max, Public.Decay)).
+		WithReserve(limit.History, 5)
+}
+
+// limiters are the rate limiters of the process ,
+// one for the private calls of each api key , as kraken keeps one api counter per key ,
+// and one for the public calls of all the kraken clients.
+var limiters = struct {
+	lock   *sync.Mutex
+	keys   map[string]*limit.Limiter
+	public *limit.Limiter
+}{
+	lock:   new(sync.Mutex),
+	keys:   make(map[string]*limit.Limiter),
+	public: NewPublicLimiter(),
+}
+
+// keyLimiter returns the limiter of the api key , shared by all the exchanges of the key in the process.
+// The name labels the limiter metrics , so that the key itself is not exposed.
+func keyLimiter(key string, name string) *limit.Limiter {
+	limiters.lock.Lock()
+	defer limiters.lock.Unlock()
+	if l, ok := limiters.keys[key]; ok {
+		return l
+	}
+	l := newLimiter(fmt.Sprintf("%s-%s", Name, name), Starter)
+	limiters.keys[key] = l
+	return l
+}
+
+// throttled checks if kraken rejected the call for exceeding the rate limit ,
+// in which case the limiter waits for the full counter to decay.
+func throttled(limiter *limit.Limiter, err error) {
+	if err != nil && strings.Contains(err.Error(), rateLimitExceeded) {
+		limiter.Exceeded()
+	}
+}
@@ -0,0 +1,63 @@
+package kraken
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	cointime "github.com/drakos74/free-coin/internal/time"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestThrottled(t *testing.T) {
+
+	type test struct {
+		err      error
+		exceeded int
+	}
+
+	tests := map[string]test{
+		"no-error": {},
+		"error": {
+			err: errors.New("EOrder:Insufficient funds"),
+		},
+		"rate-limit": {
+			err:      errors.New("Could not execute request! #5 ([EAPI:Rate limit exceeded])"),
+			exceeded: 1,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			limiter := NewLimiter(Starter).WithClock(cointime.NewSimClock(time.Now()))
+			throttled(limiter, tt.err)
+			assert.Equal(t, tt.exceeded, limiter.ExceededCount())
+			if tt.exceeded > 0 {
+				assert.Equal(t, Starter.Max, limiter.Level())
+			}
+		})
+	}
+}
+
+func TestClient_WithLimiter(t *testing.T) {
+	// the clients share the public limiter by default
+	source, ok := NewClient().Source.(*RemoteSource)
+	assert.True(t, ok)
+	assert.True(t, limiters.public == source.limiter)
+
+	custom := NewLimiter(Pro)
+	source, ok = NewClient().WithLimiter(custom).Source.(*RemoteSource)
+	assert.True(t, ok)
+	assert.True(t, custom == source.limiter)
+}
+
+func TestKeyLimiter(t *testing.T) {
+	// the exchanges of the same key share the api counter
+	first := keyLimiter("key-1", "first")
+	assert.True(t, first == keyLimiter("key-1", "other"))
+	// each key has its own counter , separate from the public calls
+	second := keyLimiter("key-2", "second")
+	assert.True(t, first != second)
+	assert.True(t, first != limiters.public)
+	assert.True(t, second != limiters.public)
+}
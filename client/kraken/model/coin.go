@@ -1,6 +1,10 @@
 package model
 
 import (
+	"sort"
+	"strings"
+	"sync"
+
 	ws "github.com/aopoltorzhicky/go_kraken/websocket"
 	krakenapi "github.com/beldur/kraken-go-api-client"
 	"github.com/drakos74/free-coin/internal/model"
@@ -44,8 +48,9 @@ type ApiPair struct {
 }
 
 // Coin creates a new coin converter for kraken.
+// Apart from the known coins , it also converts the pairs loaded from the exchange with LoadPairs.
 func Coin() CoinConverter {
-	return CoinConverter{coins: map[model.Coin]ApiPair{
+	return newCoinConverter(map[model.Coin]ApiPair{
 		model.BTC: {
 			Altname: "XBTEUR",
 			AltPair: "XBT/EUR",
@@ -166,22 +171,38 @@ func Coin() CoinConverter {
 			Rest:    REPZEUR,
 			Socket:  ws.REPEUR,
 		},
-	}}
+	}, tradable)
 }
 
 // CoinConverter converts from the internal coin representation to kraken specific model
 type CoinConverter struct {
 	coins map[model.Coin]ApiPair
+	index pairIndex
+	pairs *pairs
+}
+
+func newCoinConverter(coins map[model.Coin]ApiPair, pairs *pairs) CoinConverter {
+	index := newPairIndex()
+	for coin, pair := range coins {
+		index.add(coin, pair)
+	}
+	return CoinConverter{
+		coins: coins,
+		index: index,
+		pairs: pairs,
+	}
 }
 
 // Alt transforms the internal coin type to an exchange traded pair.
 func (c CoinConverter) Alt(p string) (ApiPair, bool) {
-	for _, pair := range c.coins {
-		if pair.Altname == p {
-			return pair, true
-		}
+	coin, ok := c.index.alts[p]
+	if !ok && c.pairs != nil {
+		coin, ok = c.pairs.alt(p)
 	}
-	return ApiPair{}, false
+	if !ok {
+		return ApiPair{}, false
+	}
+	return c.Pair(coin)
 }
 
 // Pair transforms the internal coin type to an exchange traded pair.
@@ -189,14 +210,21 @@ func (c CoinConverter) Pair(p model.Coin) (ApiPair, bool) {
 	if coin, ok := c.coins[p]; ok {
 		return coin, true
 	}
+	if c.pairs != nil {
+		return c.pairs.get(p)
+	}
 	//log.Debug().Str("coin", string(p)).Msg("unknown coin")
 	return ApiPair{}, false
 }
 
 // Coin transforms the kraken coin representation to the internal coin types.
+// The known pairs take precedence over the loaded ones.
 func (c CoinConverter) Coin(p string) model.Coin {
-	for coin, pair := range c.coins {
-		if pair.Rest == p || pair.Socket == p || pair.AltPair == p {
+	if coin, ok := c.index.names[p]; ok {
+		return coin
+	}
+	if c.pairs != nil {
+		if coin, ok := c.pairs.coin(p); ok {
 			return coin
 		}
 	}
@@ -204,6 +232,114 @@ func (c CoinConverter) Coin(p string) model.Coin {
 	return model.NoCoin
 }
 
+// pairIndex is the reverse index of the pairs , so that the lookups on every message do not go through all the pairs.
+type pairIndex struct {
+	// names indexes the rest , socket and alt pair names.
+	names map[string]model.Coin
+	// alts indexes the alt names.
+	alts map[string]model.Coin
+}
+
+func newPairIndex() pairIndex {
+	return pairIndex{
+		names: make(map[string]model.Coin),
+		alts:  make(map[string]model.Coin),
+	}
+}
+
+func (i pairIndex) add(coin model.Coin, pair ApiPair) {
+	for _, name := range []string{pair.Rest, pair.Socket, pair.AltPair} {
+		if name != "" {
+			i.names[name] = coin
+		}
+	}
+	if pair.Altname != "" {
+		i.alts[pair.Altname] = coin
+	}
+}
+
+// Quote is the quote currency for the traded pairs.
+const Quote = "EUR"
+
+// pairs holds the tradable pairs loaded from the exchange.
+type pairs struct {
+	lock  *sync.RWMutex
+	pairs map[model.Coin]ApiPair
+	index pairIndex
+}
+
+// tradable is shared by all the coin converters , so that the loaded pairs are available everywhere.
+var tradable = &pairs{
+	lock:  new(sync.RWMutex),
+	pairs: make(map[model.Coin]ApiPair),
+	index: newPairIndex(),
+}
+
+func (p *pairs) get(coin model.Coin) (ApiPair, bool) {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+	pair, ok := p.pairs[coin]
+	return pair, ok
+}
+
+func (p *pairs) coin(name string) (model.Coin, bool) {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+	coin, ok := p.index.names[name]
+	return coin, ok
+}
+
+func (p *pairs) alt(name string) (model.Coin, bool) {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+	coin, ok := p.index.alts[name]
+	return coin, ok
+}
+
+// LoadPairs loads the tradable pairs from the kraken asset pairs response ,
+// and returns the coins that can be traded against the quote currency.
+func LoadPairs(info map[string]krakenapi.AssetPairInfo) []model.Coin {
+	tradable.lock.Lock()
+	defer tradable.lock.Unlock()
+	coins := make([]model.Coin, 0)
+	for name, pair := range info {
+		// skip the dark pool pairs
+		if strings.HasSuffix(name, ".d") {
+			continue
+		}
+		// the alt name has no legacy asset prefixes e.g. 'XBTEUR' for 'XXBTZEUR'
+		if pair.Quote != "Z"+Quote && pair.Quote != Quote || !strings.HasSuffix(pair.Altname, Quote) {
+			continue
+		}
+		base := strings.TrimSuffix(pair.Altname, Quote)
+		coin := AssetCoin(base)
+		apiPair := ApiPair{
+			Altname: pair.Altname,
+			Rest:    name,
+			Socket:  base + "/" + Quote,
+		}
+		tradable.pairs[coin] = apiPair
+		tradable.index.add(coin, apiPair)
+		coins = append(coins, coin)
+	}
+	sort.Slice(coins, func(i, j int) bool {
+		return coins[i] < coins[j]
+	})
+	return coins
+}
+
+// AssetCoin converts the kraken asset name to the internal coin.
+func AssetCoin(asset string) model.Coin {
+	switch asset {
+	case "XBT":
+		return model.BTC
+	case "XDG":
+		return model.Coin("DOGE")
+	default:
+		return model.Coin(asset)
+	}
+}
+
 // Type creates a new type converter for kraken.
 func Type() TypeConverter {
 	return TypeConverter{types: map[model.Type]string{
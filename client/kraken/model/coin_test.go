@@ -0,0 +1,74 @@
+package model
+
+import (
+	"testing"
+
+	krakenapi "github.com/beldur/kraken-go-api-client"
+	"github.com/drakos74/free-coin/internal/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoadPairs(t *testing.T) {
+
+	coins := LoadPairs(map[string]krakenapi.AssetPairInfo{
+		"XXBTZEUR":   {Altname: "XBTEUR", Base: "XXBT", Quote: "ZEUR"},
+		"XXBTZEUR.d": {Altname: "XBTEUR.d", Base: "XXBT", Quote: "ZEUR"},
+		"XXBTZUSD":   {Altname: "XBTUSD", Base: "XXBT", Quote: "ZUSD"},
+		"XDGEUR":     {Altname: "XDGEUR", Base: "XXDG", Quote: "ZEUR"},
+		"PEPEEUR":    {Altname: "PEPEEUR", Base: "PEPE", Quote: "ZEUR"},
+	})
+	assert.Equal(t, []model.Coin{model.BTC, "DOGE", "PEPE"}, coins)
+
+	converter := Coin()
+
+	type test struct {
+		coin   model.Coin
+		pair   ApiPair
+		socket string
+	}
+
+	tests := map[string]test{
+		"known": {
+			coin:   model.BTC,
+			pair:   converter.coins[model.BTC],
+			socket: converter.coins[model.BTC].Socket,
+		},
+		"alias": {
+			coin: "DOGE",
+			pair: ApiPair{
+				Altname: "XDGEUR",
+				Rest:    "XDGEUR",
+				Socket:  "XDG/EUR",
+			},
+			socket: "XDG/EUR",
+		},
+		"loaded": {
+			coin: "PEPE",
+			pair: ApiPair{
+				Altname: "PEPEEUR",
+				Rest:    "PEPEEUR",
+				Socket:  "PEPE/EUR",
+			},
+			socket: "PEPE/EUR",
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			pair, ok := converter.Pair(tt.coin)
+			assert.True(t, ok)
+			assert.Equal(t, tt.pair, pair)
+			assert.Equal(t, tt.coin, converter.Coin(tt.socket))
+			assert.Equal(t, tt.coin, converter.Coin(tt.pair.Rest))
+			alt, ok := converter.Alt(tt.pair.Altname)
+			assert.True(t, ok)
+			assert.Equal(t, tt.pair, alt)
+		})
+	}
+
+	_, ok := converter.Pair("UNKNOWN")
+	assert.False(t, ok)
+	_, ok = converter.Alt("UNKNOWN")
+	assert.False(t, ok)
+	assert.Equal(t, model.NoCoin, converter.Coin("UNKNOWN"))
+}
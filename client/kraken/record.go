@@ -0,0 +1,242 @@
+package kraken
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"sync"
+	"time"
+
+	ws "github.com/aopoltorzhicky/go_kraken/websocket"
+	krakenapi "github.com/beldur/kraken-go-api-client"
+	"github.com/drakos74/free-coin/client/replay"
+	coinmodel "github.com/drakos74/free-coin/internal/model"
+	"github.com/rs/zerolog/log"
+)
+
+// connection is the websocket connection to kraken , so that it can be replaced by a recorded session.
+type connection interface {
+	Connect() error
+	Close() error
+	Listen() <-chan ws.Update
+	SubscribeTicker(pairs []string) error
+	SubscribeSpread(pairs []string) error
+	SubscribeTrades(pairs []string) error
+	SubscribeBook(pairs []string, depth int64) error
+	Unsubscribe(channelType string, pairs []string) error
+	UnsubscribeBook(pairs []string, depth int64) error
+}
+
+// record records the raw socket frame of the update , as kraken sent it.
+func (s *Socket) record(update ws.Update, frame []byte) {
+	if s.recorder == nil {
+		return
+	}
+	if err := s.recorder.Record(replay.Socket, update.ChannelName, update.Pair, frame); err != nil {
+		log.Error().Err(err).Str("pair", update.Pair).Msg("could not record socket frame")
+	}
+}
+
+// decodeUpdate decodes a recorded socket frame to the update kraken would emit.
+func decodeUpdate(message replay.Message) (ws.Update, error) {
+	return decodeFrame(message.Data)
+}
+
+// replayConnection plays back a recorded socket session , the subscriptions are part of the recording.
+type replayConnection struct {
+	player  *replay.Player
+	updates chan ws.Update
+	done    chan struct{}
+	once    *sync.Once
+}
+
+func newReplayConnection(player *replay.Player) *replayConnection {
+	return &replayConnection{
+		player:  player,
+		updates: make(chan ws.Update),
+		done:    make(chan struct{}),
+		once:    new(sync.Once),
+	}
+}
+
+func (r *replayConnection) Connect() error {
+	go func() {
+		defer close(r.updates)
+		err := r.player.Play(r.done, func(message replay.Message) error {
+			update, err := decodeUpdate(message)
+			if err != nil {
+				return err
+			}
+			select {
+			case <-r.done:
+			case r.updates <- update:
+			}
+			return nil
+		})
+		if err != nil {
+			log.Error().Err(err).Msg("could not replay socket session")
+		}
+	}()
+	return nil
+}
+
+func (r *replayConnection) Close() error {
+	r.once.Do(func() {
+		close(r.done)
+	})
+	return nil
+}
+
+func (r *replayConnection) Listen() <-chan ws.Update {
+	return r.updates
+}
+
+func (r *replayConnection) SubscribeTicker(pairs []string) error {
+	return nil
+}
+
+func (r *replayConnection) SubscribeSpread(pairs []string) error {
+	return nil
+}
+
+func (r *replayConnection) SubscribeTrades(pairs []string) error {
+	return nil
+}
+
+func (r *replayConnection) SubscribeBook(pairs []string, depth int64) error {
+	return nil
+}
+
+func (r *replayConnection) Unsubscribe(channelType string, pairs []string) error {
+	return nil
+}
+
+func (r *replayConnection) UnsubscribeBook(pairs []string, depth int64) error {
+	return nil
+}
+
+// recordTransport records the raw bodies of the rest responses , keyed by the method and the pair of the request.
+type recordTransport struct {
+	next     http.RoundTripper
+	recorder *replay.Recorder
+}
+
+func (t recordTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	method, pair := request(req)
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		return resp, err
+	}
+	body, err := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	if err != nil {
+		return nil, fmt.Errorf("could not read response: %w", err)
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	if !json.Valid(body) {
+		log.Warn().Str("method", method).Str("pair", pair).Msg("could not record invalid response")
+		return resp, nil
+	}
+	if err := t.recorder.Record(replay.Rest, method, pair, body); err != nil {
+		log.Error().Err(err).Str("method", method).Str("pair", pair).Msg("could not record response")
+	}
+	return resp, nil
+}
+
+// request returns the rest method and the pair of the request.
+func request(req *http.Request) (string, string) {
+	method := path.Base(req.URL.Path)
+	if req.GetBody == nil {
+		return method, ""
+	}
+	body, err := req.GetBody()
+	if err != nil {
+		return method, ""
+	}
+	defer body.Close()
+	b, err := io.ReadAll(body)
+	if err != nil {
+		return method, ""
+	}
+	values, err := url.ParseQuery(string(b))
+	if err != nil {
+		return method, ""
+	}
+	return method, values.Get("pair")
+}
+
+// replayTransport serves the recorded rest responses , in the order they were recorded for each method and pair.
+type replayTransport struct {
+	lock      *sync.Mutex
+	index     map[string]int
+	responses map[string][]replay.Message
+}
+
+func newReplayTransport(messages []replay.Message) *replayTransport {
+	responses := make(map[string][]replay.Message)
+	for _, m := range replay.Filter(messages, replay.Rest) {
+		k := path.Join(m.Channel, m.Key)
+		responses[k] = append(responses[k], m)
+	}
+	return &replayTransport{
+		lock:      new(sync.Mutex),
+		index:     make(map[string]int),
+		responses: responses,
+	}
+}
+
+func (t *replayTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	method, pair := request(req)
+	k := path.Join(method, pair)
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	i := t.index[k]
+	if i >= len(t.responses[k]) {
+		return nil, fmt.Errorf("no recorded response for '%s'", k)
+	}
+	t.index[k] = i + 1
+	return &http.Response{
+		Status:     http.StatusText(http.StatusOK),
+		StatusCode: http.StatusOK,
+		Header:     http.Header{"Content-Type": []string{"application/json"}},
+		Body:       io.NopCloser(bytes.NewReader(t.responses[k][i].Data)),
+		Request:    req,
+	}, nil
+}
+
+// ReplaySource serves the recorded rest trades responses , in the order they were recorded for each coin.
+// The raw responses go through the same decoding as the live ones.
+type ReplaySource struct {
+	*baseSource
+	public *krakenapi.KrakenAPI
+}
+
+// NewReplaySource creates a new source from the recorded rest messages.
+func NewReplaySource(messages []replay.Message) *ReplaySource {
+	return &ReplaySource{
+		baseSource: newSource(),
+		public:     krakenapi.NewWithClient("KEY", "SECRET", &http.Client{Transport: newReplayTransport(messages)}),
+	}
+}
+
+// Trades returns the next recorded trades response for the coin.
+func (r *ReplaySource) Trades(coin coinmodel.Coin, since int64) (*coinmodel.TradeBatch, error) {
+	pair, ok := r.converter.Coin.Pair(coin)
+	if !ok {
+		return nil, fmt.Errorf("could not find pair: %s", coin)
+	}
+	response, err := r.public.Trades(pair.Rest, since)
+	if err != nil {
+		return nil, fmt.Errorf("could not get recorded trades: %w", err)
+	}
+	return r.transform(pair.Rest, time.Second, response)
+}
+
+// Close closes the replay source.
+func (r *ReplaySource) Close() error {
+	return nil
+}
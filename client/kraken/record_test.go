@@ -0,0 +1,300 @@
+package kraken
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"path/filepath"
+	"testing"
+	"time"
+
+	ws "github.com/aopoltorzhicky/go_kraken/websocket"
+	krakenapi "github.com/beldur/kraken-go-api-client"
+	"github.com/drakos74/free-coin/client/replay"
+	"github.com/drakos74/free-coin/internal/api"
+	"github.com/drakos74/free-coin/internal/model"
+	"github.com/stretchr/testify/assert"
+)
+
+// session is a raw socket session with the events , a book snapshot , spreads , a ticker , trades and a book update.
+var session = []string{
+	`{"event":"systemStatus","status":"online","version":"1.8.7"}`,
+	`{"channelID":336,"channelName":"book-10","event":"subscriptionStatus","pair":"XBT/EUR","status":"subscribed","subscription":{"depth":10,"name":"book"}}`,
+	`[336,{"as":[["41000.10000","0.50000000","1640995200.123456"],["41000.20000","1.00000000","1640995200.123456"]],"bs":[["40999.90000","0.25000000","1640995200.123456"],["40999.80000","2.00000000","1640995200.123456"]]},"book-10","XBT/EUR"]`,
+	`[337,["40999.90000","41000.10000","1640995200.223456","0.25000000","0.50000000"],"spread","XBT/EUR"]`,
+	`{"event":"heartbeat"}`,
+	`[337,["40999.80000","41000.10000","1640995200.323456","2.00000000","0.50000000"],"spread","XBT/EUR"]`,
+	`[338,{"a":["41000.10000",0,"0.50000000"],"b":["40999.80000",2,"2.00000000"],"c":["41000.10000","0.10000000"],"v":["10.5","20.1"],"p":["40900.1","40800.2"],"t":[100,200],"l":["40500.0","40400.0"],"h":["41100.0","41200.0"],"o":["40600.0","40700.0"]},"ticker","XBT/EUR"]`,
+	`[339,[["41000.10000","0.10000000","1640995201.123456","b","m",""]],"trade","XBT/EUR"]`,
+	`[336,{"a":[["41000.10000","0.40000000","1640995201.500000"],["41000.30000","1.00000000","1640995201.500000","r"]]},{"b":[["40999.90000","0.30000000","1640995201.600000"]]},"book-10","XBT/EUR"]`,
+	`[339,[["40999.90000","0.20000000","1640995202.123456","s","l",""],["40999.80000","0.30000000","1640995202.223456","s","l","x"]],"trade","XBT/EUR"]`,
+}
+
+func TestDecodeFrame(t *testing.T) {
+	type test struct {
+		frame   string
+		channel string
+		data    interface{}
+		err     bool
+	}
+
+	tests := map[string]test{
+		"book-snapshot": {
+			frame:   session[2],
+			channel: "book-10",
+			data: ws.OrderBookUpdate{
+				IsSnapshot: true,
+				Asks: []ws.OrderBookItem{
+					{Price: "41000.10000", Volume: "0.50000000", Time: "1640995200.123456"},
+					{Price: "41000.20000", Volume: "1.00000000", Time: "1640995200.123456"},
+				},
+				Bids: []ws.OrderBookItem{
+					{Price: "40999.90000", Volume: "0.25000000", Time: "1640995200.123456"},
+					{Price: "40999.80000", Volume: "2.00000000", Time: "1640995200.123456"},
+				},
+			},
+		},
+		"book-update": {
+			frame:   session[8],
+			channel: "book-10",
+			data: ws.OrderBookUpdate{
+				Asks: []ws.OrderBookItem{
+					{Price: "41000.10000", Volume: "0.40000000", Time: "1640995201.500000"},
+					{Price: "41000.30000", Volume: "1.00000000", Time: "1640995201.500000", Republish: true},
+				},
+				Bids: []ws.OrderBookItem{
+					{Price: "40999.90000", Volume: "0.30000000", Time: "1640995201.600000"},
+				},
+			},
+		},
+		"spread": {
+			frame:   session[3],
+			channel: ws.ChanSpread,
+			data: ws.Spread{
+				Bid: "40999.90000", Ask: "41000.10000", Time: "1640995200.223456", BidVolume: "0.50000000", AskVolume: "0.25000000",
+			},
+		},
+		"ticker": {
+			frame:   session[6],
+			channel: ws.ChanTicker,
+			data: ws.TickerUpdate{
+				Ask:                ws.Level{Price: "41000.10000", WholeLotVolume: 0, Volume: "0.50000000"},
+				Bid:                ws.Level{Price: "40999.80000", WholeLotVolume: 2, Volume: "2.00000000"},
+				Close:              ws.DecimalValues{Today: "41000.10000", Last24: "0.10000000"},
+				Volume:             ws.DecimalValues{Today: "10.5", Last24: "20.1"},
+				VolumeAveragePrice: ws.DecimalValues{Today: "40900.1", Last24: "40800.2"},
+				TradeVolume:        ws.IntValues{Today: 100, Last24: 200},
+				Low:                ws.DecimalValues{Today: "40500.0", Last24: "40400.0"},
+				High:               ws.DecimalValues{Today: "41100.0", Last24: "41200.0"},
+				Open:               ws.DecimalValues{Today: "40600.0", Last24: "40700.0"},
+			},
+		},
+		"trades": {
+			frame:   session[9],
+			channel: ws.ChanTrades,
+			data: []ws.Trade{
+				{Price: "40999.90000", Volume: "0.20000000", Time: "1640995202.123456", Side: "s", OrderType: "l"},
+				{Price: "40999.80000", Volume: "0.30000000", Time: "1640995202.223456", Side: "s", OrderType: "l", Misc: "x"},
+			},
+		},
+		"unknown-channel": {
+			frame: `[340,[["1","2"]],"ohlc-5","XBT/EUR"]`,
+			err:   true,
+		},
+		"event": {
+			frame: session[4],
+			err:   true,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			update, err := decodeFrame([]byte(tt.frame))
+			if tt.err {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.channel, update.ChannelName)
+			assert.Equal(t, ws.BTCEUR, update.Pair)
+			assert.Equal(t, tt.data, update.Data)
+		})
+	}
+}
+
+// frames emits the raw session frames , as the kraken socket would , and fails after the last one.
+type frames struct {
+	frames []string
+}
+
+func (f *frames) ReadMessage() (int, []byte, error) {
+	if len(f.frames) == 0 {
+		return 0, nil, io.EOF
+	}
+	frame := f.frames[0]
+	f.frames = f.frames[1:]
+	return 1, []byte(frame), nil
+}
+
+func (f *frames) WriteMessage(messageType int, data []byte) error {
+	return nil
+}
+
+func (f *frames) SetReadDeadline(t time.Time) error {
+	return nil
+}
+
+func (f *frames) Close() error {
+	return nil
+}
+
+func collect(t *testing.T, out <-chan *model.TradeSignal, process chan<- api.Signal) []model.TradeSignal {
+	signals := make([]model.TradeSignal, 0)
+	for signal := range out {
+		// the id is unique for each received message
+		signal.Meta.ID = ""
+		signals = append(signals, *signal)
+		if process != nil {
+			process <- api.Signal{}
+		}
+	}
+	return signals
+}
+
+// live runs a recorded session on the given raw frames , and returns the signals and the recorded messages.
+func live(t *testing.T, session []string) ([]model.TradeSignal, []replay.Message) {
+	filename := filepath.Join(t.TempDir(), "session.json")
+	recorder, err := replay.NewRecorder(filename)
+	assert.NoError(t, err)
+	socket := NewSocket(model.BTC).WithBook(10).WithRecorder(recorder)
+	socket.connect = func() connection {
+		conn := newFrameConnection(ws.ProdBaseURL, socket.record)
+		conn.dial = func(url string) (frameConn, error) {
+			return &frames{frames: session}, nil
+		}
+		return conn
+	}
+	out := make(chan *model.TradeSignal)
+	go socket.replay(context.Background(), out, nil)
+	signals := collect(t, out, nil)
+	assert.NoError(t, recorder.Close())
+	messages, err := replay.Load(filename)
+	assert.NoError(t, err)
+	return signals, messages
+}
+
+func play(t *testing.T, messages []replay.Message) []model.TradeSignal {
+	process := make(chan api.Signal)
+	out, err := NewSocket(model.BTC).
+		WithBook(10).
+		WithReplay(replay.NewPlayer(messages)).
+		Run(process)
+	assert.NoError(t, err)
+	return collect(t, out, process)
+}
+
+func TestSocket_Replay(t *testing.T) {
+	signals, messages := live(t, session)
+	// the channel frames are recorded as they are , including the ticker , the events are skipped
+	frames := make([]string, 0)
+	for _, frame := range session {
+		if frame[0] == '[' {
+			frames = append(frames, frame)
+		}
+	}
+	assert.Equal(t, len(frames), len(messages))
+	channels := make([]string, 0)
+	for i, message := range messages {
+		assert.Equal(t, replay.Socket, message.Source)
+		assert.Equal(t, ws.BTCEUR, message.Key)
+		assert.JSONEq(t, frames[i], string(message.Data))
+		channels = append(channels, message.Channel)
+	}
+	assert.Equal(t, []string{"book-10", ws.ChanSpread, ws.ChanSpread, ws.ChanTicker, ws.ChanTrades, "book-10", ws.ChanTrades}, channels)
+
+	assert.Equal(t, 2, len(signals))
+	assert.Equal(t, model.Buy, signals[0].Tick.Type)
+	assert.Equal(t, 41000.1, signals[0].Tick.Price)
+	assert.True(t, signals[0].OrderBook.Ready())
+	assert.InDelta(t, 41000.0, signals[0].OrderBook.Mid, 1e-9)
+	// the book update is applied before the second trade
+	assert.Equal(t, model.Sell, signals[1].Tick.Type)
+	assert.Equal(t, 40999.8, signals[1].Tick.Price)
+	assert.NotEqual(t, signals[0].OrderBook, signals[1].OrderBook)
+
+	// the replay gives the same signals as the live session
+	assert.Equal(t, signals, play(t, messages))
+}
+
+// roundTrip serves the rest requests in the tests.
+type roundTrip func(req *http.Request) (*http.Response, error)
+
+func (r roundTrip) RoundTrip(req *http.Request) (*http.Response, error) {
+	return r(req)
+}
+
+// tradesResponse is a raw kraken trades response.
+const tradesResponse = `{"error":[],"result":{"XXBTZEUR":[["41000.10000","0.10000000",1640995201.1234,"b","m",""],["40999.90000","0.20000000",1640995202.1234,"s","l",""]],"last":"1640995202123456789"}}`
+
+func TestReplaySource_Trades(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "session.json")
+	recorder, err := replay.NewRecorder(filename)
+	assert.NoError(t, err)
+	public := krakenapi.NewWithClient("KEY", "SECRET", &http.Client{Transport: recordTransport{
+		next: roundTrip(func(req *http.Request) (*http.Response, error) {
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Header:     http.Header{"Content-Type": []string{"application/json"}},
+				Body:       io.NopCloser(bytes.NewReader([]byte(tradesResponse))),
+			}, nil
+		}),
+		recorder: recorder,
+	}})
+	response, err := public.Trades(krakenapi.XXBTZEUR, 0)
+	assert.NoError(t, err)
+	assert.NoError(t, recorder.Close())
+
+	messages, err := replay.Load(filename)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(messages))
+	// the raw response is recorded , keyed by the method and the pair
+	assert.Equal(t, replay.Rest, messages[0].Source)
+	assert.Equal(t, "Trades", messages[0].Channel)
+	assert.Equal(t, krakenapi.XXBTZEUR, messages[0].Key)
+	assert.JSONEq(t, tradesResponse, string(messages[0].Data))
+
+	source := NewReplaySource(append(messages, replay.Message{
+		Time: time.Now(), Source: replay.Socket, Channel: ws.ChanTrades, Key: ws.BTCEUR,
+	}))
+	batch, err := source.Trades(model.BTC, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, response.Last, batch.Index)
+	assert.Equal(t, 2, len(batch.Trades))
+	assert.Equal(t, model.Buy, batch.Trades[0].Tick.Type)
+	assert.Equal(t, 40999.9, batch.Trades[1].Tick.Price)
+
+	_, err = source.Trades(model.BTC, batch.Index)
+	assert.Error(t, err)
+	_, err = source.Trades(model.ETH, 0)
+	assert.Error(t, err)
+}
+
+func TestRecordTransport_Error(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "session.json")
+	recorder, err := replay.NewRecorder(filename)
+	assert.NoError(t, err)
+	public := krakenapi.NewWithClient("KEY", "SECRET", &http.Client{Transport: recordTransport{
+		next: roundTrip(func(req *http.Request) (*http.Response, error) {
+			return nil, errors.New("connection refused")
+		}),
+		recorder: recorder,
+	}})
+	_, err = public.Trades(krakenapi.XXBTZEUR, 0)
+	assert.Error(t, err)
+	assert.NoError(t, recorder.Close())
+	messages, err := replay.Load(filename)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, len(messages))
+}
@@ -1,18 +1,24 @@
 package kraken
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"math"
 	"os"
 	"os/signal"
 	"reflect"
 	"strconv"
+	"sync"
 	"syscall"
 	"time"
 
 	ws "github.com/aopoltorzhicky/go_kraken/websocket"
+	"github.com/drakos74/free-coin/client"
 	kraken_model "github.com/drakos74/free-coin/client/kraken/model"
+	"github.com/drakos74/free-coin/client/replay"
 	"github.com/drakos74/free-coin/internal/api"
+	"github.com/drakos74/free-coin/internal/book"
 	"github.com/drakos74/free-coin/internal/buffer"
 	"github.com/drakos74/free-coin/internal/metrics"
 	"github.com/drakos74/free-coin/internal/model"
@@ -21,10 +27,28 @@ import (
 )
 
 type Socket struct {
+	lock          *sync.RWMutex
 	coins         []string
+	updates       chan subscription
 	converter     kraken_model.CoinConverter
 	typeConverter kraken_model.TypeConverter
 	signals       map[model.Coin]model.TradeSignal
+	depth         int
+	books         map[model.Coin]*book.Book
+	supervisor    *client.Supervisor
+	connect       func() connection
+	recorder      *replay.Recorder
+	player        *replay.Player
+}
+
+// checksumLevels is the number of book levels kraken uses for the checksum.
+const checksumLevels = 10
+
+// subscription is a runtime change of the socket subscriptions.
+type subscription struct {
+	coins []model.Coin
+	pairs []string
+	add   bool
 }
 
 func NewSocket(coins ...model.Coin) *Socket {
@@ -35,74 +59,225 @@ func NewSocket(coins ...model.Coin) *Socket {
 			cc[i] = c.Socket
 		}
 	}
-	return &Socket{
+	s := &Socket{
+		lock:          new(sync.RWMutex),
 		coins:         cc,
+		updates:       make(chan subscription, 16),
 		converter:     converter,
 		typeConverter: kraken_model.Type(),
 		signals:       make(map[model.Coin]model.TradeSignal),
+		books:         make(map[model.Coin]*book.Book),
+	}
+	s.connect = func() connection {
+		return newFrameConnection(ws.ProdBaseURL, s.record)
+	}
+	s.supervisor = client.NewSupervisor("socket", s.session)
+	return s
+}
+
+// WithBook subscribes to the level 2 order book of the given depth.
+// Valid depths for kraken are 10, 25, 100, 500 and 1000.
+func (s *Socket) WithBook(depth int) *Socket {
+	s.depth = depth
+	return s
+}
+
+// WithBackFill adds the back-fill logic for the trades missed during a disconnect.
+func (s *Socket) WithBackFill(backFill client.BackFill) *Socket {
+	s.supervisor.WithBackFill(backFill)
+	return s
+}
+
+// WithWarmUp replays the history of the given coins since the given time , before connecting to the socket.
+func (s *Socket) WithWarmUp(since time.Time, history client.BackFill, coins ...model.Coin) *Socket {
+	s.supervisor.WithWarmUp(since, history, coins...)
+	return s
+}
+
+// WithReport adds a reporter for the connection events.
+func (s *Socket) WithReport(report func(event client.Event)) *Socket {
+	s.supervisor.WithReport(report)
+	return s
+}
+
+// Subscribe subscribes to the given coins on the live socket.
+// If the socket is not connected , the coins will be subscribed on the next connection.
+func (s *Socket) Subscribe(coins ...model.Coin) error {
+	pairs, err := s.pairs(coins...)
+	if err != nil {
+		return err
+	}
+	s.lock.Lock()
+	for _, pair := range pairs {
+		if !contains(s.coins, pair) {
+			s.coins = append(s.coins, pair)
+		}
+	}
+	s.lock.Unlock()
+	s.update(subscription{coins: coins, pairs: pairs, add: true})
+	return nil
+}
+
+// Unsubscribe unsubscribes from the given coins on the live socket.
+func (s *Socket) Unsubscribe(coins ...model.Coin) error {
+	pairs, err := s.pairs(coins...)
+	if err != nil {
+		return err
+	}
+	s.lock.Lock()
+	cc := make([]string, 0, len(s.coins))
+	for _, pair := range s.coins {
+		if !contains(pairs, pair) {
+			cc = append(cc, pair)
+		}
+	}
+	s.coins = cc
+	s.lock.Unlock()
+	s.update(subscription{coins: coins, pairs: pairs})
+	return nil
+}
+
+func (s *Socket) pairs(coins ...model.Coin) ([]string, error) {
+	pairs := make([]string, len(coins))
+	for i, coin := range coins {
+		pair, ok := s.converter.Pair(coin)
+		if !ok {
+			return nil, fmt.Errorf("could not find pair: %s", coin)
+		}
+		pairs[i] = pair.Socket
 	}
+	return pairs, nil
+}
+
+func (s *Socket) update(sub subscription) {
+	select {
+	case s.updates <- sub:
+	default:
+		// the subscriptions will be in sync on the next connection
+		logger.Warn().Str("pairs", fmt.Sprintf("%v", sub.pairs)).Bool("add", sub.add).Msg("subscription update dropped")
+	}
+}
+
+func contains(pairs []string, pair string) bool {
+	for _, p := range pairs {
+		if p == pair {
+			return true
+		}
+	}
+	return false
+}
+
+// WithRecorder records the raw socket messages , so that the session can be replayed.
+func (s *Socket) WithRecorder(recorder *replay.Recorder) *Socket {
+	s.recorder = recorder
+	return s
+}
+
+// WithReplay replays the recorded socket messages instead of connecting to kraken.
+// The messages go through the same handling as the live ones , and the socket closes when they are all played.
+func (s *Socket) WithReplay(player *replay.Player) *Socket {
+	s.player = player
+	s.connect = func() connection {
+		return newReplayConnection(player)
+	}
+	return s
 }
 
 func (s *Socket) Run(process <-chan api.Signal) (chan *model.TradeSignal, error) {
 	out := make(chan *model.TradeSignal)
 
+	ctx, cancel := context.WithCancel(context.Background())
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM, syscall.SIGINT)
 	go func() {
-		err := s.connect(out, process)
-		if err != nil {
-			logger.Error().Err(err).Msg("failed to connect to socket")
-		}
+		<-signals
+		logger.Info().Msg("closing kraken socket ... ")
+		cancel()
 	}()
 
+	if s.player != nil {
+		go s.replay(ctx, out, process)
+		return out, nil
+	}
+
+	go s.supervisor.Run(ctx, out, process)
+
 	return out, nil
 }
 
-// connect will receive and transform each message from the socket appropriately
-// and propagate it to the next layer. Responsibility is to ensure common data structures amongst different connectors.
-func (s *Socket) connect(out chan *model.TradeSignal, process <-chan api.Signal) (cErr error) {
-	defer func() {
-		logger.Error().Err(cErr).Msg("error during connect, reconnecting ... ")
-		time.AfterFunc(5*time.Second, func() {
-			s.connect(out, process)
-		})
+// replay runs a single session on the recorded messages , without the reconnect logic of the supervisor.
+func (s *Socket) replay(ctx context.Context, out chan<- *model.TradeSignal, process <-chan api.Signal) {
+	defer close(out)
+	trades := make(chan *model.TradeSignal)
+	go func() {
+		defer close(trades)
+		if err := s.session(ctx, trades, func() {}); err != nil {
+			logger.Error().Err(err).Msg("could not replay kraken socket session")
+		}
 	}()
+	for trade := range trades {
+		out <- trade
+		if process != nil {
+			<-process
+		}
+	}
+}
 
+// session will receive and transform each message from the socket appropriately
+// and propagate it to the next layer. Responsibility is to ensure common data structures amongst different connectors.
+// It returns when the context is cancelled , or the connection fails.
+func (s *Socket) session(ctx context.Context, out chan<- *model.TradeSignal, beat func()) error {
 	logger.Info().Msg("connecting to kraken socket ... ")
 
-	signals := make(chan os.Signal, 1)
-	signal.Notify(signals, os.Interrupt, syscall.SIGTERM, syscall.SIGINT)
-
-	kraken := ws.NewKraken(ws.ProdBaseURL)
+	kraken := s.connect()
 	if err := kraken.Connect(); err != nil {
-		cErr = fmt.Errorf("error connecting to web socket: %w", err)
-		return
-	}
-
-	if err := kraken.SubscribeTicker(s.coins); err != nil {
-		cErr = fmt.Errorf("error for ticker subscription: %w", err)
-		return
+		return fmt.Errorf("error connecting to web socket: %w", err)
 	}
+	defer func() {
+		if err := kraken.Close(); err != nil {
+			logger.Error().Err(err).Msg("could not close kraken socket connection")
+		}
+	}()
 
-	if err := kraken.SubscribeSpread(s.coins); err != nil {
-		cErr = fmt.Errorf("error for spread subscription: %w", err)
-		return
+	// the pending updates are already part of the subscribed coins
+	s.drain()
+	s.lock.RLock()
+	coins := append([]string{}, s.coins...)
+	s.lock.RUnlock()
+	if s.depth > 0 {
+		// we will get a new snapshot for the new subscription
+		s.books = make(map[model.Coin]*book.Book)
 	}
-
-	if err := kraken.SubscribeTrades(s.coins); err != nil {
-		cErr = fmt.Errorf("error for trades subscription: %w", err)
-		return
+	if err := s.subscribe(kraken, coins); err != nil {
+		return err
 	}
 
 	spread := make(map[model.Coin]*buffer.Window)
 
 	for {
 		select {
-		case <-signals:
-			if err := kraken.Close(); err != nil {
-				cErr = fmt.Errorf("could not close kraken socket connection: %w", err)
+		case <-ctx.Done():
+			return nil
+		case sub := <-s.updates:
+			if sub.add {
+				if err := s.subscribe(kraken, sub.pairs); err != nil {
+					return err
+				}
+				continue
 			}
-			close(out)
-			return
-		case update := <-kraken.Listen():
+			if err := s.unsubscribe(kraken, sub.pairs); err != nil {
+				return err
+			}
+			for _, coin := range sub.coins {
+				delete(s.signals, coin)
+				delete(s.books, coin)
+				delete(spread, coin)
+			}
+		case update, ok := <-kraken.Listen():
+			if !ok {
+				return nil
+			}
+			beat()
 			coin := s.converter.Coin(update.Pair)
 
 			if _, ok := spread[coin]; !ok {
@@ -115,7 +290,6 @@ func (s *Socket) connect(out chan *model.TradeSignal, process <-chan api.Signal)
 			id, _ := uuid.NewUUID()
 			signal.Meta = model.Meta{
 				Exchange: "kraken",
-				Time:     time.Now(),
 				Live:     true,
 				ID:       id.String(),
 			}
@@ -130,12 +304,34 @@ func (s *Socket) connect(out chan *model.TradeSignal, process <-chan api.Signal)
 					continue
 				}
 				spread[coin].Push(1, sp.Bid.Price*sp.Bid.Volume, sp.Ask.Price*sp.Ask.Volume)
+			case ws.OrderBookUpdate:
+				if _, ok := s.books[coin]; !ok {
+					s.books[coin] = book.NewBook(coin, s.depth).WithChecksum(book.CRC32(checksumLevels))
+				}
+				err := updateBook(s.books[coin], data)
+				if err != nil {
+					logger.Warn().Err(err).Str("pair", update.Pair).Msg("could not update book")
+					if errors.Is(err, book.ChecksumErr) {
+						// re-subscribe to get a fresh snapshot
+						pair := []string{update.Pair}
+						if err := kraken.UnsubscribeBook(pair, int64(s.depth)); err != nil {
+							logger.Error().Err(err).Str("pair", update.Pair).Msg("could not unsubscribe from book")
+						}
+						if err := kraken.SubscribeBook(pair, int64(s.depth)); err != nil {
+							logger.Error().Err(err).Str("pair", update.Pair).Msg("could not re-subscribe to book")
+						}
+					}
+				}
+				continue
 			case []ws.Trade:
 				tick, err := tradeToTick(data, s.typeConverter)
 				if err != nil {
 					logger.Err(err).Str("trade", fmt.Sprintf("%+v", update)).Msg("could not parse values")
 				}
 				signal.Tick = tick
+				// use the trade time , so that the signals are consistent with the back-filled ones
+				signal.Meta.Time = tick.Time
+				signal.Meta.Unix = tick.Time.Unix()
 				if _, b, ok := spread[coin].Push(2, 0, 0); ok {
 					signal.Book = model.Book{
 						Count: b.Values().Stats()[0].Count(),
@@ -145,20 +341,19 @@ func (s *Socket) connect(out chan *model.TradeSignal, process <-chan api.Signal)
 					}
 				}
 				spread[coin] = buffer.NewWindow(0, 2)
+				if b, ok := s.books[coin]; ok && b.Ready() {
+					signal.OrderBook = b.Features()
+				}
 				s.signals[coin] = signal
 				if signal.Tick.Active {
 					f, _ := strconv.ParseFloat(signal.Meta.Time.Format("20060102.1504"), 64)
 					metrics.Observer.NoteLag(f, string(coin), "socket", "ticker")
 					metrics.Observer.IncrementEvents(string(coin), "_", "ticker", "socket")
-					// TODO : highlight the data flow better
-					//logger.Info().
-					//	Timestamp().
-					//Str("meta", fmt.Sprintf("%+v", signal.Meta)).
-					//Str("coin", string(signal.Coin)).
-					//Str("tick.level", fmt.Sprintf("%+v", signal.Tick.Level)).
-					//Msg("trade=debug")
-					out <- &signal
-					<-process
+					select {
+					case <-ctx.Done():
+						return nil
+					case out <- &signal:
+					}
 				}
 			default:
 				fmt.Printf("data = %+v\n %s\n", data, reflect.TypeOf(data))
@@ -175,6 +370,86 @@ func (s *Socket) connect(out chan *model.TradeSignal, process <-chan api.Signal)
 	}
 }
 
+// subscribe subscribes to the trades , spread and book channels for the given pairs.
+func (s *Socket) subscribe(kraken connection, pairs []string) error {
+	if err := kraken.SubscribeTicker(pairs); err != nil {
+		return fmt.Errorf("error for ticker subscription: %w", err)
+	}
+
+	if err := kraken.SubscribeSpread(pairs); err != nil {
+		return fmt.Errorf("error for spread subscription: %w", err)
+	}
+
+	if err := kraken.SubscribeTrades(pairs); err != nil {
+		return fmt.Errorf("error for trades subscription: %w", err)
+	}
+
+	if s.depth > 0 {
+		if err := kraken.SubscribeBook(pairs, int64(s.depth)); err != nil {
+			return fmt.Errorf("error for book subscription: %w", err)
+		}
+	}
+	return nil
+}
+
+// unsubscribe unsubscribes from all the channels for the given pairs.
+func (s *Socket) unsubscribe(kraken connection, pairs []string) error {
+	for _, channel := range []string{ws.ChanTicker, ws.ChanSpread, ws.ChanTrades} {
+		if err := kraken.Unsubscribe(channel, pairs); err != nil {
+			return fmt.Errorf("error for %s unsubscription: %w", channel, err)
+		}
+	}
+	if s.depth > 0 {
+		if err := kraken.UnsubscribeBook(pairs, int64(s.depth)); err != nil {
+			return fmt.Errorf("error for book unsubscription: %w", err)
+		}
+	}
+	return nil
+}
+
+// drain discards the pending subscription updates.
+func (s *Socket) drain() {
+	for {
+		select {
+		case <-s.updates:
+		default:
+			return
+		}
+	}
+}
+
+// updateBook applies the book snapshot or update to the local order book.
+func updateBook(b *book.Book, data ws.OrderBookUpdate) error {
+	asks, at := bookEntries(data.Asks)
+	bids, bt := bookEntries(data.Bids)
+	t := at
+	if bt.After(t) {
+		t = bt
+	}
+	if data.IsSnapshot {
+		return b.Snapshot(t, asks, bids)
+	}
+	return b.Update(t, asks, bids, data.CheckSum)
+}
+
+func bookEntries(items []ws.OrderBookItem) ([]book.Entry, time.Time) {
+	var last time.Time
+	entries := make([]book.Entry, len(items))
+	for i, item := range items {
+		entries[i] = book.Entry{
+			Price:  item.Price.String(),
+			Volume: item.Volume.String(),
+		}
+		if f, err := item.Time.Float64(); err == nil {
+			s, n := math.Modf(f)
+			if t := time.Unix(int64(s), int64(n*math.Pow(10, 9))); t.After(last) {
+				last = t
+			}
+		}
+	}
+	return entries, last
+}
+
 func tradeToTick(data []ws.Trade, converter kraken_model.TypeConverter) (tick model.Tick, err error) {
 	trade := data[len(data)-1]
 	p, err := trade.Price.Float64()
@@ -2,19 +2,27 @@ package kraken
 
 import (
 	"fmt"
+	"os"
 	"testing"
 
 	"github.com/drakos74/free-coin/internal/account"
+	"github.com/drakos74/free-coin/internal/api"
 	"github.com/drakos74/free-coin/internal/model"
 )
 
 func TestRun(t *testing.T) {
 
+	// the live socket needs the kraken credentials
+	if os.Getenv(account.NewFormat(account.Drakos, Name).Key()) == "" {
+		t.Skip("no kraken credentials")
+	}
+
 	exchange := NewExchange(account.Drakos)
 	fmt.Printf("exchange = %+v\n", exchange)
 
 	socket := NewSocket(model.BTC)
-	ch, err := socket.Run()
+	process := make(chan api.Signal)
+	ch, err := socket.Run(process)
 	if err != nil {
 		t.Fail()
 	}
@@ -22,6 +30,10 @@ func TestRun(t *testing.T) {
 	for signal := range ch {
 		fmt.Printf("book = %+v\n", signal.Book)
 		fmt.Printf("tick = %+v\n", signal.Tick)
+		select {
+		case process <- *api.NewSignal("test").ForCoin(signal.Coin):
+		default:
+		}
 	}
 
 }
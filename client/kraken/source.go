@@ -1,11 +1,13 @@
 package kraken
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
+	"net/http"
 	"os"
 	"path"
 	"path/filepath"
@@ -13,6 +15,8 @@ import (
 
 	krakenapi "github.com/beldur/kraken-go-api-client"
 	"github.com/drakos74/free-coin/client/kraken/model"
+	"github.com/drakos74/free-coin/client/limit"
+	"github.com/drakos74/free-coin/client/replay"
 	"github.com/drakos74/free-coin/internal/api"
 	coinmodel "github.com/drakos74/free-coin/internal/model"
 	cointime "github.com/drakos74/free-coin/internal/time"
@@ -36,11 +40,32 @@ type RemoteSource struct {
 	Interval time.Duration
 	public   *krakenapi.KrakenAPI
 	count    int64
+	limiter  *limit.Limiter
+}
+
+// WithRecorder records the raw trades responses , so that the session can be replayed.
+func (r *RemoteSource) WithRecorder(recorder *replay.Recorder) *RemoteSource {
+	r.public = r.public.WithClient(&http.Client{Transport: recordTransport{
+		next:     http.DefaultTransport,
+		recorder: recorder,
+	}})
+	return r
+}
+
+// WithLimiter sets the rate limiter for the trades polling.
+func (r *RemoteSource) WithLimiter(limiter *limit.Limiter) *RemoteSource {
+	r.limiter = limiter
+	return r
 }
 
 // AssetPairs retrieves the active asset pairs with their trading details from kraken.
 func (r *RemoteSource) AssetPairs() (*krakenapi.AssetPairsResponse, error) {
-	return r.public.AssetPairs()
+	if err := r.limiter.Wait(context.Background(), limit.Sync, callCost); err != nil {
+		return nil, err
+	}
+	pairs, err := r.public.AssetPairs()
+	throttled(r.limiter, err)
+	return pairs, err
 }
 
 // Trades retrieves the next trades batch from kraken.
@@ -55,13 +80,12 @@ func (r *RemoteSource) Trades(coin coinmodel.Coin, since int64) (*coinmodel.Trad
 		Int64("count", r.count).
 		Time("since-time", cointime.FromNano(since)).
 		Msg("calling remote")
+	if err := r.limiter.Wait(context.Background(), limit.History, callCost); err != nil {
+		return nil, fmt.Errorf("could not get trades from kraken: %w", err)
+	}
 	// TODO : avoid the duplicate iteration on the trades
 	response, err := r.public.Trades(pair.Rest, since)
-
-	// storing the response for the tests ...
-	//rr, err := json.Marshal(response)
-	//err = ioutil.WriteFile(fmt.Sprintf("testdata/response-trades/%s/%d.json", string(coin), since), rr, 0644)
-
+	throttled(r.limiter, err)
 	if err != nil {
 		return nil, fmt.Errorf("could not get trades from kraken: %w", err)
 	}
@@ -0,0 +1,223 @@
+package limit
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/drakos74/free-coin/internal/metrics"
+	cointime "github.com/drakos74/free-coin/internal/time"
+)
+
+// Priority is the class of an exchange call , the calls of a higher class are served first.
+type Priority int
+
+const (
+	// Order is the priority of the order placement and the order status lookups.
+	Order Priority = iota
+	// Sync is the priority of the position , balance and price requests.
+	Sync
+	// History is the priority of the trade history polling.
+	History
+)
+
+// String returns the name of the priority.
+func (p Priority) String() string {
+	switch p {
+	case Order:
+		return "order"
+	case Sync:
+		return "sync"
+	case History:
+		return "history"
+	}
+	return fmt.Sprintf("priority-%d", int(p))
+}
+
+// Stats are the calls of a priority , and how long they waited for the limit.
+type Stats struct {
+	Calls  int
+	Waits  int
+	Waited time.Duration
+}
+
+// Limiter schedules the calls to an exchange , so that they stay within its rate limit.
+// The waiting calls are served in priority order , and in arrival order within the same priority.
+// Lower priority calls can be made to leave part of the capacity to the higher priority ones ,
+// so that e.g. the history polling never delays an order.
+type Limiter struct {
+	name     string
+	lock     *sync.Mutex
+	clock    cointime.Clock
+	policy   Policy
+	reserve  map[Priority]float64
+	queue    []*waiter
+	seq      int
+	stats    map[Priority]Stats
+	exceeded int
+}
+
+type waiter struct {
+	priority Priority
+	seq      int
+	wake     chan struct{}
+}
+
+// New creates a new limiter for the exchange with the given policy.
+func New(name string, policy Policy) *Limiter {
+	return &Limiter{
+		name:    name,
+		lock:    new(sync.Mutex),
+		policy:  policy,
+		reserve: make(map[Priority]float64),
+		queue:   make([]*waiter, 0),
+		stats:   make(map[Priority]Stats),
+	}
+}
+
+// WithClock sets the clock of the limiter , by default it follows the current clock.
+func (l *Limiter) WithClock(clock cointime.Clock) *Limiter {
+	l.clock = clock
+	return l
+}
+
+// WithReserve keeps the given capacity free for the higher priorities , when serving the calls of the priority.
+func (l *Limiter) WithReserve(priority Priority, capacity float64) *Limiter {
+	l.reserve[priority] = capacity
+	return l
+}
+
+func (l *Limiter) now() cointime.Clock {
+	if l.clock != nil {
+		return l.clock
+	}
+	return cointime.Current()
+}
+
+// Wait blocks until the call of the given priority and cost can be made , or the context is done.
+// A nil limiter does not limit the calls.
+func (l *Limiter) Wait(ctx context.Context, priority Priority, cost float64) error {
+	if l == nil {
+		return nil
+	}
+	clock := l.now()
+	l.lock.Lock()
+	start := clock.Now()
+	l.seq++
+	w := &waiter{
+		priority: priority,
+		seq:      l.seq,
+		wake:     make(chan struct{}, 1),
+	}
+	l.queue = append(l.queue, w)
+	sort.SliceStable(l.queue, func(i, j int) bool {
+		if l.queue[i].priority == l.queue[j].priority {
+			return l.queue[i].seq < l.queue[j].seq
+		}
+		return l.queue[i].priority < l.queue[j].priority
+	})
+	waited := false
+	for {
+		var timer <-chan time.Time
+		if l.queue[0] == w {
+			now := clock.Now()
+			delay := l.policy.Delay(now, cost+l.reserve[priority])
+			if delay <= 0 {
+				l.policy.Spend(now, cost)
+				l.remove(w)
+				l.notify()
+				level := l.policy.Level(now)
+				wait := now.Sub(start)
+				stats := l.stats[priority]
+				stats.Calls++
+				if waited {
+					stats.Waits++
+					stats.Waited += wait
+				}
+				l.stats[priority] = stats
+				l.lock.Unlock()
+				metrics.Observer.NoteLevel(level, l.name)
+				metrics.Observer.TrackWait(wait.Seconds(), l.name, priority.String())
+				return nil
+			}
+			timer = clock.After(delay)
+		}
+		waited = true
+		l.lock.Unlock()
+		select {
+		case <-ctx.Done():
+			l.lock.Lock()
+			l.remove(w)
+			l.notify()
+			l.lock.Unlock()
+			return fmt.Errorf("could not wait for '%s' rate limit: %w", l.name, ctx.Err())
+		case <-w.wake:
+		case <-timer:
+		}
+		l.lock.Lock()
+	}
+}
+
+// remove removes the waiter from the queue , the caller must hold the lock.
+func (l *Limiter) remove(w *waiter) {
+	for i, q := range l.queue {
+		if q == w {
+			l.queue = append(l.queue[:i], l.queue[i+1:]...)
+			return
+		}
+	}
+}
+
+// notify wakes the first waiter , the caller must hold the lock.
+func (l *Limiter) notify() {
+	if len(l.queue) == 0 {
+		return
+	}
+	select {
+	case l.queue[0].wake <- struct{}{}:
+	default:
+	}
+}
+
+// Exceeded spends all the capacity , after the exchange rejected a call for exceeding the rate limit.
+func (l *Limiter) Exceeded() {
+	if l == nil {
+		return
+	}
+	l.lock.Lock()
+	l.policy.Exhaust(l.now().Now())
+	l.exceeded++
+	l.notify()
+	l.lock.Unlock()
+	metrics.Observer.IncrementExceeded(l.name)
+}
+
+// Level returns the spent capacity.
+func (l *Limiter) Level() float64 {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	return l.policy.Level(l.now().Now())
+}
+
+// Queued returns the number of waiting calls.
+func (l *Limiter) Queued() int {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	return len(l.queue)
+}
+
+// Stats returns the calls of the given priority.
+func (l *Limiter) Stats(priority Priority) Stats {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	return l.stats[priority]
+}
+
+// ExceededCount returns how many times the exchange reported that the limit was exceeded.
+func (l *Limiter) ExceededCount() int {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	return l.exceeded
+}
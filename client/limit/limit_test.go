@@ -0,0 +1,233 @@
+package limit
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	cointime "github.com/drakos74/free-coin/internal/time"
+	"github.com/stretchr/testify/assert"
+)
+
+var start = time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// call makes the call in the background , and returns a channel that closes once it is made.
+func call(l *Limiter, priority Priority, cost float64) chan error {
+	done := make(chan error, 1)
+	go func() {
+		done <- l.Wait(context.Background(), priority, cost)
+		close(done)
+	}()
+	return done
+}
+
+// queued waits until the given number of calls is waiting on the limiter.
+func queued(t *testing.T, l *Limiter, n int) {
+	for i := 0; i < 1000; i++ {
+		if l.Queued() == n {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatalf("expected %d queued calls but was %d", n, l.Queued())
+}
+
+// made checks that the call is made.
+func made(t *testing.T, done chan error) bool {
+	select {
+	case err := <-done:
+		assert.NoError(t, err)
+		return true
+	case <-time.After(time.Second):
+		return false
+	}
+}
+
+// blocked checks that the call is still waiting.
+func blocked(done chan error) bool {
+	select {
+	case <-done:
+		return false
+	case <-time.After(50 * time.Millisecond):
+		return true
+	}
+}
+
+func TestPolicy_Delay(t *testing.T) {
+
+	type test struct {
+		policy Policy
+		spend  float64
+		after  time.Duration
+		cost   float64
+		delay  time.Duration
+		level  float64
+	}
+
+	tests := map[string]test{
+		"decay-available": {
+			policy: NewDecay(15, 0.5),
+			spend:  14,
+			cost:   1,
+			level:  14,
+		},
+		"decay-full": {
+			policy: NewDecay(15, 0.5),
+			spend:  15,
+			cost:   2,
+			delay:  4 * time.Second,
+			level:  15,
+		},
+		"decay-decreased": {
+			policy: NewDecay(15, 0.5),
+			spend:  15,
+			after:  3 * time.Second,
+			cost:   2,
+			delay:  time.Second,
+			level:  13.5,
+		},
+		"decay-above-max": {
+			policy: NewDecay(15, 0.5),
+			spend:  1,
+			cost:   20,
+			delay:  2 * time.Second,
+			level:  1,
+		},
+		"window-available": {
+			policy: NewWindow(1200, time.Minute),
+			spend:  1100,
+			after:  10 * time.Second,
+			cost:   100,
+			level:  1100,
+		},
+		"window-full": {
+			policy: NewWindow(1200, time.Minute),
+			spend:  1100,
+			after:  10 * time.Second,
+			cost:   101,
+			delay:  50 * time.Second,
+			level:  1100,
+		},
+		"window-reset": {
+			policy: NewWindow(1200, time.Minute),
+			spend:  1200,
+			after:  time.Minute,
+			cost:   100,
+			level:  0,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			tt.policy.Spend(start, tt.spend)
+			now := start.Add(tt.after)
+			assert.Equal(t, tt.delay, tt.policy.Delay(now, tt.cost))
+			assert.InDelta(t, tt.level, tt.policy.Level(now), 1e-9)
+		})
+	}
+}
+
+func TestLimiter_Wait(t *testing.T) {
+	clock := cointime.NewSimClock(start)
+	l := New("test", NewDecay(3, 1)).WithClock(clock)
+
+	for i := 0; i < 3; i++ {
+		assert.NoError(t, l.Wait(context.Background(), Sync, 1))
+	}
+	assert.Equal(t, 3.0, l.Level())
+
+	// the counter is full , so the next call waits for it to decay
+	done := call(l, Sync, 1)
+	queued(t, l, 1)
+	assert.True(t, blocked(done))
+	clock.Add(time.Second)
+	assert.True(t, made(t, done))
+
+	stats := l.Stats(Sync)
+	assert.Equal(t, 4, stats.Calls)
+	assert.Equal(t, 1, stats.Waits)
+	assert.Equal(t, time.Second, stats.Waited)
+}
+
+func TestLimiter_Priority(t *testing.T) {
+	clock := cointime.NewSimClock(start)
+	l := New("test", NewDecay(3, 1)).WithClock(clock)
+	assert.NoError(t, l.Wait(context.Background(), Sync, 3))
+
+	history := call(l, History, 1)
+	queued(t, l, 1)
+	sync := call(l, Sync, 1)
+	queued(t, l, 2)
+	order := call(l, Order, 1)
+	queued(t, l, 3)
+
+	// the calls are served in priority order , as the counter decays
+	clock.Add(time.Second)
+	assert.True(t, made(t, order))
+	queued(t, l, 2)
+	clock.Add(time.Second)
+	assert.True(t, made(t, sync))
+	queued(t, l, 1)
+	clock.Add(time.Second)
+	assert.True(t, made(t, history))
+	assert.Equal(t, 0, l.Queued())
+}
+
+func TestLimiter_Reserve(t *testing.T) {
+	clock := cointime.NewSimClock(start)
+	l := New("test", NewDecay(10, 1)).
+		WithClock(clock).
+		WithReserve(History, 5).
+		WithReserve(Sync, 2)
+	assert.NoError(t, l.Wait(context.Background(), History, 5))
+
+	// the history polling leaves part of the counter to the orders and the sync
+	history := call(l, History, 1)
+	queued(t, l, 1)
+	assert.True(t, blocked(history))
+	assert.NoError(t, l.Wait(context.Background(), Sync, 3))
+	assert.NoError(t, l.Wait(context.Background(), Order, 2))
+	assert.Equal(t, 10.0, l.Level())
+
+	clock.Add(6 * time.Second)
+	assert.True(t, made(t, history))
+}
+
+func TestLimiter_Cancel(t *testing.T) {
+	clock := cointime.NewSimClock(start)
+	l := New("test", NewDecay(1, 1)).WithClock(clock)
+	assert.NoError(t, l.Wait(context.Background(), History, 1))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- l.Wait(ctx, History, 1)
+	}()
+	queued(t, l, 1)
+	cancel()
+	err := <-done
+	assert.True(t, errors.Is(err, context.Canceled))
+	assert.Equal(t, 0, l.Queued())
+
+	// a nil limiter does not limit
+	var none *Limiter
+	assert.NoError(t, none.Wait(context.Background(), Order, 100))
+}
+
+func TestLimiter_Exceeded(t *testing.T) {
+	clock := cointime.NewSimClock(start)
+	l := New("test", NewDecay(15, 0.5)).WithClock(clock)
+	assert.NoError(t, l.Wait(context.Background(), Sync, 1))
+
+	// the exchange counter is ahead of ours e.g. because of calls from another process
+	l.Exceeded()
+	assert.Equal(t, 1, l.ExceededCount())
+	assert.Equal(t, 15.0, l.Level())
+	done := call(l, Sync, 1)
+	queued(t, l, 1)
+	clock.Add(time.Second)
+	assert.True(t, blocked(done))
+	clock.Add(time.Second)
+	assert.True(t, made(t, done))
+}
@@ -0,0 +1,131 @@
+package limit
+
+import (
+	"math"
+	"time"
+)
+
+// Policy models the rate limit rules of an exchange , as the capacity spent by the calls.
+// The policies are not safe for concurrent use , the limiter guards them.
+type Policy interface {
+	// Delay returns how long to wait , until the given capacity is available.
+	// A call of more than the full capacity waits for the full capacity.
+	Delay(now time.Time, capacity float64) time.Duration
+	// Spend spends the cost of a call.
+	Spend(now time.Time, cost float64)
+	// Level returns the spent capacity.
+	Level(now time.Time) float64
+	// Exhaust spends all the capacity , after the exchange reports that the limit was exceeded.
+	Exhaust(now time.Time)
+}
+
+// Decay is a counter that the calls increase , and that decreases at a constant rate e.g. the kraken api counter.
+type Decay struct {
+	// Max is the maximum counter value.
+	Max float64
+	// Rate is the decrease of the counter per second.
+	Rate  float64
+	level float64
+	at    time.Time
+}
+
+// NewDecay creates a new decaying counter.
+func NewDecay(max, rate float64) *Decay {
+	return &Decay{
+		Max:  max,
+		Rate: rate,
+	}
+}
+
+func (d *Decay) decay(now time.Time) {
+	if now.After(d.at) {
+		if !d.at.IsZero() {
+			d.level = math.Max(0, d.level-d.Rate*now.Sub(d.at).Seconds())
+		}
+		d.at = now
+	}
+}
+
+// Delay returns the time needed for the counter to decrease enough for the capacity.
+func (d *Decay) Delay(now time.Time, capacity float64) time.Duration {
+	d.decay(now)
+	excess := d.level - (d.Max - math.Min(capacity, d.Max))
+	if excess <= epsilon {
+		return 0
+	}
+	return time.Duration(math.Ceil(excess / d.Rate * float64(time.Second)))
+}
+
+// Spend increases the counter.
+func (d *Decay) Spend(now time.Time, cost float64) {
+	d.decay(now)
+	d.level += cost
+}
+
+// Level returns the counter value.
+func (d *Decay) Level(now time.Time) float64 {
+	d.decay(now)
+	return d.level
+}
+
+// Exhaust sets the counter to its maximum.
+func (d *Decay) Exhaust(now time.Time) {
+	d.decay(now)
+	d.level = math.Max(d.level, d.Max)
+}
+
+// Window is a weight budget that resets at the start of each window e.g. the binance request weight per minute.
+type Window struct {
+	// Max is the weight budget of a window.
+	Max float64
+	// Length is the duration of the window , the windows are aligned to the clock.
+	Length time.Duration
+	used   float64
+	start  time.Time
+}
+
+// NewWindow creates a new window weight budget.
+func NewWindow(max float64, length time.Duration) *Window {
+	return &Window{
+		Max:    max,
+		Length: length,
+	}
+}
+
+func (w *Window) roll(now time.Time) {
+	start := now.Truncate(w.Length)
+	if start.After(w.start) {
+		w.start = start
+		w.used = 0
+	}
+}
+
+// Delay returns the time until the next window , if the capacity is not available in the current one.
+func (w *Window) Delay(now time.Time, capacity float64) time.Duration {
+	w.roll(now)
+	if w.used+math.Min(capacity, w.Max) <= w.Max+epsilon {
+		return 0
+	}
+	return w.start.Add(w.Length).Sub(now)
+}
+
+// Spend adds the weight to the current window.
+func (w *Window) Spend(now time.Time, cost float64) {
+	w.roll(now)
+	w.used += cost
+}
+
+// Level returns the weight used in the current window.
+func (w *Window) Level(now time.Time) float64 {
+	w.roll(now)
+	return w.used
+}
+
+// Exhaust uses the full budget of the current window.
+func (w *Window) Exhaust(now time.Time) {
+	w.roll(now)
+	w.used = math.Max(w.used, w.Max)
+}
+
+// epsilon absorbs the rounding of the decay.
+const epsilon = 1e-9
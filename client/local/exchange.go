@@ -230,6 +230,22 @@ func (e *Exchange) Orders() (orders []model.TrackedOrder) {
 	return e.orders
 }
 
+// QueryOrder returns the submitted order with the same client order id.
+func (e *Exchange) QueryOrder(ctx context.Context, order *model.TrackedOrder) (*model.TrackedOrder, bool, error) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	if order.CID == "" {
+		return nil, false, fmt.Errorf("no client id for order '%s'", order.ID)
+	}
+	for _, o := range e.orders {
+		if o.CID == order.CID {
+			found := o
+			return &found, true, nil
+		}
+	}
+	return nil, false, nil
+}
+
 func (e *Exchange) Balance(ctx context.Context, priceMap map[model.Coin]model.CurrentPrice) (map[model.Coin]model.Balance, error) {
 	// TODO :
 	return make(map[model.Coin]model.Balance), nil
@@ -0,0 +1,194 @@
+package local
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/drakos74/free-coin/client"
+	"github.com/drakos74/free-coin/internal/model"
+)
+
+// Cash is the quote currency of the paper wallet.
+const Cash model.Coin = "EUR"
+
+// Paper is a paper-trading exchange , it fills the orders against a virtual wallet
+// at the last price of the running stream , applying the exchange fees.
+// It can run side by side with the live exchange for the same strategy.
+type Paper struct {
+	*Exchange
+	lock     *sync.Mutex
+	name     string
+	initial  float64
+	cash     float64
+	fee      float64
+	fees     float64
+	orders   int
+	holdings map[model.Coin]float64
+	prices   map[model.Coin]float64
+}
+
+// PaperStats is the summary of the paper wallet.
+// Value is the cash along with the holdings at the last price , PnL is the value difference to the starting balance.
+type PaperStats struct {
+	Name    string
+	Initial float64
+	Cash    float64
+	Value   float64
+	PnL     float64
+	Fees    float64
+	Orders  int
+}
+
+// NewPaper creates a new paper exchange with the given starting balance.
+func NewPaper(name string, balance float64) *Paper {
+	return &Paper{
+		Exchange: NewExchange(VoidLog),
+		lock:     new(sync.Mutex),
+		name:     name,
+		initial:  balance,
+		cash:     balance,
+		fee:      model.Fees,
+		holdings: make(map[model.Coin]float64),
+		prices:   make(map[model.Coin]float64),
+	}
+}
+
+// WithFee sets the fee percentage applied on the order value , a zero value keeps the default fee.
+func (p *Paper) WithFee(fee float64) *Paper {
+	if fee > 0 {
+		p.fee = fee
+	}
+	return p
+}
+
+// Name returns the name of the paper account.
+func (p *Paper) Name() string {
+	return p.name
+}
+
+// Process tracks the last price of the trade coin.
+func (p *Paper) Process(trade *model.TradeSignal) {
+	if trade == nil {
+		return
+	}
+	p.lock.Lock()
+	p.prices[trade.Coin] = trade.Tick.Price
+	p.lock.Unlock()
+	p.Exchange.Process(trade)
+}
+
+// OpenOrder fills the order at the last price and updates the wallet , reporting the fill along with the fees.
+// Spot orders need enough cash for buying and enough volume for selling.
+func (p *Paper) OpenOrder(order *model.TrackedOrder) (*model.TrackedOrder, []string, error) {
+	p.lock.Lock()
+	price, ok := p.prices[order.Coin]
+	if !ok {
+		price = order.Price
+	}
+	if price <= 0 {
+		p.lock.Unlock()
+		return nil, nil, fmt.Errorf("no price for '%s'", order.Coin)
+	}
+	value := price * order.Volume
+	fee := value * p.fee / 100
+	spot := order.Leverage == model.NoLeverage
+	switch order.Type {
+	case model.Buy:
+		if spot && value+fee > p.cash {
+			p.lock.Unlock()
+			return nil, nil, fmt.Errorf("insufficient balance for '%s': %.2f < %.2f", order.Coin, p.cash, value+fee)
+		}
+		p.cash -= value + fee
+		p.holdings[order.Coin] += order.Volume
+	case model.Sell:
+		if spot && order.Volume > p.holdings[order.Coin] {
+			p.lock.Unlock()
+			return nil, nil, fmt.Errorf("insufficient volume for '%s': %f < %f", order.Coin, p.holdings[order.Coin], order.Volume)
+		}
+		p.cash += value - fee
+		p.holdings[order.Coin] -= order.Volume
+	default:
+		p.lock.Unlock()
+		return nil, nil, fmt.Errorf("invalid order type '%s'", order.Type.String())
+	}
+	p.fees += fee
+	p.orders++
+	p.lock.Unlock()
+	order.Price = price
+	order.Fill = &model.Fill{
+		Price:  price,
+		Volume: order.Volume,
+		Fees:   fee,
+	}
+	return p.Exchange.OpenOrder(order)
+}
+
+// Gather returns the report of the paper orders for each coin.
+func (p *Paper) Gather(print bool) map[model.Coin]client.Report {
+	p.Exchange.mutex.Lock()
+	defer p.Exchange.mutex.Unlock()
+	return p.Exchange.Gather(print)
+}
+
+// CurrentPrice returns the last price for each coin of the stream.
+func (p *Paper) CurrentPrice(ctx context.Context) (map[model.Coin]model.CurrentPrice, error) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	prices := make(map[model.Coin]model.CurrentPrice)
+	for coin, price := range p.prices {
+		prices[coin] = model.CurrentPrice{
+			Coin:  coin,
+			Price: price,
+		}
+	}
+	return prices, nil
+}
+
+// Balance returns the cash and the coin holdings of the paper wallet.
+// The holdings are valued at the given prices , or the last price of the stream.
+func (p *Paper) Balance(ctx context.Context, priceMap map[model.Coin]model.CurrentPrice) (map[model.Coin]model.Balance, error) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	balance := map[model.Coin]model.Balance{
+		Cash: {
+			Coin:   Cash,
+			Volume: p.cash,
+			Price:  1,
+		},
+	}
+	for coin, volume := range p.holdings {
+		if volume == 0 {
+			continue
+		}
+		price := p.prices[coin]
+		if current, ok := priceMap[coin]; ok {
+			price = current.Price
+		}
+		balance[coin] = model.Balance{
+			Coin:   coin,
+			Volume: volume,
+			Price:  price,
+		}
+	}
+	return balance, nil
+}
+
+// Stats returns the summary of the paper wallet.
+func (p *Paper) Stats() PaperStats {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	value := p.cash
+	for coin, volume := range p.holdings {
+		value += volume * p.prices[coin]
+	}
+	return PaperStats{
+		Name:    p.name,
+		Initial: p.initial,
+		Cash:    p.cash,
+		Value:   value,
+		PnL:     value - p.initial,
+		Fees:    p.fees,
+		Orders:  p.orders,
+	}
+}
@@ -0,0 +1,130 @@
+package local
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/drakos74/free-coin/internal/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPaper_OpenOrder(t *testing.T) {
+
+	type test struct {
+		orders []*model.TrackedOrder
+		price  float64
+		err    string
+		stats  PaperStats
+	}
+
+	order := func(t model.Type, volume float64, leverage model.Leverage) *model.TrackedOrder {
+		return model.NewOrder(model.BTC).
+			Market().
+			WithType(t).
+			WithVolume(volume).
+			WithLeverage(leverage).
+			CreateTracked(model.Key{Coin: model.BTC}, time.Now(), "")
+	}
+
+	tests := map[string]test{
+		"buy": {
+			orders: []*model.TrackedOrder{order(model.Buy, 0.5, model.NoLeverage)},
+			price:  100,
+			stats:  PaperStats{Cash: 949.9, Value: 999.9, PnL: -0.1, Fees: 0.1, Orders: 1},
+		},
+		"buy-and-sell-higher": {
+			orders: []*model.TrackedOrder{
+				order(model.Buy, 0.5, model.NoLeverage),
+				order(model.Sell, 0.5, model.NoLeverage),
+			},
+			price: 100,
+			stats: PaperStats{Cash: 999.8, Value: 999.8, PnL: -0.2, Fees: 0.2, Orders: 2},
+		},
+		"insufficient-balance": {
+			orders: []*model.TrackedOrder{order(model.Buy, 20, model.NoLeverage)},
+			price:  100,
+			err:    "insufficient balance",
+			stats:  PaperStats{Cash: 1000, Value: 1000},
+		},
+		"spot-short": {
+			orders: []*model.TrackedOrder{order(model.Sell, 0.5, model.NoLeverage)},
+			price:  100,
+			err:    "insufficient volume",
+			stats:  PaperStats{Cash: 1000, Value: 1000},
+		},
+		"margin-short": {
+			orders: []*model.TrackedOrder{order(model.Sell, 0.5, model.L_5)},
+			price:  100,
+			stats:  PaperStats{Cash: 1049.9, Value: 999.9, PnL: -0.1, Fees: 0.1, Orders: 1},
+		},
+		"no-price": {
+			orders: []*model.TrackedOrder{order(model.Buy, 0.5, model.NoLeverage)},
+			err:    "no price",
+			stats:  PaperStats{Cash: 1000, Value: 1000},
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			paper := NewPaper(name, 1000).WithFee(0.2)
+			if tt.price > 0 {
+				paper.Process(&model.TradeSignal{
+					Coin: model.BTC,
+					Tick: model.NewTick(tt.price, 1, model.Buy, time.Now()),
+				})
+			}
+			var err error
+			for _, o := range tt.orders {
+				_, _, err = paper.OpenOrder(o)
+				if err != nil {
+					break
+				}
+				assert.Equal(t, tt.price, o.Price)
+			}
+			if tt.err != "" {
+				if assert.Error(t, err) {
+					assert.Contains(t, err.Error(), tt.err)
+				}
+			} else {
+				assert.NoError(t, err)
+			}
+			stats := paper.Stats()
+			assert.Equal(t, name, stats.Name)
+			assert.Equal(t, 1000.0, stats.Initial)
+			assert.InDelta(t, tt.stats.Cash, stats.Cash, 1e-9)
+			assert.InDelta(t, tt.stats.Value, stats.Value, 1e-9)
+			assert.InDelta(t, tt.stats.PnL, stats.PnL, 1e-9)
+			assert.InDelta(t, tt.stats.Fees, stats.Fees, 1e-9)
+			assert.Equal(t, tt.stats.Orders, stats.Orders)
+		})
+	}
+}
+
+func TestPaper_Balance(t *testing.T) {
+	paper := NewPaper("paper", 1000)
+	paper.Process(&model.TradeSignal{
+		Coin: model.ETH,
+		Tick: model.NewTick(10, 1, model.Buy, time.Now()),
+	})
+	_, _, err := paper.OpenOrder(model.NewOrder(model.ETH).
+		Market().
+		WithType(model.Buy).
+		WithVolume(2).
+		WithLeverage(model.NoLeverage).
+		CreateTracked(model.Key{Coin: model.ETH}, time.Now(), ""))
+	assert.NoError(t, err)
+
+	prices, err := paper.CurrentPrice(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 10.0, prices[model.ETH].Price)
+
+	balance, err := paper.Balance(context.Background(), map[model.Coin]model.CurrentPrice{
+		model.ETH: {Coin: model.ETH, Price: 12},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(balance))
+	assert.InDelta(t, 1000-20-20*model.Fees/100, balance[Cash].Volume, 1e-9)
+	assert.Equal(t, 2.0, balance[model.ETH].Volume)
+	assert.Equal(t, 24.0, balance[model.ETH].Value())
+}
@@ -0,0 +1,155 @@
+package replay
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	cointime "github.com/drakos74/free-coin/internal/time"
+)
+
+const (
+	// Socket is the source of the websocket messages.
+	Socket = "socket"
+	// Rest is the source of the rest api responses.
+	Rest = "rest"
+)
+
+// Message is a timestamped raw message of an exchange session.
+// Channel is the socket channel or the rest method , and Key the pair or coin it refers to.
+type Message struct {
+	Time    time.Time       `json:"time"`
+	Source  string          `json:"source"`
+	Channel string          `json:"channel"`
+	Key     string          `json:"key"`
+	Data    json.RawMessage `json:"data"`
+}
+
+// Recorder writes the raw messages of a session to a file , one json message per line.
+type Recorder struct {
+	lock *sync.Mutex
+	file *os.File
+	enc  *json.Encoder
+}
+
+// NewRecorder creates a new recorder appending to the given file.
+func NewRecorder(filename string) (*Recorder, error) {
+	if err := os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
+		return nil, fmt.Errorf("could not create recording dir: %w", err)
+	}
+	file, err := os.OpenFile(filename, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("could not open recording file: %w", err)
+	}
+	return &Recorder{
+		lock: new(sync.Mutex),
+		file: file,
+		enc:  json.NewEncoder(file),
+	}, nil
+}
+
+// Record writes the raw data of a message , stamped with the current time.
+func (r *Recorder) Record(source, channel, key string, data []byte) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	err := r.enc.Encode(Message{
+		Time:    cointime.Now(),
+		Source:  source,
+		Channel: channel,
+		Key:     key,
+		Data:    data,
+	})
+	if err != nil {
+		return fmt.Errorf("could not record message: %w", err)
+	}
+	return nil
+}
+
+// Close closes the recording file.
+func (r *Recorder) Close() error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return r.file.Close()
+}
+
+// Load reads the messages of a recording file.
+func Load(filename string) ([]Message, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, fmt.Errorf("could not open recording file: %w", err)
+	}
+	defer file.Close()
+	messages := make([]Message, 0)
+	dec := json.NewDecoder(file)
+	for {
+		var message Message
+		err := dec.Decode(&message)
+		if errors.Is(err, io.EOF) {
+			return messages, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("could not read message %d: %w", len(messages), err)
+		}
+		messages = append(messages, message)
+	}
+}
+
+// Filter returns the messages of the given source.
+func Filter(messages []Message, source string) []Message {
+	filtered := make([]Message, 0)
+	for _, m := range messages {
+		if m.Source == source {
+			filtered = append(filtered, m)
+		}
+	}
+	return filtered
+}
+
+// Player feeds back recorded messages , with the original timing or accelerated.
+type Player struct {
+	messages []Message
+	speed    float64
+}
+
+// NewPlayer creates a new player for the messages , playing them as fast as possible.
+func NewPlayer(messages []Message) *Player {
+	return &Player{
+		messages: messages,
+	}
+}
+
+// WithSpeed plays the messages with the original timing , accelerated by the given factor ,
+// i.e. 1 for the original timing and 10 for 10 times faster.
+func (p *Player) WithSpeed(speed float64) *Player {
+	p.speed = speed
+	return p
+}
+
+// Play emits the messages in order , until they are all played or the done channel is closed.
+func (p *Player) Play(done <-chan struct{}, emit func(message Message) error) error {
+	for i, m := range p.messages {
+		if i > 0 && p.speed > 0 {
+			if wait := time.Duration(float64(m.Time.Sub(p.messages[i-1].Time)) / p.speed); wait > 0 {
+				select {
+				case <-done:
+					return nil
+				case <-cointime.Current().After(wait):
+				}
+			}
+		}
+		select {
+		case <-done:
+			return nil
+		default:
+		}
+		if err := emit(m); err != nil {
+			return fmt.Errorf("could not play message %d: %w", i, err)
+		}
+	}
+	return nil
+}
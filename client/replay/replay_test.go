@@ -0,0 +1,118 @@
+package replay
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRecorder_Load(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "session", "kraken.json")
+	recorder, err := NewRecorder(filename)
+	assert.NoError(t, err)
+	assert.NoError(t, recorder.Record(Socket, "trade", "XBT/EUR", []byte(`[["41000.1","0.1"]]`)))
+	assert.NoError(t, recorder.Record(Rest, "trades", "BTC", []byte(`{"last":1}`)))
+	assert.NoError(t, recorder.Close())
+
+	// a new recorder appends to the same file
+	recorder, err = NewRecorder(filename)
+	assert.NoError(t, err)
+	assert.NoError(t, recorder.Record(Socket, "spread", "XBT/EUR", []byte(`["1","2"]`)))
+	assert.NoError(t, recorder.Close())
+
+	messages, err := Load(filename)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, len(messages))
+	assert.Equal(t, `[["41000.1","0.1"]]`, string(messages[0].Data))
+	assert.Equal(t, "BTC", messages[1].Key)
+
+	socket := Filter(messages, Socket)
+	assert.Equal(t, 2, len(socket))
+	assert.Equal(t, "spread", socket[1].Channel)
+
+	_, err = Load(filepath.Join(t.TempDir(), "missing.json"))
+	assert.Error(t, err)
+}
+
+func TestPlayer_Play(t *testing.T) {
+
+	start := time.Now()
+	messages := []Message{
+		{Time: start, Key: "0"},
+		{Time: start.Add(100 * time.Millisecond), Key: "1"},
+		{Time: start.Add(200 * time.Millisecond), Key: "2"},
+	}
+
+	type test struct {
+		speed    float64
+		emit     func(m Message) error
+		keys     []string
+		min, max time.Duration
+		err      bool
+	}
+
+	tests := map[string]test{
+		"fast": {
+			keys: []string{"0", "1", "2"},
+			max:  50 * time.Millisecond,
+		},
+		"timing": {
+			speed: 2,
+			keys:  []string{"0", "1", "2"},
+			min:   100 * time.Millisecond,
+			max:   190 * time.Millisecond,
+		},
+		"error": {
+			emit: func(m Message) error {
+				if m.Key == "1" {
+					return errors.New("emit error")
+				}
+				return nil
+			},
+			keys: []string{"0", "1"},
+			err:  true,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			keys := make([]string, 0)
+			begin := time.Now()
+			err := NewPlayer(messages).WithSpeed(tt.speed).Play(make(chan struct{}), func(m Message) error {
+				keys = append(keys, m.Key)
+				if tt.emit != nil {
+					return tt.emit(m)
+				}
+				return nil
+			})
+			elapsed := time.Since(begin)
+			if tt.err {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+				assert.True(t, elapsed >= tt.min, "elapsed %v", elapsed)
+				assert.True(t, elapsed < tt.max, "elapsed %v", elapsed)
+			}
+			assert.Equal(t, tt.keys, keys)
+		})
+	}
+}
+
+func TestPlayer_Done(t *testing.T) {
+	start := time.Now()
+	done := make(chan struct{})
+	keys := make([]string, 0)
+	err := NewPlayer([]Message{
+		{Time: start, Key: "0"},
+		{Time: start.Add(time.Hour), Key: "1"},
+	}).WithSpeed(1).Play(done, func(m Message) error {
+		keys = append(keys, m.Key)
+		close(done)
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"0"}, keys)
+}
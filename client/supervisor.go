@@ -0,0 +1,368 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/drakos74/free-coin/internal/api"
+	"github.com/drakos74/free-coin/internal/concurrent"
+	"github.com/drakos74/free-coin/internal/metrics"
+	"github.com/drakos74/free-coin/internal/model"
+	"github.com/rs/zerolog/log"
+)
+
+// Session is a single connection to a streaming source.
+// It should push the received trades to the out channel and call beat on every message received,
+// including the non-trade ones, until the context is cancelled or the connection fails.
+type Session func(ctx context.Context, out chan<- *model.TradeSignal, beat func()) error
+
+// BackFill retrieves the trades for the given coin since the given time.
+type BackFill func(coin model.Coin, since time.Time) ([]model.TradeSignal, error)
+
+// EventType is the type of connection event.
+type EventType string
+
+const (
+	// Disconnect signals a lost connection.
+	Disconnect EventType = "disconnect"
+	// Reconnect signals a re-established connection.
+	Reconnect EventType = "reconnect"
+	// Fill signals the back-fill of the missed trades after a reconnect.
+	Fill EventType = "back-fill"
+	// WarmUp signals the replay of the history before connecting.
+	WarmUp EventType = "warm-up"
+)
+
+// Event is a connection event reported by the supervisor.
+type Event struct {
+	Type    EventType
+	Coin    model.Coin
+	Since   time.Time
+	Count   int
+	Attempt int
+	Err     error
+}
+
+func (e Event) String() string {
+	switch e.Type {
+	case Disconnect:
+		return fmt.Sprintf("%s #%d: %v", e.Type, e.Attempt, e.Err)
+	case Fill, WarmUp:
+		if e.Err != nil {
+			return fmt.Sprintf("%s %s since %s failed: %v", e.Type, e.Coin, e.Since.Format(time.Stamp), e.Err)
+		}
+		return fmt.Sprintf("%s %s since %s: %d trades", e.Type, e.Coin, e.Since.Format(time.Stamp), e.Count)
+	}
+	return fmt.Sprintf("%s #%d", e.Type, e.Attempt)
+}
+
+// warmUp holds the history replay details before going live.
+type warmUp struct {
+	since   time.Time
+	coins   []model.Coin
+	history BackFill
+}
+
+// tradeKey identifies a trade for de-duplication.
+type tradeKey struct {
+	time   int64
+	price  float64
+	volume float64
+	t      model.Type
+}
+
+// Supervisor keeps a streaming session alive.
+// It restarts the session with an exponential backoff when it fails or stays silent for longer than the timeout,
+// and back-fills the trades missed during the disconnect, so that the trades are emitted once and in timestamp order.
+type Supervisor struct {
+	name     string
+	session  Session
+	backFill BackFill
+	backoff  *concurrent.Backoff
+	timeout  time.Duration
+	report   func(event Event)
+	warmUp   warmUp
+	last     map[model.Coin]time.Time
+	seen     map[model.Coin]map[tradeKey]bool
+}
+
+// NewSupervisor creates a new supervisor for the given session.
+func NewSupervisor(name string, session Session) *Supervisor {
+	return &Supervisor{
+		name:    name,
+		session: session,
+		backoff: concurrent.NewBackoff(time.Second, 2*time.Minute).WithJitter(0.2),
+		timeout: 30 * time.Second,
+		report:  func(event Event) {},
+		last:    make(map[model.Coin]time.Time),
+		seen:    make(map[model.Coin]map[tradeKey]bool),
+	}
+}
+
+// WithBackFill adds the back-fill logic for the missed trades.
+func (s *Supervisor) WithBackFill(backFill BackFill) *Supervisor {
+	s.backFill = backFill
+	return s
+}
+
+// WithBackoff overrides the default reconnect backoff.
+func (s *Supervisor) WithBackoff(backoff *concurrent.Backoff) *Supervisor {
+	s.backoff = backoff
+	return s
+}
+
+// WithTimeout sets the max silence period , after which the session is considered dead.
+func (s *Supervisor) WithTimeout(timeout time.Duration) *Supervisor {
+	s.timeout = timeout
+	return s
+}
+
+// WithReport adds a reporter for the connection events.
+func (s *Supervisor) WithReport(report func(event Event)) *Supervisor {
+	s.report = report
+	return s
+}
+
+// WithWarmUp replays the history of the given coins since the given time , before connecting the session.
+// The replayed trades are not live , the session then continues from the last replayed trade for each coin.
+func (s *Supervisor) WithWarmUp(since time.Time, history BackFill, coins ...model.Coin) *Supervisor {
+	s.warmUp = warmUp{
+		since:   since,
+		coins:   coins,
+		history: history,
+	}
+	return s
+}
+
+// Run runs the session until the context is cancelled.
+// If the process channel is given , it waits for a signal after each emitted trade.
+// It closes the out channel when done.
+func (s *Supervisor) Run(ctx context.Context, out chan<- *model.TradeSignal, process <-chan api.Signal) {
+	defer close(out)
+	if s.warmUp.history != nil {
+		s.replay(ctx, out, process)
+	}
+	reconnect := false
+	for {
+		err := s.run(ctx, out, process, reconnect)
+		if ctx.Err() != nil {
+			return
+		}
+		delay := s.backoff.Next()
+		metrics.Observer.IncrementEvents("_", "_", string(Disconnect), s.name)
+		log.Warn().Err(err).
+			Str("source", s.name).
+			Int("attempt", s.backoff.Attempt()).
+			Dur("delay", delay).
+			Msg("session disconnected")
+		s.report(Event{Type: Disconnect, Attempt: s.backoff.Attempt(), Err: err})
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(delay):
+		}
+		reconnect = true
+	}
+}
+
+// run runs a single session , until it fails or times out.
+func (s *Supervisor) run(ctx context.Context, out chan<- *model.TradeSignal, process <-chan api.Signal, reconnect bool) error {
+	sessionCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	trades := make(chan *model.TradeSignal)
+	beats := make(chan struct{}, 1)
+	done := make(chan error, 1)
+	go func() {
+		defer close(trades)
+		done <- s.session(sessionCtx, trades, func() {
+			select {
+			case beats <- struct{}{}:
+			default:
+			}
+		})
+	}()
+	// make sure the session is drained and finished , before we start a new one
+	defer func() {
+		cancel()
+		for range trades {
+		}
+	}()
+
+	connected := false
+	connect := func() {
+		// we need to be connected first , so that we back-fill before the new trades
+		if !connected {
+			connected = true
+			s.connected(ctx, out, process, reconnect)
+		}
+	}
+	timer := time.NewTimer(s.timeout)
+	defer timer.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-timer.C:
+			return fmt.Errorf("no messages received for %v", s.timeout)
+		case <-beats:
+			connect()
+		case trade, ok := <-trades:
+			if !ok {
+				err := <-done
+				if err == nil {
+					err = fmt.Errorf("session closed")
+				}
+				return err
+			}
+			connect()
+			s.emit(ctx, out, process, trade)
+		}
+		if !timer.Stop() {
+			select {
+			case <-timer.C:
+			default:
+			}
+		}
+		timer.Reset(s.timeout)
+	}
+}
+
+// connected handles a newly established session.
+// It back-fills the gap since the last emitted trades , either from a previous session or the warm-up replay.
+func (s *Supervisor) connected(ctx context.Context, out chan<- *model.TradeSignal, process <-chan api.Signal, reconnect bool) {
+	if reconnect {
+		attempt := s.backoff.Attempt()
+		s.backoff.Reset()
+		metrics.Observer.IncrementEvents("_", "_", string(Reconnect), s.name)
+		log.Info().Str("source", s.name).Int("attempt", attempt).Msg("session reconnected")
+		s.report(Event{Type: Reconnect, Attempt: attempt})
+	}
+	s.fill(ctx, out, process)
+}
+
+// replay emits the history for the warm-up coins , merged in timestamp order.
+func (s *Supervisor) replay(ctx context.Context, out chan<- *model.TradeSignal, process <-chan api.Signal) {
+	since := s.warmUp.since
+	trades := make([]model.TradeSignal, 0)
+	failed := make(map[model.Coin]bool)
+	for _, coin := range s.warmUp.coins {
+		history, err := s.warmUp.history(coin, since)
+		if err != nil {
+			failed[coin] = true
+			metrics.Observer.IncrementErrors(string(coin), s.name)
+			log.Error().Err(err).Str("source", s.name).Str("coin", string(coin)).Msg("could not replay history")
+			s.report(Event{Type: WarmUp, Coin: coin, Since: since, Err: err})
+		}
+		for i := range history {
+			if history[i].Coin == model.NoCoin {
+				history[i].Coin = coin
+			}
+		}
+		trades = append(trades, history...)
+	}
+	sort.SliceStable(trades, func(i, j int) bool {
+		return trades[i].Tick.Time.Before(trades[j].Tick.Time)
+	})
+	count := make(map[model.Coin]int)
+	for i := range trades {
+		trade := trades[i]
+		trade.Meta.Live = false
+		if s.emit(ctx, out, process, &trade) {
+			count[trade.Coin]++
+		}
+		if ctx.Err() != nil {
+			return
+		}
+	}
+	for _, coin := range s.warmUp.coins {
+		if failed[coin] {
+			continue
+		}
+		metrics.Observer.AddTrades(float64(count[coin]), string(coin), s.name, string(WarmUp))
+		log.Info().Str("source", s.name).Str("coin", string(coin)).Int("count", count[coin]).Time("since", since).Msg("replayed history")
+		s.report(Event{Type: WarmUp, Coin: coin, Since: since, Count: count[coin]})
+	}
+}
+
+// fill back-fills the missed trades for all the coins we have seen so far.
+func (s *Supervisor) fill(ctx context.Context, out chan<- *model.TradeSignal, process <-chan api.Signal) {
+	if s.backFill == nil {
+		return
+	}
+	coins := make([]model.Coin, 0, len(s.last))
+	for coin := range s.last {
+		coins = append(coins, coin)
+	}
+	sort.Slice(coins, func(i, j int) bool {
+		return coins[i] < coins[j]
+	})
+	for _, coin := range coins {
+		since := s.last[coin]
+		trades, err := s.backFill(coin, since)
+		if err != nil {
+			metrics.Observer.IncrementErrors(string(coin), s.name)
+			log.Error().Err(err).Str("source", s.name).Str("coin", string(coin)).Msg("could not back-fill trades")
+			s.report(Event{Type: Fill, Coin: coin, Since: since, Err: err})
+			continue
+		}
+		sort.SliceStable(trades, func(i, j int) bool {
+			return trades[i].Tick.Time.Before(trades[j].Tick.Time)
+		})
+		count := 0
+		for i := range trades {
+			trade := trades[i]
+			if trade.Coin == model.NoCoin {
+				trade.Coin = coin
+			}
+			if s.emit(ctx, out, process, &trade) {
+				count++
+			}
+		}
+		metrics.Observer.AddTrades(float64(count), string(coin), s.name, string(Fill))
+		log.Info().Str("source", s.name).Str("coin", string(coin)).Int("count", count).Time("since", since).Msg("back-filled trades")
+		s.report(Event{Type: Fill, Coin: coin, Since: since, Count: count})
+	}
+}
+
+// emit pushes the trade to the output , if it is not a duplicate or older than the last emitted one.
+func (s *Supervisor) emit(ctx context.Context, out chan<- *model.TradeSignal, process <-chan api.Signal, trade *model.TradeSignal) bool {
+	coin := trade.Coin
+	t := trade.Tick.Time
+	last := s.last[coin]
+	if t.Before(last) {
+		metrics.Observer.IncrementTrades(string(coin), s.name, "out-of-order")
+		return false
+	}
+	key := tradeKey{
+		time:   t.UnixNano(),
+		price:  trade.Tick.Price,
+		volume: trade.Tick.Volume,
+		t:      trade.Tick.Type,
+	}
+	if t.After(last) {
+		// we only need to keep the trades at the latest timestamp for de-duplication
+		s.seen[coin] = make(map[tradeKey]bool)
+		s.last[coin] = t
+	}
+	if s.seen[coin][key] {
+		metrics.Observer.IncrementTrades(string(coin), s.name, "duplicate")
+		return false
+	}
+	s.seen[coin][key] = true
+	select {
+	case <-ctx.Done():
+		return false
+	case out <- trade:
+	}
+	if process != nil {
+		select {
+		case <-ctx.Done():
+			return false
+		case <-process:
+		}
+	}
+	return true
+}
@@ -0,0 +1,224 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/drakos74/free-coin/internal/concurrent"
+	"github.com/drakos74/free-coin/internal/model"
+	"github.com/stretchr/testify/assert"
+)
+
+var start = time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func newTrade(coin model.Coin, s int, price float64) model.TradeSignal {
+	return model.TradeSignal{
+		Coin: coin,
+		Tick: model.NewTick(price, 1, model.Buy, start.Add(time.Duration(s)*time.Second)),
+	}
+}
+
+// sessions returns a session that serves the given trades on each consecutive connection.
+// It fails after each batch , apart from the last one , where it stays connected.
+func sessions(batches ...[]model.TradeSignal) Session {
+	i := 0
+	return func(ctx context.Context, out chan<- *model.TradeSignal, beat func()) error {
+		batch := batches[i]
+		i++
+		beat()
+		for j := range batch {
+			trade := batch[j]
+			select {
+			case <-ctx.Done():
+				return nil
+			case out <- &trade:
+			}
+		}
+		if i < len(batches) {
+			return fmt.Errorf("connection lost")
+		}
+		<-ctx.Done()
+		return nil
+	}
+}
+
+func live(trades ...model.TradeSignal) []model.TradeSignal {
+	for i := range trades {
+		trades[i].Meta.Live = true
+	}
+	return trades
+}
+
+func collect(t *testing.T, s *Supervisor, n int) []model.TradeSignal {
+	ctx, cancel := context.WithCancel(context.Background())
+	out := make(chan *model.TradeSignal)
+	go s.Run(ctx, out, nil)
+	trades := make([]model.TradeSignal, 0)
+	timeout := time.After(5 * time.Second)
+	for len(trades) < n {
+		select {
+		case trade := <-out:
+			trades = append(trades, *trade)
+		case <-timeout:
+			t.Fatalf("timed out after %d trades", len(trades))
+		}
+	}
+	cancel()
+	// the output should be closed after the cancellation
+	for range out {
+	}
+	return trades
+}
+
+func TestSupervisor_BackFill(t *testing.T) {
+
+	lock := new(sync.Mutex)
+	events := make([]Event, 0)
+	fills := make(map[model.Coin]time.Time)
+
+	s := NewSupervisor("test", sessions(
+		[]model.TradeSignal{newTrade(model.BTC, 1, 100), newTrade(model.ETH, 1, 10), newTrade(model.BTC, 2, 101)},
+		// first trade after the reconnect overlaps with the back-fill
+		[]model.TradeSignal{newTrade(model.BTC, 5, 104), newTrade(model.BTC, 6, 105)},
+	)).
+		WithBackoff(concurrent.NewBackoff(time.Millisecond, time.Millisecond)).
+		WithBackFill(func(coin model.Coin, since time.Time) ([]model.TradeSignal, error) {
+			lock.Lock()
+			fills[coin] = since
+			lock.Unlock()
+			switch coin {
+			case model.BTC:
+				// duplicate of the last emitted , out of order and overlapping trades
+				return []model.TradeSignal{
+					newTrade(model.BTC, 4, 103),
+					newTrade(model.BTC, 2, 101),
+					newTrade(model.BTC, 3, 102),
+					newTrade(model.BTC, 5, 104),
+				}, nil
+			}
+			return nil, fmt.Errorf("no trades for %s", coin)
+		}).
+		WithReport(func(event Event) {
+			lock.Lock()
+			events = append(events, event)
+			lock.Unlock()
+		})
+
+	trades := collect(t, s, 7)
+
+	prices := make([]float64, 0)
+	for _, trade := range trades {
+		if trade.Coin == model.BTC {
+			prices = append(prices, trade.Tick.Price)
+		}
+	}
+	assert.Equal(t, []float64{100, 101, 102, 103, 104, 105}, prices)
+
+	lock.Lock()
+	defer lock.Unlock()
+	assert.Equal(t, start.Add(2*time.Second), fills[model.BTC])
+	assert.Equal(t, start.Add(1*time.Second), fills[model.ETH])
+
+	types := make(map[EventType]int)
+	for _, event := range events {
+		types[event.Type]++
+		if event.Type == Fill && event.Coin == model.BTC {
+			assert.Equal(t, 3, event.Count)
+			assert.NoError(t, event.Err)
+		}
+		if event.Type == Fill && event.Coin == model.ETH {
+			assert.Error(t, event.Err)
+		}
+	}
+	assert.Equal(t, 1, types[Disconnect])
+	assert.Equal(t, 1, types[Reconnect])
+	assert.Equal(t, 2, types[Fill])
+}
+
+func TestSupervisor_Timeout(t *testing.T) {
+
+	lock := new(sync.Mutex)
+	events := make([]Event, 0)
+
+	connections := 0
+	s := NewSupervisor("test", func(ctx context.Context, out chan<- *model.TradeSignal, beat func()) error {
+		connections++
+		if connections == 1 {
+			// a silent connection , that never fails
+			<-ctx.Done()
+			return nil
+		}
+		return sessions([]model.TradeSignal{newTrade(model.BTC, 1, 100)})(ctx, out, beat)
+	}).
+		WithTimeout(50 * time.Millisecond).
+		WithBackoff(concurrent.NewBackoff(time.Millisecond, time.Millisecond)).
+		WithReport(func(event Event) {
+			lock.Lock()
+			events = append(events, event)
+			lock.Unlock()
+		})
+
+	trades := collect(t, s, 1)
+	assert.Equal(t, 100.0, trades[0].Tick.Price)
+
+	lock.Lock()
+	defer lock.Unlock()
+	assert.True(t, len(events) >= 2)
+	assert.Equal(t, Disconnect, events[0].Type)
+	assert.Error(t, events[0].Err)
+	assert.Equal(t, Reconnect, events[1].Type)
+}
+
+func TestSupervisor_WarmUp(t *testing.T) {
+
+	lock := new(sync.Mutex)
+	events := make([]Event, 0)
+
+	s := NewSupervisor("test", sessions(
+		// the socket starts with a trade already covered by the gap fill
+		live(newTrade(model.BTC, 4, 103), newTrade(model.BTC, 5, 104)),
+	)).
+		WithWarmUp(start, func(coin model.Coin, since time.Time) ([]model.TradeSignal, error) {
+			switch coin {
+			case model.BTC:
+				return []model.TradeSignal{newTrade(model.BTC, 0, 99), newTrade(model.BTC, 2, 101)}, nil
+			case model.ETH:
+				return []model.TradeSignal{newTrade(model.ETH, 1, 10)}, nil
+			}
+			return nil, fmt.Errorf("no history for %s", coin)
+		}, model.BTC, model.ETH).
+		WithBackFill(func(coin model.Coin, since time.Time) ([]model.TradeSignal, error) {
+			if coin == model.BTC {
+				return live(newTrade(model.BTC, 2, 101), newTrade(model.BTC, 3, 102), newTrade(model.BTC, 4, 103)), nil
+			}
+			return nil, nil
+		}).
+		WithReport(func(event Event) {
+			lock.Lock()
+			events = append(events, event)
+			lock.Unlock()
+		})
+
+	trades := collect(t, s, 6)
+
+	prices := make([]float64, len(trades))
+	isLive := make([]bool, len(trades))
+	for i, trade := range trades {
+		prices[i] = trade.Tick.Price
+		isLive[i] = trade.Meta.Live
+	}
+	assert.Equal(t, []float64{99, 10, 101, 102, 103, 104}, prices)
+	assert.Equal(t, []bool{false, false, false, true, true, true}, isLive)
+
+	lock.Lock()
+	defer lock.Unlock()
+	types := make(map[EventType]int)
+	for _, event := range events {
+		types[event.Type]++
+	}
+	assert.Equal(t, 2, types[WarmUp])
+	assert.Equal(t, 0, types[Reconnect])
+}
@@ -0,0 +1,160 @@
+package synthetic
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/drakos74/free-coin/internal/api"
+	"github.com/drakos74/free-coin/internal/model"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// Exchange is the exchange name of the synthetic trades.
+	Exchange = "synthetic"
+)
+
+// Point is the ground truth of a synthetic market at each step.
+type Point struct {
+	Time   time.Time
+	Price  float64
+	Regime model.Regime
+	Jump   bool
+}
+
+// Client is a trade source generating reproducible trade streams for several coins ,
+// so that the processors and strategies can be tested against a known ground truth.
+// Each step creates one trade for each coin , in the order the markets are added.
+// The processes keep their state , so they should not be shared between markets or clients.
+type Client struct {
+	once     *sync.Once
+	seed     int64
+	start    time.Time
+	interval time.Duration
+	steps    int
+	markets  []Market
+	trades   []*model.TradeSignal
+	paths    map[model.Coin][]Point
+	err      error
+}
+
+// New creates a new synthetic client for the given number of steps , every interval from the start.
+func New(seed int64, start time.Time, interval time.Duration, steps int) *Client {
+	return &Client{
+		once:     new(sync.Once),
+		seed:     seed,
+		start:    start,
+		interval: interval,
+		steps:    steps,
+		markets:  make([]Market, 0),
+	}
+}
+
+// WithMarket adds the markets to the client.
+func (c *Client) WithMarket(markets ...Market) *Client {
+	c.markets = append(c.markets, markets...)
+	return c
+}
+
+// Generate returns the generated trades , the same ones on every call.
+func (c *Client) Generate() ([]*model.TradeSignal, error) {
+	c.once.Do(c.generate)
+	return c.trades, c.err
+}
+
+// Path returns the ground truth of the market of the coin.
+func (c *Client) Path(coin model.Coin) []Point {
+	c.once.Do(c.generate)
+	return c.paths[coin]
+}
+
+// Trades emits the generated trades , waiting for each one to be processed.
+func (c *Client) Trades(process <-chan api.Signal) (model.TradeSource, error) {
+	trades, err := c.Generate()
+	if err != nil {
+		return nil, fmt.Errorf("could not generate trades: %w", err)
+	}
+	out := make(model.TradeSource)
+	go func() {
+		defer func() {
+			log.Info().Str("processor", "synthetic-source").Msg("closing processor")
+			close(out)
+		}()
+		for _, trade := range trades {
+			// emit a copy , so that the processors cannot modify the generated trades
+			t := *trade
+			out <- &t
+			<-process
+		}
+	}()
+	return out, nil
+}
+
+func (c *Client) generate() {
+	rnd := rand.New(rand.NewSource(c.seed))
+	dt := c.interval.Hours() / 24
+
+	index := make(map[model.Coin]int)
+	for i, m := range c.markets {
+		if m.Correlation != nil {
+			if _, ok := index[m.Correlation.Coin]; !ok || m.Correlation.Coin == m.Coin {
+				c.err = fmt.Errorf("coin '%s' is correlated to '%s' , which is not added before it", m.Coin, m.Correlation.Coin)
+				return
+			}
+			if math.Abs(m.Correlation.Rho) > 1 {
+				c.err = fmt.Errorf("invalid correlation for '%s': %f", m.Coin, m.Correlation.Rho)
+				return
+			}
+		}
+		index[m.Coin] = i
+	}
+
+	prices := make([]float64, len(c.markets))
+	for i, m := range c.markets {
+		prices[i] = m.Price
+	}
+	c.trades = make([]*model.TradeSignal, 0, c.steps*len(c.markets))
+	c.paths = make(map[model.Coin][]Point)
+	shocks := make([]float64, len(c.markets))
+	for s := 0; s < c.steps; s++ {
+		t := c.start.Add(time.Duration(s) * c.interval)
+		for i, m := range c.markets {
+			z := rnd.NormFloat64()
+			if m.Correlation != nil {
+				rho := m.Correlation.Rho
+				z = rho*shocks[index[m.Correlation.Coin]] + math.Sqrt(1-rho*rho)*z
+			}
+			shocks[i] = z
+
+			// the first step is at the start price
+			prev := prices[i]
+			price := prev
+			jump := false
+			if s > 0 {
+				price, jump = m.Jumps.next(rnd, m.Process.Next(rnd, prev, dt, z))
+			}
+			prices[i] = price
+
+			volume, side := m.Volume.next(rnd, price-prev)
+			c.paths[m.Coin] = append(c.paths[m.Coin], Point{
+				Time:   t,
+				Price:  price,
+				Regime: m.Process.Regime(),
+				Jump:   jump,
+			})
+			c.trades = append(c.trades, &model.TradeSignal{
+				Coin: m.Coin,
+				Tick: model.NewTick(price, volume, side, t),
+				Meta: model.Meta{
+					ID:       fmt.Sprintf("%s-%d", m.Coin, s),
+					Time:     t,
+					Unix:     t.Unix(),
+					Exchange: Exchange,
+				},
+			})
+		}
+	}
+}
@@ -0,0 +1,196 @@
+package synthetic
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/drakos74/free-coin/internal/api"
+	"github.com/drakos74/free-coin/internal/model"
+	"github.com/stretchr/testify/assert"
+)
+
+var _ api.Client = &Client{}
+
+var start = time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func returns(path []Point) []float64 {
+	rr := make([]float64, 0)
+	for i := 1; i < len(path); i++ {
+		rr = append(rr, math.Log(path[i].Price/path[i-1].Price))
+	}
+	return rr
+}
+
+func correlation(x, y []float64) float64 {
+	var mx, my float64
+	for i := range x {
+		mx += x[i]
+		my += y[i]
+	}
+	mx /= float64(len(x))
+	my /= float64(len(y))
+	var cov, vx, vy float64
+	for i := range x {
+		cov += (x[i] - mx) * (y[i] - my)
+		vx += (x[i] - mx) * (x[i] - mx)
+		vy += (y[i] - my) * (y[i] - my)
+	}
+	return cov / math.Sqrt(vx*vy)
+}
+
+func TestClient_Generate(t *testing.T) {
+
+	type test struct {
+		markets []Market
+		steps   int
+		assert  func(t *testing.T, c *Client)
+	}
+
+	tests := map[string]test{
+		"gbm-drift": {
+			markets: []Market{NewMarket(model.BTC, 100, GBM(0.1, 0))},
+			steps:   25,
+			assert: func(t *testing.T, c *Client) {
+				path := c.Path(model.BTC)
+				// without volatility the price follows the drift , 24h steps of 1 day
+				assert.InDelta(t, 100*math.Exp(0.1), path[24].Price, 1e-9)
+				assert.Equal(t, start.Add(24*time.Hour), path[24].Time)
+			},
+		},
+		"mean-reverting": {
+			markets: []Market{NewMarket(model.BTC, 150, MeanReverting(100, 5, 0.01))},
+			steps:   24 * 10,
+			assert: func(t *testing.T, c *Client) {
+				path := c.Path(model.BTC)
+				assert.InDelta(t, 100, path[len(path)-1].Price, 5)
+			},
+		},
+		"regime-switching": {
+			markets: []Market{NewMarket(model.BTC, 100, RegimeSwitching(0.05,
+				Regime{Label: model.Trending, Process: GBM(1, 0.01)},
+				Regime{Label: model.Ranging, Process: MeanReverting(100, 5, 0.01)},
+				Regime{Label: model.Volatile, Process: GBM(0, 1)},
+			))},
+			steps: 1000,
+			assert: func(t *testing.T, c *Client) {
+				regimes := make(map[model.Regime]int)
+				for _, p := range c.Path(model.BTC) {
+					regimes[p.Regime]++
+				}
+				assert.Equal(t, 3, len(regimes))
+				assert.Equal(t, model.Trending, c.Path(model.BTC)[0].Regime)
+			},
+		},
+		"correlated": {
+			markets: []Market{
+				NewMarket(model.BTC, 30000, GBM(0, 0.05)),
+				NewMarket(model.ETH, 2000, GBM(0, 0.05)).CorrelatedWith(model.BTC, 0.8),
+				NewMarket(model.DOT, 20, GBM(0, 0.05)),
+			},
+			steps: 2000,
+			assert: func(t *testing.T, c *Client) {
+				btc := returns(c.Path(model.BTC))
+				assert.InDelta(t, 0.8, correlation(btc, returns(c.Path(model.ETH))), 0.05)
+				assert.InDelta(t, 0, correlation(btc, returns(c.Path(model.DOT))), 0.1)
+			},
+		},
+		"jumps": {
+			markets: []Market{NewMarket(model.BTC, 100, GBM(0, 0)).
+				WithJumps(Jumps{Probability: 0.1, Mean: -0.1})},
+			steps: 1000,
+			assert: func(t *testing.T, c *Client) {
+				path := c.Path(model.BTC)
+				jumps := 0
+				for i, p := range path {
+					if p.Jump {
+						jumps++
+						assert.InDelta(t, path[i-1].Price*math.Exp(-0.1), p.Price, 1e-9)
+					}
+				}
+				assert.InDelta(t, 100, jumps, 30)
+			},
+		},
+		"volume-side": {
+			markets: []Market{NewMarket(model.BTC, 100, GBM(0, 0.05)).
+				WithVolume(Volume{Mean: 2, Buy: 0.5, Momentum: 0.5})},
+			steps: 1000,
+			assert: func(t *testing.T, c *Client) {
+				trades, err := c.Generate()
+				assert.NoError(t, err)
+				volume := 0.0
+				for i := 1; i < len(trades); i++ {
+					volume += trades[i].Tick.Volume
+					// the full momentum follows the price move
+					if trades[i].Tick.Price > trades[i-1].Tick.Price {
+						assert.Equal(t, model.Buy, trades[i].Tick.Type)
+					} else {
+						assert.Equal(t, model.Sell, trades[i].Tick.Type)
+					}
+				}
+				assert.InDelta(t, 2, volume/float64(len(trades)-1), 0.2)
+			},
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			tt.assert(t, New(42, start, time.Hour, tt.steps).WithMarket(tt.markets...))
+		})
+	}
+}
+
+func TestClient_Reproducible(t *testing.T) {
+	client := func(seed int64) *Client {
+		return New(seed, start, time.Minute, 100).WithMarket(
+			NewMarket(model.BTC, 30000, GBM(0.1, 0.5)),
+			NewMarket(model.ETH, 2000, GBM(0.1, 0.5)).CorrelatedWith(model.BTC, 0.5),
+		)
+	}
+	first, err := client(1).Generate()
+	assert.NoError(t, err)
+	second, err := client(1).Generate()
+	assert.NoError(t, err)
+	other, err := client(2).Generate()
+	assert.NoError(t, err)
+	assert.Equal(t, 200, len(first))
+	assert.Equal(t, first, second)
+	assert.NotEqual(t, first, other)
+}
+
+func TestClient_Trades(t *testing.T) {
+	client := New(1, start, time.Minute, 10).WithMarket(
+		NewMarket(model.BTC, 30000, GBM(0, 0.1)),
+		NewMarket(model.ETH, 2000, GBM(0, 0.1)),
+	)
+	process := make(chan api.Signal)
+	trades, err := client.Trades(process)
+	assert.NoError(t, err)
+	count := 0
+	for trade := range trades {
+		assert.Equal(t, start.Add(time.Duration(count/2)*time.Minute), trade.Tick.Time)
+		assert.Equal(t, Exchange, trade.Meta.Exchange)
+		count++
+		process <- api.Signal{}
+	}
+	assert.Equal(t, 20, count)
+
+	_, err = New(1, start, time.Minute, 10).WithMarket(
+		NewMarket(model.ETH, 2000, GBM(0, 0.1)).CorrelatedWith(model.BTC, 0.5),
+	).Trades(process)
+	assert.Error(t, err)
+}
+
+// a trend following strategy must profit on a drifting market.
+func TestClient_TrendFollowing(t *testing.T) {
+	client := New(7, start, time.Hour, 24*30).WithMarket(NewMarket(model.BTC, 100, GBM(0.05, 0.02)))
+	path := client.Path(model.BTC)
+	pnl := 0.0
+	for i := 2; i < len(path); i++ {
+		// hold the coin for the next step if the last step moved up
+		if path[i-1].Price > path[i-2].Price {
+			pnl += path[i].Price - path[i-1].Price
+		}
+	}
+	assert.True(t, pnl > 0)
+}
@@ -0,0 +1,103 @@
+package synthetic
+
+import (
+	"math"
+	"math/rand"
+
+	"github.com/drakos74/free-coin/internal/model"
+)
+
+// Volume defines the volume and the side of the synthetic trades.
+// The volume is exponentially distributed around the mean.
+// Buy is the probability of a buy trade , moved by the momentum towards the side of the last price move.
+type Volume struct {
+	Mean     float64
+	Buy      float64
+	Momentum float64
+}
+
+// DefaultVolume creates trades of 1 unit on average , with no side bias.
+func DefaultVolume() Volume {
+	return Volume{
+		Mean: 1,
+		Buy:  0.5,
+	}
+}
+
+func (v Volume) next(rnd *rand.Rand, move float64) (float64, model.Type) {
+	p := v.Buy
+	if move > 0 {
+		p += v.Momentum
+	} else if move < 0 {
+		p -= v.Momentum
+	}
+	t := model.Sell
+	if rnd.Float64() < p {
+		t = model.Buy
+	}
+	return v.Mean * rnd.ExpFloat64(), t
+}
+
+// Jumps defines the price jumps injected to the process.
+// On each step a jump happens with the given probability ,
+// moving the log price by a normal amount with the given mean and deviation.
+type Jumps struct {
+	Probability float64
+	Mean        float64
+	Std         float64
+}
+
+func (j Jumps) next(rnd *rand.Rand, price float64) (float64, bool) {
+	if j.Probability <= 0 || rnd.Float64() >= j.Probability {
+		return price, false
+	}
+	return price * math.Exp(j.Mean+j.Std*rnd.NormFloat64()), true
+}
+
+// Correlation defines the correlation of the price shocks of a coin to the ones of another coin.
+type Correlation struct {
+	Coin model.Coin
+	Rho  float64
+}
+
+// Market defines the synthetic market of a coin.
+type Market struct {
+	Coin        model.Coin
+	Price       float64
+	Process     Process
+	Volume      Volume
+	Jumps       Jumps
+	Correlation *Correlation
+}
+
+// NewMarket creates a new synthetic market for the coin , starting at the given price.
+func NewMarket(coin model.Coin, price float64, process Process) Market {
+	return Market{
+		Coin:    coin,
+		Price:   price,
+		Process: process,
+		Volume:  DefaultVolume(),
+	}
+}
+
+// WithVolume sets the volume model of the market.
+func (m Market) WithVolume(volume Volume) Market {
+	m.Volume = volume
+	return m
+}
+
+// WithJumps injects jumps to the market prices.
+func (m Market) WithJumps(jumps Jumps) Market {
+	m.Jumps = jumps
+	return m
+}
+
+// CorrelatedWith correlates the price shocks of the market to the ones of the given coin ,
+// which needs to be added to the client before this one.
+func (m Market) CorrelatedWith(coin model.Coin, rho float64) Market {
+	m.Correlation = &Correlation{
+		Coin: coin,
+		Rho:  rho,
+	}
+	return m
+}
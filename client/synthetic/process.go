@@ -0,0 +1,105 @@
+package synthetic
+
+import (
+	"math"
+	"math/rand"
+
+	"github.com/drakos74/free-coin/internal/model"
+)
+
+// Process defines the price dynamics of a synthetic market.
+// Next evolves the price over dt days , given a standard normal shock ,
+// so that the shocks of correlated coins can be shared.
+type Process interface {
+	Next(rnd *rand.Rand, price, dt, z float64) float64
+	Regime() model.Regime
+}
+
+// gbm is the geometric brownian motion process.
+type gbm struct {
+	drift      float64
+	volatility float64
+}
+
+// GBM creates a geometric brownian motion process ,
+// with the drift and the volatility of the log returns per day.
+func GBM(drift, volatility float64) Process {
+	return &gbm{
+		drift:      drift,
+		volatility: volatility,
+	}
+}
+
+func (g *gbm) Next(rnd *rand.Rand, price, dt, z float64) float64 {
+	return price * math.Exp((g.drift-g.volatility*g.volatility/2)*dt+g.volatility*math.Sqrt(dt)*z)
+}
+
+func (g *gbm) Regime() model.Regime {
+	return model.NoRegime
+}
+
+// meanReverting is the ornstein-uhlenbeck process on the log price.
+type meanReverting struct {
+	mean       float64
+	speed      float64
+	volatility float64
+}
+
+// MeanReverting creates a process reverting to the mean price ,
+// with the speed of the reversion and the volatility of the log returns per day.
+func MeanReverting(mean, speed, volatility float64) Process {
+	return &meanReverting{
+		mean:       mean,
+		speed:      speed,
+		volatility: volatility,
+	}
+}
+
+func (m *meanReverting) Next(rnd *rand.Rand, price, dt, z float64) float64 {
+	x := math.Log(price)
+	x += m.speed*(math.Log(m.mean)-x)*dt + m.volatility*math.Sqrt(dt)*z
+	return math.Exp(x)
+}
+
+func (m *meanReverting) Regime() model.Regime {
+	return model.NoRegime
+}
+
+// Regime is a labelled process of a regime switching market.
+type Regime struct {
+	Label   model.Regime
+	Process Process
+}
+
+// regimeSwitching switches between the processes of the regimes as a markov chain.
+type regimeSwitching struct {
+	probability float64
+	regimes     []Regime
+	current     int
+}
+
+// RegimeSwitching creates a process starting with the first regime ,
+// and switching to one of the other ones with the given probability on each step.
+func RegimeSwitching(probability float64, regimes ...Regime) Process {
+	return &regimeSwitching{
+		probability: probability,
+		regimes:     regimes,
+	}
+}
+
+func (r *regimeSwitching) Next(rnd *rand.Rand, price, dt, z float64) float64 {
+	if len(r.regimes) == 0 {
+		return price
+	}
+	if len(r.regimes) > 1 && rnd.Float64() < r.probability {
+		r.current = (r.current + 1 + rnd.Intn(len(r.regimes)-1)) % len(r.regimes)
+	}
+	return r.regimes[r.current].Process.Next(rnd, price, dt, z)
+}
+
+func (r *regimeSwitching) Regime() model.Regime {
+	if len(r.regimes) == 0 {
+		return model.NoRegime
+	}
+	return r.regimes[r.current].Label
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"flag"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/drakos74/free-coin/client/history"
+	"github.com/drakos74/free-coin/client/local"
+	coin "github.com/drakos74/free-coin/internal"
+	"github.com/drakos74/free-coin/internal/algo/processor/rule"
+	"github.com/drakos74/free-coin/internal/analytics"
+	"github.com/drakos74/free-coin/internal/api"
+	"github.com/drakos74/free-coin/internal/model"
+	"github.com/drakos74/free-coin/internal/storage"
+	json_storage "github.com/drakos74/free-coin/internal/storage/file/json"
+	cointime "github.com/drakos74/free-coin/internal/time"
+	"github.com/drakos74/free-coin/internal/trader"
+	userlocal "github.com/drakos74/free-coin/user/local"
+	"github.com/rs/zerolog"
+)
+
+func init() {
+	zerolog.SetGlobalLevel(zerolog.InfoLevel)
+}
+
+// backtest replays the trades of the history registry through the rule processor on a paper account ,
+// with the engine and the processor on a simulated clock that follows the trade times
+// e.g. 'backtest -coin BTC -from 2021-01-01 -to 2021-02-01'
+func main() {
+	c := flag.String("coin", "BTC", "the coin to replay the trades for")
+	from := flag.String("from", "", "the start date of the replay e.g. 2021-01-01")
+	to := flag.String("to", "", "the end date of the replay e.g. 2021-02-01")
+	path := flag.String("registry", storage.HistoryDir, "the history registry path")
+	balance := flag.Float64("balance", 1000, "the balance of the paper account")
+	fee := flag.Float64("fee", 0.26, "the fee of the paper account , in percent")
+	flag.Parse()
+
+	start, err := time.Parse("2006-01-02", *from)
+	if err != nil {
+		log.Fatalf("error parsing start date: %s", err.Error())
+	}
+	end, err := time.Parse("2006-01-02", *to)
+	if err != nil {
+		log.Fatalf("error parsing end date: %s", err.Error())
+	}
+	cc := model.Coin(strings.ToUpper(*c))
+
+	source := history.New(nil).
+		WithRegistry(json_storage.NewEventRegistry(*path)).
+		Reader(&history.Request{
+			Coin: cc,
+			From: start,
+			To:   end,
+		})
+	clock := cointime.NewSimClock(start)
+	engine, err := coin.NewEngine(source)
+	if err != nil {
+		log.Fatalf("error creating engine: %s", err.Error())
+	}
+	engine.WithClock(clock)
+
+	u, err := userlocal.NewUser("")
+	if err != nil {
+		log.Fatalf("error creating user: %s", err.Error())
+	}
+	exchange := local.NewPaper("backtest", *balance).WithFee(*fee)
+	// the paper account submits all orders , the windows flush on the trade times of the simulated clock
+	config := rule.DefaultConfig(false, cc).NoLive().WithClock(engine.Clock())
+	config.Position.Paper = true
+	index := api.Index("backtest")
+	engine.AddProcessor(coin.NewStrategy(rule.Name).
+		ForUser(u).
+		ForExchange(exchange).
+		WithProcessor(rule.Processor(index,
+			storage.VoidShard(rule.Name),
+			func(path string) (storage.Registry, error) {
+				return storage.NewVoidRegistry(), nil
+			},
+			config)).
+		Apply())
+
+	err = engine.Run()
+	if err != nil {
+		log.Fatalf("error running engine: %s", err.Error())
+	}
+
+	for _, report := range analytics.Reports("", trader.Query{}, analytics.DefaultPeriod) {
+		log.Printf("%s: %+v", report.Account, report.Metrics)
+	}
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/drakos74/free-coin/internal/algo/processor/ml"
+	"github.com/drakos74/free-coin/internal/api"
+	"github.com/drakos74/free-coin/internal/model"
+	"github.com/drakos74/free-coin/internal/storage"
+	json_storage "github.com/drakos74/free-coin/internal/storage/file/json"
+	"github.com/drakos74/free-coin/user/local"
+	"github.com/rs/zerolog"
+)
+
+const (
+	coin        = model.BTC
+	historyPath = "2018_2019"
+	exportDir   = "ml/export"
+	registry    = "ml-event-registry"
+)
+
+func init() {
+	zerolog.SetGlobalLevel(zerolog.InfoLevel)
+}
+
+func main() {
+
+	files := make([]string, 0)
+
+	err := filepath.Walk(fmt.Sprintf("%s/%s/%s/%s", storage.DefaultDir, storage.HistoryDir, coin, historyPath), func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return fmt.Errorf("could not read directory : %w", err)
+		}
+		if !info.IsDir() && !strings.HasPrefix(info.Name(), ".") {
+			files = append(files, path)
+		}
+		return nil
+	})
+	if err != nil {
+		panic(fmt.Errorf("error during directory reading: %+v", err))
+	}
+
+	sort.Strings(files)
+	fmt.Printf("files = %+v\n", files)
+
+	u, err := local.NewUser("")
+	if err != nil {
+		panic(fmt.Sprintf("could not set up user : %+v", err))
+	}
+
+	config := ml.Config(coin)
+	config.Buffer.History = false
+	proc := ml.Exporter(api.Index(ml.ExportName), *config,
+		json_storage.EventRegistry(registry),
+		fmt.Sprintf("%s/%s", storage.DefaultDir, exportDir))(u, nil)
+
+	in := make(chan *model.TradeSignal)
+	out := make(chan *model.TradeSignal)
+
+	go func() {
+		defer close(in)
+		for _, path := range files {
+			data, err := os.ReadFile(path)
+			if err != nil {
+				panic(fmt.Sprintf("could not read file : %+v", err))
+			}
+
+			trades := make([]model.TradeSignal, 0)
+			err = json.Unmarshal(data, &trades)
+			if err != nil {
+				panic(fmt.Sprintf("could not unmarshall trades : %+v", err))
+			}
+
+			fmt.Printf("trades = %s %+v\n", path, len(trades))
+
+			for i := range trades {
+				in <- &trades[i]
+			}
+		}
+	}()
+
+	go func() {
+		for range out {
+
+		}
+	}()
+
+	// the export happens on shutdown , so we need to wait for the processor to return
+	proc(in, out)
+}
@@ -2,113 +2,212 @@ package main
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 	"log"
 	"os"
+	"time"
 
 	"github.com/drakos74/free-coin/internal/algo/processor"
+	"github.com/drakos74/free-coin/internal/algo/processor/quality"
+	"github.com/drakos74/free-coin/internal/algo/processor/regime"
+	"github.com/drakos74/free-coin/internal/algo/processor/rule"
 	"github.com/drakos74/free-coin/internal/algo/processor/trade"
 
+	"github.com/drakos74/free-coin/client"
+	"github.com/drakos74/free-coin/client/binance"
 	"github.com/drakos74/free-coin/client/kraken"
+	"github.com/drakos74/free-coin/client/local"
 	coin "github.com/drakos74/free-coin/internal"
 	"github.com/drakos74/free-coin/internal/account"
 	"github.com/drakos74/free-coin/internal/algo/processor/ml"
+	"github.com/drakos74/free-coin/internal/analytics"
 	"github.com/drakos74/free-coin/internal/api"
-	"github.com/drakos74/free-coin/internal/model"
-	json_storage "github.com/drakos74/free-coin/internal/storage/file/json"
+	"github.com/drakos74/free-coin/internal/candle"
+	"github.com/drakos74/free-coin/internal/config"
+	"github.com/drakos74/free-coin/internal/server"
+	"github.com/drakos74/free-coin/internal/storage"
+	"github.com/drakos74/free-coin/internal/trader"
 	"github.com/drakos74/free-coin/user/telegram"
 	"github.com/rs/zerolog"
 )
 
+// analyticsPort is the port of the http api for the performance analytics.
+const analyticsPort = 6050
+
 func init() {
 	zerolog.SetGlobalLevel(zerolog.InfoLevel)
 }
 
-const (
-	exchange = "exchange"
-	bot      = "bot"
-)
-
-var CONFIG = map[model.Coin]bool{
-	model.BTC:  true,
-	model.DOT:  true,
-	model.ETH:  true,
-	model.LINK: true,
-	model.SOL:  true,
-	//model.FLOW : false,
-	//model.MATIC: false,
-	//model.AAVE:  false,
-	//model.KSM: false,
-	model.XRP: true,
-	//model.ADA: false,
-	//model.KAVA: false,
+// exchanges creates the exchange for each account ,
+// the paper accounts use the corresponding paper exchange.
+func exchanges(papers map[account.Name]*local.Paper) trader.ExchangeProvider {
+	return func(details account.Details) (api.Exchange, error) {
+		if paper, ok := papers[details.Name]; ok {
+			return paper, nil
+		}
+		switch details.Exchange.Name {
+		case kraken.Name, "":
+			return kraken.NewExchange(details.Name), nil
+		case binance.Name:
+			if details.Exchange.Margin {
+				return binance.NewMarginExchange(details.Name), nil
+			}
+			return binance.NewExchange(details.Name), nil
+		}
+		return nil, fmt.Errorf("exchange not supported: %s", details.Exchange.Name)
+	}
 }
 
 func main() {
-	//config := ml.Config(model.ETH)
-	//config := ml.Config()
-	config := ml.WithConfig(CONFIG)
-
-	cc := make([]model.Coin, 0)
-	coins := make(map[model.Coin]bool)
-	for k, _ := range config.Segments {
-		if !coins[k.Coin] {
-			cc = append(cc, k.Coin)
-			coins[k.Coin] = false
-		}
+	f := "free-coin.json"
+	if len(os.Args) > 1 {
+		f = os.Args[1]
 	}
+	cfg, err := config.Load(f)
+	if err != nil {
+		log.Fatalf("error loading config: %s", err.Error())
+	}
+	if cfg.Storage.Path != "" {
+		storage.DefaultDir = cfg.Storage.Path
+	}
+
+	mlConfig := ml.FromConfig(cfg)
+	cc := cfg.CoinList()
 
 	// main engine trade input ...
-	client := kraken.NewClient(cc...).
-		//Since(cointime.LastXHours(48)).
+	source := kraken.NewClient(cc...).
 		//Interval(2 * time.Second).
-		Live(true)
-	engine, err := coin.NewEngine(client)
+		WithBook(cfg.Source.Book).
+		Live(cfg.Source.Live)
+	if cfg.Source.WarmUp.Duration > 0 {
+		source.Since(time.Now().Add(-1 * cfg.Source.WarmUp.Duration).UnixNano())
+	}
+	engine, err := coin.NewEngine(source)
 	if err != nil {
 		log.Fatalf("error creating engine: %s", err.Error())
 	}
 
-	// get config
-	f := "free-coin.json"
-	//f := "free-coin.json"
-	dat, err := os.ReadFile(f)
-	if err != nil {
-		panic(any(fmt.Sprintf("could not load config file: %s", f)))
+	// paper accounts trade the same signals as the live ones against a virtual wallet
+	papers := make(map[account.Name]*local.Paper)
+	for _, acc := range cfg.Accounts {
+		if acc.Exchange.Paper {
+			papers[acc.Name] = local.NewPaper(string(acc.Name), acc.Exchange.Balance).WithFee(acc.Exchange.Fee)
+		}
 	}
-	var cfg map[string]string
-	err = json.Unmarshal(dat, &cfg)
-	if err != nil {
-		panic(any(fmt.Sprintf("could not decode config file: %s", f)))
+	// each account can report to its own channel
+	index := cfg.User.Bot
+	indexes := []api.Index{index}
+	for _, acc := range cfg.Accounts {
+		if idx := acc.User.Index; idx != "" && idx != index {
+			indexes = append(indexes, idx)
+		}
 	}
-
-	// position tracker for kraken
-	exchange := kraken.NewExchange(account.Name(cfg[exchange])) //account.Drakos
-	u, err := telegram.NewBot(api.Index(cfg[bot]))              //api.FreeCoin
+	u, err := telegram.NewBot(indexes...)
 	if err != nil {
 		log.Fatalf("error creating user: %s", err.Error())
 	}
+	// report the socket disconnects and back-fills
+	source.WithUser(index, u)
+	// each account has one trader , shared by the processors that trade on it ,
+	// so that they do not reconcile and journal each others positions
+	accounts, err := trader.NewAccounts(string(index),
+		cfg.Shard("trader"),
+		cfg.Registry("trader-event-registry"),
+		cfg.Settings(),
+		exchanges(papers), u, cfg.Accounts...)
+	if err != nil {
+		log.Fatalf("error creating accounts: %s", err.Error())
+	}
+	for _, acc := range accounts {
+		analytics.Register(string(acc.Details.Name), acc.Capital(), acc.Journal())
+	}
+	// the first account is the default exchange for the processors
+	exchange := accounts[0].Exchange
 	//positionTracker := coin.NewStrategy("position-tracker").
 	//	ForExchange(exchange).
 	//	ForUser(u).
 	//	WithProcessor(position.Processor(api.FreeCoin)).Apply()
 	//engine.AddProcessor(positionTracker)
 
-	shard := json_storage.BlobShard("ml")
-	registry := json_storage.EventRegistry("ml-event-registry")
-	//shard := json_storage.BlobShard("ml")
-	//registry := json_storage.EventRegistry("ml-event-registry")
-	strategy := processor.NewStrategy(config)
-	engine.AddProcessor(coin.NewStrategy(trade.Name).
+	shard := cfg.Shard("ml")
+	registry := cfg.Registry("ml-event-registry")
+	strategy := processor.NewStrategy(mlConfig)
+	regimes := regime.NewRegimes()
+	ruleCoins := rule.NewCoins()
+	// add and remove coins at runtime , with the '?coin' command or through the config file
+	coins := client.NewCoins(source, cc...).
+		WithPairs(source.Pairs).
+		OnChange(func(change client.CoinChange) {
+			diff := strategy.Reload(ml.WithCoins(strategy.Config(), change.Added, change.Removed))
+			regimes.Remove(change.Removed...)
+			ruleCoins.Add(change.Added...)
+			ruleCoins.Remove(change.Removed...)
+			u.Send(index, api.NewMessage(fmt.Sprintf("[coin] %s\n%s", change.String(), diff.String())), nil)
+		})
+	if err := coins.Load(); err != nil {
+		log.Printf("could not load tradable pairs: %s", err.Error())
+	}
+	// build the candles of the trade stream for all the timeframes
+	candleRegistry, err := cfg.Registry("candles")("")
+	if err != nil {
+		log.Fatalf("error creating candle registry: %s", err.Error())
+	}
+	candles := candle.NewService(candleRegistry)
+	// the rules are live , the orders are submitted only for the live accounts
+	ruleConfig := rule.DefaultConfig(true, cc...).WithTraders(accounts...).WithCoins(ruleCoins)
+	ruleConfig.Position = cfg.Settings()
+	if !cfg.Source.Live {
+		// the polled trades are flushed on their own times , without the wall clock heartbeat
+		ruleConfig = ruleConfig.NoLive()
+	}
+	// check the data quality first , so that all the downstream processors get the clean trade stream
+	engine.AddProcessor(quality.Processor(quality.NewChecker(quality.DefaultConfig()), engine.Skip))
+	engine.AddProcessor(candle.Processor(candles))
+	// the regime processor goes first among the strategies , so that all the downstream processors get the regime
+	engine.AddProcessor(coin.NewStrategy(regime.Name).
 		ForUser(u).
 		ForExchange(exchange).
-		WithProcessor(trade.Processor(api.FreeCoin, shard, registry, strategy)).
+		WithProcessor(regime.Processor(index, regimes, regime.DefaultConfig())).
 		Apply()).
+		AddProcessor(coin.NewStrategy(trade.Name).
+			ForUser(u).
+			ForExchange(exchange).
+			WithProcessor(trade.Processor(index, shard, registry, strategy, accounts...)).
+			Apply()).
 		AddProcessor(coin.NewStrategy(ml.Name).
 			ForUser(u).
 			ForExchange(exchange).
-			WithProcessor(ml.Processor(api.FreeCoin, shard, strategy)).
+			WithProcessor(ml.Processor(index, shard, strategy)).
+			Apply()).
+		// rule based baseline strategy , trading on all the accounts
+		AddProcessor(coin.NewStrategy(rule.Name).
+			ForUser(u).
+			ForExchange(exchange).
+			WithProcessor(rule.Processor(index,
+				cfg.Shard(rule.Name),
+				cfg.Registry("rule-event-registry"),
+				ruleConfig)).
 			Apply())
+	// hot-reload the strategy config on file changes and on the '?cfg reload' command
+	watcher := config.NewWatcher(f, cfg).OnCheck(func(current, next *config.Config) error {
+		return coins.Check(next.CoinList()...)
+	}).OnReload(func(current, next *config.Config) (string, error) {
+		change, err := coins.Set(next.CoinList()...)
+		if err != nil {
+			return "", err
+		}
+		diff := strategy.Reload(ml.FromConfig(next))
+		return fmt.Sprintf("%s\n%s", change.String(), diff.String()), nil
+	})
+	go watcher.Run(context.Background(), index, u)
+	// serve the performance analytics of the accounts e.g. '/api/analytics?coin=BTC'
+	go func() {
+		err := server.NewServer("analytics", analyticsPort).Add(analytics.Route()).Run()
+		if err != nil {
+			log.Printf("could not start analytics server: %s", err.Error())
+		}
+	}()
+	go coins.Run(index, u)
 	go u.Run(context.Background())
 	err = engine.Run()
 	if err != nil {
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"flag"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/drakos74/free-coin/client/history"
+	"github.com/drakos74/free-coin/internal/model"
+	"github.com/drakos74/free-coin/internal/storage"
+	json_storage "github.com/drakos74/free-coin/internal/storage/file/json"
+	"github.com/rs/zerolog"
+)
+
+func init() {
+	zerolog.SetGlobalLevel(zerolog.InfoLevel)
+}
+
+// import ingests the csv trade archives of an exchange into the history registry
+// e.g. 'import -format binance -coin BTC BTCEUR-aggTrades-2021-01.csv'
+func main() {
+	format := flag.String("format", string(history.KrakenFormat), "the archive format , 'kraken' or 'binance'")
+	coin := flag.String("coin", "", "the coin of the archive trades e.g. BTC")
+	path := flag.String("registry", storage.HistoryDir, "the history registry path")
+	every := flag.Int("progress", 100000, "the number of lines between the progress reports")
+	flag.Parse()
+
+	registry := json_storage.NewEventRegistry(*path)
+	importer, err := history.NewImporter(history.Format(*format), model.Coin(strings.ToUpper(*coin)), registry)
+	if err != nil {
+		log.Fatalf("error creating importer: %s", err.Error())
+	}
+	importer.WithProgress(*every, func(progress history.Progress) {
+		log.Printf("progress: %s", progress.String())
+	})
+
+	for _, file := range flag.Args() {
+		f, err := os.Open(file)
+		if err != nil {
+			log.Fatalf("error opening archive: %s", err.Error())
+		}
+		progress, err := importer.Import(f)
+		_ = f.Close()
+		if err != nil {
+			log.Fatalf("error importing '%s': %s", file, err.Error())
+		}
+		verification := importer.Verify(progress.From, progress.To)
+		log.Printf("imported '%s': %s", file, progress.String())
+		log.Printf("verified '%s': ranges = %d , missing = %d %v",
+			file, verification.Ranges, len(verification.Missing), verification.Missing)
+	}
+}
@@ -4,6 +4,15 @@ import (
 	"fmt"
 
 	"github.com/drakos74/free-coin/internal/api"
+	"github.com/drakos74/free-coin/internal/model"
+)
+
+// Name is the account name , used to look up the account credentials.
+type Name string
+
+const (
+	// Drakos is the default account.
+	Drakos Name = "drakos"
 )
 
 // Secret defines a security pair of a key and secret
@@ -51,31 +60,65 @@ func (m *Mapping) add(key, value string) error {
 }
 
 // Details define the account details.
+// Multiplier scales the position size for the account , a zero value means no scaling.
+// Live defines if the orders of the account are submitted to the exchange , otherwise the positions are only tracked.
 type Details struct {
-	Name       Name
-	Alias      []string
-	Multiplier float64
-	Exchange   ExchangeDetails
-	User       UserDetails
-	Filter     Filter
+	Name       Name            `json:"name"`
+	Alias      []string        `json:"alias"`
+	Multiplier float64         `json:"multiplier"`
+	Live       bool            `json:"live"`
+	Exchange   ExchangeDetails `json:"exchange"`
+	User       UserDetails     `json:"user"`
+	Filter     Filter          `json:"filter"`
+}
+
+// Scale scales the given value by the account multiplier.
+func (d Details) Scale(v float64) float64 {
+	if d.Multiplier == 0 {
+		return v
+	}
+	return d.Multiplier * v
 }
 
 // ExchangeDetails are the exchange specific details.
+// Paper accounts trade against a virtual wallet with the given starting balance and fee percentage ,
+// instead of submitting the orders to the exchange.
 type ExchangeDetails struct {
-	Name   api.ExchangeName
-	Margin bool
+	Name    api.ExchangeName `json:"name"`
+	Margin  bool             `json:"margin"`
+	Paper   bool             `json:"paper"`
+	Balance float64          `json:"balance"`
+	Fee     float64          `json:"fee"`
 }
 
 // UserDetails are the user communication details.
 type UserDetails struct {
-	Index  api.Index
-	ChatID int64
-	Alias  string
+	Index  api.Index `json:"index"`
+	ChatID int64     `json:"chat_id"`
+	Alias  string    `json:"alias"`
 }
 
 // Filter allows specific filtering logic per user account.
+// Coins is the declarative form of the filter , an empty list allows all coins.
 type Filter struct {
-	Apply func(s string) error
+	Coins []model.Coin         `json:"coins"`
+	Apply func(s string) error `json:"-"`
+}
+
+// Allow checks if the given coin passes the filter.
+func (f Filter) Allow(coin model.Coin) error {
+	if f.Apply != nil {
+		return f.Apply(string(coin))
+	}
+	if len(f.Coins) == 0 {
+		return nil
+	}
+	for _, c := range f.Coins {
+		if c == coin || c == model.AllCoins {
+			return nil
+		}
+	}
+	return fmt.Errorf("coin '%s' not in filter %v", coin, f.Coins)
 }
 
 // NoFilter defines a 'pass-all' filter.
@@ -91,3 +134,22 @@ func NegFilter() Filter {
 		return fmt.Errorf("negative filter")
 	}}
 }
+
+// Validate checks that the account names and aliases are unique ,
+// and that the paper accounts have a starting balance.
+func Validate(details ...Details) error {
+	mapping := NewMapping()
+	for _, d := range details {
+		if d.Name == "" {
+			return fmt.Errorf("account without name")
+		}
+		err := mapping.Add(string(d.Name), d.Alias...)
+		if err != nil {
+			return fmt.Errorf("invalid account '%s': %w", d.Name, err)
+		}
+		if d.Exchange.Paper && d.Exchange.Balance <= 0 {
+			return fmt.Errorf("invalid account '%s': paper account without balance", d.Name)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,73 @@
+package account
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/drakos74/free-coin/internal/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDetails_Decode(t *testing.T) {
+	var details []Details
+	err := json.Unmarshal([]byte(`[
+		{"name":"main","multiplier":2,"live":true,"exchange":{"name":"kraken","margin":true},"user":{"index":"main-bot"}},
+		{"name":"small","alias":["s"],"multiplier":0.5,"exchange":{"name":"binance"},"filter":{"coins":["BTC"]}}
+	]`), &details)
+	assert.NoError(t, err)
+	assert.NoError(t, Validate(details...))
+
+	assert.Equal(t, 200.0, details[0].Scale(100))
+	assert.True(t, details[0].Exchange.Margin)
+	assert.True(t, details[0].Live)
+	assert.NoError(t, details[0].Filter.Allow(model.ETH))
+
+	assert.Equal(t, 50.0, details[1].Scale(100))
+	assert.False(t, details[1].Live)
+	assert.NoError(t, details[1].Filter.Allow(model.BTC))
+	assert.Error(t, details[1].Filter.Allow(model.ETH))
+}
+
+func TestValidate(t *testing.T) {
+
+	type test struct {
+		details []Details
+		err     bool
+	}
+
+	tests := map[string]test{
+		"unique": {
+			details: []Details{{Name: "a", Alias: []string{"b"}}, {Name: "c"}},
+		},
+		"duplicate-name": {
+			details: []Details{{Name: "a"}, {Name: "a"}},
+			err:     true,
+		},
+		"duplicate-alias": {
+			details: []Details{{Name: "a", Alias: []string{"c"}}, {Name: "c"}},
+			err:     true,
+		},
+		"no-name": {
+			details: []Details{{Alias: []string{"a"}}},
+			err:     true,
+		},
+		"paper": {
+			details: []Details{{Name: "a", Exchange: ExchangeDetails{Paper: true, Balance: 1000}}},
+		},
+		"paper-no-balance": {
+			details: []Details{{Name: "a", Exchange: ExchangeDetails{Paper: true}}},
+			err:     true,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			err := Validate(tt.details...)
+			if tt.err {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
@@ -1,6 +1,7 @@
 package processor
 
 import (
+	"sync"
 	"time"
 
 	"github.com/drakos74/free-coin/internal/buffer"
@@ -9,10 +10,15 @@ import (
 )
 
 // SignalBuffer is a consistent signal source that will emit a signal each pre-specified interval.
+// The trades are aggregated on their event time , so that live and replayed trades produce the same signals.
 type SignalBuffer struct {
 	duration time.Duration
+	lateness time.Duration
 	windows  map[string]*buffer.IntervalWindow
+	regimes  map[model.Coin]model.Regime
+	books    map[model.Coin]model.OrderBook
 	trades   chan *model.TradeSignal
+	lock     *sync.RWMutex
 	live     bool
 	echo     bool
 }
@@ -22,6 +28,9 @@ func NewSignalBuffer(duration time.Duration) (*SignalBuffer, <-chan *model.Trade
 	trades := make(chan *model.TradeSignal)
 	sb := &SignalBuffer{
 		windows:  make(map[string]*buffer.IntervalWindow),
+		regimes:  make(map[model.Coin]model.Regime),
+		books:    make(map[model.Coin]model.OrderBook),
+		lock:     new(sync.RWMutex),
 		duration: duration,
 		trades:   trades,
 		live:     true,
@@ -29,11 +38,18 @@ func NewSignalBuffer(duration time.Duration) (*SignalBuffer, <-chan *model.Trade
 	return sb, trades
 }
 
+// NoLive disables the heartbeat of the windows , so that they are flushed only by the trade times.
 func (sb *SignalBuffer) NoLive() *SignalBuffer {
 	sb.live = false
 	return sb
 }
 
+// WithLateness sets the allowed lateness for out of order trades.
+func (sb *SignalBuffer) WithLateness(lateness time.Duration) *SignalBuffer {
+	sb.lateness = lateness
+	return sb
+}
+
 func (sb *SignalBuffer) WithEcho() *SignalBuffer {
 	sb.echo = true
 	return sb
@@ -42,15 +58,25 @@ func (sb *SignalBuffer) WithEcho() *SignalBuffer {
 // Push adds an element ot the buffer.
 func (sb *SignalBuffer) Push(trade *model.TradeSignal) {
 	coin := string(trade.Coin)
-	if _, ok := sb.windows[coin]; !ok {
-		bf, trades := buffer.NewIntervalWindow(coin, 5, sb.duration)
+	// keep track of the latest regime and order book , so that we pass them on to the aggregated signals
+	sb.lock.Lock()
+	window, ok := sb.windows[coin]
+	if !ok {
+		bf, trades := buffer.NewIntervalWindow(coin, 6, sb.duration)
+		bf = bf.WithLateness(sb.lateness)
 		if !sb.live {
-			bf = bf.WithInterval(int(sb.duration.Seconds()))
+			bf = bf.WithoutHeartbeat()
 		}
 		sb.windows[coin] = bf
+		window = bf
 		// start consuming for the new created window
-		go bufferedProcessor(trade.Coin, trades, sb.trades)
+		go bufferedProcessor(trade.Coin, trades, sb.trades, sb.latest)
 	}
+	sb.regimes[trade.Coin] = trade.Meta.Regime
+	if trade.OrderBook.Ready() {
+		sb.books[trade.Coin] = trade.OrderBook
+	}
+	sb.lock.Unlock()
 	buy := 0.0
 	sell := 0.0
 	if trade.Tick.Type == model.Buy {
@@ -58,11 +84,44 @@ func (sb *SignalBuffer) Push(trade *model.TradeSignal) {
 	} else {
 		sell = trade.Tick.Volume
 	}
-	sb.windows[coin].Push(trade.Tick.Time, trade.Tick.Price, trade.Tick.Volume, buy, sell, float64(trade.Meta.Size))
+	window.Push(trade.Tick.Time, trade.Tick.Price, trade.Tick.Volume, buy, sell, float64(trade.Meta.Size), float64(trade.Meta.Anomalies))
+}
+
+// latest returns the latest regime and order book for the given coin.
+func (sb *SignalBuffer) latest(coin model.Coin) (model.Regime, model.OrderBook) {
+	sb.lock.RLock()
+	defer sb.lock.RUnlock()
+	return sb.regimes[coin], sb.books[coin]
+}
+
+// Remove drops the windows of the given coins , so that they stop emitting signals.
+// A new window is created , if a trade for the coin comes in later on.
+func (sb *SignalBuffer) Remove(coins ...model.Coin) {
+	sb.lock.Lock()
+	windows := make(map[string]*buffer.IntervalWindow)
+	for _, coin := range coins {
+		if window, ok := sb.windows[string(coin)]; ok {
+			windows[string(coin)] = window
+		}
+		delete(sb.windows, string(coin))
+		delete(sb.regimes, coin)
+		delete(sb.books, coin)
+	}
+	sb.lock.Unlock()
+	closeWindows(windows)
 }
 
 func (sb *SignalBuffer) Close() {
-	for coin, ch := range sb.windows {
+	sb.lock.Lock()
+	windows := sb.windows
+	sb.windows = make(map[string]*buffer.IntervalWindow)
+	sb.lock.Unlock()
+	closeWindows(windows)
+}
+
+// closeWindows closes the given windows.
+func closeWindows(windows map[string]*buffer.IntervalWindow) {
+	for coin, ch := range windows {
 		err := ch.Close()
 		if err != nil {
 			log.Err(err).Str("coin", coin).Msg("error closing buffer")
@@ -72,7 +131,7 @@ func (sb *SignalBuffer) Close() {
 
 // bufferedProcessor is the buffer aggregating logic for the incoming signals
 // essentially this is where the magic happens ... see for yourselves
-func bufferedProcessor(coin model.Coin, trades <-chan buffer.StatsMessage, signals chan<- *model.TradeSignal) {
+func bufferedProcessor(coin model.Coin, trades <-chan buffer.StatsMessage, signals chan<- *model.TradeSignal, latest func(coin model.Coin) (model.Regime, model.OrderBook)) {
 	var lastSignal model.TradeSignal
 	for bucket := range trades {
 		// TODO : highlight the data flow better
@@ -83,6 +142,7 @@ func bufferedProcessor(coin model.Coin, trades <-chan buffer.StatsMessage, signa
 		if bucket.OK {
 			min, max := bucket.Stats[0].Range()
 			size := int(bucket.Stats[4].Sum()) // bucket.Stats[0].Count()
+			regime, book := latest(coin)
 			signal := &model.TradeSignal{
 				Coin: coin,
 				Tick: model.Tick{
@@ -101,12 +161,17 @@ func bufferedProcessor(coin model.Coin, trades <-chan buffer.StatsMessage, signa
 					Type: model.SignedType(bucket.Stats[0].Diff()),
 				},
 				Meta: model.Meta{
-					First: bucket.First,
-					Time:  bucket.Last,
-					Init:  bucket.Init,
-					Live:  bucket.OK,
-					Size:  size,
+					First:    bucket.First,
+					Time:     bucket.Last,
+					Init:     bucket.Init,
+					Live:     bucket.OK,
+					Size:     size,
+					Regime:   regime,
+					Interval: bucket.Duration,
+					// mark the windows with data quality anomalies
+					Anomalies: int(bucket.Stats[5].Sum()),
 				},
+				OrderBook: book,
 			}
 			signal.Tick.Level = model.Level{
 				Price:  bucket.Stats[0].Avg(),
@@ -1,8 +1,11 @@
 package ml
 
 import (
+	"errors"
 	"fmt"
+	"sync"
 
+	"github.com/drakos74/free-coin/internal/algo/processor/ml/feature"
 	mlmodel "github.com/drakos74/free-coin/internal/algo/processor/ml/model"
 	"github.com/drakos74/free-coin/internal/buffer"
 	coinmath "github.com/drakos74/free-coin/internal/math"
@@ -38,103 +41,162 @@ func fit(xx, yy []float64, degree ...int) ([]float64, error) {
 	return p, nil
 }
 
-// Collector collects trade stats based on the collector functions
+// Collector collects trade stats based on the feature pipelines of each segment
 // it groups previous trade stats, so we can join the previous and next stats
 // and can effectively train a model
 type Collector struct {
+	lock    *sync.RWMutex
 	store   storage.Persistence
 	history storage.Persistence
 	tracker map[model.Key]*buffer.MultiBuffer
+	in      map[model.Key]*feature.Pipeline
+	out     map[model.Key]*feature.Pipeline
 	vectors chan mlmodel.Vector
 	config  mlmodel.Config
-	in      Collect
-	out     Collect
 }
 
-func NewCollector(shard storage.Shard, config mlmodel.Config, in, out Collect) (*Collector, error) {
+func NewCollector(shard storage.Shard, config mlmodel.Config) (*Collector, error) {
 	store, err := shard(Name)
 	if err != nil {
 		log.Error().Err(err).Msg("could not init storage")
 		store = storage.NewVoidStorage()
 	}
 
-	tracker := make(map[model.Key]*buffer.MultiBuffer)
-
 	col := &Collector{
+		lock:    new(sync.RWMutex),
 		store:   store,
 		config:  config,
+		tracker: make(map[model.Key]*buffer.MultiBuffer),
+		in:      make(map[model.Key]*feature.Pipeline),
+		out:     make(map[model.Key]*feature.Pipeline),
 		vectors: make(chan mlmodel.Vector),
-		in:      in,
-		out:     out,
 	}
 
 	for k, cfg := range config.Segments {
-		tracker[k] = buffer.NewMultiBuffer(cfg.Stats.LookBack + cfg.Stats.LookAhead)
-		log.Info().Str("Index", k.ToString()).Msg("init collector")
+		err := col.add(k, cfg)
+		if err != nil {
+			return nil, err
+		}
 	}
 
-	col.tracker = tracker
-
 	return col, nil
 }
 
+func (c *Collector) add(k model.Key, cfg mlmodel.Segments) error {
+	in, out, err := pipelines(cfg.Stats)
+	if err != nil {
+		return fmt.Errorf("could not init collector for '%s': %w", k.ToString(), err)
+	}
+	c.in[k] = in
+	c.out[k] = out
+	c.tracker[k] = buffer.NewMultiBuffer(cfg.Stats.LookBack + cfg.Stats.LookAhead)
+	log.Info().
+		Str("Index", k.ToString()).
+		Int("in", in.Dim()).
+		Int("out", out.Dim()).
+		Msg("init collector")
+	return nil
+}
+
+func (c *Collector) remove(k model.Key) {
+	delete(c.in, k)
+	delete(c.out, k)
+	delete(c.tracker, k)
+}
+
+// Reload applies the config diff to the collector.
+// The removed segments are dropped , the added ones start collecting from scratch
+// and the changed ones only if the shape of the data changed.
+func (c *Collector) Reload(diff mlmodel.Diff, config mlmodel.Config) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.config = config
+	for _, k := range diff.Removed {
+		c.remove(k)
+	}
+	for k, sd := range diff.Changed {
+		if sd.Shape {
+			c.remove(k)
+			diff.Added = append(diff.Added, k)
+		}
+	}
+	errs := make([]error, 0)
+	for _, k := range diff.Added {
+		if err := c.add(k, config.Segments[k]); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// pipelines creates the input and output feature pipelines for the given stats config.
+func pipelines(stats mlmodel.Stats) (*feature.Pipeline, *feature.Pipeline, error) {
+	inFeatures := stats.In
+	if len(inFeatures) == 0 {
+		inFeatures = feature.DefaultIn
+	}
+	outFeatures := stats.Out
+	if len(outFeatures) == 0 {
+		outFeatures = feature.DefaultOut
+	}
+	in, err := feature.NewPipeline(stats, inFeatures...)
+	if err != nil {
+		return nil, nil, fmt.Errorf("could not create input pipeline: %w", err)
+	}
+	out, err := feature.NewPipeline(stats, outFeatures...)
+	if err != nil {
+		return nil, nil, fmt.Errorf("could not create output pipeline: %w", err)
+	}
+	return in, out, nil
+}
+
+// Dim returns the input and output feature dimensions for the given key.
+func (c *Collector) Dim(key model.Key) (int, int) {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	in, ok := c.in[key]
+	if !ok {
+		return 0, 0
+	}
+	return in.Dim(), c.out[key].Dim()
+}
+
 func (c *Collector) push(trade *model.TradeSignal) {
+	for _, vector := range c.collect(trade) {
+		c.vectors <- vector
+	}
+}
+
+// collect extracts the features for the segments of the trade coin ,
+// the vectors are sent out of the lock , so that a reload cannot block the vector processing.
+// No vectors are collected for the signals marked with data quality anomalies.
+func (c *Collector) collect(trade *model.TradeSignal) []mlmodel.Vector {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	vectors := make([]mlmodel.Vector, 0)
 	for k, track := range c.tracker {
 		if k.Match(trade.Coin) {
-			x := c.in(trade)
+			x := c.in[k].Extract(trade)
+			// extract the output also on every trade , to keep the pipeline state consistent
+			next := c.out[k].Extract(trade)
 			prev := track.Last()
 
-			if _, fill := track.Push(x...); fill {
+			// skip the windows with data quality anomalies , but keep the track going
+			if _, fill := track.Push(x...); fill && trade.Meta.Anomalies == 0 {
 				// format to the observed output vector
-				next := c.out(trade)
 				vector := mlmodel.Vector{
 					Meta: mlmodel.Meta{
-						Key:  k,
-						Tick: trade.Tick,
+						Key:    k,
+						Tick:   trade.Tick,
+						Regime: trade.Meta.Regime,
 					},
 					PrevIn:  prev,
 					PrevOut: next,
 					NewIn:   x,
 				}
-				c.vectors <- vector
+				vectors = append(vectors, vector)
 			}
 		}
 	}
-}
-
-type Collect func(trade *model.TradeSignal) []float64
-
-var Trend Collect = func(trade *model.TradeSignal) []float64 {
-	// pick the right attributes for the ml input vector
-	trend := 100 * trade.Tick.StatsData.Trend.Price / trade.Tick.Price
-	return []float64{trend, trade.Tick.Price}
-}
-
-var CollectStats Collect = func(trade *model.TradeSignal) []float64 {
-	// pick the right attributes for the ml input vector
-	trend := 100 * trade.Tick.StatsData.Trend.Price / trade.Tick.Price
-	std := trade.Tick.StatsData.Std.Price / trade.Tick.Price
-	volume := trade.Tick.StatsData.Std.Volume / trade.Tick.Volume
-	size := float64(trade.Meta.Size)
-	buyEvents := trade.Tick.StatsData.Buy.Count / size
-	sellEvents := trade.Tick.StatsData.Sell.Count / size
-	buyVolume := trade.Tick.StatsData.Buy.Volume / trade.Tick.Volume
-	sellVolume := trade.Tick.StatsData.Sell.Volume / trade.Tick.Volume
-	return []float64{trend, std, volume, buyVolume, buyEvents, sellVolume, sellEvents, size, trade.Tick.Price}
-}
-
-var SplitOnTrend = func(gap float64) Collect {
-	threshold := gap
-	return func(trade *model.TradeSignal) []float64 {
-		trend := trade.Tick.StatsData.Trend.Price / trade.Tick.Price
-		y := make([]float64, 3)
-		if trend > threshold {
-			y[0] = 1
-		} else if trend < -1*threshold {
-			y[2] = 1
-		} else {
-			y[1] = 1
-		}
-		return y
-	}
+	return vectors
 }
@@ -7,6 +7,7 @@ import (
 	"github.com/drakos74/free-coin/internal/algo/processor/ml/net"
 
 	mlmodel "github.com/drakos74/free-coin/internal/algo/processor/ml/model"
+	"github.com/drakos74/free-coin/internal/config"
 	"github.com/drakos74/free-coin/internal/model"
 )
 
@@ -53,6 +54,89 @@ func CoinConfig(coins map[model.Coin]mlmodel.ConfigSegment) *mlmodel.Config {
 	}
 }
 
+// FromConfig creates the ml config from the bot config.
+// The coins without explicit segments get the default segments.
+func FromConfig(c *config.Config) *mlmodel.Config {
+	cfg := make(map[model.Coin]mlmodel.ConfigSegment)
+	for coin, live := range c.Coins {
+		segments := make([]config.Segment, 0)
+		for _, s := range c.Segments {
+			if s.Coin == coin {
+				segments = append(segments, s)
+			}
+		}
+		live := live
+		cfg[coin] = func(coin model.Coin) func(cfg mlmodel.SegmentConfig) mlmodel.SegmentConfig {
+			if len(segments) == 0 {
+				return ForCoin(coin, live)
+			}
+			return func(sgm mlmodel.SegmentConfig) mlmodel.SegmentConfig {
+				for _, s := range segments {
+					sgm[ConfigKey(coin, int(s.Duration.Minutes()))] = mlmodel.Segments{
+						Stats:  s.Stats,
+						Trader: s.Trader,
+					}
+				}
+				return sgm
+			}
+		}
+	}
+
+	mlConfig := CoinConfig(cfg)
+	settings := c.Settings()
+	mlConfig.Position = mlmodel.Position{
+		OpenValue:      settings.OpenValue,
+		StopLoss:       settings.StopLoss,
+		TakeProfit:     settings.TakeProfit,
+		TrackingConfig: settings.TrackingConfig,
+		MaxPositions:   settings.MaxPositions,
+		MaxExposure:    settings.MaxExposure,
+	}
+	trace := make(map[string]bool)
+	for _, t := range c.Option.Trace {
+		trace[t] = true
+	}
+	mlConfig.Option = mlmodel.Option{
+		Trace:     trace,
+		Log:       c.Option.Log,
+		Debug:     c.Option.Debug,
+		Benchmark: c.Option.Benchmark,
+	}
+	mlConfig.Buffer.Interval = c.Buffer.Interval.Duration
+	mlConfig.Segment.Interval = c.Buffer.Segment.Duration
+	return mlConfig
+}
+
+// WithCoins returns a copy of the config , with the segments of the removed coins dropped
+// and the default segments for the added coins , if they have none yet.
+// The segments of the added coins are not live , until enabled by the user or the config file.
+func WithCoins(c mlmodel.Config, added, removed []model.Coin) *mlmodel.Config {
+	segments := make(mlmodel.SegmentConfig)
+	for k, s := range c.Segments {
+		segments[k] = s
+	}
+	for _, coin := range removed {
+		for k := range segments {
+			if k.Coin == coin {
+				delete(segments, k)
+			}
+		}
+	}
+	for _, coin := range added {
+		exists := false
+		for k := range segments {
+			if k.Coin == coin {
+				exists = true
+			}
+		}
+		if !exists {
+			segments = ForCoin(coin, false)(segments)
+		}
+	}
+	c.Segments = segments
+	return &c
+}
+
 func WithConfig(coin map[model.Coin]bool) *mlmodel.Config {
 	cfg := make(map[model.Coin]mlmodel.ConfigSegment)
 	for c, live := range coin {
@@ -0,0 +1,191 @@
+package dataset
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	mlmodel "github.com/drakos74/free-coin/internal/algo/processor/ml/model"
+	"github.com/drakos74/free-coin/internal/model"
+	"github.com/drakos74/free-coin/internal/storage"
+	"github.com/drakos74/free-coin/internal/trader"
+)
+
+// Meta is the metadata of a dataset , needed to reproduce it.
+// Hash is the hash of the stats config used by the collector to build the vectors.
+// In and Out are the feature names of the input and output vectors.
+type Meta struct {
+	Key   model.Key     `json:"key"`
+	Coin  model.Coin    `json:"coin"`
+	Hash  string        `json:"hash"`
+	Stats mlmodel.Stats `json:"stats"`
+	From  time.Time     `json:"from"`
+	To    time.Time     `json:"to"`
+	In    []string      `json:"in"`
+	Out   []string      `json:"out"`
+	Size  int           `json:"size"`
+}
+
+// Row is a single aligned sample of the dataset.
+// In is the previous input vector , Out is the observed output and Next is the new input vector
+// as they are emitted by the collector.
+// Type and PnL are the labels from the trade outcomes , if a position was opened at the time of the sample.
+type Row struct {
+	Time   time.Time    `json:"time"`
+	Price  float64      `json:"price"`
+	Regime model.Regime `json:"regime"`
+	In     []float64    `json:"in"`
+	Out    []float64    `json:"out"`
+	Next   []float64    `json:"next"`
+	Type   model.Type   `json:"type"`
+	PnL    float64      `json:"pnl"`
+}
+
+// Dataset is a collection of aligned feature and label rows for a key.
+type Dataset struct {
+	Meta Meta  `json:"meta"`
+	Rows []Row `json:"rows"`
+}
+
+// New creates a new dataset for the given key and stats config.
+func New(key model.Key, stats mlmodel.Stats, in, out []string) *Dataset {
+	return &Dataset{
+		Meta: Meta{
+			Key:   key,
+			Coin:  key.Coin,
+			Hash:  Hash(stats),
+			Stats: stats,
+			In:    in,
+			Out:   out,
+		},
+		Rows: make([]Row, 0),
+	}
+}
+
+// Hash returns a short deterministic hash for the given stats config.
+func Hash(stats mlmodel.Stats) string {
+	b, err := json.Marshal(stats)
+	if err != nil {
+		return ""
+	}
+	h := sha256.Sum256(b)
+	return hex.EncodeToString(h[:8])
+}
+
+// Add adds a new collector vector to the dataset.
+func (ds *Dataset) Add(vv mlmodel.Vector) error {
+	if vv.Meta.Key.Hash() != ds.Meta.Key.Hash() {
+		return fmt.Errorf("wrong key '%s' for dataset '%s'", vv.Meta.Key.ToString(), ds.Meta.Key.ToString())
+	}
+	if len(vv.PrevIn) != len(ds.Meta.In) || len(vv.NewIn) != len(ds.Meta.In) {
+		return fmt.Errorf("wrong input dimension %d for dataset with %d features", len(vv.PrevIn), len(ds.Meta.In))
+	}
+	if len(vv.PrevOut) != len(ds.Meta.Out) {
+		return fmt.Errorf("wrong output dimension %d for dataset with %d features", len(vv.PrevOut), len(ds.Meta.Out))
+	}
+	t := vv.Meta.Tick.Time
+	if len(ds.Rows) == 0 || t.Before(ds.Meta.From) {
+		ds.Meta.From = t
+	}
+	if t.After(ds.Meta.To) {
+		ds.Meta.To = t
+	}
+	ds.Rows = append(ds.Rows, Row{
+		Time:   t,
+		Price:  vv.Meta.Tick.Price,
+		Regime: vv.Meta.Regime,
+		In:     vv.PrevIn,
+		Out:    vv.PrevOut,
+		Next:   vv.NewIn,
+	})
+	ds.Meta.Size = len(ds.Rows)
+	return nil
+}
+
+// Label aligns the trade events with the dataset rows.
+// Each opening event labels the latest row at or before its time with the position type ,
+// and the following closing event adds the realised PnL to the same row.
+// It returns the number of labelled rows.
+func (ds *Dataset) Label(events []trader.Event) int {
+	sort.Slice(events, func(i, j int) bool {
+		return events[i].Time.Before(events[j].Time)
+	})
+	labelled := 0
+	open := -1
+	for _, event := range events {
+		if event.Key.Hash() != ds.Meta.Key.Hash() || !valid(event.Reason) {
+			continue
+		}
+		if event.Value != 0 {
+			// the event closed a position , so it carries the outcome
+			if open >= 0 {
+				ds.Rows[open].PnL = event.PnL
+				labelled++
+			}
+			open = -1
+			continue
+		}
+		open = ds.at(event.Time)
+		if open >= 0 {
+			ds.Rows[open].Type = event.Type
+		}
+	}
+	return labelled
+}
+
+// at returns the index of the latest row at or before the given time.
+func (ds *Dataset) at(t time.Time) int {
+	i := sort.Search(len(ds.Rows), func(i int) bool {
+		return ds.Rows[i].Time.After(t)
+	})
+	return i - 1
+}
+
+// valid checks if the event reason corresponds to an actual order.
+func valid(reason trader.Reason) bool {
+	switch reason {
+	case trader.VoidReasonIgnore,
+		trader.VoidReasonConflict,
+		trader.VoidReasonType,
+		trader.VoidHistoryReasonType,
+		trader.VoidReasonClose,
+		trader.VoidReasonReverse,
+		trader.VoidReasonRisk:
+		return false
+	}
+	return true
+}
+
+// Vectors converts the dataset rows back to collector vectors.
+func (ds *Dataset) Vectors() []mlmodel.Vector {
+	vv := make([]mlmodel.Vector, len(ds.Rows))
+	for i, row := range ds.Rows {
+		vv[i] = mlmodel.Vector{
+			Meta: mlmodel.Meta{
+				Key:    ds.Meta.Key,
+				Tick:   model.NewTick(row.Price, 0, model.NoType, row.Time),
+				Regime: row.Regime,
+			},
+			PrevIn:  row.In,
+			PrevOut: row.Out,
+			NewIn:   row.Next,
+		}
+	}
+	return vv
+}
+
+// Events loads the trade events for the given key from the registry.
+func Events(registry storage.Registry, key model.Key) ([]trader.Event, error) {
+	events := []trader.Event{{}}
+	err := registry.GetAll(storage.K{
+		Pair:  string(key.Coin),
+		Label: key.ToString(),
+	}, &events)
+	if err != nil {
+		return nil, fmt.Errorf("could not load events for '%s': %w", key.ToString(), err)
+	}
+	return events, nil
+}
@@ -0,0 +1,206 @@
+package dataset
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	mlmodel "github.com/drakos74/free-coin/internal/algo/processor/ml/model"
+	"github.com/drakos74/free-coin/internal/model"
+	"github.com/drakos74/free-coin/internal/trader"
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	testKey = model.Key{
+		Coin:     model.BTC,
+		Duration: 15 * time.Minute,
+		Strategy: "BTC_15",
+	}
+	testStart = time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+)
+
+func newDataset(t *testing.T, n int) *Dataset {
+	ds := New(testKey, mlmodel.Stats{LookBack: 3, LookAhead: 1, Gap: 0.5}, []string{"trend", "price"}, []string{"trend"})
+	for i := 0; i < n; i++ {
+		err := ds.Add(mlmodel.Vector{
+			Meta: mlmodel.Meta{
+				Key:    testKey,
+				Tick:   model.NewTick(100+float64(i), 1, model.Buy, testStart.Add(time.Duration(i)*15*time.Minute)),
+				Regime: model.Trending,
+			},
+			PrevIn:  []float64{float64(i), 0.5},
+			PrevOut: []float64{-float64(i)},
+			NewIn:   []float64{float64(i + 1), 0.25},
+		})
+		assert.NoError(t, err)
+	}
+	return ds
+}
+
+func TestDataset_Add(t *testing.T) {
+
+	type test struct {
+		vector mlmodel.Vector
+		err    bool
+	}
+
+	tests := map[string]test{
+		"valid": {
+			vector: mlmodel.Vector{
+				Meta:    mlmodel.Meta{Key: testKey},
+				PrevIn:  []float64{1, 2},
+				PrevOut: []float64{1},
+				NewIn:   []float64{1, 2},
+			},
+		},
+		"wrong-key": {
+			vector: mlmodel.Vector{
+				Meta:    mlmodel.Meta{Key: model.Key{Coin: model.ETH, Duration: 15 * time.Minute}},
+				PrevIn:  []float64{1, 2},
+				PrevOut: []float64{1},
+				NewIn:   []float64{1, 2},
+			},
+			err: true,
+		},
+		"wrong-in": {
+			vector: mlmodel.Vector{
+				Meta:    mlmodel.Meta{Key: testKey},
+				PrevIn:  []float64{1},
+				PrevOut: []float64{1},
+				NewIn:   []float64{1, 2},
+			},
+			err: true,
+		},
+		"wrong-out": {
+			vector: mlmodel.Vector{
+				Meta:    mlmodel.Meta{Key: testKey},
+				PrevIn:  []float64{1, 2},
+				PrevOut: []float64{1, 2},
+				NewIn:   []float64{1, 2},
+			},
+			err: true,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			ds := newDataset(t, 0)
+			err := ds.Add(tt.vector)
+			if tt.err {
+				assert.Error(t, err)
+				assert.Equal(t, 0, ds.Meta.Size)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, 1, ds.Meta.Size)
+		})
+	}
+}
+
+func TestDataset_Label(t *testing.T) {
+	ds := newDataset(t, 10)
+	assert.Equal(t, testStart, ds.Meta.From)
+	assert.Equal(t, testStart.Add(9*15*time.Minute), ds.Meta.To)
+
+	at := func(i int, minutes int) time.Time {
+		return testStart.Add(time.Duration(i)*15*time.Minute + time.Duration(minutes)*time.Minute)
+	}
+	events := []trader.Event{
+		// close of the first position
+		{Key: testKey, Time: at(4, 0), Type: model.Sell, Value: 100, PnL: 0.02, Reason: trader.SignalReason},
+		// open of the first position within the second bar
+		{Key: testKey, Time: at(1, 5), Type: model.Buy, Reason: trader.SignalReason},
+		// ignored signal , should not be taken into account
+		{Key: testKey, Time: at(2, 0), Type: model.Buy, Value: 100, PnL: 0.5, Reason: trader.VoidReasonIgnore},
+		// open rejected by the risk limits , the position was never opened
+		{Key: testKey, Time: at(3, 0), Type: model.Sell, Reason: trader.VoidReasonRisk},
+		// event for another key
+		{Key: model.Key{Coin: model.ETH}, Time: at(5, 0), Type: model.Sell, Reason: trader.SignalReason},
+		// still open position
+		{Key: testKey, Time: at(7, 0), Type: model.Sell, Reason: trader.SignalReason},
+	}
+
+	assert.Equal(t, 1, ds.Label(events))
+	for i, row := range ds.Rows {
+		switch i {
+		case 1:
+			assert.Equal(t, model.Buy, row.Type)
+			assert.Equal(t, 0.02, row.PnL)
+		case 7:
+			assert.Equal(t, model.Sell, row.Type)
+			assert.Equal(t, 0.0, row.PnL)
+		default:
+			assert.Equal(t, model.NoType, row.Type)
+			assert.Equal(t, 0.0, row.PnL)
+		}
+	}
+}
+
+func TestDataset_Formats(t *testing.T) {
+	ds := newDataset(t, 5)
+	ds.Rows[2].Type = model.Sell
+	ds.Rows[2].PnL = -0.01
+
+	t.Run("csv", func(t *testing.T) {
+		buf := new(bytes.Buffer)
+		err := ds.WriteCSV(buf)
+		assert.NoError(t, err)
+		loaded, err := ReadCSV(buf, ds.Meta)
+		assert.NoError(t, err)
+		assert.Equal(t, ds, loaded)
+	})
+
+	t.Run("csv-wrong-meta", func(t *testing.T) {
+		buf := new(bytes.Buffer)
+		err := ds.WriteCSV(buf)
+		assert.NoError(t, err)
+		meta := ds.Meta
+		meta.Out = []string{"trend", "price"}
+		_, err = ReadCSV(buf, meta)
+		assert.Error(t, err)
+	})
+
+	t.Run("columns", func(t *testing.T) {
+		cols := ds.ToColumns()
+		assert.Equal(t, 5, len(cols.Time))
+		assert.Equal(t, []float64{0, 1, 2, 3, 4}, cols.Features["in.trend"])
+		assert.Equal(t, []float64{1, 2, 3, 4, 5}, cols.Features["next.trend"])
+		loaded, err := FromColumns(cols)
+		assert.NoError(t, err)
+		assert.Equal(t, ds, loaded)
+	})
+
+	t.Run("export", func(t *testing.T) {
+		dir := t.TempDir()
+		paths, err := ds.Export(dir)
+		assert.NoError(t, err)
+		assert.Equal(t, 3, len(paths))
+		for _, path := range paths {
+			_, err := os.Stat(path)
+			assert.NoError(t, err)
+		}
+		for _, path := range paths[:2] {
+			loaded, err := Load(path)
+			assert.NoError(t, err)
+			assert.Equal(t, ds, loaded)
+		}
+		_, err = Load(filepath.Join(dir, "unknown.txt"))
+		assert.Error(t, err)
+	})
+}
+
+func TestDataset_Vectors(t *testing.T) {
+	ds := newDataset(t, 3)
+	vv := ds.Vectors()
+	assert.Equal(t, 3, len(vv))
+	for i, v := range vv {
+		assert.Equal(t, testKey, v.Meta.Key)
+		assert.Equal(t, ds.Rows[i].Time, v.Meta.Tick.Time)
+		assert.Equal(t, ds.Rows[i].In, v.PrevIn)
+		assert.Equal(t, ds.Rows[i].Out, v.PrevOut)
+		assert.Equal(t, ds.Rows[i].Next, v.NewIn)
+	}
+}
@@ -0,0 +1,304 @@
+package dataset
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/drakos74/free-coin/internal/model"
+)
+
+const (
+	csvExt     = "csv"
+	columnsExt = "columns.json"
+	metaExt    = "meta.json"
+
+	inPrefix   = "in."
+	outPrefix  = "out."
+	nextPrefix = "next."
+)
+
+// Columns is the columnar representation of a dataset.
+// Every column holds the values of a single field for all the rows ,
+// so that the dataset can be efficiently loaded per feature.
+type Columns struct {
+	Meta     Meta                 `json:"meta"`
+	Time     []int64              `json:"time"`
+	Price    []float64            `json:"price"`
+	Regime   []model.Regime       `json:"regime"`
+	Features map[string][]float64 `json:"features"`
+	Type     []model.Type         `json:"type"`
+	PnL      []float64            `json:"pnl"`
+}
+
+// header returns the csv header for the dataset.
+func (ds *Dataset) header() []string {
+	header := []string{"time", "price", "regime"}
+	for _, prefix := range []string{inPrefix, outPrefix, nextPrefix} {
+		header = append(header, prefixed(prefix, ds.names(prefix))...)
+	}
+	return append(header, "type", "pnl")
+}
+
+// names returns the feature names for the given column prefix.
+func (ds *Dataset) names(prefix string) []string {
+	if prefix == outPrefix {
+		return ds.Meta.Out
+	}
+	return ds.Meta.In
+}
+
+func prefixed(prefix string, names []string) []string {
+	pp := make([]string, len(names))
+	for i, name := range names {
+		pp[i] = prefix + name
+	}
+	return pp
+}
+
+// WriteCSV writes the dataset rows in csv format.
+func (ds *Dataset) WriteCSV(w io.Writer) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(ds.header()); err != nil {
+		return fmt.Errorf("could not write header: %w", err)
+	}
+	for _, row := range ds.Rows {
+		record := []string{
+			row.Time.Format(time.RFC3339Nano),
+			formatFloat(row.Price),
+			string(row.Regime),
+		}
+		for _, vv := range [][]float64{row.In, row.Out, row.Next} {
+			for _, v := range vv {
+				record = append(record, formatFloat(v))
+			}
+		}
+		record = append(record, row.Type.String(), formatFloat(row.PnL))
+		if err := writer.Write(record); err != nil {
+			return fmt.Errorf("could not write row at %v: %w", row.Time, err)
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// ReadCSV reads the dataset rows in csv format for the given metadata.
+func ReadCSV(r io.Reader, meta Meta) (*Dataset, error) {
+	ds := &Dataset{Meta: meta, Rows: make([]Row, 0)}
+	reader := csv.NewReader(r)
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("could not read csv: %w", err)
+	}
+	if len(records) == 0 {
+		return nil, fmt.Errorf("missing csv header")
+	}
+	header := ds.header()
+	if len(records[0]) != len(header) {
+		return nil, fmt.Errorf("header %v does not match metadata %v", records[0], header)
+	}
+	in, out := len(meta.In), len(meta.Out)
+	for i, record := range records[1:] {
+		t, err := time.Parse(time.RFC3339Nano, record[0])
+		if err != nil {
+			return nil, fmt.Errorf("could not parse time at row %d: %w", i, err)
+		}
+		values := make([]float64, 0, len(record)-2)
+		for j, s := range append([]string{record[1]}, record[3:len(record)-2]...) {
+			v, err := strconv.ParseFloat(s, 64)
+			if err != nil {
+				return nil, fmt.Errorf("could not parse value at row %d column %d: %w", i, j, err)
+			}
+			values = append(values, v)
+		}
+		pnl, err := strconv.ParseFloat(record[len(record)-1], 64)
+		if err != nil {
+			return nil, fmt.Errorf("could not parse pnl at row %d: %w", i, err)
+		}
+		ds.Rows = append(ds.Rows, Row{
+			Time:   t,
+			Price:  values[0],
+			Regime: model.Regime(record[2]),
+			In:     values[1 : 1+in],
+			Out:    values[1+in : 1+in+out],
+			Next:   values[1+in+out:],
+			Type:   model.TypeFromString(record[len(record)-2]),
+			PnL:    pnl,
+		})
+	}
+	return ds, nil
+}
+
+// ToColumns converts the dataset to the columnar format.
+func (ds *Dataset) ToColumns() Columns {
+	n := len(ds.Rows)
+	cols := Columns{
+		Meta:     ds.Meta,
+		Time:     make([]int64, n),
+		Price:    make([]float64, n),
+		Regime:   make([]model.Regime, n),
+		Features: make(map[string][]float64),
+		Type:     make([]model.Type, n),
+		PnL:      make([]float64, n),
+	}
+	for _, name := range ds.header()[3 : len(ds.header())-2] {
+		cols.Features[name] = make([]float64, n)
+	}
+	for i, row := range ds.Rows {
+		cols.Time[i] = row.Time.UnixNano()
+		cols.Price[i] = row.Price
+		cols.Regime[i] = row.Regime
+		cols.Type[i] = row.Type
+		cols.PnL[i] = row.PnL
+		for prefix, vv := range map[string][]float64{inPrefix: row.In, outPrefix: row.Out, nextPrefix: row.Next} {
+			for j, name := range prefixed(prefix, ds.names(prefix)) {
+				cols.Features[name][i] = vv[j]
+			}
+		}
+	}
+	return cols
+}
+
+// FromColumns converts the columnar format back to a dataset.
+func FromColumns(cols Columns) (*Dataset, error) {
+	ds := &Dataset{Meta: cols.Meta, Rows: make([]Row, len(cols.Time))}
+	column := func(name string) ([]float64, error) {
+		c, ok := cols.Features[name]
+		if !ok {
+			return nil, fmt.Errorf("missing column '%s'", name)
+		}
+		if len(c) != len(cols.Time) {
+			return nil, fmt.Errorf("wrong size %d for column '%s'", len(c), name)
+		}
+		return c, nil
+	}
+	for i, t := range cols.Time {
+		row := Row{
+			Time:   time.Unix(0, t).UTC(),
+			Price:  cols.Price[i],
+			Regime: cols.Regime[i],
+			Type:   cols.Type[i],
+			PnL:    cols.PnL[i],
+		}
+		for _, prefix := range []string{inPrefix, outPrefix, nextPrefix} {
+			names := ds.names(prefix)
+			vv := make([]float64, len(names))
+			for j, name := range prefixed(prefix, names) {
+				c, err := column(name)
+				if err != nil {
+					return nil, err
+				}
+				vv[j] = c[i]
+			}
+			switch prefix {
+			case inPrefix:
+				row.In = vv
+			case outPrefix:
+				row.Out = vv
+			case nextPrefix:
+				row.Next = vv
+			}
+		}
+		ds.Rows[i] = row
+	}
+	return ds, nil
+}
+
+// Export writes the dataset in csv and columnar format , together with its metadata , under the given directory.
+// It returns the paths of the created files.
+func (ds *Dataset) Export(dir string) ([]string, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("could not create export dir '%s': %w", dir, err)
+	}
+	name := ds.Name()
+	paths := []string{
+		filepath.Join(dir, fmt.Sprintf("%s.%s", name, csvExt)),
+		filepath.Join(dir, fmt.Sprintf("%s.%s", name, columnsExt)),
+		filepath.Join(dir, fmt.Sprintf("%s.%s", name, metaExt)),
+	}
+	err := writeFile(paths[0], ds.WriteCSV)
+	if err != nil {
+		return nil, err
+	}
+	err = writeFile(paths[1], func(w io.Writer) error {
+		return json.NewEncoder(w).Encode(ds.ToColumns())
+	})
+	if err != nil {
+		return nil, err
+	}
+	err = writeFile(paths[2], func(w io.Writer) error {
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(ds.Meta)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return paths, nil
+}
+
+// Name returns the file name of the dataset , based on the key and the config hash.
+func (ds *Dataset) Name() string {
+	return fmt.Sprintf("%s_%s", ds.Meta.Key.ToString(), ds.Meta.Hash)
+}
+
+// Load loads a dataset from the given columnar or csv file.
+// For csv files the metadata are loaded from the corresponding metadata file.
+func Load(path string) (*Dataset, error) {
+	switch {
+	case hasExt(path, columnsExt):
+		var cols Columns
+		if err := readFile(path, &cols); err != nil {
+			return nil, err
+		}
+		return FromColumns(cols)
+	case hasExt(path, csvExt):
+		var meta Meta
+		if err := readFile(fmt.Sprintf("%s.%s", path[:len(path)-len(csvExt)-1], metaExt), &meta); err != nil {
+			return nil, err
+		}
+		file, err := os.Open(path)
+		if err != nil {
+			return nil, fmt.Errorf("could not open '%s': %w", path, err)
+		}
+		defer file.Close()
+		return ReadCSV(file, meta)
+	}
+	return nil, fmt.Errorf("unknown dataset format for '%s'", path)
+}
+
+func hasExt(path, ext string) bool {
+	return len(path) > len(ext) && path[len(path)-len(ext)-1:] == "."+ext
+}
+
+func writeFile(path string, write func(w io.Writer) error) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("could not create '%s': %w", path, err)
+	}
+	defer file.Close()
+	if err := write(file); err != nil {
+		return fmt.Errorf("could not write '%s': %w", path, err)
+	}
+	return nil
+}
+
+func readFile(path string, v interface{}) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("could not read '%s': %w", path, err)
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("could not decode '%s': %w", path, err)
+	}
+	return nil
+}
+
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
@@ -0,0 +1,93 @@
+package ml
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/drakos74/free-coin/internal/algo/processor"
+	"github.com/drakos74/free-coin/internal/algo/processor/ml/dataset"
+	mlmodel "github.com/drakos74/free-coin/internal/algo/processor/ml/model"
+	"github.com/drakos74/free-coin/internal/api"
+	"github.com/drakos74/free-coin/internal/model"
+	"github.com/drakos74/free-coin/internal/storage"
+	"github.com/drakos74/free-coin/internal/trader"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	ExportName = "ml-export"
+)
+
+// Exporter is the dataset export processor.
+// It collects the vectors for each segment of the config, labels them with the trade outcomes
+// of the given event registry and exports them to the given directory on shutdown.
+func Exporter(index api.Index, config mlmodel.Config, registry storage.EventRegistry, dir string) func(u api.User, e api.Exchange) api.Processor {
+	col, err := NewCollector(storage.VoidShard(ExportName), config)
+	if err != nil {
+		log.Error().Err(err).Str("processor", ExportName).Msg("could not init processor")
+		return func(u api.User, e api.Exchange) api.Processor {
+			return processor.Void(ExportName)
+		}
+	}
+
+	lock := new(sync.Mutex)
+	datasets := make(map[model.Key]*dataset.Dataset)
+	for k, segments := range config.Segments {
+		datasets[k] = dataset.New(k, segments.Stats, col.in[k].Names(), col.out[k].Names())
+	}
+
+	go func(col *Collector) {
+		for vv := range col.vectors {
+			lock.Lock()
+			if ds, ok := datasets[vv.Meta.Key]; ok {
+				if err := ds.Add(vv); err != nil {
+					log.Error().Err(err).Str("key", vv.Meta.Key.ToString()).Msg("could not add vector to dataset")
+				}
+			}
+			lock.Unlock()
+		}
+	}(col)
+
+	return func(u api.User, e api.Exchange) api.Processor {
+		return processor.ProcessBufferedWithClose(ExportName, config.Segment.Interval, false, func(trade *model.TradeSignal) error {
+			col.push(trade)
+			return nil
+		}, func() {
+			lock.Lock()
+			defer lock.Unlock()
+			msg := new(strings.Builder)
+			for k, ds := range datasets {
+				paths, err := export(ds, registry, dir)
+				if err != nil {
+					log.Error().Err(err).Str("key", k.ToString()).Msg("could not export dataset")
+					msg.WriteString(fmt.Sprintf("%s export failed: %s\n", k.ToString(), err.Error()))
+					continue
+				}
+				msg.WriteString(fmt.Sprintf("%s %d rows [%s - %s] -> %s\n",
+					k.ToString(), ds.Meta.Size,
+					ds.Meta.From.Format("2006-01-02 15:04"), ds.Meta.To.Format("2006-01-02 15:04"),
+					paths[0]))
+			}
+			u.Send(index, api.NewMessage(fmt.Sprintf("%s exported datasets to %s\n%s", ExportName, dir, msg.String())), nil)
+		})
+	}
+}
+
+// export labels the dataset with the trade outcomes and writes it to the export directory.
+func export(ds *dataset.Dataset, registry storage.EventRegistry, dir string) ([]string, error) {
+	if registry != nil {
+		reg, err := registry(trader.EventRegistryPath)
+		if err != nil {
+			return nil, fmt.Errorf("could not open event registry: %w", err)
+		}
+		events, err := dataset.Events(reg, ds.Meta.Key)
+		if err != nil {
+			// we can still export the unlabelled dataset
+			log.Warn().Err(err).Str("key", ds.Meta.Key.ToString()).Msg("no trade events for dataset")
+		} else {
+			ds.Label(events)
+		}
+	}
+	return ds.Export(dir)
+}
@@ -0,0 +1,268 @@
+package feature
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	mlmodel "github.com/drakos74/free-coin/internal/algo/processor/ml/model"
+	coinmath "github.com/drakos74/free-coin/internal/math"
+	"github.com/drakos74/free-coin/internal/model"
+)
+
+const (
+	// window is the default number of signals used by the window based features
+	window = 14
+)
+
+// Extractor extracts a feature vector from a trade signal.
+// Extractors can be stateful, in which case a new one is needed for each key.
+type Extractor interface {
+	Dim() int
+	Extract(trade *model.TradeSignal) []float64
+}
+
+// Constructor creates a new extractor for the given segment stats config.
+type Constructor func(stats mlmodel.Stats) Extractor
+
+// Func is a stateless extractor based on a plain extraction function.
+type Func struct {
+	dim int
+	fn  func(trade *model.TradeSignal) []float64
+}
+
+// NewFunc creates a new stateless extractor.
+func NewFunc(dim int, fn func(trade *model.TradeSignal) []float64) Func {
+	return Func{
+		dim: dim,
+		fn:  fn,
+	}
+}
+
+// Dim returns the dimension of the feature.
+func (f Func) Dim() int {
+	return f.dim
+}
+
+// Extract extracts the feature values from the trade signal.
+func (f Func) Extract(trade *model.TradeSignal) []float64 {
+	return f.fn(trade)
+}
+
+// stateless wraps a stateless extraction function into a constructor.
+func stateless(dim int, fn func(trade *model.TradeSignal) []float64) Constructor {
+	return func(stats mlmodel.Stats) Extractor {
+		return NewFunc(dim, fn)
+	}
+}
+
+var (
+	lock     = new(sync.RWMutex)
+	registry = map[string]Constructor{
+		"price": stateless(1, func(trade *model.TradeSignal) []float64 {
+			return []float64{trade.Tick.Price}
+		}),
+		"trend": stateless(1, func(trade *model.TradeSignal) []float64 {
+			return []float64{ratio(100*trade.Tick.StatsData.Trend.Price, trade.Tick.Price)}
+		}),
+		"std": stateless(1, func(trade *model.TradeSignal) []float64 {
+			return []float64{ratio(trade.Tick.StatsData.Std.Price, trade.Tick.Price)}
+		}),
+		"volatility": stateless(1, func(trade *model.TradeSignal) []float64 {
+			return []float64{ratio(100*(trade.Tick.Range.Max.Price-trade.Tick.Range.Min.Price), trade.Tick.Price)}
+		}),
+		"volume": stateless(1, func(trade *model.TradeSignal) []float64 {
+			return []float64{ratio(trade.Tick.StatsData.Std.Volume, trade.Tick.Volume)}
+		}),
+		"buy": stateless(2, func(trade *model.TradeSignal) []float64 {
+			return []float64{
+				ratio(trade.Tick.StatsData.Buy.Volume, trade.Tick.Volume),
+				ratio(trade.Tick.StatsData.Buy.Count, float64(trade.Meta.Size)),
+			}
+		}),
+		"sell": stateless(2, func(trade *model.TradeSignal) []float64 {
+			return []float64{
+				ratio(trade.Tick.StatsData.Sell.Volume, trade.Tick.Volume),
+				ratio(trade.Tick.StatsData.Sell.Count, float64(trade.Meta.Size)),
+			}
+		}),
+		"imbalance": stateless(1, func(trade *model.TradeSignal) []float64 {
+			buy := trade.Tick.StatsData.Buy.Volume
+			sell := trade.Tick.StatsData.Sell.Volume
+			return []float64{ratio(buy-sell, buy+sell)}
+		}),
+		"book_imbalance": stateless(1, func(trade *model.TradeSignal) []float64 {
+			return []float64{trade.OrderBook.Imbalance}
+		}),
+		"book_pressure": stateless(1, func(trade *model.TradeSignal) []float64 {
+			return []float64{trade.OrderBook.Pressure}
+		}),
+		"book_spread": stateless(1, func(trade *model.TradeSignal) []float64 {
+			return []float64{100 * trade.OrderBook.Spread}
+		}),
+		"microprice": stateless(1, func(trade *model.TradeSignal) []float64 {
+			book := trade.OrderBook
+			return []float64{ratio(100*(book.Microprice-book.Mid), book.Mid)}
+		}),
+		"book_depth": stateless(len(model.DefaultBookDepth), func(trade *model.TradeSignal) []float64 {
+			// the depth imbalance for each of the default distances from the mid price
+			y := make([]float64, len(model.DefaultBookDepth))
+			for i, d := range trade.OrderBook.Depth {
+				if i < len(y) {
+					y[i] = ratio(d.Bid-d.Ask, d.Bid+d.Ask)
+				}
+			}
+			return y
+		}),
+		"size": stateless(1, func(trade *model.TradeSignal) []float64 {
+			return []float64{float64(trade.Meta.Size)}
+		}),
+		"velocity": stateless(1, func(trade *model.TradeSignal) []float64 {
+			return []float64{trade.Tick.Move.Velocity}
+		}),
+		"momentum": stateless(1, func(trade *model.TradeSignal) []float64 {
+			return []float64{trade.Tick.Move.Momentum}
+		}),
+		"split": func(stats mlmodel.Stats) Extractor {
+			threshold := stats.Gap
+			return NewFunc(3, func(trade *model.TradeSignal) []float64 {
+				trend := ratio(trade.Tick.StatsData.Trend.Price, trade.Tick.Price)
+				y := make([]float64, 3)
+				if trend > threshold {
+					y[0] = 1
+				} else if trend < -1*threshold {
+					y[2] = 1
+				} else {
+					y[1] = 1
+				}
+				return y
+			})
+		},
+		"returns": func(stats mlmodel.Stats) Extractor {
+			return newSeries(1, 2, func(prices []float64) []float64 {
+				return []float64{ratio(100*(prices[1]-prices[0]), prices[0])}
+			})
+		},
+		"rsi": func(stats mlmodel.Stats) Extractor {
+			return newSeries(1, window+1, func(prices []float64) []float64 {
+				rsi := coinmath.NewRSI()
+				for i := 1; i < len(prices); i++ {
+					rsi.Add(prices[i] - prices[i-1])
+				}
+				value, _, _ := rsi.Get()
+				return []float64{float64(value) / 100}
+			})
+		},
+		"ema": func(stats mlmodel.Stats) Extractor {
+			return newSeries(1, window, func(prices []float64) []float64 {
+				ema := coinmath.NewEMA()
+				for _, p := range prices {
+					ema.Add(p)
+				}
+				value, _ := ema.Get()
+				last := prices[len(prices)-1]
+				return []float64{ratio(100*(value-last), last)}
+			})
+		},
+		"fft": func(stats mlmodel.Stats) Extractor {
+			return newSeries(1, 2*window, func(prices []float64) []float64 {
+				spectrum := coinmath.FFT(normalise(prices))
+				energy := 0.0
+				for _, v := range spectrum.Values {
+					// skip the dc component , as it only reflects the price level
+					if v.Frequency > 0 {
+						energy += v.Amplitude * v.Amplitude
+					}
+				}
+				return []float64{energy / float64(len(prices))}
+			})
+		},
+	}
+)
+
+// Register adds a new feature constructor to the registry.
+func Register(name string, constructor Constructor) error {
+	lock.Lock()
+	defer lock.Unlock()
+	if _, ok := registry[name]; ok {
+		return fmt.Errorf("feature '%s' already registered", name)
+	}
+	registry[name] = constructor
+	return nil
+}
+
+// Names returns the names of all registered features.
+func Names() []string {
+	lock.RLock()
+	defer lock.RUnlock()
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// New creates a new extractor for the given feature name.
+func New(name string, stats mlmodel.Stats) (Extractor, error) {
+	lock.RLock()
+	defer lock.RUnlock()
+	if constructor, ok := registry[name]; ok {
+		return constructor(stats), nil
+	}
+	return nil, fmt.Errorf("unknown feature '%s'", name)
+}
+
+// series is a window based extractor on the price of the incoming signals.
+// It returns zero values until the window is filled.
+type series struct {
+	dim    int
+	size   int
+	values []float64
+	fn     func(values []float64) []float64
+}
+
+func newSeries(dim, size int, fn func(values []float64) []float64) *series {
+	return &series{
+		dim:    dim,
+		size:   size,
+		values: make([]float64, 0, size),
+		fn:     fn,
+	}
+}
+
+func (s *series) Dim() int {
+	return s.dim
+}
+
+func (s *series) Extract(trade *model.TradeSignal) []float64 {
+	s.values = append(s.values, trade.Tick.Price)
+	if len(s.values) > s.size {
+		s.values = s.values[1:]
+	}
+	if len(s.values) < s.size {
+		return make([]float64, s.dim)
+	}
+	return s.fn(s.values)
+}
+
+func ratio(a, b float64) float64 {
+	if b == 0 {
+		return 0
+	}
+	return a / b
+}
+
+// normalise scales the values relative to their mean.
+func normalise(values []float64) []float64 {
+	mean := 0.0
+	for _, v := range values {
+		mean += v
+	}
+	mean = mean / float64(len(values))
+	vv := make([]float64, len(values))
+	for i, v := range values {
+		vv[i] = ratio(v-mean, mean)
+	}
+	return vv
+}
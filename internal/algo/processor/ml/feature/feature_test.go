@@ -0,0 +1,168 @@
+package feature
+
+import (
+	"testing"
+	"time"
+
+	mlmodel "github.com/drakos74/free-coin/internal/algo/processor/ml/model"
+	"github.com/drakos74/free-coin/internal/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func newSignal(price, trend float64) *model.TradeSignal {
+	tick := model.NewTick(price, 1, model.Buy, time.Now())
+	tick.StatsData.Trend.Price = trend
+	return &model.TradeSignal{
+		Coin: model.BTC,
+		Meta: model.Meta{Size: 1},
+		Tick: tick,
+	}
+}
+
+func TestNewPipeline(t *testing.T) {
+
+	type test struct {
+		features []mlmodel.Feature
+		dim      int
+		names    []string
+		err      bool
+	}
+
+	tests := map[string]test{
+		"default": {
+			features: DefaultIn,
+			dim:      2,
+			names:    []string{"trend", "price"},
+		},
+		"multi-dim": {
+			features: mlmodel.Features("trend", "buy", "sell", "split", "price"),
+			dim:      9,
+			names:    []string{"trend", "buy_0", "buy_1", "sell_0", "sell_1", "split_0", "split_1", "split_2", "price"},
+		},
+		"unknown-feature": {
+			features: mlmodel.Features("trend", "unknown"),
+			err:      true,
+		},
+		"unknown-scale": {
+			features: []mlmodel.Feature{{Name: "trend", Scale: "log"}},
+			err:      true,
+		},
+		"empty": {
+			err: true,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			p, err := NewPipeline(mlmodel.Stats{Gap: 0.05}, tt.features...)
+			if tt.err {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.dim, p.Dim())
+			assert.Equal(t, tt.dim, len(p.Extract(newSignal(100, 1))))
+			assert.Equal(t, tt.names, p.Names())
+		})
+	}
+}
+
+func TestPipeline_Extract(t *testing.T) {
+
+	type test struct {
+		features []mlmodel.Feature
+		signals  []*model.TradeSignal
+		output   []float64
+	}
+
+	tests := map[string]test{
+		"trend-price": {
+			features: DefaultIn,
+			signals:  []*model.TradeSignal{newSignal(200, 2)},
+			output:   []float64{1, 200},
+		},
+		"split-up": {
+			features: mlmodel.Features("split"),
+			signals:  []*model.TradeSignal{newSignal(100, 10)},
+			output:   []float64{1, 0, 0},
+		},
+		"split-down": {
+			features: mlmodel.Features("split"),
+			signals:  []*model.TradeSignal{newSignal(100, -10)},
+			output:   []float64{0, 0, 1},
+		},
+		"returns": {
+			features: mlmodel.Features("returns"),
+			signals:  []*model.TradeSignal{newSignal(100, 0), newSignal(110, 0)},
+			output:   []float64{10},
+		},
+		"returns-not-filled": {
+			features: mlmodel.Features("returns"),
+			signals:  []*model.TradeSignal{newSignal(100, 0)},
+			output:   []float64{0},
+		},
+		"order-book": {
+			features: mlmodel.Features("book_imbalance", "book_pressure", "book_spread", "microprice", "book_depth"),
+			signals: []*model.TradeSignal{func() *model.TradeSignal {
+				signal := newSignal(100, 0)
+				signal.OrderBook = model.OrderBook{
+					Mid:        100,
+					Spread:     0.001,
+					Microprice: 100.02,
+					Imbalance:  0.5,
+					Pressure:   -0.25,
+					Depth:      []model.BookDepth{{Bps: 10, Bid: 3, Ask: 1}},
+				}
+				return signal
+			}()},
+			output: []float64{0.5, -0.25, 0.1, 0.02, 0.5, 0, 0},
+		},
+		"order-book-missing": {
+			features: mlmodel.Features("book_imbalance", "microprice", "book_depth"),
+			signals:  []*model.TradeSignal{newSignal(100, 0)},
+			output:   []float64{0, 0, 0, 0, 0},
+		},
+		"normalize": {
+			features: []mlmodel.Feature{{Name: "price", Scale: Normalize}},
+			signals:  []*model.TradeSignal{newSignal(100, 0), newSignal(200, 0), newSignal(150, 0)},
+			output:   []float64{0.5},
+		},
+		"standardize": {
+			features: []mlmodel.Feature{{Name: "price", Scale: Standardize}},
+			signals:  []*model.TradeSignal{newSignal(100, 0), newSignal(200, 0), newSignal(150, 0)},
+			output:   []float64{0},
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			p, err := NewPipeline(mlmodel.Stats{Gap: 0.05}, tt.features...)
+			assert.NoError(t, err)
+			var vv []float64
+			for _, signal := range tt.signals {
+				vv = p.Extract(signal)
+			}
+			assert.Equal(t, len(tt.output), len(vv))
+			for i, v := range tt.output {
+				assert.InDelta(t, v, vv[i], 0.0001)
+			}
+		})
+	}
+}
+
+func TestRegister(t *testing.T) {
+	err := Register("test-feature", stateless(1, func(trade *model.TradeSignal) []float64 {
+		return []float64{1}
+	}))
+	assert.NoError(t, err)
+	assert.Contains(t, Names(), "test-feature")
+
+	err = Register("test-feature", stateless(1, func(trade *model.TradeSignal) []float64 {
+		return []float64{2}
+	}))
+	assert.Error(t, err)
+
+	extractor, err := New("test-feature", mlmodel.Stats{})
+	assert.NoError(t, err)
+	assert.Equal(t, []float64{1}, extractor.Extract(newSignal(100, 0)))
+}
@@ -0,0 +1,145 @@
+package feature
+
+import (
+	"fmt"
+	"math"
+
+	mlmodel "github.com/drakos74/free-coin/internal/algo/processor/ml/model"
+	"github.com/drakos74/free-coin/internal/model"
+)
+
+const (
+	// NoScale leaves the feature values as they are
+	NoScale = ""
+	// Normalize scales the feature values to [0,1] based on the min and max values seen so far
+	Normalize = "norm"
+	// Standardize scales the feature values based on the mean and standard deviation seen so far
+	Standardize = "std"
+)
+
+var (
+	// DefaultIn is the default input feature set for a segment
+	DefaultIn = mlmodel.Features("trend", "price")
+	// DefaultOut is the default output feature set for a segment
+	DefaultOut = mlmodel.Features("trend", "price")
+)
+
+// Pipeline is an ordered set of feature extractors, that produces the combined feature vector.
+// It keeps its own state for the extractors and the scaling, so it should be created per key.
+type Pipeline struct {
+	features   []mlmodel.Feature
+	extractors []Extractor
+	scalers    [][]*scaler
+	dim        int
+}
+
+// NewPipeline creates a new pipeline for the given features.
+func NewPipeline(stats mlmodel.Stats, features ...mlmodel.Feature) (*Pipeline, error) {
+	if len(features) == 0 {
+		return nil, fmt.Errorf("no features given")
+	}
+	p := &Pipeline{
+		features:   features,
+		extractors: make([]Extractor, len(features)),
+		scalers:    make([][]*scaler, len(features)),
+	}
+	for i, f := range features {
+		extractor, err := New(f.Name, stats)
+		if err != nil {
+			return nil, fmt.Errorf("could not create pipeline: %w", err)
+		}
+		switch f.Scale {
+		case NoScale, Normalize, Standardize:
+		default:
+			return nil, fmt.Errorf("unknown scale '%s' for feature '%s'", f.Scale, f.Name)
+		}
+		p.extractors[i] = extractor
+		p.scalers[i] = make([]*scaler, extractor.Dim())
+		for j := 0; j < extractor.Dim(); j++ {
+			p.scalers[i][j] = newScaler(f.Scale)
+		}
+		p.dim += extractor.Dim()
+	}
+	return p, nil
+}
+
+// Dim returns the dimension of the combined feature vector.
+func (p *Pipeline) Dim() int {
+	return p.dim
+}
+
+// Features returns the features of the pipeline.
+func (p *Pipeline) Features() []mlmodel.Feature {
+	return p.features
+}
+
+// Names returns the name of each dimension of the combined feature vector.
+// Features with more than one dimension are suffixed with the dimension index.
+func (p *Pipeline) Names() []string {
+	names := make([]string, 0, p.dim)
+	for i, extractor := range p.extractors {
+		if extractor.Dim() == 1 {
+			names = append(names, p.features[i].Name)
+			continue
+		}
+		for j := 0; j < extractor.Dim(); j++ {
+			names = append(names, fmt.Sprintf("%s_%d", p.features[i].Name, j))
+		}
+	}
+	return names
+}
+
+// Extract extracts the combined feature vector from the trade signal.
+func (p *Pipeline) Extract(trade *model.TradeSignal) []float64 {
+	vv := make([]float64, 0, p.dim)
+	for i, extractor := range p.extractors {
+		for j, v := range extractor.Extract(trade) {
+			vv = append(vv, p.scalers[i][j].scale(v))
+		}
+	}
+	return vv
+}
+
+// scaler keeps the running stats for scaling the values of a single feature dimension.
+type scaler struct {
+	method   string
+	count    int
+	mean     float64
+	dSquared float64
+	min, max float64
+}
+
+func newScaler(method string) *scaler {
+	return &scaler{
+		method: method,
+		min:    math.MaxFloat64,
+		max:    -1 * math.MaxFloat64,
+	}
+}
+
+func (s *scaler) scale(v float64) float64 {
+	if s.method == NoScale {
+		return v
+	}
+	s.count++
+	diff := (v - s.mean) / float64(s.count)
+	mean := s.mean + diff
+	s.dSquared += (v - mean) * (v - s.mean)
+	s.mean = mean
+	s.min = math.Min(s.min, v)
+	s.max = math.Max(s.max, v)
+	switch s.method {
+	case Normalize:
+		if s.max == s.min {
+			return 0
+		}
+		return (v - s.min) / (s.max - s.min)
+	case Standardize:
+		std := math.Sqrt(s.dSquared / float64(s.count))
+		if std == 0 {
+			return 0
+		}
+		return (v - s.mean) / std
+	}
+	return v
+}
@@ -8,6 +8,7 @@ import (
 
 	"github.com/drakos74/free-coin/client"
 	mlmodel "github.com/drakos74/free-coin/internal/algo/processor/ml/model"
+	"github.com/drakos74/free-coin/internal/algo/processor/ml/net"
 	"github.com/drakos74/free-coin/internal/emoji"
 	"github.com/drakos74/free-coin/internal/math"
 	"github.com/drakos74/free-coin/internal/math/ml"
@@ -36,6 +37,15 @@ func formatDetail(detail mlmodel.Detail) string {
 	return fmt.Sprintf("%s (%s)", detail.Type, detail.Hash)
 }
 
+func formatDrift(t time.Time, key model.Key, d net.Drift) string {
+	msg := fmt.Sprintf("%s %s %s drift detected (loss %.4f) restarted:%v warm:%v",
+		formatTime(t), key.ToString(), formatDetail(d.Detail), d.Mean, emoji.MapToValid(d.Restarted), emoji.MapToValid(d.Warm))
+	if d.Err != nil {
+		return fmt.Sprintf("%s\n%s", msg, d.Err.Error())
+	}
+	return msg
+}
+
 func formatRecentData(dd [][]float64) string {
 	one := make([]string, len(dd))
 	two := make([]string, len(dd))
@@ -62,7 +62,7 @@ func (p Performance) Value(lazy bool) float64 {
 // Processor is the position processor main routine.
 func Processor(index api.Index, shard storage.Shard, strategy *processor.Strategy) func(u api.User, e api.Exchange) api.Processor {
 	config := strategy.Config()
-	col, err := NewCollector(shard, config, Trend, Trend)
+	col, err := NewCollector(shard, config)
 	// make sure we don't break the pipeline
 	if err != nil {
 		log.Error().Err(err).Str("processor", Name).Msg("could not init processor")
@@ -71,7 +71,7 @@ func Processor(index api.Index, shard storage.Shard, strategy *processor.Strateg
 		}
 	}
 
-	var networkConstructor = net.BaseNetworkConstructor(8, 3)
+	var networkConstructor = net.BaseNetworkConstructor(col.Dim)
 	networks := make(map[model.Key]*net.BaseNetwork)
 
 	tracker := make(map[model.Key]*Tracker)
@@ -81,10 +81,31 @@ func Processor(index api.Index, shard storage.Shard, strategy *processor.Strateg
 		go trackUserActions(index, u, strategy, tracker)
 		u.Send(index, api.NewMessage(fmt.Sprintf("%s starting processor ... %s", Name, formatConfig(config))), nil)
 
+		// apply the config reloads to the collector right away ,
+		// and to the networks within the vector processing routine
+		reloads := make(chan reload)
+		strategy.OnReload(func(diff mlmodel.Diff, config mlmodel.Config) {
+			if err := col.Reload(diff, config); err != nil {
+				log.Error().Err(err).Str("processor", Name).Msg("could not reload collector")
+			}
+			reloads <- reload{diff: diff, config: config}
+		})
+
 		numEvents := 0
 		// process the collector vectors for sophisticated analysis
 		go func(col *Collector) {
-			for vv := range col.vectors {
+			for {
+				var vv mlmodel.Vector
+				select {
+				case r := <-reloads:
+					r.apply(networks, tracker, networkConstructor)
+					continue
+				case v, ok := <-col.vectors:
+					if !ok {
+						return
+					}
+					vv = v
+				}
 				coin := string(vv.Meta.Key.Coin)
 				duration := vv.Meta.Key.Duration.String()
 				metrics.Observer.IncrementEvents(coin, duration, "collector", Name)
@@ -96,9 +117,15 @@ func Processor(index api.Index, shard storage.Shard, strategy *processor.Strateg
 				p := vv.NewIn[len(vv.NewIn)-1]
 				for key, segments := range configSegments {
 
-					// process only if we have it enabled
+					// process only if we have it enabled ,
+					// the routine must keep running , as it also applies the config reloads
 					if !strategy.IsEnabledML(key) {
-						return
+						continue
+					}
+
+					// skip the segments that are not meant for the current market regime
+					if !model.AnyRegime(vv.Meta.Regime, segments.Stats.Regimes...) {
+						continue
 					}
 
 					if _, ok := tracker[key]; !ok {
@@ -164,6 +191,24 @@ func Processor(index api.Index, shard storage.Shard, strategy *processor.Strateg
 						}
 						network := networks[key]
 						out, done, err := network.Push(key, vv)
+						// disable the signal output of the drifting networks
+						for _, d := range network.Drifts() {
+							strategy.EnableDetail(key, d.Detail, false)
+							u.Send(index, api.NewMessage(formatDrift(t, key, d)), nil)
+						}
+						for detail := range out {
+							if strategy.IsEnabledDetail(key, detail) {
+								continue
+							}
+							if network.Stable(detail) {
+								// the network has recovered , enable it again
+								strategy.EnableDetail(key, detail, true)
+								u.Send(index, api.NewMessage(fmt.Sprintf("%s %s %s recovered from drift",
+									formatTime(t), key.ToString(), formatDetail(detail))), nil)
+								continue
+							}
+							delete(out, detail)
+						}
 						if hasTrigger(out) {
 							u.Send(index,
 								api.NewMessage(formatOutPredictions(t, key, p, out, tracker[key].Performance)).
@@ -215,3 +260,55 @@ func hasTrigger(out map[mlmodel.Detail][][]float64) bool {
 	}
 	return false
 }
+
+// reload is a config reload to be applied to the networks.
+type reload struct {
+	diff   mlmodel.Diff
+	config mlmodel.Config
+}
+
+// apply drops the networks of the removed segments and re-creates the ones that changed ,
+// taking over the networks with an unchanged config , as long as the data shape is the same.
+// The networks for the new segments will be created on the first vector.
+func (r reload) apply(networks map[model.Key]*net.BaseNetwork, tracker map[model.Key]*Tracker,
+	constructor func(key model.Key, segments mlmodel.Segments) *net.BaseNetwork) {
+	for _, k := range r.diff.Removed {
+		delete(networks, k)
+		delete(tracker, k)
+	}
+	for k, sd := range r.diff.Changed {
+		if !sd.Reset() {
+			continue
+		}
+		if sd.Shape {
+			// the networks cannot be re-used with a different data shape
+			delete(networks, k)
+			delete(tracker, k)
+			continue
+		}
+		old, ok := networks[k]
+		if !ok {
+			continue
+		}
+		network := constructor(k, r.config.Segments[k])
+		inherited := network.Inherit(old)
+		networks[k] = network
+		// keep the performance only for the inherited networks
+		if t, ok := tracker[k]; ok {
+			keep := make(map[mlmodel.Detail]bool)
+			for _, detail := range inherited {
+				keep[detail] = true
+			}
+			for detail := range t.Prediction {
+				if !keep[detail] {
+					delete(t.Prediction, detail)
+					delete(t.Performance, detail)
+				}
+			}
+		}
+		log.Info().
+			Str("key", k.ToString()).
+			Int("inherited", len(inherited)).
+			Msg("reloaded network")
+	}
+}
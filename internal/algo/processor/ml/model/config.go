@@ -11,6 +11,7 @@ import (
 	"github.com/drakos74/free-coin/client/local"
 	"github.com/drakos74/free-coin/internal/emoji"
 	coin_math "github.com/drakos74/free-coin/internal/math"
+	"github.com/drakos74/free-coin/internal/math/drift"
 	"github.com/drakos74/free-coin/internal/model"
 	"github.com/drakos74/free-coin/internal/storage/file/json"
 	"github.com/drakos74/free-coin/internal/trader"
@@ -87,6 +88,8 @@ func (c *Config) SetPrecisionThreshold(coin model.Coin, network string, precisio
 // depends strictly on the stats output
 // MaxEpochs defines the maximmum epochs for the training process
 // LearningRate defines the learning rate for the model
+// In and Out are the dimensions of the input and output feature vectors
+// they are derived from the feature pipeline of the segment and are not meant to be configured
 type Model struct {
 	Detail       Detail  `json:"type"`
 	BufferSize   int     `json:"buffer"`
@@ -97,6 +100,8 @@ type Model struct {
 	MaxEpochs    int     `json:"max_epochs"`
 	LearningRate float64 `json:"learning_rate"`
 	Multi        bool    `json:"multi"`
+	In           int     `json:"-"`
+	Out          int     `json:"-"`
 }
 
 // NewConfig defines a numeric way to initialise the config
@@ -223,11 +228,15 @@ type Buffer struct {
 	History  bool
 }
 
+// Position defines the position settings of the trader.
+// MaxPositions and MaxExposure are the risk limits for opening new positions , zero means no limit.
 type Position struct {
 	OpenValue      float64
 	StopLoss       float64
 	TakeProfit     float64
 	TrackingConfig []*model.TrackingConfig
+	MaxPositions   int
+	MaxExposure    float64
 }
 
 // GetSegments returns the segments that match the given parameters.
@@ -242,12 +251,36 @@ func (c *Config) GetSegments(coin model.Coin, duration time.Duration) map[model.
 }
 
 // Stats defines the statistical properties of the set
+// In defines the features that make up the input vector of the models
+// Out defines the features that make up the output vector of the models
+// Regimes defines the market regimes the segment is active for , empty means all of them
+// Drift defines the drift detection config for the models
 type Stats struct {
-	LookBack  int     `json:"prev"`
-	LookAhead int     `json:"next"`
-	Gap       float64 `json:"gap"`
-	Live      bool    `json:"live"`
-	Model     []Model `json:"model"`
+	LookBack  int            `json:"prev"`
+	LookAhead int            `json:"next"`
+	Gap       float64        `json:"gap"`
+	Live      bool           `json:"live"`
+	Model     []Model        `json:"model"`
+	In        []Feature      `json:"in"`
+	Out       []Feature      `json:"out"`
+	Regimes   []model.Regime `json:"regimes"`
+	Drift     drift.Config   `json:"drift"`
+}
+
+// Feature defines a named feature from the feature registry and the scaling to apply on it.
+// Scale can be left empty , or be one of 'norm' or 'std'
+type Feature struct {
+	Name  string `json:"name"`
+	Scale string `json:"scale"`
+}
+
+// Features creates a list of features with no scaling from the given names.
+func Features(names ...string) []Feature {
+	ff := make([]Feature, len(names))
+	for i, name := range names {
+		ff[i] = Feature{Name: name}
+	}
+	return ff
 }
 
 func (s Stats) Format() string {
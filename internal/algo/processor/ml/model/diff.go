@@ -0,0 +1,147 @@
+package model
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/drakos74/free-coin/internal/model"
+)
+
+// Diff describes the changes between two segment configs.
+// Added and Removed are the segments that appear only in the new or the old config respectively.
+// Changed are the segments that exist in both , but with a different config.
+type Diff struct {
+	Added   []model.Key
+	Removed []model.Key
+	Changed map[model.Key]SegmentDiff
+}
+
+// SegmentDiff describes the changes of a single segment.
+// Shape is set if the data layout changed e.g. look back , look ahead or features ,
+// in which case the collected data for the segment cannot be re-used.
+// Settings is set if any other property of the segment changed e.g. gap , live flags or the trader config.
+// Models lists the models that were added or removed , a model with a changed config appears in both.
+type SegmentDiff struct {
+	Shape    bool
+	Settings bool
+	Added    []Detail
+	Removed  []Detail
+}
+
+// Empty returns true if there are no changes.
+func (d Diff) Empty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Changed) == 0
+}
+
+// Reset returns true if the segment needs new networks and data in order to apply the changes.
+func (sd SegmentDiff) Reset() bool {
+	return sd.Shape || len(sd.Added) > 0 || len(sd.Removed) > 0
+}
+
+// DiffConfig compares the new segment config against the current one.
+func DiffConfig(current, next SegmentConfig) Diff {
+	diff := Diff{
+		Added:   make([]model.Key, 0),
+		Removed: make([]model.Key, 0),
+		Changed: make(map[model.Key]SegmentDiff),
+	}
+	for k, segment := range next {
+		old, ok := current[k]
+		if !ok {
+			diff.Added = append(diff.Added, k)
+			continue
+		}
+		if sd, changed := diffSegment(old, segment); changed {
+			diff.Changed[k] = sd
+		}
+	}
+	for k := range current {
+		if _, ok := next[k]; !ok {
+			diff.Removed = append(diff.Removed, k)
+		}
+	}
+	sortKeys(diff.Added)
+	sortKeys(diff.Removed)
+	return diff
+}
+
+func diffSegment(current, next Segments) (SegmentDiff, bool) {
+	sd := SegmentDiff{
+		Shape: current.Stats.LookBack != next.Stats.LookBack ||
+			current.Stats.LookAhead != next.Stats.LookAhead ||
+			!reflect.DeepEqual(current.Stats.In, next.Stats.In) ||
+			!reflect.DeepEqual(current.Stats.Out, next.Stats.Out),
+		Settings: current.Stats.Gap != next.Stats.Gap ||
+			current.Stats.Live != next.Stats.Live ||
+			!reflect.DeepEqual(current.Stats.Regimes, next.Stats.Regimes) ||
+			current.Stats.Drift != next.Stats.Drift ||
+			current.Trader != next.Trader,
+		Added:   make([]Detail, 0),
+		Removed: make([]Detail, 0),
+	}
+	models := make(map[string]Model)
+	for _, m := range current.Stats.Model {
+		models[m.Detail.ToString()] = m
+	}
+	for _, m := range next.Stats.Model {
+		old, ok := models[m.Detail.ToString()]
+		if !ok || !reflect.DeepEqual(old, m) {
+			sd.Added = append(sd.Added, m.Detail)
+		}
+		if ok && !reflect.DeepEqual(old, m) {
+			sd.Removed = append(sd.Removed, old.Detail)
+		}
+		delete(models, m.Detail.ToString())
+	}
+	for _, m := range current.Stats.Model {
+		if _, ok := models[m.Detail.ToString()]; ok {
+			sd.Removed = append(sd.Removed, m.Detail)
+		}
+	}
+	return sd, sd.Reset() || sd.Settings
+}
+
+func sortKeys(keys []model.Key) {
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].ToString() < keys[j].ToString()
+	})
+}
+
+// String formats the diff for the user.
+func (d Diff) String() string {
+	if d.Empty() {
+		return "no changes"
+	}
+	lines := make([]string, 0)
+	for _, k := range d.Added {
+		lines = append(lines, fmt.Sprintf("+ %s", k.ToString()))
+	}
+	for _, k := range d.Removed {
+		lines = append(lines, fmt.Sprintf("- %s", k.ToString()))
+	}
+	changed := make([]model.Key, 0, len(d.Changed))
+	for k := range d.Changed {
+		changed = append(changed, k)
+	}
+	sortKeys(changed)
+	for _, k := range changed {
+		sd := d.Changed[k]
+		line := fmt.Sprintf("~ %s", k.ToString())
+		if sd.Shape {
+			line += " [shape]"
+		}
+		if sd.Settings {
+			line += " [settings]"
+		}
+		for _, detail := range sd.Removed {
+			line += fmt.Sprintf(" -%s", detail.ToString())
+		}
+		for _, detail := range sd.Added {
+			line += fmt.Sprintf(" +%s", detail.ToString())
+		}
+		lines = append(lines, line)
+	}
+	return strings.Join(lines, "\n")
+}
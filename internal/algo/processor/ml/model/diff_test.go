@@ -0,0 +1,110 @@
+package model
+
+import (
+	"testing"
+	"time"
+
+	"github.com/drakos74/free-coin/internal/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiffConfig(t *testing.T) {
+
+	btc := model.TempKey(model.BTC, 15*time.Minute)
+	eth := model.TempKey(model.ETH, 15*time.Minute)
+	sol := model.TempKey(model.SOL, 15*time.Minute)
+
+	segment := func(lookBack int, models ...Model) Segments {
+		return Segments{
+			Stats: Stats{
+				LookBack:  lookBack,
+				LookAhead: 1,
+				Gap:       0.05,
+				Model:     models,
+			},
+		}
+	}
+
+	gru := Model{Detail: Detail{Type: "net.GRU", Hash: "a"}, Size: []int{10}}
+	hmm := Model{Detail: Detail{Type: "net.HMM", Hash: "b"}, Size: []int{5}}
+
+	current := SegmentConfig{
+		btc: segment(5, gru, hmm),
+		eth: segment(5, gru),
+	}
+
+	type test struct {
+		next    SegmentConfig
+		added   []model.Key
+		removed []model.Key
+		changed map[model.Key]SegmentDiff
+	}
+
+	tests := map[string]test{
+		"no-changes": {
+			next: SegmentConfig{
+				btc: segment(5, gru, hmm),
+				eth: segment(5, gru),
+			},
+		},
+		"add-and-remove-segments": {
+			next: SegmentConfig{
+				btc: segment(5, gru, hmm),
+				sol: segment(5, gru),
+			},
+			added:   []model.Key{sol},
+			removed: []model.Key{eth},
+		},
+		"remove-model": {
+			next: SegmentConfig{
+				btc: segment(5, gru),
+				eth: segment(5, gru),
+			},
+			changed: map[model.Key]SegmentDiff{
+				btc: {Added: []Detail{}, Removed: []Detail{hmm.Detail}},
+			},
+		},
+		"change-model": {
+			next: SegmentConfig{
+				btc: segment(5, gru, hmm),
+				eth: segment(5, Model{Detail: gru.Detail, Size: []int{20}}),
+			},
+			changed: map[model.Key]SegmentDiff{
+				eth: {Added: []Detail{gru.Detail}, Removed: []Detail{gru.Detail}},
+			},
+		},
+		"change-shape-and-settings": {
+			next: func() SegmentConfig {
+				s := segment(5, gru)
+				s.Stats.Live = true
+				return SegmentConfig{
+					btc: segment(10, gru, hmm),
+					eth: s,
+				}
+			}(),
+			changed: map[model.Key]SegmentDiff{
+				btc: {Shape: true, Added: []Detail{}, Removed: []Detail{}},
+				eth: {Settings: true, Added: []Detail{}, Removed: []Detail{}},
+			},
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			diff := DiffConfig(current, tt.next)
+			if tt.added == nil {
+				tt.added = []model.Key{}
+			}
+			if tt.removed == nil {
+				tt.removed = []model.Key{}
+			}
+			if tt.changed == nil {
+				tt.changed = map[model.Key]SegmentDiff{}
+			}
+			assert.Equal(t, tt.added, diff.Added)
+			assert.Equal(t, tt.removed, diff.Removed)
+			assert.Equal(t, tt.changed, diff.Changed)
+			assert.Equal(t, len(tt.added)+len(tt.removed)+len(tt.changed) == 0, diff.Empty())
+		})
+	}
+}
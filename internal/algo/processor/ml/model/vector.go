@@ -8,9 +8,10 @@ import (
 )
 
 type Meta struct {
-	Key    model.Key  `json:"key"`
-	Tick   model.Tick `json:"tick"`
-	Active bool       `json:"active"`
+	Key    model.Key    `json:"key"`
+	Tick   model.Tick   `json:"tick"`
+	Regime model.Regime `json:"regime"`
+	Active bool         `json:"active"`
 }
 
 type Vector struct {
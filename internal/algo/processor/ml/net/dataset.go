@@ -8,6 +8,7 @@ import (
 
 	"github.com/drakos74/free-coin/internal/emoji"
 
+	"github.com/drakos74/free-coin/internal/algo/processor/ml/dataset"
 	mlmodel "github.com/drakos74/free-coin/internal/algo/processor/ml/model"
 	"github.com/drakos74/free-coin/internal/model"
 )
@@ -65,6 +66,25 @@ func (ds *DataSet) Push(k model.Key, vv mlmodel.Vector) ([][]float64, bool, erro
 	return input, filled, nil
 }
 
+// LoadDataSet replays the vectors of an exported dataset into a new data set.
+// The given function is called for every input tensor that is ready for training.
+func LoadDataSet(d *dataset.Dataset, in, out int, fn func(set DataSet, input [][]float64) error) (DataSet, error) {
+	set := NewDataSet(in, out)
+	set.Key = d.Meta.Key
+	for _, vv := range d.Vectors() {
+		input, filled, err := set.Push(d.Meta.Key, vv)
+		if err != nil {
+			return set, fmt.Errorf("could not load vector at %v: %w", vv.Meta.Tick.Time, err)
+		}
+		if filled && fn != nil {
+			if err := fn(set, input); err != nil {
+				return set, err
+			}
+		}
+	}
+	return set, nil
+}
+
 func toSeries(index int, x, y [][]float64) []float64 {
 	series := make([]float64, len(x))
 	for i := range x {
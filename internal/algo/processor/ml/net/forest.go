@@ -130,12 +130,20 @@ func toFeatureFile(parentPath string, description string, vectors [][]float64, p
 	return fn, nil
 }
 
+// Load loads the training samples , the tree is trained again from them on the next sample.
 func (r *RandomForest) Load(key model.Key, detail mlmodel.Detail) error {
-	log.Warn().Msg("TODO load for forest network")
+	samples := make([][]float64, 0)
+	if err := loadState(key, detail, &samples); err != nil {
+		return err
+	}
+	r.buffer = buffer.NewMultiBuffer(r.cfg.BufferSize)
+	for _, sample := range samples {
+		r.buffer.Push(sample...)
+	}
 	return nil
 }
 
+// Save saves the training samples , as the tree is trained from scratch on every sample.
 func (r *RandomForest) Save(key model.Key, detail mlmodel.Detail) error {
-	log.Warn().Msg("TODO save for forest network")
-	return nil
+	return saveState(key, detail, r.buffer.Get())
 }
@@ -68,10 +68,12 @@ func (m *MultiNetwork) Config() mlmodel.Model {
 	return mlmodel.Model{}
 }
 
+// Load is not supported , the multi network is restarted from its fresh networks.
 func (m *MultiNetwork) Load(key model.Key, detail mlmodel.Detail) error {
-	return nil
+	return ErrNotPersisted
 }
 
+// Save is not supported , the multi network is restarted from its fresh networks.
 func (m *MultiNetwork) Save(key model.Key, detail mlmodel.Detail) error {
-	return nil
+	return ErrNotPersisted
 }
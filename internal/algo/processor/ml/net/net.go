@@ -1,16 +1,23 @@
 package net
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io/ioutil"
 	"math"
+	"os"
+	"path/filepath"
 	"reflect"
 	"strings"
 
 	mlmodel "github.com/drakos74/free-coin/internal/algo/processor/ml/model"
 	"github.com/drakos74/free-coin/internal/buffer"
 	coinmath "github.com/drakos74/free-coin/internal/math"
+	"github.com/drakos74/free-coin/internal/math/drift"
 	"github.com/drakos74/free-coin/internal/math/ml"
 	"github.com/drakos74/free-coin/internal/model"
+	"github.com/drakos74/free-coin/internal/storage"
 	"github.com/drakos74/go-ex-machina/xmath"
 	"github.com/rs/zerolog/log"
 )
@@ -29,7 +36,6 @@ func NewNetwork(s string, cfg mlmodel.Model) Network {
 		return NewNeuralNet(cfg)
 	}
 	panic(fmt.Sprintf("unknown network detail : %v", s))
-	return nil
 }
 
 func quantify(y float64, gap float64) float64 {
@@ -85,21 +91,58 @@ func NewStats(s int) Stats {
 
 // BaseNetwork represents the basic network flow logic
 type BaseNetwork struct {
+	key    model.Key
 	set    DataSet
 	net    map[mlmodel.Detail]Network
+	gen    map[mlmodel.Detail]ConstructNetwork
 	config map[mlmodel.Detail]mlmodel.Model
 	track  map[mlmodel.Detail]*Tracker
+	drift  *driftMonitor
+}
+
+// ErrNotPersisted is returned by the networks that cannot save their state ,
+// these are restarted cold on drift.
+var ErrNotPersisted = errors.New("network state is not persisted")
+
+// Drift describes a drift event for a network.
+// Mean is the mean loss of the network before the drift.
+// Warm is set if the restarted network was loaded from the last checkpoint ,
+// otherwise it starts over without any state.
+type Drift struct {
+	Detail    mlmodel.Detail
+	Mean      float64
+	Restarted bool
+	Warm      bool
+	Err       error
 }
 
-func BaseNetworkConstructor(in, out int) func(key model.Key, segments mlmodel.Segments) *BaseNetwork {
+// driftMonitor tracks the loss of the networks for drift.
+type driftMonitor struct {
+	config    drift.Config
+	detectors map[mlmodel.Detail]*drift.PageHinkley
+	quiet     map[mlmodel.Detail]int
+	events    []Drift
+}
+
+// Dimension returns the input and output feature dimensions for the given key.
+type Dimension func(key model.Key) (int, int)
+
+// BaseNetworkConstructor creates the networks for a segment.
+// The tensor sizes are derived from the segment look back and look ahead
+// and the feature dimensions are passed on to the model config.
+func BaseNetworkConstructor(dim Dimension) func(key model.Key, segments mlmodel.Segments) *BaseNetwork {
 	return func(key model.Key, segments mlmodel.Segments) *BaseNetwork {
+		in, out := dim(key)
 		gen := make([]ConstructNetwork, len(segments.Stats.Model))
 		for i, segment := range segments.Stats.Model {
+			segment.In = in
+			segment.Out = out
 			gen[i] = func() (Network, mlmodel.Model) {
 				return NewNetwork(segment.Detail.Type, segment), segment
 			}
 		}
-		return NewBaseNetwork(key, in, out, gen...)
+		return NewBaseNetwork(key, segments.Stats.LookBack, segments.Stats.LookAhead, gen...).
+			WithDrift(segments.Stats.Drift.OrDefault())
 	}
 }
 
@@ -107,8 +150,10 @@ func NewBaseNetwork(key model.Key, in, out int, gen ...ConstructNetwork) *BaseNe
 
 	trackers := make(map[mlmodel.Detail]*Tracker)
 	networks := make(map[mlmodel.Detail]Network)
+	generators := make(map[mlmodel.Detail]ConstructNetwork)
 	config := make(map[mlmodel.Detail]mlmodel.Model)
 
+	multiGen := make([]ConstructNetwork, 0)
 	multi := make([]Network, 0)
 	multiHash := make([]string, 0)
 	multiType := make([]string, 0)
@@ -116,6 +161,7 @@ func NewBaseNetwork(key model.Key, in, out int, gen ...ConstructNetwork) *BaseNe
 		nw, cfg := net()
 		if cfg.Multi {
 			nvw, _ := net()
+			multiGen = append(multiGen, net)
 			multi = append(multi, nvw)
 			multiType = append(multiType, cfg.Detail.Type)
 			multiHash = append(multiHash, cfg.Detail.Hash)
@@ -138,6 +184,7 @@ func NewBaseNetwork(key model.Key, in, out int, gen ...ConstructNetwork) *BaseNe
 		//	Msg("loaded network")
 
 		networks[detail] = nw
+		generators[detail] = net
 		config[detail] = cfg
 		trackers[detail] = NewTracker(12)
 	}
@@ -148,17 +195,169 @@ func NewBaseNetwork(key model.Key, in, out int, gen ...ConstructNetwork) *BaseNe
 		Index: len(gen),
 	}
 	networks[multiDetail] = NewMultiNetwork(multi...)
+	generators[multiDetail] = func() (Network, mlmodel.Model) {
+		nn := make([]Network, len(multiGen))
+		for i, net := range multiGen {
+			nn[i], _ = net()
+		}
+		return NewMultiNetwork(nn...), mlmodel.Model{}
+	}
 	config[multiDetail] = mlmodel.Model{}
 	trackers[multiDetail] = NewTracker(12)
 
 	return &BaseNetwork{
+		key:    key,
 		set:    NewDataSet(in, out),
 		config: config,
 		net:    networks,
+		gen:    generators,
 		track:  trackers,
 	}
 }
 
+// WithDrift enables the drift monitoring for the networks , unless the config is disabled.
+func (b *BaseNetwork) WithDrift(config drift.Config) *BaseNetwork {
+	if config.Disabled {
+		b.drift = nil
+		return b
+	}
+	detectors := make(map[mlmodel.Detail]*drift.PageHinkley)
+	for detail := range b.net {
+		detectors[detail] = drift.NewPageHinkley(config)
+	}
+	b.drift = &driftMonitor{
+		config:    config,
+		detectors: detectors,
+		quiet:     make(map[mlmodel.Detail]int),
+		events:    make([]Drift, 0),
+	}
+	return b
+}
+
+// Inherit takes over the networks of the old base network that have the same config ,
+// so that a config reload does not throw away the state of the unchanged models.
+// The inherited networks keep their detail , and the collected data is kept as well ,
+// so both networks must have the same data shape.
+// It returns the details of the inherited networks.
+func (b *BaseNetwork) Inherit(old *BaseNetwork) []mlmodel.Detail {
+	inherited := make([]mlmodel.Detail, 0)
+	if old == nil {
+		return inherited
+	}
+	b.set = old.set
+	details := make([]mlmodel.Detail, 0, len(b.config))
+	for detail := range b.config {
+		details = append(details, detail)
+	}
+	taken := make(map[mlmodel.Detail]bool)
+	for _, detail := range details {
+		cfg := b.config[detail]
+		for oldDetail, oldCfg := range old.config {
+			if taken[oldDetail] || !reflect.DeepEqual(cfg, oldCfg) {
+				continue
+			}
+			// the multi network has no config of its own , so we match it on the detail
+			if cfg.Detail.Type == "" && (detail.Type != oldDetail.Type || detail.Hash != oldDetail.Hash) {
+				continue
+			}
+			taken[oldDetail] = true
+			delete(b.net, detail)
+			delete(b.gen, detail)
+			delete(b.config, detail)
+			delete(b.track, detail)
+			b.net[oldDetail] = old.net[oldDetail]
+			b.gen[oldDetail] = old.gen[oldDetail]
+			b.config[oldDetail] = oldCfg
+			b.track[oldDetail] = old.track[oldDetail]
+			if b.drift != nil {
+				delete(b.drift.detectors, detail)
+				if old.drift != nil && old.drift.detectors[oldDetail] != nil {
+					b.drift.detectors[oldDetail] = old.drift.detectors[oldDetail]
+					b.drift.quiet[oldDetail] = old.drift.quiet[oldDetail]
+				} else {
+					b.drift.detectors[oldDetail] = drift.NewPageHinkley(b.drift.config)
+				}
+			}
+			inherited = append(inherited, oldDetail)
+			break
+		}
+	}
+	return inherited
+}
+
+// Drifts returns the drift events since the last call.
+func (b *BaseNetwork) Drifts() []Drift {
+	if b.drift == nil {
+		return []Drift{}
+	}
+	events := b.drift.events
+	b.drift.events = make([]Drift, 0)
+	return events
+}
+
+// Stable returns true if the drift detector for the given detail has enough samples since the last drift.
+func (b *BaseNetwork) Stable(detail mlmodel.Detail) bool {
+	if b.drift == nil {
+		return true
+	}
+	if detector, ok := b.drift.detectors[detail]; ok {
+		return detector.Ready()
+	}
+	return true
+}
+
+// trackDrift tracks the loss of the network for drift,
+// it saves the network state at regular intervals without drift
+// and restarts it on drift from the last saved state , or from scratch if the network has no saved state.
+func (b *BaseNetwork) trackDrift(detail mlmodel.Detail, loss float64) {
+	if b.drift == nil {
+		return
+	}
+	detector, ok := b.drift.detectors[detail]
+	if !ok {
+		return
+	}
+	mean := detector.Mean()
+	if !detector.Add(loss) {
+		b.drift.quiet[detail]++
+		if b.drift.config.Checkpoint > 0 && b.drift.quiet[detail]%b.drift.config.Checkpoint == 0 {
+			if err := b.net[detail].Save(b.key, detail); err != nil && !errors.Is(err, ErrNotPersisted) {
+				log.Warn().Err(err).
+					Str("key", b.key.ToString()).
+					Str("detail", detail.ToString()).
+					Msg("could not save network checkpoint")
+			}
+		}
+		return
+	}
+	event := Drift{
+		Detail: detail,
+		Mean:   mean,
+	}
+	b.drift.quiet[detail] = 0
+	if b.drift.config.Restart {
+		// warm restart the network from the last checkpoint , if there is one
+		nw, _ := b.gen[detail]()
+		if err := nw.Load(b.key, detail); err == nil {
+			event.Warm = true
+		} else if !errors.Is(err, ErrNotPersisted) {
+			event.Err = err
+		}
+		b.net[detail] = nw
+		b.track[detail] = NewTracker(12)
+		event.Restarted = true
+	}
+	log.Warn().
+		Err(event.Err).
+		Str("key", b.key.ToString()).
+		Str("detail", detail.ToString()).
+		Float64("loss", loss).
+		Bool("restarted", event.Restarted).
+		Bool("warm", event.Warm).
+		Msg("network drift detected")
+	b.drift.events = append(b.drift.events, event)
+}
+
 // Push receives a vector event and processes it with the provided network as a input-output tensor
 func (b *BaseNetwork) Push(k model.Key, vv mlmodel.Vector) (map[mlmodel.Detail][][]float64, bool, error) {
 	in, ready, _ := b.set.Push(k, vv)
@@ -198,6 +397,7 @@ func (b *BaseNetwork) Push(k model.Key, vv mlmodel.Vector) (map[mlmodel.Detail][
 						if loss < minLoss {
 							minLoss = loss
 						}
+						b.trackDrift(detail, loss)
 						//fmt.Printf("b.track[%v].metrics = %+v\n", detail, b.track[detail].metrics)
 					}
 				}
@@ -276,3 +476,37 @@ func (u Performances) Swap(i, j int) {
 func (u Performances) Less(i, j int) bool {
 	return u[i].Accuracy > u[j].Accuracy
 }
+
+// stateFile returns the file for the saved state of a network.
+func stateFile(key model.Key, detail mlmodel.Detail) string {
+	dir := fmt.Sprintf("%s/net/%s", storage.DefaultDir, key.ToString())
+	return fmt.Sprintf("%s/%s.json", dir, detail.ToString())
+}
+
+// saveState saves the state of a network as json.
+func saveState(key model.Key, detail mlmodel.Detail, state interface{}) error {
+	filename := stateFile(key, detail)
+	if err := os.MkdirAll(filepath.Dir(filename), os.ModePerm); err != nil {
+		return fmt.Errorf("could not create directory for model: %w", err)
+	}
+	b, err := json.Marshal(state)
+	if err != nil {
+		return fmt.Errorf("could not encode model state: %w", err)
+	}
+	if err := ioutil.WriteFile(filename, b, 0644); err != nil {
+		return fmt.Errorf("could not save model state: %w", err)
+	}
+	return nil
+}
+
+// loadState loads the state of a network saved with saveState.
+func loadState(key model.Key, detail mlmodel.Detail, state interface{}) error {
+	b, err := ioutil.ReadFile(stateFile(key, detail))
+	if err != nil {
+		return fmt.Errorf("could not load model state: %w", err)
+	}
+	if err := json.Unmarshal(b, state); err != nil {
+		return fmt.Errorf("could not decode model state: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,153 @@
+package net
+
+import (
+	"testing"
+	"time"
+
+	mlmodel "github.com/drakos74/free-coin/internal/algo/processor/ml/model"
+	"github.com/drakos74/free-coin/internal/math/drift"
+	"github.com/drakos74/free-coin/internal/math/ml"
+	"github.com/drakos74/free-coin/internal/model"
+	"github.com/drakos74/free-coin/internal/storage"
+	"github.com/stretchr/testify/assert"
+)
+
+// level is a network that slowly follows the last output ,
+// so that its loss stays high for a while after a regime shift.
+type level struct {
+	value     float64
+	persisted bool
+}
+
+func (l *level) Train(x [][]float64, y [][]float64) (ml.Metadata, error) {
+	l.value += 0.01 * (last(y)[0] - l.value)
+	return ml.NewMetadata(), nil
+}
+
+func (l *level) Predict(x [][]float64) ([][]float64, ml.Metadata, error) {
+	return [][]float64{{l.value}}, ml.NewMetadata(), nil
+}
+
+func (l *level) Loss(actual, predicted [][]float64) []float64 {
+	return SV(last(actual)).Diff(SV(last(predicted)))
+}
+
+func (l *level) Config() mlmodel.Model {
+	return mlmodel.Model{}
+}
+
+func (l *level) Load(key model.Key, detail mlmodel.Detail) error {
+	if !l.persisted {
+		return ErrNotPersisted
+	}
+	return loadState(key, detail, &l.value)
+}
+
+func (l *level) Save(key model.Key, detail mlmodel.Detail) error {
+	if !l.persisted {
+		return ErrNotPersisted
+	}
+	return saveState(key, detail, l.value)
+}
+
+func TestBaseNetwork_Drift(t *testing.T) {
+
+	type test struct {
+		persisted bool
+		warm      bool
+		disabled  bool
+	}
+
+	tests := map[string]test{
+		"warm-restart": {
+			persisted: true,
+			warm:      true,
+		},
+		"cold-restart": {
+			persisted: false,
+		},
+		"disabled": {
+			persisted: true,
+			disabled:  true,
+		},
+	}
+
+	dir := storage.DefaultDir
+	defer func() {
+		storage.DefaultDir = dir
+	}()
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			storage.DefaultDir = t.TempDir()
+			key := model.Key{
+				Coin:     model.BTC,
+				Duration: time.Minute,
+				Strategy: name,
+			}
+			b := NewBaseNetwork(key, 1, 1, func() (Network, mlmodel.Model) {
+				return &level{persisted: tt.persisted}, mlmodel.Model{Detail: mlmodel.Detail{Hash: name}, Multi: true}
+			}).WithDrift(drift.Config{
+				Delta:      0.05,
+				Threshold:  5,
+				Alpha:      1,
+				MinSamples: 10,
+				Restart:    true,
+				Checkpoint: 10,
+				Disabled:   tt.disabled,
+			})
+
+			var detail mlmodel.Detail
+			for d := range b.net {
+				if _, ok := b.net[d].(*level); ok {
+					detail = d
+				}
+			}
+
+			// a stable regime , followed by a shift that the network cannot follow quickly
+			events := make([]Drift, 0)
+			for i := 0; i < 200 && len(events) == 0; i++ {
+				y := 0.2
+				if i >= 100 {
+					y = 1.0
+				}
+				_, _, err := b.Push(key, mlmodel.Vector{
+					PrevIn:  []float64{y},
+					PrevOut: []float64{y},
+					NewIn:   []float64{y},
+				})
+				assert.NoError(t, err)
+				for _, event := range b.Drifts() {
+					if event.Detail == detail {
+						events = append(events, event)
+					}
+				}
+				if i < 100 {
+					assert.Empty(t, events)
+				}
+			}
+
+			if tt.disabled {
+				// the network is never restarted , and keeps following the new regime
+				assert.Empty(t, events)
+				assert.True(t, b.Stable(detail))
+				return
+			}
+
+			assert.Equal(t, 1, len(events))
+			event := events[0]
+			assert.True(t, event.Restarted)
+			assert.Equal(t, tt.warm, event.Warm)
+			assert.NoError(t, event.Err)
+			assert.False(t, b.Stable(detail))
+
+			// the restarted network has the state of the old regime , or no state at all
+			restarted := b.net[detail].(*level)
+			if tt.warm {
+				assert.True(t, restarted.value > 0 && restarted.value < 0.5)
+			} else {
+				assert.Equal(t, 0.0, restarted.value)
+			}
+		})
+	}
+}
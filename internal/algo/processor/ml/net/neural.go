@@ -9,7 +9,6 @@ import (
 	xml "github.com/drakos74/go-ex-machina/xmachina/ml"
 	"github.com/drakos74/go-ex-machina/xmachina/net"
 	"github.com/drakos74/go-ex-machina/xmachina/net/ff"
-	"github.com/rs/zerolog/log"
 
 	"github.com/drakos74/go-ex-machina/xmath"
 )
@@ -27,7 +26,11 @@ func NewNeuralNet(cfg mlmodel.Model) *NeuralNet {
 
 	initW := xmath.Rand(-1, 1, math.Sqrt)
 	initB := xmath.Rand(-1, 1, math.Sqrt)
-	network := ff.New(7, 3).
+	in, out := 7, 3
+	if cfg.In > 0 && cfg.Out > 0 {
+		in, out = cfg.In, cfg.Out
+	}
+	network := ff.New(in, out).
 		Add(42, net.NewBuilder().
 			WithModule(xml.Base().
 				WithRate(rate).
@@ -46,7 +49,7 @@ func NewNeuralNet(cfg mlmodel.Model) *NeuralNet {
 				WithActivation(xml.TanH)).
 			WithWeights(initW, initB).
 			Factory(net.NewActivationCell)).
-		Add(3, net.NewBuilder().CellFactory(net.NewSoftCell))
+		Add(out, net.NewBuilder().CellFactory(net.NewSoftCell))
 	network.Loss(xml.Pow)
 
 	return &NeuralNet{
@@ -84,12 +87,12 @@ func (n *NeuralNet) Config() mlmodel.Model {
 
 }
 
+// Load is not supported , as the feed forward network does not expose its weights.
 func (n *NeuralNet) Load(key model.Key, detail mlmodel.Detail) error {
-	log.Warn().Msg("TODO load for neural network")
-	return nil
+	return ErrNotPersisted
 }
 
+// Save is not supported , as the feed forward network does not expose its weights.
 func (n *NeuralNet) Save(key model.Key, detail mlmodel.Detail) error {
-	log.Warn().Msg("TODO save for neural network")
-	return nil
+	return ErrNotPersisted
 }
@@ -5,7 +5,6 @@ import (
 	math2 "math"
 
 	"github.com/drakos74/free-coin/internal/model"
-	"github.com/rs/zerolog/log"
 
 	mlmodel "github.com/drakos74/free-coin/internal/algo/processor/ml/model"
 	"github.com/drakos74/free-coin/internal/buffer"
@@ -86,12 +85,20 @@ func (p *Polynomial) Config() mlmodel.Model {
 	return p.cfg
 }
 
+// Load loads the buffered values , which the polynomial is fitted to.
 func (p *Polynomial) Load(key model.Key, detail mlmodel.Detail) error {
-	log.Debug().Msg("nothing to load for polynomial network")
+	values := make([]float64, 0)
+	if err := loadState(key, detail, &values); err != nil {
+		return err
+	}
+	p.buffer = buffer.NewBuffer(p.cfg.BufferSize)
+	for _, v := range values {
+		p.buffer.Push(v)
+	}
 	return nil
 }
 
+// Save saves the buffered values , which the polynomial is fitted to.
 func (p *Polynomial) Save(key model.Key, detail mlmodel.Detail) error {
-	log.Debug().Msg("nothing to save for polynomial network")
-	return nil
+	return saveState(key, detail, p.buffer.GetAsFloats(false))
 }
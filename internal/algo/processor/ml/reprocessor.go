@@ -30,7 +30,9 @@ func ReProcessor() (proc api.Processor, err error) {
 	config := CoinConfig(map[model.Coin]mlmodel.ConfigSegment{
 		model.BTC: func(coin model.Coin) func(cfg mlmodel.SegmentConfig) mlmodel.SegmentConfig {
 			return func(cfg mlmodel.SegmentConfig) mlmodel.SegmentConfig {
-				cfg[ConfigKey(coin, 15)] = defaultConfig(true)
+				segments := defaultConfig(true)
+				segments.Stats.In = mlmodel.Features("trend", "std", "volume", "buy", "sell", "size", "price")
+				cfg[ConfigKey(coin, 15)] = segments
 				return cfg
 			}
 		},
@@ -38,12 +40,12 @@ func ReProcessor() (proc api.Processor, err error) {
 
 	config.Buffer.History = false
 	shard := json_storage.BlobShard("train-ml")
-	col, err := NewCollector(shard, *config, CollectStats, Trend)
+	col, err := NewCollector(shard, *config)
 	if err != nil {
 		return nil, err
 	}
 
-	var networkConstructor = net.BaseNetworkConstructor(8, 3)
+	var networkConstructor = net.BaseNetworkConstructor(col.Dim)
 	networks := make(map[model.Key]*net.BaseNetwork)
 
 	// process the collector vectors for sophisticated analysis
@@ -9,6 +9,7 @@ import (
 
 	"github.com/drakos74/free-coin/internal/api"
 	"github.com/drakos74/free-coin/internal/model"
+	cointime "github.com/drakos74/free-coin/internal/time"
 	"github.com/rs/zerolog/log"
 )
 
@@ -51,13 +52,13 @@ func trackUserActions(index api.Index, user api.User, strategy *processor.Strate
 			txtBuffer.WriteString(fmt.Sprintf("%d\n", len(tracker)))
 			if track, ok := tracker[key]; ok {
 				txtBuffer.WriteString(fmt.Sprintf("%s\n%s",
-					formatOutPredictions(time.Now(), key, 0, track.Prediction, track.Performance),
+					formatOutPredictions(cointime.Now(), key, 0, track.Prediction, track.Performance),
 					formatRecentData(track.Buffer.Get())))
 			} else if key.Coin == model.AllCoins {
 				for k, _ := range strategy.Config().Segments {
 					if track, ok := tracker[k]; ok {
 						txtBuffer.WriteString(fmt.Sprintf("%s\n%s\n",
-							formatOutPredictions(time.Now(), k, 0, track.Prediction, track.Performance),
+							formatOutPredictions(cointime.Now(), k, 0, track.Prediction, track.Performance),
 							formatRecentData(track.Buffer.Get())))
 					} else {
 						txtBuffer.WriteString(fmt.Sprintf("no ds yet for ... %+v\n", k))
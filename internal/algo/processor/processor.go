@@ -82,6 +82,12 @@ func ProcessBufferedWithClose(name string, duration time.Duration, live bool, p
 		signalBuffer.NoLive()
 	}
 	//signalBuffer.WithEcho()
+	return ProcessSignalBuffer(name, signalBuffer, trades, p, shutdown, enrich...)
+}
+
+// ProcessSignalBuffer is a wrapper for a processor logic with a close execution func on the signals of the given buffer ,
+// so that the caller keeps control of the buffer e.g. to remove coins.
+func ProcessSignalBuffer(name string, signalBuffer *SignalBuffer, trades <-chan *model.TradeSignal, p func(trade *model.TradeSignal) error, shutdown func(), enrich ...Enrich) api.Processor {
 
 	// buffered signal processing happens here
 	go func(trades <-chan *model.TradeSignal) {
@@ -0,0 +1,65 @@
+package quality
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/drakos74/free-coin/internal/model"
+)
+
+// Coverage is the data quality report of a stored history range.
+// First and Last are the times of the first and last trade within the range ,
+// a range without any trades is missing.
+type Coverage struct {
+	Path   string
+	From   time.Time
+	To     time.Time
+	First  time.Time
+	Last   time.Time
+	Counts Counts
+}
+
+// NewCoverage checks the trades of the range , without modifying them.
+func NewCoverage(path string, from, to time.Time, trades []model.TradeSignal, config Config) Coverage {
+	checker := NewChecker(config)
+	coverage := Coverage{
+		Path: path,
+		From: from,
+		To:   to,
+	}
+	var coin model.Coin
+	for _, trade := range trades {
+		// the trades are passed by value , so the repairs do not touch the stored ones
+		checker.Check(&trade)
+		coin = trade.Coin
+		t := trade.Tick.Time
+		if coverage.First.IsZero() || t.Before(coverage.First) {
+			coverage.First = t
+		}
+		if t.After(coverage.Last) {
+			coverage.Last = t
+		}
+	}
+	coverage.Counts = checker.Counts(coin)
+	return coverage
+}
+
+// Missing returns true if there are no trades within the range.
+func (c Coverage) Missing() bool {
+	return c.Counts.Trades == 0
+}
+
+// Ratio is the part of the range covered by the trades.
+func (c Coverage) Ratio() float64 {
+	if c.Missing() || !c.To.After(c.From) {
+		return 0
+	}
+	return c.Last.Sub(c.First).Seconds() / c.To.Sub(c.From).Seconds()
+}
+
+func (c Coverage) String() string {
+	if c.Missing() {
+		return fmt.Sprintf("%s missing", c.From.Format(time.RFC3339))
+	}
+	return fmt.Sprintf("%s [%s] %.2f | %s", c.From.Format(time.RFC3339), c.Path, c.Ratio(), c.Counts.String())
+}
@@ -0,0 +1,45 @@
+package quality
+
+import (
+	"github.com/drakos74/free-coin/internal/api"
+	"github.com/drakos74/free-coin/internal/metrics"
+	"github.com/drakos74/free-coin/internal/model"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	Name = "quality"
+)
+
+// Processor checks the trade stream against the data quality rules ,
+// it should go first in the processor chain , so that all the downstream processors get the clean stream.
+// The dropped trades are handed to the skip func , so that the source does not wait for them e.g. the engine Skip.
+func Processor(checker *Checker, skip func(trade *model.TradeSignal)) api.Processor {
+	return func(in <-chan *model.TradeSignal, out chan<- *model.TradeSignal) {
+		log.Info().Str("processor", Name).Msg("started processor")
+		defer func() {
+			log.Info().Str("processor", Name).Msg("closing processor")
+			close(out)
+		}()
+		for trade := range in {
+			coin := string(trade.Coin)
+			metrics.Observer.IncrementTrades(coin, Name, "source")
+			result := checker.Check(trade)
+			for _, anomaly := range result.Anomalies {
+				metrics.Observer.IncrementTrades(coin, Name, string(anomaly))
+			}
+			if result.Drop {
+				metrics.Observer.IncrementTrades(coin, Name, "drop")
+				log.Debug().
+					Str("coin", coin).
+					Time("time", trade.Tick.Time).
+					Float64("price", trade.Tick.Price).
+					Interface("anomalies", result.Anomalies).
+					Msg("dropped trade")
+				skip(trade)
+				continue
+			}
+			out <- trade
+		}
+	}
+}
@@ -0,0 +1,281 @@
+package quality
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/drakos74/free-coin/internal/model"
+)
+
+// Anomaly is a data quality issue of the trade stream.
+type Anomaly string
+
+const (
+	// Duplicate is a trade received more than once e.g. a repeated websocket message.
+	Duplicate Anomaly = "duplicate"
+	// Backwards is a trade with a time before the previous trade of the coin.
+	Backwards Anomaly = "backwards"
+	// Gap is a trade following a period without any trades , longer than the configured one.
+	Gap Anomaly = "gap"
+	// Outlier is a trade with a price too far from the recent prices e.g. a fat-finger trade.
+	Outlier Anomaly = "outlier"
+)
+
+// Anomalies are all the anomalies checked on the trade stream.
+var Anomalies = []Anomaly{Duplicate, Backwards, Gap, Outlier}
+
+// Action is the handling of an anomaly.
+type Action string
+
+const (
+	// Flag passes the trade on , marking it as an anomaly.
+	Flag Action = "flag"
+	// Repair fixes the trade and passes it on , marking it as an anomaly.
+	// A backwards trade gets the time of the previous trade ,
+	// and an outlier the median of the recent prices.
+	// The anomalies that cannot be repaired are flagged.
+	Repair Action = "repair"
+	// Drop removes the trade from the stream.
+	Drop Action = "drop"
+)
+
+// Config defines the data quality rules.
+// Gap is the max period without trades , zero disables the gap check.
+// Deviation is the max relative distance of the price from the median of the recent prices ,
+// zero disables the outlier check.
+// Window is the number of recent trades kept for the duplicate and the outlier checks.
+// Anchor is the number of consecutive outliers close to each other , after which the price is taken to have moved
+// to a new level , and the outliers become the reference prices , zero disables the re-anchoring.
+// Actions defines the action for each anomaly , the missing ones are flagged.
+type Config struct {
+	Gap       time.Duration
+	Deviation float64
+	Window    int
+	Anchor    int
+	Actions   map[Anomaly]Action
+}
+
+// DefaultConfig drops the duplicates , repairs the backwards and outlier trades ,
+// flags the gaps of more than 5 min , and re-anchors the prices after 5 consecutive outliers.
+func DefaultConfig() Config {
+	return Config{
+		Gap:       5 * time.Minute,
+		Deviation: 0.1,
+		Window:    20,
+		Anchor:    5,
+		Actions: map[Anomaly]Action{
+			Duplicate: Drop,
+			Backwards: Repair,
+			Gap:       Flag,
+			Outlier:   Repair,
+		},
+	}
+}
+
+// WithAction sets the action for the given anomaly.
+func (c Config) WithAction(anomaly Anomaly, action Action) Config {
+	actions := make(map[Anomaly]Action)
+	for a, act := range c.Actions {
+		actions[a] = act
+	}
+	actions[anomaly] = action
+	c.Actions = actions
+	return c
+}
+
+func (c Config) action(anomaly Anomaly) Action {
+	if action, ok := c.Actions[anomaly]; ok {
+		return action
+	}
+	return Flag
+}
+
+// Counts are the number of anomalies found and of trades dropped , per coin.
+type Counts struct {
+	Trades    int
+	Dropped   int
+	Anomalies map[Anomaly]int
+}
+
+func newCounts() Counts {
+	return Counts{Anomalies: make(map[Anomaly]int)}
+}
+
+func (c Counts) String() string {
+	return fmt.Sprintf("trades = %d , dropped = %d , duplicate = %d , backwards = %d , gap = %d , outlier = %d",
+		c.Trades, c.Dropped, c.Anomalies[Duplicate], c.Anomalies[Backwards], c.Anomalies[Gap], c.Anomalies[Outlier])
+}
+
+// Result is the outcome of the checks for a trade.
+type Result struct {
+	Anomalies []Anomaly
+	Drop      bool
+}
+
+type state struct {
+	last     time.Time
+	recent   []model.Tick
+	prices   []float64
+	outliers []float64
+	counts   Counts
+}
+
+// Checker checks the trade stream of each coin against the data quality rules.
+type Checker struct {
+	lock   *sync.Mutex
+	config Config
+	coins  map[model.Coin]*state
+}
+
+// NewChecker creates a new data quality checker.
+func NewChecker(config Config) *Checker {
+	return &Checker{
+		lock:   new(sync.Mutex),
+		config: config,
+		coins:  make(map[model.Coin]*state),
+	}
+}
+
+// Check checks the trade against the rules , repairing it in place if configured so.
+// The number of anomalies is marked on the trade meta , so that the downstream windows can be skipped.
+func (c *Checker) Check(trade *model.TradeSignal) Result {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	s, ok := c.coins[trade.Coin]
+	if !ok {
+		s = &state{
+			recent: make([]model.Tick, 0),
+			prices: make([]float64, 0),
+			counts: newCounts(),
+		}
+		c.coins[trade.Coin] = s
+	}
+	s.counts.Trades++
+
+	result := Result{Anomalies: make([]Anomaly, 0)}
+	mark := func(anomaly Anomaly) Action {
+		result.Anomalies = append(result.Anomalies, anomaly)
+		s.counts.Anomalies[anomaly]++
+		action := c.config.action(anomaly)
+		if action == Drop {
+			result.Drop = true
+		}
+		return action
+	}
+
+	if s.duplicate(trade.Tick) {
+		mark(Duplicate)
+	}
+
+	if !s.last.IsZero() {
+		if trade.Tick.Time.Before(s.last) {
+			if mark(Backwards) == Repair {
+				trade.Tick.Time = s.last
+				trade.Meta.Time = s.last
+				trade.Meta.Unix = s.last.Unix()
+			}
+		} else if c.config.Gap > 0 && trade.Tick.Time.Sub(s.last) > c.config.Gap {
+			mark(Gap)
+		}
+	}
+
+	outlier := false
+	if c.config.Deviation > 0 && len(s.prices) >= (c.config.Window+1)/2 {
+		median := median(s.prices)
+		if median > 0 && math.Abs(trade.Tick.Price/median-1) > c.config.Deviation {
+			if !s.anchor(trade.Tick.Price, c.config) {
+				outlier = true
+				if mark(Outlier) == Repair {
+					trade.Tick.Price = median
+				}
+			}
+		} else {
+			s.outliers = s.outliers[:0]
+		}
+	}
+
+	if result.Drop {
+		s.counts.Dropped++
+		return result
+	}
+
+	if trade.Tick.Time.After(s.last) {
+		s.last = trade.Tick.Time
+	}
+	s.recent = append(s.recent, trade.Tick)
+	if len(s.recent) > c.config.Window {
+		s.recent = s.recent[1:]
+	}
+	// the outliers do not move the reference prices
+	if !outlier {
+		s.prices = append(s.prices, trade.Tick.Price)
+		if len(s.prices) > c.config.Window {
+			s.prices = s.prices[1:]
+		}
+	}
+	trade.Meta.Anomalies += len(result.Anomalies)
+	return result
+}
+
+// Counts returns the anomaly counts for the coin.
+func (c *Checker) Counts(coin model.Coin) Counts {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	s, ok := c.coins[coin]
+	if !ok {
+		return newCounts()
+	}
+	counts := Counts{
+		Trades:    s.counts.Trades,
+		Dropped:   s.counts.Dropped,
+		Anomalies: make(map[Anomaly]int),
+	}
+	for anomaly, count := range s.counts.Anomalies {
+		counts.Anomalies[anomaly] = count
+	}
+	return counts
+}
+
+// anchor keeps track of the consecutive outliers , and replaces the reference prices with them ,
+// once there are as many as the configured anchor , as a step change of the price is not an outlier.
+// It returns true if the price has been re-anchored , so that the trade is not an outlier.
+func (s *state) anchor(price float64, config Config) bool {
+	if config.Anchor <= 0 {
+		return false
+	}
+	// the outliers need to be close to each other , otherwise they are just noise
+	if len(s.outliers) > 0 && math.Abs(price/median(s.outliers)-1) > config.Deviation {
+		s.outliers = s.outliers[:0]
+	}
+	if len(s.outliers)+1 < config.Anchor {
+		s.outliers = append(s.outliers, price)
+		return false
+	}
+	s.prices = append(make([]float64, 0, config.Window), s.outliers...)
+	s.outliers = s.outliers[:0]
+	return true
+}
+
+// duplicate checks if the same trade is among the recent ones.
+func (s *state) duplicate(tick model.Tick) bool {
+	for _, t := range s.recent {
+		if t.Time.Equal(tick.Time) && t.Price == tick.Price && t.Volume == tick.Volume && t.Type == tick.Type {
+			return true
+		}
+	}
+	return false
+}
+
+func median(values []float64) float64 {
+	sorted := make([]float64, len(values))
+	copy(sorted, values)
+	sort.Float64s(sorted)
+	n := len(sorted)
+	if n%2 == 0 {
+		return (sorted[n/2-1] + sorted[n/2]) / 2
+	}
+	return sorted[n/2]
+}
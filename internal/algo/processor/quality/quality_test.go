@@ -0,0 +1,188 @@
+package quality
+
+import (
+	"testing"
+	"time"
+
+	"github.com/drakos74/free-coin/internal/model"
+	"github.com/stretchr/testify/assert"
+)
+
+var start = time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func trade(price float64, at time.Duration) *model.TradeSignal {
+	t := start.Add(at)
+	return &model.TradeSignal{
+		Coin: model.BTC,
+		Tick: model.NewTick(price, 1, model.Buy, t),
+		Meta: model.Meta{Time: t},
+	}
+}
+
+// stream creates a trade every 10 seconds around the price of 100.
+func stream(n int) []*model.TradeSignal {
+	trades := make([]*model.TradeSignal, n)
+	for i := 0; i < n; i++ {
+		trades[i] = trade(100+float64(i%3), time.Duration(i)*10*time.Second)
+	}
+	return trades
+}
+
+func TestChecker_Check(t *testing.T) {
+
+	type test struct {
+		config  Config
+		trades  []*model.TradeSignal
+		drops   []bool
+		marks   []int
+		prices  []float64
+		times   []time.Time
+		counts  map[Anomaly]int
+		dropped int
+	}
+
+	tests := map[string]test{
+		"clean": {
+			config: DefaultConfig(),
+			trades: stream(4),
+			drops:  []bool{false, false, false, false},
+			marks:  []int{0, 0, 0, 0},
+			counts: map[Anomaly]int{},
+		},
+		"duplicate-drop": {
+			config:  DefaultConfig(),
+			trades:  append(stream(2), trade(101, 10*time.Second)),
+			drops:   []bool{false, false, true},
+			marks:   []int{0, 0, 0},
+			counts:  map[Anomaly]int{Duplicate: 1},
+			dropped: 1,
+		},
+		"duplicate-flag": {
+			config: DefaultConfig().WithAction(Duplicate, Flag),
+			trades: append(stream(2), trade(101, 10*time.Second)),
+			drops:  []bool{false, false, false},
+			marks:  []int{0, 0, 1},
+			counts: map[Anomaly]int{Duplicate: 1},
+		},
+		"backwards-repair": {
+			config: DefaultConfig(),
+			trades: append(stream(3), trade(101, 5*time.Second)),
+			drops:  []bool{false, false, false, false},
+			marks:  []int{0, 0, 0, 1},
+			times:  []time.Time{start, start.Add(10 * time.Second), start.Add(20 * time.Second), start.Add(20 * time.Second)},
+			counts: map[Anomaly]int{Backwards: 1},
+		},
+		"gap": {
+			config: DefaultConfig(),
+			trades: append(stream(2), trade(101, 10*time.Minute)),
+			drops:  []bool{false, false, false},
+			marks:  []int{0, 0, 1},
+			counts: map[Anomaly]int{Gap: 1},
+		},
+		"outlier-repair": {
+			config: Config{Deviation: 0.1, Window: 4, Actions: map[Anomaly]Action{Outlier: Repair}},
+			trades: append(stream(3), trade(1000, 30*time.Second), trade(102, 40*time.Second)),
+			drops:  []bool{false, false, false, false, false},
+			marks:  []int{0, 0, 0, 1, 0},
+			prices: []float64{100, 101, 102, 101, 102},
+			counts: map[Anomaly]int{Outlier: 1},
+		},
+		"outlier-drop": {
+			config:  Config{Deviation: 0.1, Window: 4, Actions: map[Anomaly]Action{Outlier: Drop}},
+			trades:  append(stream(3), trade(10, 30*time.Second)),
+			drops:   []bool{false, false, false, true},
+			marks:   []int{0, 0, 0, 0},
+			counts:  map[Anomaly]int{Outlier: 1},
+			dropped: 1,
+		},
+		"outlier-step": {
+			config: Config{Deviation: 0.1, Window: 4, Anchor: 3, Actions: map[Anomaly]Action{Outlier: Repair}},
+			trades: append(stream(3),
+				trade(150, 30*time.Second), trade(151, 40*time.Second), trade(152, 50*time.Second), trade(153, 60*time.Second)),
+			drops:  []bool{false, false, false, false, false, false, false},
+			marks:  []int{0, 0, 0, 1, 1, 0, 0},
+			prices: []float64{100, 101, 102, 101, 101, 152, 153},
+			counts: map[Anomaly]int{Outlier: 2},
+		},
+		"outlier-noise": {
+			config: Config{Deviation: 0.1, Window: 4, Anchor: 2, Actions: map[Anomaly]Action{Outlier: Repair}},
+			trades: append(stream(3),
+				trade(1000, 30*time.Second), trade(10, 40*time.Second), trade(1000, 50*time.Second), trade(102, 60*time.Second)),
+			drops:  []bool{false, false, false, false, false, false, false},
+			marks:  []int{0, 0, 0, 1, 1, 1, 0},
+			prices: []float64{100, 101, 102, 101, 101, 101, 102},
+			counts: map[Anomaly]int{Outlier: 3},
+		},
+		"outlier-warmup": {
+			config: Config{Deviation: 0.1, Window: 4, Actions: map[Anomaly]Action{Outlier: Drop}},
+			trades: append(stream(1), trade(1000, 10*time.Second)),
+			drops:  []bool{false, false},
+			marks:  []int{0, 0},
+			counts: map[Anomaly]int{},
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			checker := NewChecker(tt.config)
+			for i, trade := range tt.trades {
+				result := checker.Check(trade)
+				assert.Equal(t, tt.drops[i], result.Drop, "drop %d", i)
+				assert.Equal(t, tt.marks[i], trade.Meta.Anomalies, "mark %d", i)
+				if tt.prices != nil {
+					assert.Equal(t, tt.prices[i], trade.Tick.Price, "price %d", i)
+				}
+				if tt.times != nil {
+					assert.Equal(t, tt.times[i], trade.Tick.Time, "time %d", i)
+				}
+			}
+			counts := checker.Counts(model.BTC)
+			assert.Equal(t, len(tt.trades), counts.Trades)
+			assert.Equal(t, tt.dropped, counts.Dropped)
+			assert.Equal(t, tt.counts, counts.Anomalies)
+		})
+	}
+}
+
+func TestProcessor(t *testing.T) {
+	in := make(chan *model.TradeSignal)
+	out := make(chan *model.TradeSignal)
+	skipped := make([]*model.TradeSignal, 0)
+	go Processor(NewChecker(DefaultConfig()), func(trade *model.TradeSignal) {
+		skipped = append(skipped, trade)
+	})(in, out)
+
+	go func() {
+		for _, trade := range append(stream(3), trade(102, 20*time.Second)) {
+			in <- trade
+		}
+		close(in)
+	}()
+
+	trades := 0
+	for range out {
+		trades++
+	}
+	assert.Equal(t, 3, trades)
+	assert.Equal(t, 1, len(skipped))
+}
+
+func TestNewCoverage(t *testing.T) {
+	trades := make([]model.TradeSignal, 0)
+	for _, trade := range append(stream(4), trade(101, 10*time.Second), trade(100, time.Hour)) {
+		trades = append(trades, *trade)
+	}
+	coverage := NewCoverage("kraken_BTC_1", start, start.Add(4*time.Hour), trades, DefaultConfig())
+	assert.False(t, coverage.Missing())
+	assert.Equal(t, start, coverage.First)
+	assert.Equal(t, start.Add(time.Hour), coverage.Last)
+	assert.Equal(t, 0.25, coverage.Ratio())
+	assert.Equal(t, 6, coverage.Counts.Trades)
+	assert.Equal(t, map[Anomaly]int{Duplicate: 1, Backwards: 1, Gap: 1}, coverage.Counts.Anomalies)
+	// the stored trades are not repaired
+	assert.Equal(t, start.Add(10*time.Second), trades[4].Tick.Time)
+
+	missing := NewCoverage("", start, start.Add(4*time.Hour), nil, DefaultConfig())
+	assert.True(t, missing.Missing())
+	assert.Equal(t, 0.0, missing.Ratio())
+}
@@ -0,0 +1,228 @@
+package regime
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"time"
+
+	"github.com/drakos74/free-coin/internal/math/ml"
+	"github.com/drakos74/free-coin/internal/model"
+)
+
+const (
+	// clusters is the number of clusters , one for each of the regimes
+	clusters = 3
+	// dim is the number of features used for the clustering
+	dim = 3
+	// restarts is the number of clustering runs on each training
+	restarts = 5
+)
+
+// Features extracts the volatility , trend and volume features from the signal.
+func Features(signal *model.TradeSignal) []float64 {
+	if signal.Tick.Price == 0 {
+		return make([]float64, dim)
+	}
+	return []float64{
+		100 * signal.Tick.StatsData.Std.Price / signal.Tick.Price,
+		100 * math.Abs(signal.Tick.StatsData.Trend.Price) / signal.Tick.Price,
+		signal.Tick.Volume,
+	}
+}
+
+// Classifier clusters a rolling window of signal features into regimes.
+// It re-trains the clusters every `retrain` samples,
+// and labels them based on the cluster centroids :
+// - the cluster with the highest volatility is the volatile one
+// - of the remaining ones, the cluster with the highest trend is the trending one
+// - the last one is the ranging one
+type Classifier struct {
+	size       int
+	retrain    int
+	iterations int
+	count      int
+	samples    [][]float64
+	mean, std  []float64
+	centroids  [][]float64
+	labels     map[int]model.Regime
+	rand       *rand.Rand
+}
+
+// NewClassifier creates a new regime classifier.
+func NewClassifier(size, retrain, iterations int) *Classifier {
+	return &Classifier{
+		size:       size,
+		retrain:    retrain,
+		iterations: iterations,
+		samples:    make([][]float64, 0, size),
+		rand:       rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+}
+
+// Add adds the features of a new signal and returns the regime classification for it.
+func (c *Classifier) Add(x []float64) (model.Regime, error) {
+	c.samples = append(c.samples, x)
+	if len(c.samples) > c.size {
+		c.samples = c.samples[1:]
+	}
+	c.count++
+	if len(c.samples) < c.size {
+		return model.NoRegime, nil
+	}
+	if c.centroids == nil || c.count%c.retrain == 0 {
+		if err := c.train(); err != nil {
+			return model.NoRegime, fmt.Errorf("could not train regime clusters: %w", err)
+		}
+	}
+	return c.Predict(x)
+}
+
+// Predict returns the regime for the given features.
+func (c *Classifier) Predict(x []float64) (model.Regime, error) {
+	if c.centroids == nil {
+		return model.NoRegime, fmt.Errorf("no model present")
+	}
+	y := c.scale(x)
+	guess := 0
+	min := math.MaxFloat64
+	for i, centroid := range c.centroids {
+		var d float64
+		for j, v := range centroid {
+			d += (y[j] - v) * (y[j] - v)
+		}
+		if d < min {
+			min = d
+			guess = i
+		}
+	}
+	return c.labels[guess], nil
+}
+
+func (c *Classifier) train() error {
+	c.mean, c.std = standardise(c.samples)
+	data := make([][]float64, len(c.samples))
+	for i, x := range c.samples {
+		data[i] = c.scale(x)
+	}
+	// k-means can converge to a local optimum , so we keep the best out of a few runs.
+	// The model seeds the random generator on its own , so we shuffle the samples
+	// to make sure each run starts from different centroids.
+	m := ml.NewInMemoryKMeans(clusters, c.iterations)
+	var best []int
+	distortion := math.MaxFloat64
+	for r := 0; r < restarts; r++ {
+		order := c.rand.Perm(len(data))
+		// the model updates the centroids in place , which might be pointing to the training samples
+		training := make([][]float64, len(data))
+		for i, j := range order {
+			training[i] = append([]float64{}, data[j]...)
+		}
+		fit, err := m.Fit(training)
+		if err != nil {
+			return err
+		}
+		guesses := make([]int, len(data))
+		for i, g := range fit {
+			guesses[order[i]] = g
+		}
+		if d := distort(data, guesses); d < distortion {
+			distortion = d
+			best = guesses
+		}
+	}
+	c.centroids = centroids(data, best)
+	c.labels = label(c.centroids)
+	return nil
+}
+
+// centroids computes the cluster centroids based on the guesses.
+// Empty clusters are placed far away , so that they are never picked.
+func centroids(data [][]float64, guesses []int) [][]float64 {
+	cc := make([][]float64, clusters)
+	counts := make([]int, clusters)
+	for i := range cc {
+		cc[i] = make([]float64, dim)
+	}
+	for i, g := range guesses {
+		counts[g]++
+		for j, v := range data[i] {
+			cc[g][j] += v
+		}
+	}
+	for i := range cc {
+		for j := range cc[i] {
+			if counts[i] > 0 {
+				cc[i][j] = cc[i][j] / float64(counts[i])
+			} else {
+				cc[i][j] = -1 * math.MaxFloat64
+			}
+		}
+	}
+	return cc
+}
+
+// distort computes the sum of squared distances of the samples to their cluster centroids.
+func distort(data [][]float64, guesses []int) float64 {
+	cc := centroids(data, guesses)
+	var sum float64
+	for i, g := range guesses {
+		for j, v := range data[i] {
+			sum += (v - cc[g][j]) * (v - cc[g][j])
+		}
+	}
+	return sum
+}
+
+// label assigns the regimes to the clusters based on the centroids.
+func label(centroids [][]float64) map[int]model.Regime {
+	labels := make(map[int]model.Regime)
+	pick := func(feature int) int {
+		idx := -1
+		for i, c := range centroids {
+			if _, ok := labels[i]; ok {
+				continue
+			}
+			if idx < 0 || c[feature] > centroids[idx][feature] {
+				idx = i
+			}
+		}
+		return idx
+	}
+	labels[pick(0)] = model.Volatile
+	labels[pick(1)] = model.Trending
+	labels[pick(1)] = model.Ranging
+	return labels
+}
+
+func (c *Classifier) scale(x []float64) []float64 {
+	y := make([]float64, len(x))
+	for i, v := range x {
+		if c.std[i] == 0 {
+			continue
+		}
+		y[i] = (v - c.mean[i]) / c.std[i]
+	}
+	return y
+}
+
+// standardise returns the mean and standard deviation of each dimension of the samples.
+func standardise(samples [][]float64) ([]float64, []float64) {
+	mean := make([]float64, dim)
+	std := make([]float64, dim)
+	n := float64(len(samples))
+	for _, x := range samples {
+		for i, v := range x {
+			mean[i] += v / n
+		}
+	}
+	for _, x := range samples {
+		for i, v := range x {
+			std[i] += (v - mean[i]) * (v - mean[i]) / n
+		}
+	}
+	for i := range std {
+		std[i] = math.Sqrt(std[i])
+	}
+	return mean, std
+}
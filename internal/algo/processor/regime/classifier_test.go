@@ -0,0 +1,74 @@
+package regime
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/drakos74/free-coin/internal/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLabel(t *testing.T) {
+
+	type test struct {
+		centroids [][]float64
+		labels    map[int]model.Regime
+	}
+
+	tests := map[string]test{
+		"ordered": {
+			centroids: [][]float64{{3, 1, 0}, {1, 3, 0}, {0, 0, 0}},
+			labels:    map[int]model.Regime{0: model.Volatile, 1: model.Trending, 2: model.Ranging},
+		},
+		"shuffled": {
+			centroids: [][]float64{{0, 0, 0}, {5, 5, 0}, {1, 2, 0}},
+			labels:    map[int]model.Regime{0: model.Ranging, 1: model.Volatile, 2: model.Trending},
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, tt.labels, label(tt.centroids))
+		})
+	}
+}
+
+func TestClassifier(t *testing.T) {
+	rand.Seed(1)
+	c := NewClassifier(90, 10, 100)
+
+	// volatility , trend , volume
+	regimes := map[model.Regime][]float64{
+		model.Ranging:  {0.1, 0.1, 1},
+		model.Trending: {0.2, 3, 1},
+		model.Volatile: {4, 1, 1},
+	}
+	sample := func(r model.Regime) []float64 {
+		x := make([]float64, dim)
+		for i, v := range regimes[r] {
+			x[i] = v + 0.01*rand.Float64()
+		}
+		return x
+	}
+
+	order := []model.Regime{model.Ranging, model.Trending, model.Volatile}
+	for i := 0; i < 89; i++ {
+		regime, err := c.Add(sample(order[i%3]))
+		assert.NoError(t, err)
+		assert.Equal(t, model.NoRegime, regime)
+	}
+
+	for i := 0; i < 30; i++ {
+		expected := order[i%3]
+		regime, err := c.Add(sample(expected))
+		assert.NoError(t, err)
+		assert.Equal(t, expected, regime)
+	}
+}
+
+func TestAnyRegime(t *testing.T) {
+	assert.True(t, model.AnyRegime(model.Trending))
+	assert.True(t, model.AnyRegime(model.NoRegime, model.Trending))
+	assert.True(t, model.AnyRegime(model.Trending, model.Ranging, model.Trending))
+	assert.False(t, model.AnyRegime(model.Volatile, model.Ranging, model.Trending))
+}
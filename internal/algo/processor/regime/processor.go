@@ -0,0 +1,158 @@
+package regime
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/drakos74/free-coin/internal/algo/processor"
+	"github.com/drakos74/free-coin/internal/api"
+	"github.com/drakos74/free-coin/internal/metrics"
+	"github.com/drakos74/free-coin/internal/model"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	Name = "regime"
+)
+
+// Config defines the configuration for the regime processor.
+// Interval is the aggregation interval for the signals
+// Size is the number of aggregated signals used for the clustering
+// Retrain is the number of signals after which the clusters are re-trained
+// Iterations is the max number of iterations for the clustering
+type Config struct {
+	Interval   time.Duration
+	Size       int
+	Retrain    int
+	Iterations int
+}
+
+// DefaultConfig is the default regime config , based on the last day of 15 min intervals.
+func DefaultConfig() Config {
+	return Config{
+		Interval:   15 * time.Minute,
+		Size:       96,
+		Retrain:    4,
+		Iterations: 100,
+	}
+}
+
+// Regimes keeps track of the current regime and the classifier per coin.
+type Regimes struct {
+	lock        *sync.RWMutex
+	regimes     map[model.Coin]model.Regime
+	classifiers map[model.Coin]*Classifier
+	buffers     []*processor.SignalBuffer
+}
+
+// NewRegimes creates a new regime tracker.
+func NewRegimes() *Regimes {
+	return &Regimes{
+		lock:        new(sync.RWMutex),
+		regimes:     make(map[model.Coin]model.Regime),
+		classifiers: make(map[model.Coin]*Classifier),
+	}
+}
+
+// Remove drops the regime , the classifier and the buffered signal state for the given coins.
+func (r *Regimes) Remove(coins ...model.Coin) {
+	r.lock.Lock()
+	for _, coin := range coins {
+		delete(r.regimes, coin)
+		delete(r.classifiers, coin)
+	}
+	buffers := r.buffers
+	r.lock.Unlock()
+	for _, b := range buffers {
+		b.Remove(coins...)
+	}
+}
+
+// track keeps the signal buffer of a processor , so that the removed coins are dropped from it.
+func (r *Regimes) track(b *processor.SignalBuffer) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.buffers = append(r.buffers, b)
+}
+
+// classifier returns the classifier for the given coin , creating a new one on the first encounter.
+func (r *Regimes) classifier(coin model.Coin, config Config) *Classifier {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if _, ok := r.classifiers[coin]; !ok {
+		r.classifiers[coin] = NewClassifier(config.Size, config.Retrain, config.Iterations)
+	}
+	return r.classifiers[coin]
+}
+
+// Get returns the current regime for the given coin.
+func (r *Regimes) Get(coin model.Coin) model.Regime {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	return r.regimes[coin]
+}
+
+// set updates the regime for the given coin and returns true if it changed.
+func (r *Regimes) set(coin model.Coin, regime model.Regime) (model.Regime, bool) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	prev := r.regimes[coin]
+	r.regimes[coin] = regime
+	return prev, prev != regime
+}
+
+// Processor is the regime classifier processor.
+// It clusters the aggregated signals per coin into regimes and attaches the current regime to each passing signal.
+func Processor(index api.Index, regimes *Regimes, config Config) func(u api.User, e api.Exchange) api.Processor {
+	return func(u api.User, e api.Exchange) api.Processor {
+		signalBuffer, signals := processor.NewSignalBuffer(config.Interval)
+		regimes.track(signalBuffer)
+		buffered := processor.ProcessSignalBuffer(Name, signalBuffer, signals, func(signal *model.TradeSignal) error {
+			start := time.Now()
+			coin := string(signal.Coin)
+			metrics.Observer.IncrementTrades(coin, Name, "batch")
+			regime, err := regimes.classifier(signal.Coin, config).Add(Features(signal))
+			if err != nil {
+				return fmt.Errorf("could not classify regime for %s: %w", coin, err)
+			}
+			if regime == model.NoRegime {
+				return nil
+			}
+			if prev, changed := regimes.set(signal.Coin, regime); changed {
+				log.Info().
+					Str("coin", coin).
+					Str("from", string(prev)).
+					Str("to", string(regime)).
+					Msg("regime change")
+				u.Send(index, api.NewMessage(formatChange(signal, prev, regime)), nil)
+			}
+			metrics.Observer.TrackDuration(time.Since(start).Seconds(), coin, Name, "process")
+			return nil
+		}, func() {})
+
+		return func(in <-chan *model.TradeSignal, out chan<- *model.TradeSignal) {
+			tagged := make(chan *model.TradeSignal)
+			go func() {
+				defer close(tagged)
+				for trade := range in {
+					trade.Meta.Regime = regimes.Get(trade.Coin)
+					tagged <- trade
+				}
+			}()
+			buffered(tagged, out)
+		}
+	}
+}
+
+func formatChange(signal *model.TradeSignal, prev, regime model.Regime) string {
+	from := string(prev)
+	if prev == model.NoRegime {
+		from = "-"
+	}
+	return fmt.Sprintf("%s %s regime %s -> %s (%.2f)",
+		signal.Tick.Time.Format(time.Stamp),
+		signal.Coin,
+		from, regime,
+		signal.Tick.Price)
+}
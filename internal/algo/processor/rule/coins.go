@@ -0,0 +1,73 @@
+package rule
+
+import (
+	"sync"
+
+	"github.com/drakos74/free-coin/internal/model"
+)
+
+// Coins tracks the coins added and removed at runtime , on top of the configured segments.
+// The added coins without segments trade the default rules , without going live.
+type Coins struct {
+	lock    *sync.RWMutex
+	added   map[model.Coin]bool
+	removed map[model.Coin]bool
+	version int
+}
+
+// NewCoins creates a new coin tracker.
+func NewCoins() *Coins {
+	return &Coins{
+		lock:    new(sync.RWMutex),
+		added:   make(map[model.Coin]bool),
+		removed: make(map[model.Coin]bool),
+	}
+}
+
+// Add adds the given coins.
+func (c *Coins) Add(coins ...model.Coin) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	for _, coin := range coins {
+		delete(c.removed, coin)
+		c.added[coin] = true
+	}
+	c.version++
+}
+
+// Remove removes the given coins.
+func (c *Coins) Remove(coins ...model.Coin) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	for _, coin := range coins {
+		delete(c.added, coin)
+		c.removed[coin] = true
+	}
+	c.version++
+}
+
+// segments returns the given segments adjusted for the added and removed coins ,
+// along with the version of the changes , so that the caller can skip the unchanged ones.
+// A nil tracker returns the given segments.
+func (c *Coins) segments(segments map[model.Key]Rules) (map[model.Key]Rules, int) {
+	if c == nil {
+		return segments, 0
+	}
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	ss := make(map[model.Key]Rules)
+	has := make(map[model.Coin]bool)
+	for k, rules := range segments {
+		if c.removed[k.Coin] {
+			continue
+		}
+		ss[k] = rules
+		has[k.Coin] = true
+	}
+	for coin := range c.added {
+		if !has[coin] {
+			ss[Key(coin, 15)] = DefaultRules(false)
+		}
+	}
+	return ss, c.version
+}
@@ -0,0 +1,132 @@
+package rule
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/drakos74/free-coin/internal/account"
+	"github.com/drakos74/free-coin/internal/math/indicator"
+	"github.com/drakos74/free-coin/internal/model"
+	cointime "github.com/drakos74/free-coin/internal/time"
+	"github.com/drakos74/free-coin/internal/trader"
+)
+
+// Rules defines the indicators and the entry and exit rules for a key.
+// Indicators defaults to all the available indicators with their default parameters.
+// Live defines if the orders are submitted to the exchange, or only tracked by the trader.
+// Regimes defines the market regimes the entry rules are active for , empty means all of them.
+type Rules struct {
+	Indicators []indicator.Spec `json:"indicators"`
+	Entry      []Rule           `json:"entry"`
+	Exit       []Rule           `json:"exit"`
+	Live       bool             `json:"live"`
+	Regimes    []model.Regime   `json:"regimes"`
+}
+
+// Config defines the configuration for the rule processor.
+// Accounts defines the accounts the signals are traded on , if empty the processor exchange is used.
+// Traders are the shared traders of the accounts , they take precedence over the Accounts.
+// Coins tracks the coins added and removed at runtime , if nil the segments are fixed.
+// Replay is set for the runs on historical trades , where the windows are flushed only by the trade times.
+// Clock drives the windows and the traders , if nil the current clock is used.
+type Config struct {
+	Segments map[model.Key]Rules
+	Position trader.Settings
+	Accounts []account.Details
+	Exchange trader.ExchangeProvider
+	Traders  []*trader.Account
+	Coins    *Coins
+	Replay   bool
+	Clock    cointime.Clock
+}
+
+// WithAccounts trades the signals on each of the given accounts , using the exchange provider for each one of them.
+func (c Config) WithAccounts(exchange trader.ExchangeProvider, accounts ...account.Details) Config {
+	c.Accounts = accounts
+	c.Exchange = exchange
+	return c
+}
+
+// WithTraders trades the signals on the given account traders , which are shared with the other processors.
+func (c Config) WithTraders(traders ...*trader.Account) Config {
+	c.Traders = traders
+	return c
+}
+
+// WithCoins adds and removes the segments of the coins that change at runtime.
+func (c Config) WithCoins(coins *Coins) Config {
+	c.Coins = coins
+	return c
+}
+
+// NoLive marks the run as a replay of historical trades , so that the windows have no heartbeat.
+func (c Config) NoLive() Config {
+	c.Replay = true
+	return c
+}
+
+// WithClock sets the clock of the windows and the traders e.g. the simulated clock of a back-test.
+func (c Config) WithClock(clock cointime.Clock) Config {
+	c.Clock = clock
+	return c
+}
+
+// Key creates the processor key for the given coin and interval in minutes.
+func Key(coin model.Coin, d int) model.Key {
+	return model.Key{
+		Coin:     coin,
+		Duration: time.Duration(d) * time.Minute,
+		Strategy: Name,
+	}
+}
+
+// DefaultRules is the classic macd crossing strategy , filtered by the rsi.
+func DefaultRules(live bool) Rules {
+	return Rules{
+		Entry: []Rule{
+			{
+				Name:       "macd-up",
+				Type:       model.Buy,
+				Conditions: MustParse("macd cross_up macd.signal and rsi < 30"),
+			},
+			{
+				Name:       "macd-down",
+				Type:       model.Sell,
+				Conditions: MustParse("macd cross_down macd.signal and rsi > 70"),
+			},
+		},
+		Exit: []Rule{
+			{
+				Name:       "macd-exit-long",
+				Type:       model.Buy,
+				Conditions: MustParse("macd cross_down macd.signal"),
+			},
+			{
+				Name:       "macd-exit-short",
+				Type:       model.Sell,
+				Conditions: MustParse("macd cross_up macd.signal"),
+			},
+		},
+		Live: live,
+	}
+}
+
+// DefaultConfig creates the default rule config for the given coins on 15 min intervals.
+func DefaultConfig(live bool, coins ...model.Coin) Config {
+	segments := make(map[model.Key]Rules)
+	for _, coin := range coins {
+		segments[Key(coin, 15)] = DefaultRules(live)
+	}
+	return Config{
+		Segments: segments,
+		Position: trader.Settings{
+			OpenValue:  100,
+			TakeProfit: 0.03,
+			StopLoss:   0.01,
+		},
+	}
+}
+
+func (r Rules) String() string {
+	return fmt.Sprintf("entry:%d|exit:%d|live:%v", len(r.Entry), len(r.Exit), r.Live)
+}
@@ -0,0 +1,331 @@
+package rule
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/drakos74/free-coin/client/local"
+	"github.com/drakos74/free-coin/internal/account"
+	"github.com/drakos74/free-coin/internal/algo/processor"
+	"github.com/drakos74/free-coin/internal/analytics"
+	"github.com/drakos74/free-coin/internal/api"
+	"github.com/drakos74/free-coin/internal/buffer"
+	"github.com/drakos74/free-coin/internal/emoji"
+	"github.com/drakos74/free-coin/internal/math/indicator"
+	"github.com/drakos74/free-coin/internal/metrics"
+	"github.com/drakos74/free-coin/internal/model"
+	"github.com/drakos74/free-coin/internal/storage"
+	"github.com/drakos74/free-coin/internal/trader"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	Name = "rule"
+)
+
+// keyBar is a bar for the corresponding key.
+type keyBar struct {
+	key    model.Key
+	bar    indicator.Bar
+	regime model.Regime
+}
+
+// segment is the running state of the rules for a key.
+type segment struct {
+	rules  Rules
+	ev     *evaluator
+	window *buffer.IntervalWindow
+}
+
+// Processor is the rule based strategy processor.
+// It aggregates the trades into bars of the key duration, evaluates the indicator rules on them
+// and creates the corresponding orders through the exchange trader.
+// The paper accounts are fed with the prices of the running stream , so that they can fill their orders ,
+// and are reported along with the other accounts.
+// The segments of the coins added and removed through the config coins are started and stopped on the next trade.
+func Processor(index api.Index, shard storage.Shard, registry storage.EventRegistry, config Config) func(u api.User, e api.Exchange) api.Processor {
+
+	for k, rules := range config.Segments {
+		if _, err := newEvaluator(rules); err != nil {
+			log.Error().Err(err).Str("key", k.ToString()).Str("processor", Name).Msg("could not init processor")
+			return func(u api.User, e api.Exchange) api.Processor {
+				return processor.Void(Name)
+			}
+		}
+	}
+
+	return func(u api.User, e api.Exchange) api.Processor {
+		accounts, err := newAccounts(index, shard, registry, config, e, u)
+		if err != nil {
+			log.Error().Err(err).Str("processor", Name).Msg("processor in void state")
+			return processor.NoProcess(Name)
+		}
+
+		// the shared traders are registered by their owner
+		if len(config.Traders) == 0 {
+			for _, acc := range accounts {
+				analytics.Register(fmt.Sprintf("%s-%s", Name, acc.Details.Name), acc.Capital(), acc.Journal())
+			}
+		}
+
+		papers := paperExchanges(accounts)
+		go trackUserActions(index, u, accounts, papers)
+		u.Send(index, api.NewMessage(fmt.Sprintf("%s starting processor ... %s", Name, formatConfig(config))), nil)
+
+		bars := make(chan keyBar)
+		// keep track of the latest regime for each coin , and the running segments
+		lock := new(sync.RWMutex)
+		regimes := make(map[model.Coin]model.Regime)
+		segments := make(map[model.Key]*segment)
+		version := -1
+		wg := new(sync.WaitGroup)
+
+		// start starts aggregating the trades for the key , the caller must hold the lock.
+		start := func(k model.Key, rules Rules) error {
+			ev, err := newEvaluator(rules)
+			if err != nil {
+				return err
+			}
+			window, buckets := buffer.NewIntervalWindow(k.ToString(), 2, k.Duration)
+			// the window aggregates based on the trade time , so that we can also process historical data ,
+			// where the wall clock heartbeat would flush the windows before their trades arrive
+			if config.Replay {
+				window.WithoutHeartbeat()
+			}
+			if config.Clock != nil {
+				window.WithClock(config.Clock)
+			}
+			segments[k] = &segment{
+				rules:  rules,
+				ev:     ev,
+				window: window,
+			}
+			wg.Add(1)
+			go func(k model.Key, buckets <-chan buffer.StatsMessage) {
+				defer wg.Done()
+				for bucket := range buckets {
+					if bar, ok := indicator.FromBucket(bucket); ok {
+						lock.RLock()
+						regime := regimes[k.Coin]
+						lock.RUnlock()
+						bars <- keyBar{key: k, bar: bar, regime: regime}
+					}
+				}
+			}(k, buckets)
+			return nil
+		}
+
+		// update starts and stops the segments after the coins changed ,
+		// it returns the windows of the stopped ones , which must be closed without holding the lock.
+		update := func() []*buffer.IntervalWindow {
+			lock.Lock()
+			defer lock.Unlock()
+			rules, v := config.Coins.segments(config.Segments)
+			if v == version {
+				return nil
+			}
+			version = v
+			stopped := make([]*buffer.IntervalWindow, 0)
+			for k, s := range segments {
+				if _, ok := rules[k]; !ok {
+					stopped = append(stopped, s.window)
+					delete(segments, k)
+				}
+			}
+			for k, r := range rules {
+				if _, ok := segments[k]; !ok {
+					if err := start(k, r); err != nil {
+						log.Error().Err(err).Str("key", k.ToString()).Str("processor", Name).Msg("could not start segment")
+					}
+				}
+			}
+			return stopped
+		}
+		closeWindows := func(windows []*buffer.IntervalWindow) {
+			for _, window := range windows {
+				if err := window.Close(); err != nil {
+					log.Error().Err(err).Str("key", window.ID).Msg("could not close window")
+				}
+			}
+		}
+		update()
+
+		go func() {
+			for kb := range bars {
+				lock.RLock()
+				s, ok := segments[kb.key]
+				lock.RUnlock()
+				if !ok {
+					// the segment has been stopped in the meantime
+					continue
+				}
+				start := time.Now()
+				coin := string(kb.key.Coin)
+				metrics.Observer.IncrementTrades(coin, Name, "bar")
+				err := process(index, u, accounts, config, s.rules, s.ev, kb.key, kb.bar, kb.regime)
+				if err != nil {
+					log.Error().Err(err).Str("key", kb.key.ToString()).Str("processor", Name).Msg("could not process bar")
+				}
+				metrics.Observer.TrackDuration(time.Since(start).Seconds(), coin, Name, "process")
+			}
+		}()
+
+		return processor.ProcessWithClose(Name, func(trade *model.TradeSignal) error {
+			for _, paper := range papers {
+				paper.Process(trade)
+			}
+			closeWindows(update())
+			lock.Lock()
+			regimes[trade.Coin] = trade.Meta.Regime
+			windows := make([]*buffer.IntervalWindow, 0)
+			for k, s := range segments {
+				if k.Match(trade.Coin) {
+					windows = append(windows, s.window)
+				}
+			}
+			lock.Unlock()
+			for _, window := range windows {
+				window.Push(trade.Tick.Time, trade.Tick.Price, trade.Tick.Volume)
+			}
+			return nil
+		}, func() {
+			lock.Lock()
+			windows := make([]*buffer.IntervalWindow, 0, len(segments))
+			for _, s := range segments {
+				windows = append(windows, s.window)
+			}
+			lock.Unlock()
+			closeWindows(windows)
+			wg.Wait()
+			close(bars)
+		})
+	}
+}
+
+// newAccounts returns the shared account traders , or creates the traders for the configured accounts ,
+// or a single one for the processor exchange , if there are no accounts.
+func newAccounts(index api.Index, shard storage.Shard, registry storage.EventRegistry, config Config, e api.Exchange, u api.User) ([]*trader.Account, error) {
+	if len(config.Traders) > 0 {
+		return config.Traders, nil
+	}
+	if config.Position.Clock == nil {
+		config.Position.Clock = config.Clock
+	}
+	if len(config.Accounts) > 0 {
+		return trader.NewAccounts(fmt.Sprintf("%s-%s", index, Name), shard, registry, config.Position, config.Exchange, u, config.Accounts...)
+	}
+	wallet, err := trader.SimpleTrader(fmt.Sprintf("%s-%s", index, Name), shard, registry, config.Position, e, u)
+	if err != nil {
+		return nil, err
+	}
+	return []*trader.Account{{
+		Details: account.Details{
+			Name:   account.Name(index),
+			Live:   true,
+			User:   account.UserDetails{Index: index},
+			Filter: account.NoFilter(),
+		},
+		Exchange:       e,
+		ExchangeTrader: wallet,
+	}}, nil
+}
+
+// paperExchanges returns the virtual exchanges of the paper accounts.
+func paperExchanges(accounts []*trader.Account) map[account.Name]*local.Paper {
+	papers := make(map[account.Name]*local.Paper)
+	for _, acc := range accounts {
+		if paper, ok := acc.Exchange.(*local.Paper); ok {
+			papers[acc.Details.Name] = paper
+		}
+	}
+	return papers
+}
+
+// process evaluates the rules on the new bar and creates the corresponding orders for each account.
+func process(index api.Index, u api.User, accounts []*trader.Account, config Config, rules Rules, ev *evaluator, key model.Key, bar indicator.Bar, regime model.Regime) error {
+	signal := &model.TradeSignal{
+		Coin: key.Coin,
+		Tick: model.NewTick(bar.Close, bar.Volume, model.NoType, bar.Time),
+		Meta: model.Meta{
+			Time: bar.Time,
+			Live: true,
+		},
+	}
+	// check the stop-loss and take-profit thresholds first
+	for _, acc := range accounts {
+		pp, _, _, _ := acc.Update(map[string]bool{}, signal, config.Position.TrackingConfig)
+		for k, p := range pp {
+			if k != key {
+				continue
+			}
+			reason, _ := trader.Assess(p.PnL)
+			_, ok, action, err := acc.CreateOrder(k, bar.Time, bar.Close, p.Type.Inv(), false, p.Volume, reason, p.Live, nil)
+			u.Send(acc.Index(index), api.NewMessage(formatAction(acc.Details.Name, action, string(reason), err, ok)), nil)
+		}
+	}
+
+	decisions, err := ev.add(bar)
+	if err != nil {
+		return err
+	}
+	for _, acc := range accounts {
+		if err := acc.Details.Filter.Allow(key.Coin); err != nil {
+			log.Debug().
+				Err(err).
+				Str("key", key.ToString()).
+				Str("account", string(acc.Details.Name)).
+				Msg("ignoring decisions for account")
+			continue
+		}
+		for _, decision := range decisions {
+			if decision.Exit {
+				_, positions := acc.CurrentPositions(key.Coin)
+				p, ok := positions[key]
+				if !ok || (decision.Type != model.NoType && decision.Type != p.Type) {
+					continue
+				}
+				_, ok, action, err := acc.CreateOrder(key, bar.Time, bar.Close, p.Type.Inv(), false, p.Volume, trader.SignalReason, p.Live, nil)
+				u.Send(acc.Index(index), api.NewMessage(formatAction(acc.Details.Name, action, decision.Rule, err, ok)), nil)
+				continue
+			}
+			if !model.AnyRegime(regime, rules.Regimes...) {
+				log.Debug().
+					Str("key", key.ToString()).
+					Str("regime", string(regime)).
+					Str("rule", decision.Rule).
+					Msg("ignoring entry for regime")
+				continue
+			}
+			_, ok, action, err := acc.CreateOrder(key, bar.Time, bar.Close, decision.Type, true, 0, trader.SignalReason, acc.Live(rules.Live), nil)
+			if ok || err != nil {
+				u.Send(acc.Index(index), api.NewMessage(formatAction(acc.Details.Name, action, decision.Rule, err, ok)), nil)
+			}
+		}
+	}
+	return nil
+}
+
+// formatAction formats the action , along with the overall account pnl.
+func formatAction(name account.Name, action trader.Event, rule string, err error, ok bool) string {
+	msg := fmt.Sprintf("%s %s %s|%.fm %s %.4f [%s]\n%s %.2f%s %.2f%s\n%s %.2f%s (%d|%d)",
+		name, action.Time.Format(time.Stamp),
+		action.Key.Coin, action.Key.Duration.Minutes(),
+		emoji.MapType(action.Type), action.Price, rule,
+		emoji.MapToSign(action.PnL), 100*action.PnL, "%",
+		action.Value, model.EURO,
+		emoji.MapToSign(action.Global.PnL), action.Global.Value, model.EURO,
+		action.Global.Profit, action.Global.Loss)
+	if err != nil {
+		return fmt.Sprintf("%s\n%s", msg, err.Error())
+	}
+	return fmt.Sprintf("%s %s", msg, emoji.MapToValid(ok))
+}
+
+func formatConfig(config Config) string {
+	msg := fmt.Sprintf("\n%.2f€ [%.3f|%.3f]", config.Position.OpenValue, config.Position.TakeProfit, config.Position.StopLoss)
+	for k, rules := range config.Segments {
+		msg += fmt.Sprintf("\n%s %s", k.ToString(), rules.String())
+	}
+	return msg
+}
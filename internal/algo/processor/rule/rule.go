@@ -0,0 +1,219 @@
+package rule
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/drakos74/free-coin/internal/math/indicator"
+	"github.com/drakos74/free-coin/internal/model"
+)
+
+const (
+	LessThan    = "<"
+	GreaterThan = ">"
+	CrossUp     = "cross_up"
+	CrossDown   = "cross_down"
+)
+
+// Condition compares an indicator value against another indicator value or a constant.
+// Left and Right are the names of the indicator values e.g. 'rsi' or 'macd.signal'.
+// If Right is empty, the condition is evaluated against Value.
+// The cross operators require the previous values to evaluate the crossing.
+type Condition struct {
+	Left  string  `json:"left"`
+	Op    string  `json:"op"`
+	Right string  `json:"right"`
+	Value float64 `json:"value"`
+}
+
+func (c Condition) String() string {
+	right := c.Right
+	if right == "" {
+		right = strconv.FormatFloat(c.Value, 'f', -1, 64)
+	}
+	return fmt.Sprintf("%s %s %s", c.Left, c.Op, right)
+}
+
+// operands returns the left and right value of the condition.
+func (c Condition) operands(values map[string]float64) (float64, float64, error) {
+	left, ok := values[c.Left]
+	if !ok {
+		return 0, 0, fmt.Errorf("unknown value '%s'", c.Left)
+	}
+	right := c.Value
+	if c.Right != "" {
+		right, ok = values[c.Right]
+		if !ok {
+			return 0, 0, fmt.Errorf("unknown value '%s'", c.Right)
+		}
+	}
+	return left, right, nil
+}
+
+// Eval evaluates the condition for the current values.
+// prev can be nil, in which case the cross conditions will not be met.
+func (c Condition) Eval(prev, curr map[string]float64) (bool, error) {
+	left, right, err := c.operands(curr)
+	if err != nil {
+		return false, err
+	}
+	switch c.Op {
+	case LessThan:
+		return left < right, nil
+	case GreaterThan:
+		return left > right, nil
+	case CrossUp, CrossDown:
+		if prev == nil {
+			return false, nil
+		}
+		prevLeft, prevRight, err := c.operands(prev)
+		if err != nil {
+			return false, nil
+		}
+		if c.Op == CrossUp {
+			return prevLeft <= prevRight && left > right, nil
+		}
+		return prevLeft >= prevRight && left < right, nil
+	}
+	return false, fmt.Errorf("unknown operator '%s'", c.Op)
+}
+
+// Parse parses a rule expression into a set of conditions.
+// The expression is a set of conditions joined with 'and'
+// e.g. 'macd cross_up macd.signal and rsi < 30'
+func Parse(expr string) ([]Condition, error) {
+	parts := strings.Split(expr, " and ")
+	conditions := make([]Condition, len(parts))
+	for i, part := range parts {
+		tokens := strings.Fields(part)
+		if len(tokens) != 3 {
+			return nil, fmt.Errorf("invalid condition '%s'", part)
+		}
+		condition := Condition{
+			Left: tokens[0],
+			Op:   tokens[1],
+		}
+		switch condition.Op {
+		case LessThan, GreaterThan, CrossUp, CrossDown:
+		default:
+			return nil, fmt.Errorf("unknown operator '%s' in condition '%s'", condition.Op, part)
+		}
+		if v, err := strconv.ParseFloat(tokens[2], 64); err == nil {
+			condition.Value = v
+		} else {
+			condition.Right = tokens[2]
+		}
+		conditions[i] = condition
+	}
+	return conditions, nil
+}
+
+// MustParse parses the rule expression and panics if it is not valid.
+func MustParse(expr string) []Condition {
+	conditions, err := Parse(expr)
+	if err != nil {
+		panic(any(err.Error()))
+	}
+	return conditions
+}
+
+// Rule is a named set of conditions that must all be met.
+// Type is the position type the rule opens for the entry rules, or closes for the exit rules.
+// An exit rule with no type applies to any open position.
+type Rule struct {
+	Name       string      `json:"name"`
+	Type       model.Type  `json:"type"`
+	Conditions []Condition `json:"conditions"`
+}
+
+// Eval evaluates all the rule conditions.
+func (r Rule) Eval(prev, curr map[string]float64) (bool, error) {
+	if len(r.Conditions) == 0 {
+		return false, nil
+	}
+	for _, condition := range r.Conditions {
+		ok, err := condition.Eval(prev, curr)
+		if err != nil {
+			return false, fmt.Errorf("could not evaluate rule '%s': %w", r.Name, err)
+		}
+		if !ok {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// Decision is the outcome of the rules evaluation.
+type Decision struct {
+	Rule   string
+	Type   model.Type
+	Exit   bool
+	Values map[string]float64
+}
+
+// evaluator evaluates the rules of a key on the incoming bars.
+type evaluator struct {
+	set   *indicator.Set
+	rules Rules
+	prev  map[string]float64
+}
+
+func newEvaluator(rules Rules) (*evaluator, error) {
+	specs := rules.Indicators
+	if len(specs) == 0 {
+		specs = indicator.DefaultSpecs()
+	}
+	set, err := indicator.NewSet(specs...)
+	if err != nil {
+		return nil, err
+	}
+	return &evaluator{
+		set:   set,
+		rules: rules,
+	}, nil
+}
+
+// add adds the bar to the indicators and evaluates the rules.
+// Exit rules are evaluated before the entry ones, so that a position can be closed and reversed on the same bar.
+func (e *evaluator) add(bar indicator.Bar) ([]Decision, error) {
+	e.set.Add(bar)
+	if !e.set.Ready() {
+		return nil, nil
+	}
+	curr := e.set.Values()
+	prev := e.prev
+	e.prev = curr
+
+	decisions := make([]Decision, 0)
+	for _, r := range e.rules.Exit {
+		ok, err := r.Eval(prev, curr)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			decisions = append(decisions, Decision{
+				Rule:   r.Name,
+				Type:   r.Type,
+				Exit:   true,
+				Values: curr,
+			})
+		}
+	}
+	for _, r := range e.rules.Entry {
+		ok, err := r.Eval(prev, curr)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			decisions = append(decisions, Decision{
+				Rule:   r.Name,
+				Type:   r.Type,
+				Values: curr,
+			})
+			// only one entry per bar
+			break
+		}
+	}
+	return decisions, nil
+}
@@ -0,0 +1,225 @@
+package rule
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/drakos74/free-coin/internal/account"
+	"github.com/drakos74/free-coin/internal/api"
+	"github.com/drakos74/free-coin/internal/math/indicator"
+	"github.com/drakos74/free-coin/internal/model"
+	"github.com/drakos74/free-coin/internal/storage"
+	"github.com/drakos74/free-coin/internal/trader"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParse(t *testing.T) {
+
+	type test struct {
+		expr       string
+		conditions []Condition
+		err        bool
+	}
+
+	tests := map[string]test{
+		"value": {
+			expr:       "rsi < 30",
+			conditions: []Condition{{Left: "rsi", Op: LessThan, Value: 30}},
+		},
+		"cross-and-value": {
+			expr: "macd cross_up macd.signal and rsi < 30",
+			conditions: []Condition{
+				{Left: "macd", Op: CrossUp, Right: "macd.signal"},
+				{Left: "rsi", Op: LessThan, Value: 30},
+			},
+		},
+		"unknown-operator": {
+			expr: "rsi <= 30",
+			err:  true,
+		},
+		"missing-operand": {
+			expr: "rsi <",
+			err:  true,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			conditions, err := Parse(tt.expr)
+			if tt.err {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.conditions, conditions)
+		})
+	}
+}
+
+func TestCondition_Eval(t *testing.T) {
+
+	type test struct {
+		condition string
+		prev      map[string]float64
+		curr      map[string]float64
+		ok        bool
+		err       bool
+	}
+
+	tests := map[string]test{
+		"less-than": {
+			condition: "rsi < 30",
+			curr:      map[string]float64{"rsi": 20},
+			ok:        true,
+		},
+		"greater-than": {
+			condition: "close > bb.upper",
+			curr:      map[string]float64{"close": 20, "bb.upper": 21},
+		},
+		"cross-up": {
+			condition: "macd cross_up macd.signal",
+			prev:      map[string]float64{"macd": 1, "macd.signal": 2},
+			curr:      map[string]float64{"macd": 3, "macd.signal": 2},
+			ok:        true,
+		},
+		"no-cross-up": {
+			condition: "macd cross_up macd.signal",
+			prev:      map[string]float64{"macd": 3, "macd.signal": 2},
+			curr:      map[string]float64{"macd": 4, "macd.signal": 2},
+		},
+		"cross-down": {
+			condition: "macd cross_down 0",
+			prev:      map[string]float64{"macd": 1},
+			curr:      map[string]float64{"macd": -1},
+			ok:        true,
+		},
+		"cross-no-prev": {
+			condition: "macd cross_down 0",
+			curr:      map[string]float64{"macd": -1},
+		},
+		"unknown-value": {
+			condition: "ema > 0",
+			curr:      map[string]float64{"sma": 1},
+			err:       true,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			conditions := MustParse(tt.condition)
+			ok, err := conditions[0].Eval(tt.prev, tt.curr)
+			if tt.err {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.ok, ok)
+		})
+	}
+}
+
+func TestEvaluator(t *testing.T) {
+	ev, err := newEvaluator(Rules{
+		Indicators: []indicator.Spec{{Type: "sma", Params: []float64{3}}},
+		Entry: []Rule{
+			{Name: "up", Type: model.Buy, Conditions: MustParse("close cross_up sma")},
+			{Name: "down", Type: model.Sell, Conditions: MustParse("close cross_down sma")},
+		},
+		Exit: []Rule{
+			{Name: "exit", Type: model.Buy, Conditions: MustParse("close cross_down sma")},
+		},
+	})
+	assert.NoError(t, err)
+
+	decisions := make(map[int][]Decision)
+	for i, c := range []float64{10, 9, 8, 7, 10, 11, 8} {
+		dd, err := ev.add(indicator.Bar{
+			Time:  time.Unix(int64(i*60), 0),
+			Close: c,
+		})
+		assert.NoError(t, err)
+		if len(dd) > 0 {
+			decisions[i] = dd
+		}
+	}
+
+	assert.Equal(t, 2, len(decisions))
+	// sma [8,7,10] -> 8.33
+	assert.Equal(t, 1, len(decisions[4]))
+	assert.Equal(t, "up", decisions[4][0].Rule)
+	assert.False(t, decisions[4][0].Exit)
+	// sma [10,11,8] -> 9.66 , exit is evaluated before entry
+	assert.Equal(t, 2, len(decisions[6]))
+	assert.Equal(t, "exit", decisions[6][0].Rule)
+	assert.True(t, decisions[6][0].Exit)
+	assert.Equal(t, "down", decisions[6][1].Rule)
+	assert.Equal(t, model.Sell, decisions[6][1].Type)
+}
+
+func TestCoins_Segments(t *testing.T) {
+
+	type test struct {
+		added   []model.Coin
+		removed []model.Coin
+		keys    []model.Key
+	}
+
+	segments := map[model.Key]Rules{
+		Key(model.BTC, 15): DefaultRules(true),
+	}
+
+	tests := map[string]test{
+		"unchanged": {
+			keys: []model.Key{Key(model.BTC, 15)},
+		},
+		"added": {
+			added: []model.Coin{model.ETH},
+			keys:  []model.Key{Key(model.BTC, 15), Key(model.ETH, 15)},
+		},
+		"added-configured": {
+			added: []model.Coin{model.BTC},
+			keys:  []model.Key{Key(model.BTC, 15)},
+		},
+		"removed": {
+			removed: []model.Coin{model.BTC},
+			keys:    []model.Key{},
+		},
+		"added-and-removed": {
+			added:   []model.Coin{model.ETH},
+			removed: []model.Coin{model.ETH},
+			keys:    []model.Key{Key(model.BTC, 15)},
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			coins := NewCoins()
+			_, v := coins.segments(segments)
+			coins.Add(tt.added...)
+			coins.Remove(tt.removed...)
+			ss, next := coins.segments(segments)
+			assert.True(t, next > v)
+			keys := make([]model.Key, 0)
+			for k := range ss {
+				keys = append(keys, k)
+			}
+			assert.ElementsMatch(t, tt.keys, keys)
+			// the configured segments are not modified
+			assert.Equal(t, 1, len(segments))
+		})
+	}
+}
+
+func TestNewAccounts_Traders(t *testing.T) {
+	shared := []*trader.Account{{Details: account.Details{Name: "shared", Live: true}}}
+	// the shared traders are used as they are , instead of creating new ones for the accounts
+	config := DefaultConfig(true, model.BTC).
+		WithAccounts(func(details account.Details) (api.Exchange, error) {
+			return nil, fmt.Errorf("no exchange for '%s'", details.Name)
+		}, account.Details{Name: "shared"}).
+		WithTraders(shared...)
+	accounts, err := newAccounts("test", storage.VoidShard(""), storage.MockEventRegistry(), config, nil, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, shared, accounts)
+}
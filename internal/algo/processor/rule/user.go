@@ -0,0 +1,91 @@
+package rule
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/drakos74/free-coin/client"
+	"github.com/drakos74/free-coin/client/local"
+	"github.com/drakos74/free-coin/internal/account"
+	"github.com/drakos74/free-coin/internal/analytics"
+	"github.com/drakos74/free-coin/internal/api"
+	"github.com/drakos74/free-coin/internal/emoji"
+	"github.com/drakos74/free-coin/internal/model"
+	"github.com/drakos74/free-coin/internal/trader"
+	"github.com/rs/zerolog/log"
+)
+
+// trackUserActions replies to the rule processor commands e.g. '?rule stats BTC'.
+func trackUserActions(index api.Index, user api.User, accounts []*trader.Account, papers map[account.Name]*local.Paper) {
+	for command := range user.Listen("rule", "?rule") {
+		log.Debug().
+			Str("user", command.User).
+			Str("message", command.Content).
+			Str("processor", Name).
+			Msg("message received")
+		var coin string
+		var action string
+		_, err := command.Validate(
+			api.AnyUser(),
+			api.Contains("?rule"),
+			api.OneOf(&action,
+				"stats",
+				"",
+			),
+			api.Any(&coin),
+		)
+		if err != nil {
+			api.Reply(index, user, api.NewMessage("[cmd error]").ReplyTo(command.ID), err)
+			continue
+		}
+
+		c := model.Coin(strings.ToUpper(coin))
+
+		txtBuffer := new(strings.Builder)
+		switch action {
+		case "stats", "":
+			for _, acc := range accounts {
+				trades := acc.Journal().Trades(trader.Query{Coin: c})
+				txtBuffer.WriteString(formatMetrics(acc.Details.Name, analytics.Analyse(acc.Capital(), trades, analytics.DefaultPeriod)))
+				if paper, ok := papers[acc.Details.Name]; ok {
+					reports := paper.Gather(false)
+					if !model.IsAnyCoin(c) {
+						reports = map[model.Coin]client.Report{c: reports[c]}
+					}
+					txtBuffer.WriteString(formatPaper(paper.Stats(), reports))
+				}
+			}
+		}
+
+		api.Reply(index, user,
+			api.NewMessage(fmt.Sprintf("(%s|%s) - %s",
+				Name,
+				command.User,
+				txtBuffer.String()),
+			), nil)
+	}
+}
+
+func formatMetrics(name account.Name, m analytics.Metrics) string {
+	return fmt.Sprintf("[%s] %d trades %s%.2f%s\nsharpe:%.2f sortino:%.2f calmar:%.2f\ndd:%.2f%s (%s) exposure:%.2f%s turnover:%.2f\n",
+		name,
+		m.Trades, emoji.MapToSign(m.PnL), m.PnL, model.EURO,
+		m.Sharpe, m.Sortino, m.Calmar,
+		100*m.MaxDrawdown, "%", m.DrawdownDuration.Round(time.Minute),
+		100*m.Exposure, "%", m.Turnover)
+}
+
+func formatPaper(stats local.PaperStats, reports map[model.Coin]client.Report) string {
+	buffer := new(strings.Builder)
+	buffer.WriteString(fmt.Sprintf("[paper:%s] %.2f%s -> %.2f%s %s%.2f%s fees:%.2f orders:%d\n",
+		stats.Name,
+		stats.Initial, model.EURO,
+		stats.Value, model.EURO,
+		emoji.MapToSign(stats.PnL), stats.PnL, model.EURO,
+		stats.Fees, stats.Orders))
+	for c, report := range reports {
+		buffer.WriteString(fmt.Sprintf("[%s] %.2f [%d:%d]\n", c, report.Profit, report.Buy, report.Sell))
+	}
+	return buffer.String()
+}
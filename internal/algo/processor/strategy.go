@@ -18,18 +18,91 @@ type Strategy struct {
 	config  *mlmodel.Config
 	live    map[model.Coin]bool
 	enabled map[model.Coin]bool
+	// disabled tracks the network details that have been disabled e.g. due to drift
+	disabled map[model.Key]map[mlmodel.Detail]bool
+	// reload are the listeners for config reloads
+	reload []func(diff mlmodel.Diff, config mlmodel.Config)
 }
 
 func NewStrategy(segments *mlmodel.Config) *Strategy {
 	return &Strategy{
-		lock:    new(sync.RWMutex),
-		signals: make(map[model.Key]mlmodel.Signal),
-		trades:  make(map[model.Key]model.Tick),
-		config:  segments,
-		live:    make(map[model.Coin]bool),
+		lock:     new(sync.RWMutex),
+		signals:  make(map[model.Key]mlmodel.Signal),
+		trades:   make(map[model.Key]model.Tick),
+		config:   segments,
+		live:     make(map[model.Coin]bool),
+		disabled: make(map[model.Key]map[mlmodel.Detail]bool),
+		reload:   make([]func(diff mlmodel.Diff, config mlmodel.Config), 0),
 	}
 }
 
+// OnReload registers a listener that will be notified after every config reload.
+func (s *Strategy) OnReload(listener func(diff mlmodel.Diff, config mlmodel.Config)) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.reload = append(s.reload, listener)
+}
+
+// OnRemove adds a listener for the coins that have no segments left after a reload.
+func (s *Strategy) OnRemove(listener func(coins ...model.Coin)) {
+	s.OnReload(func(diff mlmodel.Diff, config mlmodel.Config) {
+		if coins := removedCoins(diff, config); len(coins) > 0 {
+			listener(coins...)
+		}
+	})
+}
+
+// removedCoins returns the coins of the removed segments , that have no segments left in the config.
+func removedCoins(diff mlmodel.Diff, config mlmodel.Config) []model.Coin {
+	coins := make([]model.Coin, 0)
+	seen := make(map[model.Coin]bool)
+	for _, k := range diff.Removed {
+		if seen[k.Coin] {
+			continue
+		}
+		seen[k.Coin] = true
+		remaining := false
+		for key := range config.Segments {
+			if key.Coin == k.Coin {
+				remaining = true
+				break
+			}
+		}
+		if !remaining {
+			coins = append(coins, k.Coin)
+		}
+	}
+	return coins
+}
+
+// Reload replaces the running config with the given one , and returns the applied diff.
+// The state of the removed and reset segments is dropped , while the rest is kept as is.
+func (s *Strategy) Reload(config *mlmodel.Config) mlmodel.Diff {
+	s.lock.Lock()
+	diff := mlmodel.DiffConfig(s.config.Segments, config.Segments)
+	for _, k := range diff.Removed {
+		delete(s.signals, k)
+		delete(s.trades, k)
+		delete(s.disabled, k)
+	}
+	for k, sd := range diff.Changed {
+		if sd.Reset() {
+			delete(s.disabled, k)
+		}
+	}
+	for _, coin := range removedCoins(diff, *config) {
+		delete(s.live, coin)
+	}
+	s.config = config
+	listeners := s.reload
+	s.lock.Unlock()
+
+	for _, listener := range listeners {
+		listener(diff, *config)
+	}
+	return diff
+}
+
 func (s *Strategy) Config() mlmodel.Config {
 	return *s.config
 }
@@ -74,6 +147,40 @@ func (s *Strategy) IsEnabledML(k model.Key) bool {
 	return false
 }
 
+// EnableDetail enables or disables the signal output of the given network detail.
+func (s *Strategy) EnableDetail(k model.Key, detail mlmodel.Detail, enabled bool) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if _, ok := s.disabled[k]; !ok {
+		s.disabled[k] = make(map[mlmodel.Detail]bool)
+	}
+	if enabled {
+		delete(s.disabled[k], detail)
+	} else {
+		s.disabled[k][detail] = true
+	}
+}
+
+// IsEnabledDetail checks if the signal output of the given network detail is enabled.
+func (s *Strategy) IsEnabledDetail(k model.Key, detail mlmodel.Detail) bool {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	return !s.disabled[k][detail]
+}
+
+// DisabledDetails returns the network details that are currently disabled.
+func (s *Strategy) DisabledDetails() map[model.Key][]mlmodel.Detail {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	dd := make(map[model.Key][]mlmodel.Detail)
+	for k, details := range s.disabled {
+		for detail := range details {
+			dd[k] = append(dd[k], detail)
+		}
+	}
+	return dd
+}
+
 func (s *Strategy) EnableTrader(c model.Coin, enabled bool) map[string]bool {
 	s.lock.Lock()
 	defer s.lock.Unlock()
@@ -105,6 +212,8 @@ func (s *Strategy) IsEnabledTrader(k model.Key) bool {
 }
 
 func (s *Strategy) IsLive(coin model.Coin, trade model.Tick) (bool, bool) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
 	if s.live[coin] {
 		return true, false
 	}
@@ -8,6 +8,7 @@ import (
 
 	"github.com/drakos74/free-coin/client"
 	mlmodel "github.com/drakos74/free-coin/internal/algo/processor/ml/model"
+	"github.com/drakos74/free-coin/internal/analytics"
 	"github.com/drakos74/free-coin/internal/emoji"
 	"github.com/drakos74/free-coin/internal/math"
 	"github.com/drakos74/free-coin/internal/math/ml"
@@ -27,6 +28,24 @@ func formatStat(c string, stats trader.Stats) string {
 	return fmt.Sprintf("[%s] %.2f(%.2f) [%d:%d]\n", c, stats.PnL, stats.Value, stats.Profit, stats.Loss)
 }
 
+func formatMetrics(m analytics.Metrics) string {
+	return fmt.Sprintf("[analytics] %d trades %s%.2f%s\nsharpe:%.2f sortino:%.2f calmar:%.2f\ndd:%.2f%s (%s) exposure:%.2f%s turnover:%.2f\n",
+		m.Trades, emoji.MapToSign(m.PnL), m.PnL, model.EURO,
+		m.Sharpe, m.Sortino, m.Calmar,
+		100*m.MaxDrawdown, "%", m.DrawdownDuration.Round(time.Minute),
+		100*m.Exposure, "%", m.Turnover)
+}
+
+func formatSummary(summary trader.Summary) string {
+	return fmt.Sprintf("[journal] %d trades [%d:%d] win-rate:%.2f%s\n%s%.2f%s fees:%.2f%s expectancy:%.2f%s\nhold:%s mae:%.2f%s\n",
+		summary.Trades, summary.Wins, summary.Losses,
+		100*summary.WinRate, "%",
+		emoji.MapToSign(summary.PnL), summary.PnL, model.EURO,
+		summary.Fees, model.EURO,
+		summary.Expectancy, model.EURO,
+		summary.AvgHold.Round(time.Minute), 100*summary.MaxAdverse, "%")
+}
+
 func formatConfig(config mlmodel.Config) string {
 
 	buffer := new(strings.Builder)
@@ -5,7 +5,9 @@ import (
 	"strconv"
 	"time"
 
+	"github.com/drakos74/free-coin/internal/account"
 	"github.com/drakos74/free-coin/internal/algo/processor"
+	"github.com/drakos74/free-coin/internal/analytics"
 	"github.com/drakos74/free-coin/internal/api"
 	"github.com/drakos74/free-coin/internal/emoji"
 	"github.com/drakos74/free-coin/internal/metrics"
@@ -21,7 +23,10 @@ const (
 )
 
 // Processor is the position processor main routine.
-func Processor(index api.Index, shard storage.Shard, registry storage.EventRegistry, strategy *processor.Strategy) func(u api.User, e api.Exchange) api.Processor {
+// It manages the positions of each of the given account traders , which are shared with the other processors ,
+// or of a single trader for the processor exchange , if there are no accounts.
+// The user commands apply to the first account.
+func Processor(index api.Index, shard storage.Shard, registry storage.EventRegistry, strategy *processor.Strategy, accounts ...*trader.Account) func(u api.User, e api.Exchange) api.Processor {
 
 	// make sure we don't break the pipeline
 	//if err != nil {
@@ -34,22 +39,43 @@ func Processor(index api.Index, shard storage.Shard, registry storage.EventRegis
 	config := strategy.Config()
 
 	return func(u api.User, e api.Exchange) api.Processor {
-		wallet, err := trader.SimpleTrader(string(index), shard, registry, trader.Settings{
-			OpenValue:      config.Position.OpenValue,
-			TakeProfit:     config.Position.TakeProfit,
-			StopLoss:       config.Position.StopLoss,
-			TrackingConfig: config.Position.TrackingConfig,
-		}, e, u)
-		if err != nil {
-			log.Error().Err(err).Str("processor", Name).Msg("processor in void state")
-			return processor.NoProcess(Name)
+		accounts := accounts
+		if len(accounts) == 0 {
+			wallet, err := trader.SimpleTrader(string(index), shard, registry, trader.Settings{
+				OpenValue:      config.Position.OpenValue,
+				TakeProfit:     config.Position.TakeProfit,
+				StopLoss:       config.Position.StopLoss,
+				TrackingConfig: config.Position.TrackingConfig,
+				MaxPositions:   config.Position.MaxPositions,
+				MaxExposure:    config.Position.MaxExposure,
+			}, e, u)
+			if err != nil {
+				log.Error().Err(err).Str("processor", Name).Msg("processor in void state")
+				return processor.NoProcess(Name)
+			}
+			analytics.Register(string(index), config.Position.OpenValue, wallet.Journal())
+			accounts = []*trader.Account{{
+				Details: account.Details{
+					Name:   account.Name(index),
+					Live:   true,
+					User:   account.UserDetails{Index: index},
+					Filter: account.NoFilter(),
+				},
+				Exchange:       e,
+				ExchangeTrader: wallet,
+			}}
 		}
+		wallet := accounts[0].ExchangeTrader
 
 		// init the user interactions
 		go trackUserActions(index, u, strategy, wallet)
 		u.Send(index, api.NewMessage(fmt.Sprintf("%s starting processor ... %s", Name, formatConfig(config))), nil)
 
-		return processor.ProcessBufferedWithClose(Name, config.Buffer.Interval, true, func(tradeSignal *model.TradeSignal) error {
+		// drop the buffered signals of the removed coins
+		signalBuffer, signals := processor.NewSignalBuffer(config.Buffer.Interval)
+		strategy.OnRemove(signalBuffer.Remove)
+
+		return processor.ProcessSignalBuffer(Name, signalBuffer, signals, func(tradeSignal *model.TradeSignal) error {
 			coin := string(tradeSignal.Coin)
 			f, _ := strconv.ParseFloat(tradeSignal.Meta.Time.Format("20060102.1504"), 64)
 			start := time.Now()
@@ -65,27 +91,32 @@ func Processor(index api.Index, shard storage.Shard, registry storage.EventRegis
 				}
 				if tradeSignal.Meta.Live || config.Option.Debug {
 					metrics.Observer.NoteLag(f, coin, Name, "process")
-					pp, profit, trend, _ := wallet.Update(config.Option.Trace, tradeSignal, config.Position.TrackingConfig)
-					if len(pp) > 0 {
-						for k, p := range pp {
-							// for statistics reasons
-							reason, _ := trader.Assess(p.PnL)
-							_, ok, action, err := wallet.CreateOrder(k, tradeSignal.Meta.Time, tradeSignal.Tick.Price, p.Type.Inv(), false, p.Volume, reason, p.Live, nil)
-							if err != nil || !ok {
-								log.Error().Err(err).Bool("ok", ok).Msg("could not close position")
-							} else if floats.Sum(profit) < 0 {
-								ok := strategy.Reset(k)
-								if !ok {
-									log.Error().Str("Index", k.ToString()).Msg("signal already reset")
+					reset := make(map[model.Key]bool)
+					for _, acc := range accounts {
+						pp, profit, trend, _ := acc.Update(config.Option.Trace, tradeSignal, config.Position.TrackingConfig)
+						if len(pp) > 0 {
+							for k, p := range pp {
+								// for statistics reasons
+								reason, _ := trader.Assess(p.PnL)
+								_, ok, action, err := acc.CreateOrder(k, tradeSignal.Meta.Time, tradeSignal.Tick.Price, p.Type.Inv(), false, p.Volume, reason, p.Live, nil)
+								if err != nil || !ok {
+									log.Error().Err(err).Bool("ok", ok).Str("account", string(acc.Details.Name)).Msg("could not close position")
+								} else if floats.Sum(profit) < 0 && !reset[k] {
+									// the signal is reset once , for all the accounts
+									reset[k] = true
+									ok := strategy.Reset(k)
+									if !ok {
+										log.Error().Str("Index", k.ToString()).Msg("signal already reset")
+									}
 								}
+								action.SourceTime = p.OpenTime
+								u.Send(acc.Index(index), api.NewMessage(formatAction(config.Option.Log, action, trend[k], err, ok)).
+									//AddLine(fmt.Sprintf(formatDecision(p.Decision))).
+									AddLine(fmt.Sprintf("%s", emoji.MapToValid(p.Live))), nil)
 							}
-							action.SourceTime = p.OpenTime
-							u.Send(index, api.NewMessage(formatAction(config.Option.Log, action, trend[k], err, ok)).
-								//AddLine(fmt.Sprintf(formatDecision(p.Decision))).
-								AddLine(fmt.Sprintf("%s", emoji.MapToValid(p.Live))), nil)
+						} else if len(trend) > 0 && (config.Option.Trace[string(tradeSignal.Coin)] || config.Option.Trace[string(model.AllCoins)]) {
+							u.Send(acc.Index(index), api.NewMessage(fmt.Sprintf("%s %s", formatTime(tradeSignal.Tick.Time), tradeSignal.Coin)).AddLine(formatTrend(trend)), nil)
 						}
-					} else if len(trend) > 0 && (config.Option.Trace[string(tradeSignal.Coin)] || config.Option.Trace[string(model.AllCoins)]) {
-						u.Send(index, api.NewMessage(fmt.Sprintf("%s %s", formatTime(tradeSignal.Tick.Time), tradeSignal.Coin)).AddLine(formatTrend(trend)), nil)
 					}
 				}
 				strategyDuration := time.Now().Sub(startStrategy).Seconds()
@@ -3,13 +3,18 @@ package trade
 import (
 	"context"
 	"fmt"
+	"os"
+	"path/filepath"
 	"strings"
 	"time"
 
 	"github.com/drakos74/free-coin/internal/algo/processor"
 
+	"github.com/drakos74/free-coin/internal/analytics"
 	"github.com/drakos74/free-coin/internal/api"
 	"github.com/drakos74/free-coin/internal/model"
+	"github.com/drakos74/free-coin/internal/storage"
+	cointime "github.com/drakos74/free-coin/internal/time"
 	"github.com/drakos74/free-coin/internal/trader"
 	"github.com/rs/zerolog/log"
 )
@@ -41,6 +46,8 @@ func trackUserActions(index api.Index, user api.User, strategy *processor.Strate
 				"prec",
 				"ds",
 				"stats",
+				"journal",
+				"csv",
 				"log",
 				"",
 			),
@@ -78,6 +85,19 @@ func trackUserActions(index api.Index, user api.User, strategy *processor.Strate
 			for network, stat := range networkStats {
 				txtBuffer.WriteString(formatStat(network, stat))
 			}
+			trades := wallet.Journal().Trades(trader.Query{Coin: key.Coin})
+			txtBuffer.WriteString(formatMetrics(analytics.Analyse(wallet.Settings().OpenValue, trades, analytics.DefaultPeriod)))
+		case "journal":
+			// the number is the look-back in days
+			summary := wallet.Journal().Summary(journalQuery(key.Coin, num))
+			txtBuffer.WriteString(formatSummary(summary))
+		case "csv":
+			file, err := exportJournal(string(index), wallet.Journal(), journalQuery(key.Coin, num))
+			if err != nil {
+				txtBuffer.WriteString(fmt.Sprintf("err=<%s>\n", err.Error()))
+			} else {
+				txtBuffer.WriteString(file)
+			}
 		//case "cfg":
 		//	txtBuffer.WriteString(formatConfig(*config))
 		//case "gap":
@@ -116,7 +136,7 @@ func trackUserActions(index api.Index, user api.User, strategy *processor.Strate
 						if k.Match(p.Coin) {
 							if position.Type == p.Type {
 								// the least we can check here ...
-								_, ok, _, err := wallet.CreateOrder(k, time.Now(), position.OpenPrice, p.Type.Inv(), false, p.Volume, trader.ForceResetReason, true, nil)
+								_, ok, _, err := wallet.CreateOrder(k, cointime.Now(), position.OpenPrice, p.Type.Inv(), false, p.Volume, trader.ForceResetReason, true, nil)
 								if err != nil || !ok {
 									txtBuffer.WriteString(fmt.Sprintf("%v|err=<%s>\n", ok, err.Error()))
 								} else {
@@ -188,6 +208,35 @@ func trackUserActions(index api.Index, user api.User, strategy *processor.Strate
 	}
 }
 
+// journalQuery creates the journal query for the given coin and the look-back days , zero days means no limit.
+func journalQuery(coin model.Coin, days float64) trader.Query {
+	query := trader.Query{Coin: coin}
+	if days > 0 {
+		query.From = cointime.Now().Add(-1 * time.Duration(days*24) * time.Hour)
+	}
+	return query
+}
+
+// exportJournal writes the journal trades to a csv file in the storage dir and returns the file path.
+func exportJournal(name string, journal *trader.Journal, query trader.Query) (string, error) {
+	dir := filepath.Join(storage.DefaultDir, "journal")
+	err := os.MkdirAll(dir, os.ModePerm)
+	if err != nil {
+		return "", fmt.Errorf("could not create journal dir: %w", err)
+	}
+	file := filepath.Join(dir, fmt.Sprintf("%s-%s.csv", name, time.Now().Format("20060102-1504")))
+	f, err := os.Create(file)
+	if err != nil {
+		return "", fmt.Errorf("could not create journal file: %w", err)
+	}
+	defer f.Close()
+	err = journal.WriteCSV(f, query)
+	if err != nil {
+		return "", fmt.Errorf("could not export journal: %w", err)
+	}
+	return file, nil
+}
+
 type position struct {
 	p model.Position
 	k model.Key
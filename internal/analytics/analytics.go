@@ -0,0 +1,287 @@
+package analytics
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"github.com/drakos74/free-coin/internal/model"
+	"github.com/drakos74/free-coin/internal/trader"
+)
+
+const year = 365 * 24 * time.Hour
+
+// Point is the equity at a point in time.
+type Point struct {
+	Time   time.Time `json:"time"`
+	Equity float64   `json:"equity"`
+}
+
+// Curve is a time-indexed equity curve.
+type Curve []Point
+
+// FromTrades builds the equity curve of the trades , starting from the given capital.
+// Each trade adds its pnl to the equity at its close time.
+func FromTrades(capital float64, trades []trader.Trade) Curve {
+	if len(trades) == 0 {
+		return Curve{}
+	}
+	tt := make([]trader.Trade, len(trades))
+	copy(tt, trades)
+	sort.SliceStable(tt, func(i, j int) bool {
+		return tt[i].CloseTime.Before(tt[j].CloseTime)
+	})
+	start := tt[0].OpenTime
+	for _, t := range tt {
+		if t.OpenTime.Before(start) {
+			start = t.OpenTime
+		}
+	}
+	curve := Curve{{Time: start, Equity: capital}}
+	equity := capital
+	for _, t := range tt {
+		equity += t.PnL
+		curve = append(curve, Point{Time: t.CloseTime, Equity: equity})
+	}
+	return curve
+}
+
+// FromEvents builds the equity curve from the closing events of the event log , starting from the given capital.
+func FromEvents(capital float64, events []trader.Event) Curve {
+	ee := make([]trader.Event, 0)
+	for _, e := range events {
+		switch e.Reason {
+		case trader.SignalReason, trader.StopLossReason, trader.TakeProfitReason, trader.ForceResetReason:
+			if e.Value != 0 {
+				ee = append(ee, e)
+			}
+		}
+	}
+	if len(ee) == 0 {
+		return Curve{}
+	}
+	sort.SliceStable(ee, func(i, j int) bool {
+		return ee[i].Time.Before(ee[j].Time)
+	})
+	start := ee[0].Time
+	if !ee[0].SourceTime.IsZero() && ee[0].SourceTime.Before(start) {
+		start = ee[0].SourceTime
+	}
+	curve := Curve{{Time: start, Equity: capital}}
+	equity := capital
+	for _, e := range ee {
+		equity += e.Value
+		curve = append(curve, Point{Time: e.Time, Equity: equity})
+	}
+	return curve
+}
+
+// Span returns the time span of the curve.
+func (c Curve) Span() time.Duration {
+	if len(c) < 2 {
+		return 0
+	}
+	return c[len(c)-1].Time.Sub(c[0].Time)
+}
+
+// at returns the equity at the given time , i.e. the last one before or at it.
+func (c Curve) at(t time.Time) float64 {
+	i := sort.Search(len(c), func(i int) bool {
+		return c[i].Time.After(t)
+	})
+	if i == 0 {
+		return c[0].Equity
+	}
+	return c[i-1].Equity
+}
+
+// MaxSamples is the max number of returns sampled from a curve.
+const MaxSamples = 10000
+
+// Returns resamples the curve at the given period and returns the period returns , along with the period used.
+// The period is widened if the curve would need more than MaxSamples samples.
+func (c Curve) Returns(period time.Duration) ([]float64, time.Duration) {
+	returns := make([]float64, 0)
+	if len(c) < 2 || period <= 0 {
+		return returns, period
+	}
+	last := c[0].Equity
+	end := c[len(c)-1].Time
+	if span := end.Sub(c[0].Time); span/period > MaxSamples {
+		period = span / MaxSamples
+	}
+	for t := c[0].Time.Add(period); ; t = t.Add(period) {
+		if t.After(end) {
+			t = end
+		}
+		equity := c.at(t)
+		if last != 0 {
+			returns = append(returns, equity/last-1)
+		}
+		last = equity
+		if !t.Before(end) {
+			break
+		}
+	}
+	return returns, period
+}
+
+// Drawdown returns the max drawdown as a fraction of the peak equity ,
+// and the longest time the curve stayed below a previous peak.
+func (c Curve) Drawdown() (float64, time.Duration) {
+	if len(c) == 0 {
+		return 0, 0
+	}
+	maxDrawdown := 0.0
+	var maxDuration time.Duration
+	peak := c[0]
+	for _, p := range c {
+		if p.Equity >= peak.Equity {
+			peak = p
+			continue
+		}
+		if peak.Equity > 0 {
+			maxDrawdown = math.Max(maxDrawdown, (peak.Equity-p.Equity)/peak.Equity)
+		}
+		if d := p.Time.Sub(peak.Time); d > maxDuration {
+			maxDuration = d
+		}
+	}
+	return maxDrawdown, maxDuration
+}
+
+// Metrics are the performance metrics of an equity curve.
+// Return and MaxDrawdown are fractions , Sharpe , Sortino and Calmar are annualised.
+// Exposure is the fraction of time with an open position and Turnover the traded value over the capital.
+type Metrics struct {
+	Trades           int           `json:"trades"`
+	PnL              float64       `json:"pnl"`
+	Return           float64       `json:"return"`
+	Sharpe           float64       `json:"sharpe"`
+	Sortino          float64       `json:"sortino"`
+	Calmar           float64       `json:"calmar"`
+	MaxDrawdown      float64       `json:"max_drawdown"`
+	DrawdownDuration time.Duration `json:"drawdown_duration"`
+	Exposure         float64       `json:"exposure"`
+	Turnover         float64       `json:"turnover"`
+}
+
+// Metrics computes the risk-adjusted metrics of the curve , with the returns sampled at the given period ,
+// or at the wider one used by Returns for the long curves.
+func (c Curve) Metrics(period time.Duration) Metrics {
+	m := Metrics{}
+	if len(c) < 2 {
+		return m
+	}
+	start := c[0].Equity
+	end := c[len(c)-1].Equity
+	m.PnL = end - start
+	if start != 0 {
+		m.Return = end/start - 1
+	}
+	m.MaxDrawdown, m.DrawdownDuration = c.Drawdown()
+
+	// annualise with the period the returns were actually sampled at
+	returns, period := c.Returns(period)
+	annual := math.Sqrt(float64(year) / float64(period))
+	mean, std, downside := moments(returns)
+	if std > 0 {
+		m.Sharpe = annual * mean / std
+	}
+	if downside > 0 {
+		m.Sortino = annual * mean / downside
+	}
+	if span := c.Span(); span > 0 && m.MaxDrawdown > 0 && start > 0 && end > 0 {
+		annualReturn := math.Pow(end/start, float64(year)/float64(span)) - 1
+		m.Calmar = annualReturn / m.MaxDrawdown
+	}
+	return m
+}
+
+// moments returns the mean , the sample standard deviation and the downside deviation of the returns.
+func moments(returns []float64) (mean, std, downside float64) {
+	n := float64(len(returns))
+	if n == 0 {
+		return
+	}
+	for _, r := range returns {
+		mean += r
+		if r < 0 {
+			downside += r * r
+		}
+	}
+	mean = mean / n
+	downside = math.Sqrt(downside / n)
+	if n < 2 {
+		return
+	}
+	for _, r := range returns {
+		std += (r - mean) * (r - mean)
+	}
+	std = math.Sqrt(std / (n - 1))
+	return
+}
+
+// Analyse computes the metrics for the trades , starting from the given capital.
+func Analyse(capital float64, trades []trader.Trade, period time.Duration) Metrics {
+	curve := FromTrades(capital, trades)
+	m := curve.Metrics(period)
+	m.Trades = len(trades)
+	m.Exposure = exposure(curve.Span(), trades)
+	if capital > 0 {
+		for _, t := range trades {
+			m.Turnover += (t.OpenPrice + t.ClosePrice) * t.Volume / capital
+		}
+	}
+	return m
+}
+
+// ByCoin computes the metrics for the trades of each coin.
+func ByCoin(capital float64, trades []trader.Trade, period time.Duration) map[model.Coin]Metrics {
+	groups := make(map[model.Coin][]trader.Trade)
+	for _, t := range trades {
+		groups[t.Coin] = append(groups[t.Coin], t)
+	}
+	metrics := make(map[model.Coin]Metrics)
+	for coin, tt := range groups {
+		metrics[coin] = Analyse(capital, tt, period)
+	}
+	return metrics
+}
+
+// ByNetwork computes the metrics for the trades of each network.
+func ByNetwork(capital float64, trades []trader.Trade, period time.Duration) map[string]Metrics {
+	groups := make(map[string][]trader.Trade)
+	for _, t := range trades {
+		groups[t.Key.Network] = append(groups[t.Key.Network], t)
+	}
+	metrics := make(map[string]Metrics)
+	for network, tt := range groups {
+		metrics[network] = Analyse(capital, tt, period)
+	}
+	return metrics
+}
+
+// exposure returns the fraction of the span with at least one open position.
+func exposure(span time.Duration, trades []trader.Trade) float64 {
+	if span <= 0 || len(trades) == 0 {
+		return 0
+	}
+	tt := make([]trader.Trade, len(trades))
+	copy(tt, trades)
+	sort.SliceStable(tt, func(i, j int) bool {
+		return tt[i].OpenTime.Before(tt[j].OpenTime)
+	})
+	var open time.Duration
+	from, to := tt[0].OpenTime, tt[0].CloseTime
+	for _, t := range tt[1:] {
+		if t.OpenTime.After(to) {
+			open += to.Sub(from)
+			from, to = t.OpenTime, t.CloseTime
+		} else if t.CloseTime.After(to) {
+			to = t.CloseTime
+		}
+	}
+	open += to.Sub(from)
+	return float64(open) / float64(span)
+}
@@ -0,0 +1,225 @@
+package analytics
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/drakos74/free-coin/internal/model"
+	"github.com/drakos74/free-coin/internal/trader"
+	"github.com/stretchr/testify/assert"
+)
+
+var start = time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func daily(equity ...float64) Curve {
+	curve := make(Curve, len(equity))
+	for i, e := range equity {
+		curve[i] = Point{Time: start.Add(time.Duration(i) * 24 * time.Hour), Equity: e}
+	}
+	return curve
+}
+
+func TestCurve_Metrics(t *testing.T) {
+
+	type test struct {
+		curve    Curve
+		returns  []float64
+		drawdown float64
+		duration time.Duration
+		sharpe   float64
+		sortino  float64
+		calmar   float64
+	}
+
+	annual := math.Sqrt(365)
+
+	tests := map[string]test{
+		"flat": {
+			curve:   daily(100, 100, 100),
+			returns: []float64{0, 0},
+		},
+		"constant-growth": {
+			curve:   daily(100, 110, 121, 133.1),
+			returns: []float64{0.1, 0.1, 0.1},
+		},
+		"alternating": {
+			curve:    daily(100, 110, 104.5, 114.95, 109.2025),
+			returns:  []float64{0.1, -0.05, 0.1, -0.05},
+			drawdown: 0.05,
+			duration: 24 * time.Hour,
+			// mean 0.025 , std 0.075*sqrt(4/3) , downside 0.05/sqrt(2)
+			sharpe:  annual * 0.025 / (0.075 * math.Sqrt(4.0/3.0)),
+			sortino: annual * 0.025 / (0.05 / math.Sqrt(2)),
+			calmar:  (math.Pow(1.092025, 365.0/4) - 1) / 0.05,
+		},
+		"drawdown-and-recovery": {
+			curve:    daily(100, 120, 90, 100, 130),
+			returns:  []float64{0.2, -0.25, 1.0 / 9, 0.3},
+			drawdown: 0.25,
+			duration: 2 * 24 * time.Hour,
+		},
+		"no-recovery": {
+			curve:    daily(100, 80, 90),
+			returns:  []float64{-0.2, 0.125},
+			drawdown: 0.2,
+			duration: 2 * 24 * time.Hour,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			returns, period := tt.curve.Returns(24 * time.Hour)
+			assert.InDeltaSlice(t, tt.returns, returns, 1e-9)
+			assert.Equal(t, 24*time.Hour, period)
+
+			drawdown, duration := tt.curve.Drawdown()
+			assert.InDelta(t, tt.drawdown, drawdown, 1e-9)
+			assert.Equal(t, tt.duration, duration)
+
+			m := tt.curve.Metrics(24 * time.Hour)
+			first := tt.curve[0].Equity
+			last := tt.curve[len(tt.curve)-1].Equity
+			assert.InDelta(t, last-first, m.PnL, 1e-9)
+			assert.InDelta(t, last/first-1, m.Return, 1e-9)
+			if tt.sharpe != 0 {
+				assert.InDelta(t, tt.sharpe, m.Sharpe, 1e-6)
+				assert.InDelta(t, tt.sortino, m.Sortino, 1e-6)
+				assert.InDelta(t, tt.calmar, m.Calmar, 1e-6*tt.calmar)
+			}
+		})
+	}
+}
+
+func TestCurve_Returns_Resample(t *testing.T) {
+	// intra-day points are sampled at the end of each day
+	curve := Curve{
+		{Time: start, Equity: 100},
+		{Time: start.Add(6 * time.Hour), Equity: 150},
+		{Time: start.Add(12 * time.Hour), Equity: 110},
+		{Time: start.Add(36 * time.Hour), Equity: 121},
+	}
+	returns, _ := curve.Returns(24 * time.Hour)
+	assert.InDeltaSlice(t, []float64{0.1, 0.1}, returns, 1e-9)
+}
+
+func TestAnalyse(t *testing.T) {
+	trade := func(coin model.Coin, network string, from, to int, pnl float64) trader.Trade {
+		return trader.Trade{
+			Key:        model.Key{Coin: coin, Network: network},
+			Coin:       coin,
+			OpenTime:   start.Add(time.Duration(from) * time.Hour),
+			CloseTime:  start.Add(time.Duration(to) * time.Hour),
+			OpenPrice:  10,
+			ClosePrice: 10,
+			Volume:     5,
+			PnL:        pnl,
+		}
+	}
+	trades := []trader.Trade{
+		trade(model.BTC, "net-a", 0, 2, 10),
+		trade(model.ETH, "net-b", 1, 3, -5),
+		trade(model.BTC, "net-b", 6, 10, 20),
+	}
+
+	m := Analyse(1000, trades, time.Hour)
+	assert.Equal(t, 3, m.Trades)
+	assert.InDelta(t, 25.0, m.PnL, 1e-9)
+	// open from 0 to 3 and from 6 to 10 out of 10 hours
+	assert.InDelta(t, 0.7, m.Exposure, 1e-9)
+	// 3 round trips of 2 * 50 EUR each
+	assert.InDelta(t, 0.3, m.Turnover, 1e-9)
+	assert.InDelta(t, 5.0/1010, m.MaxDrawdown, 1e-9)
+
+	coins := ByCoin(1000, trades, time.Hour)
+	assert.Equal(t, 2, len(coins))
+	assert.Equal(t, 2, coins[model.BTC].Trades)
+	assert.InDelta(t, 30.0, coins[model.BTC].PnL, 1e-9)
+	assert.InDelta(t, -5.0, coins[model.ETH].PnL, 1e-9)
+
+	networks := ByNetwork(1000, trades, time.Hour)
+	assert.Equal(t, 2, len(networks))
+	assert.Equal(t, 1, networks["net-a"].Trades)
+	assert.InDelta(t, 15.0, networks["net-b"].PnL, 1e-9)
+
+	Register("test", 1000, trader.NewJournal("test", nil))
+	reports := Reports("test", trader.Query{}, DefaultPeriod)
+	assert.Equal(t, 1, len(reports))
+	assert.Equal(t, 0, reports[0].Metrics.Trades)
+}
+
+func TestCurve_ReturnsSamples(t *testing.T) {
+	curve := daily(100, 110, 121)
+	// a tiny period is widened , so that the samples stay bounded
+	returns, period := curve.Returns(time.Nanosecond)
+	assert.Equal(t, MaxSamples, len(returns))
+	assert.Equal(t, 2*24*time.Hour/MaxSamples, period)
+	equity := 1.0
+	for _, r := range returns {
+		equity *= 1 + r
+	}
+	assert.InDelta(t, 1.21, equity, 1e-9)
+}
+
+func TestCurve_MetricsSamples(t *testing.T) {
+	// 60 days of alternating returns need more than MaxSamples samples at one minute
+	equity := make([]float64, 60)
+	for i := range equity {
+		equity[i] = 100 + float64(i) + 5*float64(i%2)
+	}
+	curve := daily(equity...)
+	returns, period := curve.Returns(time.Minute)
+	assert.Equal(t, MaxSamples, len(returns))
+	assert.True(t, period > time.Minute)
+
+	// the metrics are annualised with the widened period
+	m := curve.Metrics(time.Minute)
+	expected := curve.Metrics(period)
+	assert.True(t, m.Sharpe > 0)
+	assert.InDelta(t, expected.Sharpe, m.Sharpe, 1e-9)
+	assert.InDelta(t, expected.Sortino, m.Sortino, 1e-9)
+	mean, std, _ := moments(returns)
+	assert.InDelta(t, math.Sqrt(float64(year)/float64(period))*mean/std, m.Sharpe, 1e-9)
+}
+
+func TestHandle(t *testing.T) {
+
+	type test struct {
+		query  string
+		status int
+	}
+
+	tests := map[string]test{
+		"default": {
+			status: http.StatusOK,
+		},
+		"period": {
+			query:  "period=1h",
+			status: http.StatusOK,
+		},
+		"invalid-period": {
+			query:  "period=abc",
+			status: http.StatusBadRequest,
+		},
+		"negative-period": {
+			query:  "period=-1h",
+			status: http.StatusBadRequest,
+		},
+		"short-period": {
+			query:  "period=1ns",
+			status: http.StatusBadRequest,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/api/analytics?"+tt.query, nil)
+			_, status, err := handle(context.Background(), r)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.status, status)
+		})
+	}
+}
@@ -0,0 +1,115 @@
+package analytics
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/drakos74/free-coin/internal/model"
+	"github.com/drakos74/free-coin/internal/server"
+	"github.com/drakos74/free-coin/internal/trader"
+)
+
+const (
+	// DefaultPeriod is the default sampling period of the returns.
+	DefaultPeriod = 24 * time.Hour
+	// MinPeriod is the min sampling period of the returns accepted by the http api.
+	MinPeriod = time.Minute
+)
+
+type account struct {
+	capital float64
+	journal *trader.Journal
+}
+
+var accounts = struct {
+	lock     *sync.RWMutex
+	journals map[string]account
+}{
+	lock:     new(sync.RWMutex),
+	journals: make(map[string]account),
+}
+
+// Register registers the journal of the account with its capital , so that it is served through the http api.
+func Register(name string, capital float64, journal *trader.Journal) {
+	accounts.lock.Lock()
+	defer accounts.lock.Unlock()
+	accounts.journals[name] = account{
+		capital: capital,
+		journal: journal,
+	}
+}
+
+// Report is the analytics report of an account.
+type Report struct {
+	Account  string                 `json:"account"`
+	Metrics  Metrics                `json:"metrics"`
+	Coins    map[model.Coin]Metrics `json:"coins"`
+	Networks map[string]Metrics     `json:"networks"`
+	Curve    Curve                  `json:"curve"`
+}
+
+// NewReport creates the analytics report for the given account trades.
+func NewReport(name string, capital float64, trades []trader.Trade, period time.Duration) Report {
+	return Report{
+		Account:  name,
+		Metrics:  Analyse(capital, trades, period),
+		Coins:    ByCoin(capital, trades, period),
+		Networks: ByNetwork(capital, trades, period),
+		Curve:    FromTrades(capital, trades),
+	}
+}
+
+// Reports creates the reports for the registered accounts , an empty name means all the accounts.
+func Reports(name string, query trader.Query, period time.Duration) []Report {
+	accounts.lock.RLock()
+	defer accounts.lock.RUnlock()
+	reports := make([]Report, 0)
+	for n, acc := range accounts.journals {
+		if name != "" && name != n {
+			continue
+		}
+		reports = append(reports, NewReport(n, acc.capital, acc.journal.Trades(query), period))
+	}
+	sort.Slice(reports, func(i, j int) bool {
+		return reports[i].Account < reports[j].Account
+	})
+	return reports
+}
+
+// Route is the http api route for the analytics reports
+// e.g. '/api/analytics?account=main&coin=BTC&period=1h' , the period must be at least MinPeriod.
+func Route() server.Route {
+	return server.NewRoute(server.GET, server.Api).
+		WithPath("analytics").
+		Handler(handle).
+		Create()
+}
+
+func handle(ctx context.Context, r *http.Request) ([]byte, int, error) {
+	values := r.URL.Query()
+	period := DefaultPeriod
+	if p := values.Get("period"); p != "" {
+		d, err := time.ParseDuration(p)
+		if err != nil {
+			return []byte(fmt.Sprintf("invalid period '%s'", p)), http.StatusBadRequest, nil
+		}
+		if d < MinPeriod {
+			return []byte(fmt.Sprintf("period '%s' is less than %s", p, MinPeriod)), http.StatusBadRequest, nil
+		}
+		period = d
+	}
+	query := trader.Query{
+		Coin: model.Coin(strings.ToUpper(values.Get("coin"))),
+	}
+	payload, err := json.Marshal(Reports(values.Get("account"), query, period))
+	if err != nil {
+		return nil, http.StatusInternalServerError, fmt.Errorf("could not encode reports: %w", err)
+	}
+	return payload, http.StatusOK, nil
+}
@@ -41,6 +41,13 @@ type Exchange interface {
 	CurrentPrice(ctx context.Context) (map[model.Coin]model.CurrentPrice, error)
 }
 
+// OrderTracker is implemented by the exchanges that can look up an order by its client order id ,
+// so that an order with an unknown outcome is not submitted twice.
+type OrderTracker interface {
+	// QueryOrder returns the order submitted with the client id of the given one , and false if there is none.
+	QueryOrder(ctx context.Context, order *model.TrackedOrder) (*model.TrackedOrder, bool, error)
+}
+
 // User defines an external interface for exchanging information and sharing control with the user(s)
 type User interface {
 	// Run starts the user interface implementation and initialises any external connections.
@@ -3,6 +3,8 @@ package api
 import (
 	"fmt"
 	"time"
+
+	cointime "github.com/drakos74/free-coin/internal/time"
 )
 
 // Message defines a message that should be sent to the user or group.
@@ -16,7 +18,7 @@ type Message struct {
 func NewMessage(txt string) *Message {
 	return &Message{
 		Text: txt,
-		Time: time.Now(),
+		Time: cointime.Now(),
 	}
 }
 
@@ -24,7 +26,7 @@ func NewMessage(txt string) *Message {
 func ErrorMessage(txt string) *Message {
 	return &Message{
 		Text: fmt.Sprintf("ERROR:%s", txt),
-		Time: time.Now(),
+		Time: cointime.Now(),
 	}
 }
 
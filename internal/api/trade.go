@@ -6,6 +6,7 @@ import (
 	"github.com/google/uuid"
 
 	"github.com/drakos74/free-coin/internal/model"
+	cointime "github.com/drakos74/free-coin/internal/time"
 )
 
 // Processor defines the processing model of input and output channels for trades.
@@ -42,7 +43,7 @@ type Signal struct {
 func NewSignal(name string) *Signal {
 	return &Signal{
 		Name: name,
-		Time: time.Now(),
+		Time: cointime.Now(),
 		ID:   uuid.New().String(),
 	}
 }
@@ -0,0 +1,177 @@
+package book
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/drakos74/free-coin/internal/model"
+)
+
+var (
+	// ChecksumErr signals that the local book is out of sync with the exchange.
+	ChecksumErr = errors.New("checksum mismatch")
+	// NotReadyErr signals that an update was received before the initial snapshot.
+	NotReadyErr = errors.New("book not ready")
+)
+
+// Entry is a single price level update , as received from the exchange.
+// Price and Volume are kept in the exchange string format , as they are needed for the checksum.
+// A zero volume removes the price level from the book.
+type Entry struct {
+	Price  string
+	Volume string
+}
+
+// level is a parsed price level of the book.
+type level struct {
+	Entry
+	price  float64
+	volume float64
+}
+
+func parse(e Entry) (level, error) {
+	p, err := strconv.ParseFloat(e.Price, 64)
+	if err != nil {
+		return level{}, fmt.Errorf("could not parse price '%s': %w", e.Price, err)
+	}
+	v, err := strconv.ParseFloat(e.Volume, 64)
+	if err != nil {
+		return level{}, fmt.Errorf("could not parse volume '%s': %w", e.Volume, err)
+	}
+	return level{Entry: e, price: p, volume: v}, nil
+}
+
+// Book is a local level 2 order book of a fixed depth.
+// It is built from an initial snapshot and kept up to date with the subsequent deltas.
+type Book struct {
+	Coin     model.Coin
+	depth    int
+	asks     []level
+	bids     []level
+	checksum Checksum
+	ready    bool
+	time     time.Time
+}
+
+// NewBook creates a new order book for the given coin and depth.
+func NewBook(coin model.Coin, depth int) *Book {
+	return &Book{
+		Coin:  coin,
+		depth: depth,
+		asks:  make([]level, 0, depth),
+		bids:  make([]level, 0, depth),
+	}
+}
+
+// WithChecksum adds a checksum validation for the book updates.
+func (b *Book) WithChecksum(checksum Checksum) *Book {
+	b.checksum = checksum
+	return b
+}
+
+// Snapshot resets the book to the given levels.
+func (b *Book) Snapshot(t time.Time, asks, bids []Entry) error {
+	b.Reset()
+	if err := b.apply(asks, bids); err != nil {
+		return fmt.Errorf("could not apply snapshot: %w", err)
+	}
+	b.ready = true
+	b.time = t
+	return nil
+}
+
+// Update applies the given level deltas to the book and validates the result against the checksum , if given.
+// On a checksum mismatch the book is reset and needs a new snapshot.
+func (b *Book) Update(t time.Time, asks, bids []Entry, checksum string) error {
+	if !b.ready {
+		return NotReadyErr
+	}
+	if err := b.apply(asks, bids); err != nil {
+		return fmt.Errorf("could not apply update: %w", err)
+	}
+	b.time = t
+	if checksum != "" && b.checksum != nil {
+		if c := b.checksum(b); c != checksum {
+			b.Reset()
+			return fmt.Errorf("%w: expected %s but got %s", ChecksumErr, checksum, c)
+		}
+	}
+	return nil
+}
+
+// Ready returns true if the book has received a snapshot.
+func (b *Book) Ready() bool {
+	return b.ready
+}
+
+// Reset clears the book state.
+func (b *Book) Reset() {
+	b.asks = b.asks[:0]
+	b.bids = b.bids[:0]
+	b.ready = false
+}
+
+// Asks returns the ask levels of the book , starting from the best one.
+func (b *Book) Asks() []model.Level {
+	return levels(b.asks)
+}
+
+// Bids returns the bid levels of the book , starting from the best one.
+func (b *Book) Bids() []model.Level {
+	return levels(b.bids)
+}
+
+func levels(ll []level) []model.Level {
+	out := make([]model.Level, len(ll))
+	for i, l := range ll {
+		out[i] = model.Level{Price: l.price, Volume: l.volume}
+	}
+	return out
+}
+
+func (b *Book) apply(asks, bids []Entry) error {
+	for _, e := range asks {
+		l, err := parse(e)
+		if err != nil {
+			return err
+		}
+		// asks are sorted in ascending order
+		b.asks = set(b.asks, l, b.depth, func(a, b float64) bool { return a < b })
+	}
+	for _, e := range bids {
+		l, err := parse(e)
+		if err != nil {
+			return err
+		}
+		// bids are sorted in descending order
+		b.bids = set(b.bids, l, b.depth, func(a, b float64) bool { return a > b })
+	}
+	return nil
+}
+
+// set inserts , replaces or removes the given level and keeps the levels sorted and within the depth.
+func set(ll []level, l level, depth int, better func(a, b float64) bool) []level {
+	i := sort.Search(len(ll), func(i int) bool {
+		return !better(ll[i].price, l.price)
+	})
+	found := i < len(ll) && ll[i].price == l.price
+	switch {
+	case l.volume == 0 && found:
+		ll = append(ll[:i], ll[i+1:]...)
+	case l.volume == 0:
+		// nothing to remove
+	case found:
+		ll[i] = l
+	default:
+		ll = append(ll, level{})
+		copy(ll[i+1:], ll[i:])
+		ll[i] = l
+	}
+	if depth > 0 && len(ll) > depth {
+		ll = ll[:depth]
+	}
+	return ll
+}
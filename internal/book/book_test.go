@@ -0,0 +1,131 @@
+package book
+
+import (
+	"hash/crc32"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/drakos74/free-coin/internal/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func entries(ss ...string) []Entry {
+	ee := make([]Entry, 0)
+	for i := 0; i < len(ss); i += 2 {
+		ee = append(ee, Entry{Price: ss[i], Volume: ss[i+1]})
+	}
+	return ee
+}
+
+func TestBook_Update(t *testing.T) {
+
+	type test struct {
+		asks []Entry
+		bids []Entry
+		ask  []model.Level
+		bid  []model.Level
+	}
+
+	tests := map[string]test{
+		"no-change": {
+			ask: []model.Level{{Price: 101, Volume: 1}, {Price: 102, Volume: 2}, {Price: 103, Volume: 3}},
+			bid: []model.Level{{Price: 99, Volume: 1}, {Price: 98, Volume: 2}, {Price: 97, Volume: 3}},
+		},
+		"replace": {
+			asks: entries("102.0", "5.0"),
+			bids: entries("99.0", "0.5"),
+			ask:  []model.Level{{Price: 101, Volume: 1}, {Price: 102, Volume: 5}, {Price: 103, Volume: 3}},
+			bid:  []model.Level{{Price: 99, Volume: 0.5}, {Price: 98, Volume: 2}, {Price: 97, Volume: 3}},
+		},
+		"remove": {
+			asks: entries("101.0", "0.0"),
+			bids: entries("98.0", "0.0", "95.0", "0.0"),
+			ask:  []model.Level{{Price: 102, Volume: 2}, {Price: 103, Volume: 3}},
+			bid:  []model.Level{{Price: 99, Volume: 1}, {Price: 97, Volume: 3}},
+		},
+		"insert-within-depth": {
+			asks: entries("100.5", "1.5"),
+			bids: entries("99.5", "0.5"),
+			ask:  []model.Level{{Price: 100.5, Volume: 1.5}, {Price: 101, Volume: 1}, {Price: 102, Volume: 2}},
+			bid:  []model.Level{{Price: 99.5, Volume: 0.5}, {Price: 99, Volume: 1}, {Price: 98, Volume: 2}},
+		},
+		"insert-out-of-depth": {
+			asks: entries("110.0", "1.0"),
+			bids: entries("90.0", "1.0"),
+			ask:  []model.Level{{Price: 101, Volume: 1}, {Price: 102, Volume: 2}, {Price: 103, Volume: 3}},
+			bid:  []model.Level{{Price: 99, Volume: 1}, {Price: 98, Volume: 2}, {Price: 97, Volume: 3}},
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			b := NewBook(model.BTC, 3)
+			err := b.Update(time.Now(), tt.asks, tt.bids, "")
+			assert.ErrorIs(t, err, NotReadyErr)
+
+			err = b.Snapshot(time.Now(),
+				entries("103.0", "3.0", "101.0", "1.0", "102.0", "2.0"),
+				entries("97.0", "3.0", "99.0", "1.0", "98.0", "2.0"))
+			assert.NoError(t, err)
+			assert.True(t, b.Ready())
+
+			err = b.Update(time.Now(), tt.asks, tt.bids, "")
+			assert.NoError(t, err)
+			assert.Equal(t, tt.ask, b.Asks())
+			assert.Equal(t, tt.bid, b.Bids())
+		})
+	}
+}
+
+func TestBook_Checksum(t *testing.T) {
+	b := NewBook(model.BTC, 10).WithChecksum(CRC32(10))
+	err := b.Snapshot(time.Now(),
+		entries("0.05005", "0.00000500", "0.05010", "0.00000200"),
+		entries("0.05000", "0.00000100", "0.04995", "0.00000400"))
+	assert.NoError(t, err)
+
+	crc := func(s string) string {
+		return strconv.FormatUint(uint64(crc32.ChecksumIEEE([]byte(s))), 10)
+	}
+	// decimal points and leading zeros are removed , asks come first
+	assert.Equal(t, crc("5005500"+"5010200"+"5000100"+"4995400"), CRC32(10)(b))
+	// only the top levels are taken into account
+	assert.Equal(t, crc("5005500"+"5000100"), CRC32(1)(b))
+
+	err = b.Update(time.Now(), entries("0.05005", "0.00000300"), nil, crc("5005300"+"5010200"+"5000100"+"4995400"))
+	assert.NoError(t, err)
+	assert.True(t, b.Ready())
+
+	err = b.Update(time.Now(), entries("0.05005", "0.00000100"), nil, "12345")
+	assert.ErrorIs(t, err, ChecksumErr)
+	// the book needs a new snapshot after a checksum mismatch
+	assert.False(t, b.Ready())
+	assert.Equal(t, 0, len(b.Asks()))
+}
+
+func TestBook_Features(t *testing.T) {
+	b := NewBook(model.BTC, 10)
+	assert.False(t, b.Features().Ready())
+
+	err := b.Snapshot(time.Now(),
+		entries("101.0", "1.0", "102.0", "4.0"),
+		entries("99.0", "3.0", "98.0", "2.0"))
+	assert.NoError(t, err)
+
+	ob := b.Features(150, 1000)
+	assert.True(t, ob.Ready())
+	assert.Equal(t, 100.0, ob.Mid)
+	assert.Equal(t, 0.02, ob.Spread)
+	// more volume on the bid side , pushes the microprice towards the ask
+	assert.Equal(t, (99.0*1+101.0*3)/4, ob.Microprice)
+	assert.Equal(t, 0.0, ob.Imbalance)
+	// the bid volume is closer to the mid price
+	assert.True(t, ob.Pressure > 0)
+	assert.Equal(t, []model.BookDepth{
+		{Bps: 150, Bid: 3, Ask: 1},
+		{Bps: 1000, Bid: 5, Ask: 5},
+	}, ob.Depth)
+
+	assert.Equal(t, len(model.DefaultBookDepth), len(b.Features().Depth))
+}
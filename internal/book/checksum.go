@@ -0,0 +1,33 @@
+package book
+
+import (
+	"hash/crc32"
+	"strconv"
+	"strings"
+)
+
+// Checksum computes the checksum of the book state , to compare with the one provided by the exchange.
+type Checksum func(b *Book) string
+
+// CRC32 is the checksum used by kraken.
+// It concatenates the price and volume of the top levels , first the asks and then the bids,
+// after removing the decimal point and the leading zeros, and computes the crc32 of the resulting string.
+func CRC32(levels int) Checksum {
+	return func(b *Book) string {
+		s := new(strings.Builder)
+		for _, ll := range [][]level{b.asks, b.bids} {
+			for i, l := range ll {
+				if i >= levels {
+					break
+				}
+				s.WriteString(trim(l.Price))
+				s.WriteString(trim(l.Volume))
+			}
+		}
+		return strconv.FormatUint(uint64(crc32.ChecksumIEEE([]byte(s.String()))), 10)
+	}
+}
+
+func trim(s string) string {
+	return strings.TrimLeft(strings.Replace(s, ".", "", 1), "0")
+}
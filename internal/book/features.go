@@ -0,0 +1,86 @@
+package book
+
+import (
+	"math"
+
+	"github.com/drakos74/free-coin/internal/model"
+)
+
+const bps = 10000.0
+
+// Features computes the derived order book features.
+// The depth is computed for each of the given distances from the mid price , in basis points,
+// or for the default ones , if none are given.
+func (b *Book) Features(depth ...float64) model.OrderBook {
+	if !b.ready || len(b.asks) == 0 || len(b.bids) == 0 {
+		return model.OrderBook{}
+	}
+	if len(depth) == 0 {
+		depth = model.DefaultBookDepth
+	}
+	ask := b.asks[0]
+	bid := b.bids[0]
+	mid := (ask.price + bid.price) / 2
+
+	ob := model.OrderBook{
+		Time:       b.time,
+		Mid:        mid,
+		Spread:     (ask.price - bid.price) / mid,
+		Microprice: mid,
+		Depth:      make([]model.BookDepth, len(depth)),
+	}
+	if top := ask.volume + bid.volume; top > 0 {
+		// the microprice leans towards the side with the least volume
+		ob.Microprice = (bid.price*ask.volume + ask.price*bid.volume) / top
+	}
+
+	var askVolume, bidVolume, askPressure, bidPressure float64
+	for _, l := range b.asks {
+		askVolume += l.volume
+		askPressure += l.volume * weight(l.price, mid)
+	}
+	for _, l := range b.bids {
+		bidVolume += l.volume
+		bidPressure += l.volume * weight(l.price, mid)
+	}
+	ob.Imbalance = ratio(bidVolume-askVolume, bidVolume+askVolume)
+	ob.Pressure = ratio(bidPressure-askPressure, bidPressure+askPressure)
+
+	for i, d := range depth {
+		ob.Depth[i] = model.BookDepth{
+			Bps: d,
+			Ask: volumeWithin(b.asks, mid, d),
+			Bid: volumeWithin(b.bids, mid, d),
+		}
+	}
+	return ob
+}
+
+// weight decays the level volume based on its distance from the mid price.
+func weight(price, mid float64) float64 {
+	return 1 / (1 + distance(price, mid))
+}
+
+// distance is the distance of the price from the mid price in basis points.
+func distance(price, mid float64) float64 {
+	return math.Abs(price-mid) / mid * bps
+}
+
+func volumeWithin(ll []level, mid, d float64) float64 {
+	v := 0.0
+	for _, l := range ll {
+		if distance(l.price, mid) > d {
+			// levels are sorted , so there is no need to look further
+			break
+		}
+		v += l.volume
+	}
+	return v
+}
+
+func ratio(a, b float64) float64 {
+	if b == 0 {
+		return 0
+	}
+	return a / b
+}
@@ -14,9 +14,9 @@ func TestBuffer_Push(t *testing.T) {
 		fmt.Printf("l = %+v\n", l)
 		fmt.Printf("ok = %+v\n", ok)
 		fmt.Printf("b.values = %+v\n", b.values)
-		fmt.Printf("b.Get() = %+v\n", b.Get())
+		fmt.Printf("b.Get() = %+v\n", b.GetAsFloats(false))
 		if ok {
-			vv := b.Get()
+			vv := b.GetAsFloats(false)
 			fmt.Printf("vv = %+v\n", vv)
 		}
 	}
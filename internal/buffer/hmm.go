@@ -80,6 +80,8 @@ type HMM struct {
 	Config []HMMConfig                     `json:"config"`
 	State  map[Sequence]map[Sequence]State `json:"state"`
 	Status Status                          `json:"status"`
+	// groups are the number of options of the source sequence digested last , for each config
+	groups []int
 }
 
 func (hmm *HMM) Save(filename string) error {
@@ -186,9 +188,14 @@ func (hmm *HMM) Add(s string) Status {
 		panic(any(fmt.Sprintf("illegal character found '%s' in '%s'", Delimiter, s)))
 	}
 	hmm.Status.Count++
-	if _, ok := hmm.buffer.Push(s); ok {
-		for _, cfg := range hmm.Config {
-			hmm.digest(cfg, hmm.buffer.GetAsStrings(false))
+	// digest as soon as the buffer is full , the push reports only the values it evicts
+	hmm.buffer.Push(s)
+	if hmm.buffer.Size() == hmm.max {
+		if len(hmm.groups) != len(hmm.Config) {
+			hmm.groups = make([]int, len(hmm.Config))
+		}
+		for i, cfg := range hmm.Config {
+			hmm.groups[i] = hmm.digest(cfg, hmm.buffer.GetAsStrings(false))
 		}
 	}
 	return hmm.Status
@@ -267,7 +274,7 @@ func (hmm *HMM) digest(cfg HMMConfig, vv []string) int {
 func (hmm *HMM) Predict(key Sequence) map[Sequence]Predictions {
 	predictions := make(map[Sequence]Predictions)
 	// strip down key to our length
-	for _, cfg := range hmm.Config {
+	for i, cfg := range hmm.Config {
 		vv := key.Values()
 		kk := NewSequence(vv[len(vv)-cfg.LookBack:])
 		prediction := Predictions{
@@ -289,6 +296,9 @@ func (hmm *HMM) Predict(key Sequence) map[Sequence]Predictions {
 			}
 		}
 		prediction.Sample = s
+		if i < len(hmm.groups) {
+			prediction.Groups = hmm.groups[i]
+		}
 		predictions[kk] = prediction
 	}
 
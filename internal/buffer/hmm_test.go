@@ -3,6 +3,7 @@ package buffer
 import (
 	"fmt"
 	"math/rand"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -25,16 +26,13 @@ func TestHMM(t *testing.T) {
 		} else {
 			v = "-1"
 		}
-		pp, st := hmm.Add(v, "test")
-		//hmm.Add("1", "up")
-		fmt.Printf("add = %+v\n", pp)
+		st := hmm.Add(v)
 		fmt.Printf("st = %+v\n", st)
 
 	}
 
-	pp, p := hmm.predict("1:0")
+	pp := hmm.Predict(NewSequence([]string{"1", "0"}))
 	fmt.Printf("pp = %+v\n", pp)
-	fmt.Printf("p = %+v\n", p)
 
 }
 
@@ -56,7 +54,7 @@ func TestCounter_Add(t *testing.T) {
 			predictions: []Predictions{
 				{
 					Key:    "1",
-					Sample: 98,
+					Sample: 99,
 					Groups: 1,
 					Values: PredictionList{{
 						Value:       "1",
@@ -78,7 +76,7 @@ func TestCounter_Add(t *testing.T) {
 			predictions: []Predictions{
 				{
 					Key:    "1:1",
-					Sample: 97,
+					Sample: 98,
 					Groups: 1,
 					Values: PredictionList{{
 						Value:       "1",
@@ -138,7 +136,7 @@ func TestCounter_Add(t *testing.T) {
 			predictions: []Predictions{
 				{
 					Key:    "1:2",
-					Sample: 48,
+					Sample: 49,
 					Groups: 1,
 					Values: PredictionList{{
 						Value:       "1:2",
@@ -148,7 +146,7 @@ func TestCounter_Add(t *testing.T) {
 				},
 				{
 					Key:    "2:1",
-					Sample: 47,
+					Sample: 48,
 					Groups: 1,
 					Values: PredictionList{{
 						Value:       "2:1",
@@ -255,18 +253,18 @@ func TestCounter_Add(t *testing.T) {
 				// Index:    "1:2:2",
 				{
 					Key:    "1:2:1",
-					Sample: 31,
+					Sample: 32,
 					Groups: 2,
 					Values: PredictionList{
 						{
 							Value:       "1:1",
-							Probability: 0.52,
-							EMP:         0.5,
+							Probability: 0.5,
+							EMP:         0.47,
 						},
 						{
 							Value:       "2:1",
-							Probability: 0.48,
-							EMP:         0.47,
+							Probability: 0.5,
+							EMP:         0.5,
 						}},
 				},
 				{
@@ -336,13 +334,13 @@ func TestCounter_Add(t *testing.T) {
 					Values: PredictionList{
 						{
 							Value:       "2",
-							Probability: 0.51,
-							EMP:         0.51,
+							Probability: 0.49,
+							EMP:         0.49,
 						},
 						{
 							Value:       "1",
-							Probability: 0.49,
-							EMP:         0.48,
+							Probability: 0.51,
+							EMP:         0.49,
 						},
 					},
 				},
@@ -510,22 +508,31 @@ func TestCounter_Add(t *testing.T) {
 		t.Run(name, func(t *testing.T) {
 			c := NewMultiHMM(tt.configs...)
 			p := make(map[Sequence]Predictions)
+			var lookBack int
+			for _, j := range tt.configs {
+				if j.LookBack > lookBack {
+					lookBack = j.LookBack
+				}
+			}
 			for i := 0; i < tt.eventCount; i++ {
 				// we keep track of the last prediction to assert on all possible outcomes
 				s := tt.transform(i)
 				// TODO : assert the Status
-				pp, _ := c.Add(s, "label")
+				c.Add(s)
+				// predict on the latest slice , the hmm strips it down to the look back of each config
+				if i < lookBack-1 {
+					continue
+				}
+				index := make([]string, lookBack)
+				for k := 0; k < lookBack; k++ {
+					index[lookBack-1-k] = tt.transform(i - k)
+				}
+				pp := c.Predict(NewSequence(index))
 
 				// track the last j configs
 				vv := make(map[Sequence]struct{})
 				for _, j := range tt.configs {
-					index := make([]string, j.LookBack)
-					l := 0
-					for k := j.LookBack - 1; k >= 0; k-- {
-						index[k] = tt.transform(i - l)
-						l++
-					}
-					vv[NewSequence(index)] = struct{}{}
+					vv[NewSequence(index[lookBack-j.LookBack:])] = struct{}{}
 				}
 
 				for kp, vp := range pp {
@@ -537,7 +544,9 @@ func TestCounter_Add(t *testing.T) {
 			}
 
 			for _, prediction := range tt.predictions {
-				pp, ok := p[prediction.Key]
+				// the expected sequences are written with ':' for readability
+				key := sequence(prediction.Key)
+				pp, ok := p[key]
 				assert.True(t, ok, fmt.Sprintf("missing key [%s]", prediction.Key))
 				assert.Equal(t, prediction.Sample, pp.Sample, fmt.Sprintf("wrong Sample for key [%s]", prediction.Key))
 				assert.Equal(t, prediction.Groups, pp.Groups, fmt.Sprintf("wrong Groups for key [%s]", prediction.Key))
@@ -546,7 +555,7 @@ func TestCounter_Add(t *testing.T) {
 				for i, v := range pp.Values {
 					var found bool
 					for _, ppValue := range prediction.Values {
-						if v.Value == ppValue.Value {
+						if v.Value == sequence(ppValue.Value) {
 							found = true
 							assert.Equal(t, fmt.Sprintf("%.2f", ppValue.Probability), fmt.Sprintf("%.2f", v.Probability), fmt.Sprintf("wrong Probability for key [%s] at value '%v'", prediction.Key, v.Value))
 							assert.Equal(t, fmt.Sprintf("%.2f", ppValue.EMP), fmt.Sprintf("%.2f", v.EMP), fmt.Sprintf("wrong EMP for key [%s] at value '%v'", prediction.Key, v.Value))
@@ -554,7 +563,7 @@ func TestCounter_Add(t *testing.T) {
 					}
 					assert.True(t, found, fmt.Sprintf("not found value '%v' for key [%s] at Index %d", v.Value, prediction.Key, i))
 				}
-				delete(p, prediction.Key)
+				delete(p, key)
 			}
 
 			// we should have matched ALL predictions by now
@@ -564,3 +573,7 @@ func TestCounter_Add(t *testing.T) {
 	}
 
 }
+
+func sequence(s Sequence) Sequence {
+	return NewSequence(strings.Split(string(s), ":"))
+}
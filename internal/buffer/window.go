@@ -8,6 +8,7 @@ import (
 	"github.com/rs/zerolog/log"
 
 	"github.com/drakos74/free-coin/internal/math"
+	cointime "github.com/drakos74/free-coin/internal/time"
 )
 
 const (
@@ -337,30 +338,51 @@ func (sos StackOfStats) ToData() []Data {
 }
 
 // IntervalWindow defines a struct that returns the stats for the given interval.
-// It gathers events for the given interval and flushes them at the predefined duration.
-// If no events are gathered the window will return a StatsMessage with the flag OK set to false.
+// It assigns the events to windows of the given duration based on their event time ,
+// starting from the time of the first event , and flushes each window once the watermark passes its end.
+// The watermark follows the latest event time minus the allowed lateness (see WithLateness).
+// Events arriving out of order are still gathered in their window , as long as it has not been flushed yet ,
+// while events for an already flushed window are dropped as late (see Late).
+// While no events arrive , the heartbeat advances the watermark by the idle time ,
+// so that idle windows are flushed without waiting for the next event.
+// If no events are gathered for a window the window will return a StatsMessage with the flag OK set to false.
 // The WithEcho method can control the window behaviour to flush a valid StatsMessage even without an event,
 // in this case the value of the last StatsMessage will be echoed with count of `0`
-// WithLimit sets a limit for the window in terms of number of event
-// WithInterval sets the interval for the window based on the time dimension
-// The window can either operate as 'live' , with a limit or with an interval (TODO : enforce)
+// WithLimit sets a limit for the window in terms of number of events
+// Because the windows depend only on the event times , a live and a replayed run of the same events
+// produce the same StatsMessage buckets. At the end of a replay Flush emits the remaining windows.
 type IntervalWindow struct {
 	ID          string
 	First       time.Time     `json:"first"`
 	Last        time.Time     `json:"last"`
 	Duration    time.Duration `json:"duration"`
 	Dim         int           `json:"dimensions"`
-	window      *Window
 	lastMessage StatsMessage
 	stats       chan StatsMessage
+	done        chan struct{}
 	lock        *sync.RWMutex
+	clock       cointime.Clock
+	panes       map[int64]*pane
+	next        int64
+	watermark   time.Time
+	arrival     time.Time
+	lateness    time.Duration
+	late        int
 	count       int
 	limit       int
-	interval    float64
 	echo        bool
+	heartbeat   bool
 	closed      bool
 }
 
+// pane gathers the events of a window that has not been flushed yet.
+type pane struct {
+	bucket Bucket
+	first  time.Time
+	last   time.Time
+	count  int
+}
+
 // NewIntervalWindow creates a new IntervalWindow with the given Duration.
 func NewIntervalWindow(id string, dim int, duration time.Duration) (*IntervalWindow, <-chan StatsMessage) {
 	stats := make(chan StatsMessage)
@@ -370,11 +392,15 @@ func NewIntervalWindow(id string, dim int, duration time.Duration) (*IntervalWin
 		Duration: duration,
 		Dim:      dim,
 		stats:    stats,
+		done:     make(chan struct{}),
 		lastMessage: StatsMessage{
 			Stats: make([]Stats, dim),
 			Data:  make([]Data, dim),
 		},
-		lock: new(sync.RWMutex),
+		lock:      new(sync.RWMutex),
+		clock:     cointime.Current(),
+		panes:     make(map[int64]*pane),
+		heartbeat: true,
 	}
 
 	go iw.exec()
@@ -388,9 +414,35 @@ func (iw *IntervalWindow) WithLimit(limit int) *IntervalWindow {
 	return iw
 }
 
-// WithInterval sets a duration limit on the time dimension for a window to flush
+// WithInterval sets a duration limit on the time dimension for a window to flush.
+// Deprecated: the windows are always assigned on the event time , based on the window duration.
 func (iw *IntervalWindow) WithInterval(interval int) *IntervalWindow {
-	iw.interval = float64(interval)
+	return iw
+}
+
+// WithLateness sets the allowed lateness for out of order events ,
+// i.e. how long the watermark stays behind the latest event time.
+func (iw *IntervalWindow) WithLateness(lateness time.Duration) *IntervalWindow {
+	iw.lock.Lock()
+	defer iw.lock.Unlock()
+	iw.lateness = lateness
+	return iw
+}
+
+// WithoutHeartbeat stops the heartbeat , so that the windows are flushed only by the event times.
+// This is meant for replaying events , where the processing time has no relation to the event time.
+func (iw *IntervalWindow) WithoutHeartbeat() *IntervalWindow {
+	iw.lock.Lock()
+	defer iw.lock.Unlock()
+	iw.heartbeat = false
+	return iw
+}
+
+// WithClock sets the clock for the heartbeat of the window.
+func (iw *IntervalWindow) WithClock(clock cointime.Clock) *IntervalWindow {
+	iw.lock.Lock()
+	defer iw.lock.Unlock()
+	iw.clock = clock
 	return iw
 }
 
@@ -399,49 +451,130 @@ func (iw *IntervalWindow) WithEcho() *IntervalWindow {
 	return iw
 }
 
+// Late returns the number of events dropped because their window was already flushed.
+func (iw *IntervalWindow) Late() int {
+	iw.lock.RLock()
+	defer iw.lock.RUnlock()
+	return iw.late
+}
+
 // Push adds an element to the interval Window.
 func (iw *IntervalWindow) Push(t time.Time, v ...float64) {
 	iw.lock.Lock()
-	// flag to create new window
-	if iw.window == nil {
-		iw.window = NewWindow(int64(iw.Duration.Seconds()), iw.Dim)
+	defer iw.lock.Unlock()
+
+	if iw.closed {
+		return
+	}
+
+	// the first event anchors the windows
+	if iw.First.IsZero() {
 		iw.First = t
 		iw.Last = t
+		iw.watermark = t.Add(-iw.lateness)
 	}
-	iw.lock.Unlock()
 
-	// now that we released the lock, we can check on the interval flushing case
-	if iw.interval > 0 {
-		if t.Sub(iw.First).Seconds() > iw.interval {
-			iw.Flush()
-			// and dont lose the current event that's outside the interval
-			iw.Push(t, v...)
-			// stop processing anything else
-			return
-		}
+	index := iw.index(t)
+	if t.Before(iw.First) || index < iw.next {
+		iw.late++
+		log.Debug().
+			Str("id", iw.ID).
+			Time("event", t).
+			Time("watermark", iw.watermark).
+			Msg("dropping late event")
+		return
 	}
 
-	iw.window.Push(1, v...)
-	iw.Last = t
+	p, ok := iw.panes[index]
+	if !ok {
+		p = &pane{
+			bucket: NewBucket(index, iw.Dim),
+			first:  t,
+			last:   t,
+		}
+		iw.panes[index] = p
+	}
+	p.bucket.Push(index, v...)
+	if t.Before(p.first) {
+		p.first = t
+	}
+	if t.After(p.last) {
+		p.last = t
+	}
+	p.count++
 	iw.count++
 
+	if t.After(iw.Last) {
+		iw.Last = t
+	}
+	iw.arrival = iw.clock.Now()
+	iw.advance(iw.Last.Add(-iw.lateness))
+
 	if iw.limit > 0 && iw.count > iw.limit {
-		iw.Flush()
+		iw.flush()
 	}
 }
 
-// Flush flushes the current bucket contents
+// Flush flushes all the pending windows , regardless of the watermark.
 func (iw *IntervalWindow) Flush() {
 	iw.lock.Lock()
 	defer iw.lock.Unlock()
+	iw.flush()
+}
 
+// index returns the index of the window for the given event time.
+func (iw *IntervalWindow) index(t time.Time) int64 {
+	return int64(t.Sub(iw.First) / iw.Duration)
+}
+
+// end returns the end time of the window with the given index.
+func (iw *IntervalWindow) end(index int64) time.Time {
+	return iw.First.Add(time.Duration(index+1) * iw.Duration)
+}
+
+// advance moves the watermark to the given time , if it is ahead of the current one ,
+// and emits all the windows that end before it.
+func (iw *IntervalWindow) advance(watermark time.Time) {
+	if watermark.After(iw.watermark) {
+		iw.watermark = watermark
+	}
+	for !iw.end(iw.next).After(iw.watermark) {
+		iw.emit(iw.next)
+		iw.next++
+	}
+}
+
+// flush emits all the windows up to the last one with events.
+func (iw *IntervalWindow) flush() {
+	if len(iw.panes) == 0 {
+		return
+	}
+	last := iw.next
+	for index := range iw.panes {
+		if index > last {
+			last = index
+		}
+	}
+	for iw.next <= last {
+		iw.emit(iw.next)
+		iw.next++
+	}
+	if end := iw.end(last); end.After(iw.watermark) {
+		iw.watermark = end
+	}
+}
+
+// emit sends the stats message for the window with the given index.
+func (iw *IntervalWindow) emit(index int64) {
+	p, ok := iw.panes[index]
 	// if we did not have any event
-	if iw.count == 0 || iw.window == nil {
+	if !ok {
 		if iw.echo {
 			// this will allow processing because OK will be true
 			lastMessage := iw.lastMessage
-			lastMessage.Init = time.Now()
-			lastMessage.Last = lastMessage.First.Add(iw.Duration)
+			lastMessage.First = iw.end(index - 1)
+			lastMessage.Last = iw.end(index)
+			lastMessage.Init = iw.end(index)
 			iw.lastMessage = lastMessage
 			iw.stats <- iw.lastMessage
 		} else {
@@ -450,14 +583,14 @@ func (iw *IntervalWindow) Flush() {
 		}
 		return
 	}
-	stats, flatStats := iw.window.bucket.Flush()
-	iw.window = nil
-	iw.count = 0
+	delete(iw.panes, index)
+	iw.count -= p.count
+	stats, flatStats := p.bucket.Flush()
 	message := StatsMessage{
 		OK:       true,
-		Init:     time.Now(),
-		First:    iw.First,
-		Last:     iw.Last,
+		Init:     iw.end(index),
+		First:    p.first,
+		Last:     p.last,
 		ID:       iw.ID,
 		Duration: iw.Duration,
 		Dim:      iw.Dim,
@@ -478,21 +611,55 @@ func (iw *IntervalWindow) Flush() {
 	}
 }
 
-// exec initiates the execution
+// tick advances the watermark by the time passed since the last event arrived ,
+// allowing for one heartbeat period of events in flight.
+func (iw *IntervalWindow) tick(now time.Time) {
+	iw.lock.Lock()
+	defer iw.lock.Unlock()
+	if iw.closed || !iw.heartbeat || iw.First.IsZero() {
+		return
+	}
+	idle := now.Sub(iw.arrival) - iw.period()
+	if idle <= 0 {
+		return
+	}
+	iw.advance(iw.Last.Add(idle).Add(-iw.lateness))
+}
+
+// period returns the heartbeat period.
+func (iw *IntervalWindow) period() time.Duration {
+	period := iw.Duration / 10
+	if period < time.Millisecond {
+		return time.Millisecond
+	}
+	return period
+}
+
+// exec initiates the heartbeat execution
 func (iw *IntervalWindow) exec() {
-	if iw.limit == 0 && iw.interval == 0 {
-		<-time.After(iw.Duration)
-		if !iw.closed {
-			iw.Flush()
-			iw.exec()
+	for {
+		iw.lock.RLock()
+		clock := iw.clock
+		iw.lock.RUnlock()
+		select {
+		case <-iw.done:
+			return
+		case now := <-clock.After(iw.period()):
+			iw.tick(now)
 		}
 	}
 }
 
-// Close closes the channel
+// Close closes the channel , the pending windows are not flushed (see Flush).
 func (iw *IntervalWindow) Close() error {
-	close(iw.stats)
+	iw.lock.Lock()
+	defer iw.lock.Unlock()
+	if iw.closed {
+		return nil
+	}
 	iw.closed = true
+	close(iw.done)
+	close(iw.stats)
 	return nil
 }
 
@@ -6,6 +6,7 @@ import (
 	"testing"
 	"time"
 
+	cointime "github.com/drakos74/free-coin/internal/time"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -193,6 +194,111 @@ func TestIntervalWindow_Echo(t *testing.T) {
 
 }
 
+// event is a test event with its event time and the time it arrived at the window , in milliseconds.
+type event struct {
+	at      int
+	arrival int
+	tick    bool
+}
+
+// eventTimeSequence contains out of order events , a late event and idle windows.
+var eventTimeSequence = []event{
+	{at: 0, arrival: 10},
+	{at: 500, arrival: 510},
+	{at: 1200, arrival: 1210},
+	// out of order , but within the allowed lateness
+	{at: 800, arrival: 1250},
+	{at: 3500, arrival: 3550},
+	// late , its window is already flushed
+	{at: 1500, arrival: 3600},
+	{arrival: 4000, tick: true},
+	{arrival: 5000, tick: true},
+	{at: 5100, arrival: 5150},
+	{arrival: 6000, tick: true},
+	{arrival: 6200, tick: true},
+}
+
+// runEventTime pushes the events to a new window and returns the emitted messages.
+func runEventTime(live bool, events []event) ([]StatsMessage, int) {
+	start := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	clock := cointime.NewSimClock(start)
+	window, stats := NewIntervalWindow("test", 1, time.Second)
+	window.WithClock(clock)
+	window.WithLateness(time.Second)
+	if !live {
+		window.WithoutHeartbeat()
+	}
+
+	messages := make([]StatsMessage, 0)
+	done := make(chan struct{})
+	go func() {
+		for stat := range stats {
+			messages = append(messages, stat)
+		}
+		close(done)
+	}()
+
+	for _, e := range events {
+		if !live {
+			if !e.tick {
+				window.Push(start.Add(time.Duration(e.at)*time.Millisecond), float64(e.at))
+			}
+			continue
+		}
+		clock.Advance(start.Add(time.Duration(e.arrival) * time.Millisecond))
+		if e.tick {
+			window.tick(clock.Now())
+		} else {
+			window.Push(start.Add(time.Duration(e.at)*time.Millisecond), float64(e.at))
+		}
+	}
+	late := window.Late()
+	window.Flush()
+	window.Close()
+	<-done
+	return messages, late
+}
+
+func TestIntervalWindow_EventTime(t *testing.T) {
+
+	type test struct {
+		live bool
+	}
+
+	tests := map[string]test{
+		"replay": {},
+		"live":   {live: true},
+	}
+
+	start := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	counts := []int{3, 1, 0, 1, 0, 1}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			messages, late := runEventTime(tt.live, eventTimeSequence)
+			assert.Equal(t, 1, late)
+			assert.Equal(t, len(counts), len(messages))
+			for i, count := range counts {
+				if count == 0 {
+					assert.False(t, messages[i].OK)
+					continue
+				}
+				assert.True(t, messages[i].OK)
+				assert.Equal(t, count, messages[i].Stats[0].Count())
+				assert.Equal(t, start.Add(time.Duration(i+1)*time.Second), messages[i].Init)
+			}
+			// the out of order event is part of the first window
+			assert.Equal(t, start.Add(800*time.Millisecond), messages[0].Last)
+		})
+	}
+}
+
+func TestIntervalWindow_LiveEqualsReplay(t *testing.T) {
+	live, _ := runEventTime(true, eventTimeSequence)
+	replay, _ := runEventTime(false, eventTimeSequence)
+	assert.Equal(t, replay, live)
+}
+
 func TestBatchWindow_Push(t *testing.T) {
 
 	bw, stats := NewBatchWindow("", 1, time.Second, 5)
@@ -0,0 +1,222 @@
+package candle
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/drakos74/free-coin/internal/math/indicator"
+	"github.com/drakos74/free-coin/internal/model"
+)
+
+// Timeframe is the duration of a candle.
+type Timeframe time.Duration
+
+const (
+	M1  = Timeframe(time.Minute)
+	M5  = Timeframe(5 * time.Minute)
+	M15 = Timeframe(15 * time.Minute)
+	H1  = Timeframe(time.Hour)
+	H4  = Timeframe(4 * time.Hour)
+	D1  = Timeframe(24 * time.Hour)
+)
+
+// Timeframes are the supported timeframes , from the shortest to the longest.
+var Timeframes = []Timeframe{M1, M5, M15, H1, H4, D1}
+
+var names = map[Timeframe]string{
+	M1:  "1m",
+	M5:  "5m",
+	M15: "15m",
+	H1:  "1h",
+	H4:  "4h",
+	D1:  "1d",
+}
+
+// ParseTimeframe parses the timeframe from its name e.g. '5m' or '1d'.
+func ParseTimeframe(s string) (Timeframe, error) {
+	for tf, name := range names {
+		if name == s {
+			return tf, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown timeframe '%s'", s)
+}
+
+func (tf Timeframe) String() string {
+	if name, ok := names[tf]; ok {
+		return name
+	}
+	return time.Duration(tf).String()
+}
+
+// Duration returns the duration of the timeframe.
+func (tf Timeframe) Duration() time.Duration {
+	return time.Duration(tf)
+}
+
+// Start returns the open time of the candle containing t.
+// The candles are aligned to UTC e.g. the daily candles open at midnight.
+func (tf Timeframe) Start(t time.Time) time.Time {
+	return t.Truncate(tf.Duration())
+}
+
+// Candle is an open-high-low-close-volume bar of a coin for a timeframe ,
+// along with the buy and sell volume and the number of trades.
+type Candle struct {
+	Coin       model.Coin `json:"coin"`
+	Timeframe  Timeframe  `json:"timeframe"`
+	Time       time.Time  `json:"time"`
+	Open       float64    `json:"open"`
+	High       float64    `json:"high"`
+	Low        float64    `json:"low"`
+	Close      float64    `json:"close"`
+	Volume     float64    `json:"volume"`
+	BuyVolume  float64    `json:"buy_volume"`
+	SellVolume float64    `json:"sell_volume"`
+	Trades     int        `json:"trades"`
+}
+
+// End returns the close time of the candle.
+func (c Candle) End() time.Time {
+	return c.Time.Add(c.Timeframe.Duration())
+}
+
+func (c *Candle) add(tick model.Tick) {
+	price := tick.Price
+	if c.Trades == 0 {
+		c.Open = price
+		c.High = price
+		c.Low = price
+	}
+	if price > c.High {
+		c.High = price
+	}
+	if price < c.Low {
+		c.Low = price
+	}
+	c.Close = price
+	c.Volume += tick.Volume
+	if tick.Type == model.Buy {
+		c.BuyVolume += tick.Volume
+	} else {
+		c.SellVolume += tick.Volume
+	}
+	c.Trades++
+}
+
+// Bar converts the candle to an indicator bar , at the candle close time.
+func (c Candle) Bar() indicator.Bar {
+	return indicator.Bar{
+		Time:   c.End(),
+		Open:   c.Open,
+		High:   c.High,
+		Low:    c.Low,
+		Close:  c.Close,
+		Volume: c.Volume,
+	}
+}
+
+// Signal converts the candle to a trade signal at the candle close time ,
+// with the timeframe as the signal interval.
+func (c Candle) Signal() *model.TradeSignal {
+	return &model.TradeSignal{
+		Coin: c.Coin,
+		Meta: model.Meta{
+			First:    c.Time,
+			Time:     c.End(),
+			Size:     c.Trades,
+			Live:     true,
+			Interval: c.Timeframe.Duration(),
+		},
+		Tick: model.Tick{
+			Level: model.Level{
+				Price:  c.Close,
+				Volume: c.Volume,
+			},
+			StatsData: model.StatsData{
+				Trend: model.Level{
+					Price: c.Close - c.Open,
+				},
+				Buy: model.Depth{
+					Volume: c.BuyVolume,
+				},
+				Sell: model.Depth{
+					Volume: c.SellVolume,
+				},
+			},
+			Range: model.Range{
+				Min: model.Event{Price: c.Low, Time: c.Time},
+				Max: model.Event{Price: c.High, Time: c.End()},
+			},
+			Type:   model.SignedType(c.Close - c.Open),
+			Time:   c.End(),
+			Active: true,
+		},
+	}
+}
+
+// Aggregator builds the candles of a coin for the given timeframes from the trade stream.
+// The candles are assigned on the trade time , a candle closes with the first trade after its end.
+type Aggregator struct {
+	coin       model.Coin
+	timeframes []Timeframe
+	open       map[Timeframe]*Candle
+	late       int
+}
+
+// NewAggregator creates a new aggregator for the coin , for all the timeframes if none are given.
+func NewAggregator(coin model.Coin, timeframes ...Timeframe) *Aggregator {
+	if len(timeframes) == 0 {
+		timeframes = Timeframes
+	}
+	return &Aggregator{
+		coin:       coin,
+		timeframes: timeframes,
+		open:       make(map[Timeframe]*Candle),
+	}
+}
+
+// Add adds the trade to the open candles , and returns the candles it closed.
+// Trades older than the open candle are dropped as late.
+func (a *Aggregator) Add(tick model.Tick) []Candle {
+	closed := make([]Candle, 0)
+	for _, tf := range a.timeframes {
+		start := tf.Start(tick.Time)
+		c, ok := a.open[tf]
+		if ok && start.Before(c.Time) {
+			a.late++
+			continue
+		}
+		if ok && start.After(c.Time) {
+			closed = append(closed, *c)
+			ok = false
+		}
+		if !ok {
+			c = &Candle{
+				Coin:      a.coin,
+				Timeframe: tf,
+				Time:      start,
+			}
+			a.open[tf] = c
+		}
+		c.add(tick)
+	}
+	return closed
+}
+
+// Flush returns the open candles and resets them.
+func (a *Aggregator) Flush() []Candle {
+	candles := make([]Candle, 0)
+	for _, tf := range a.timeframes {
+		if c, ok := a.open[tf]; ok {
+			candles = append(candles, *c)
+		}
+	}
+	a.open = make(map[Timeframe]*Candle)
+	return candles
+}
+
+// Late returns the number of trades dropped for arriving after their candle closed.
+func (a *Aggregator) Late() int {
+	return a.late
+}
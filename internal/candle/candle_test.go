@@ -0,0 +1,259 @@
+package candle
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/drakos74/free-coin/client"
+	"github.com/drakos74/free-coin/internal/api"
+	"github.com/drakos74/free-coin/internal/model"
+	"github.com/drakos74/free-coin/internal/storage"
+	"github.com/stretchr/testify/assert"
+)
+
+var _ client.Source = &Source{}
+
+// registry is an in-memory registry , encoding the values like the file storage.
+type registry struct {
+	lock   *sync.Mutex
+	events map[storage.K][]string
+}
+
+func newRegistry() *registry {
+	return &registry{
+		lock:   new(sync.Mutex),
+		events: make(map[storage.K][]string),
+	}
+}
+
+func (r *registry) Add(key storage.K, value interface{}) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	b, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	r.events[key] = append(r.events[key], string(b))
+	return nil
+}
+
+func (r *registry) GetAll(key storage.K, value interface{}) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return json.Unmarshal([]byte(fmt.Sprintf("[%s]", strings.Join(r.events[key], ","))), value)
+}
+
+func (r *registry) GetFor(key storage.K, value interface{}, filter func(s string) bool) error {
+	return r.GetAll(key, value)
+}
+
+func (r *registry) Check(key storage.K) (map[string]storage.RegistryPath, error) {
+	return nil, nil
+}
+
+func (r *registry) Root() string {
+	return ""
+}
+
+var start = time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func trade(coin model.Coin, price, volume float64, t model.Type, at time.Duration) *model.TradeSignal {
+	return &model.TradeSignal{
+		Coin: coin,
+		Tick: model.NewTick(price, volume, t, start.Add(at)),
+	}
+}
+
+func TestParseTimeframe(t *testing.T) {
+	for _, tf := range Timeframes {
+		parsed, err := ParseTimeframe(tf.String())
+		assert.NoError(t, err)
+		assert.Equal(t, tf, parsed)
+	}
+	_, err := ParseTimeframe("2m")
+	assert.Error(t, err)
+	assert.Equal(t, start.Add(4*time.Hour), H4.Start(start.Add(7*time.Hour)))
+}
+
+func TestAggregator(t *testing.T) {
+
+	type test struct {
+		trades  []*model.TradeSignal
+		closed  []Candle
+		open    int
+		late    int
+		flushed Candle
+	}
+
+	tests := map[string]test{
+		"single-candle": {
+			trades: []*model.TradeSignal{
+				trade(model.BTC, 10, 1, model.Buy, 0),
+				trade(model.BTC, 12, 2, model.Sell, 10*time.Second),
+				trade(model.BTC, 9, 1, model.Buy, 20*time.Second),
+				trade(model.BTC, 11, 1, model.Sell, 50*time.Second),
+			},
+			closed: []Candle{},
+			open:   1,
+			flushed: Candle{
+				Coin: model.BTC, Timeframe: M1, Time: start,
+				Open: 10, High: 12, Low: 9, Close: 11,
+				Volume: 5, BuyVolume: 2, SellVolume: 3, Trades: 4,
+			},
+		},
+		"closing-candle": {
+			trades: []*model.TradeSignal{
+				trade(model.BTC, 10, 1, model.Buy, 0),
+				trade(model.BTC, 12, 1, model.Buy, 30*time.Second),
+				// gap of one candle
+				trade(model.BTC, 13, 1, model.Sell, 2*time.Minute+10*time.Second),
+				// late for the closed candle
+				trade(model.BTC, 15, 1, model.Buy, 59*time.Second),
+			},
+			closed: []Candle{{
+				Coin: model.BTC, Timeframe: M1, Time: start,
+				Open: 10, High: 12, Low: 10, Close: 12,
+				Volume: 2, BuyVolume: 2, Trades: 2,
+			}},
+			open: 1,
+			late: 1,
+			flushed: Candle{
+				Coin: model.BTC, Timeframe: M1, Time: start.Add(2 * time.Minute),
+				Open: 13, High: 13, Low: 13, Close: 13,
+				Volume: 1, SellVolume: 1, Trades: 1,
+			},
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			aggregator := NewAggregator(model.BTC, M1)
+			closed := make([]Candle, 0)
+			for _, trade := range tt.trades {
+				closed = append(closed, aggregator.Add(trade.Tick)...)
+			}
+			assert.Equal(t, tt.closed, closed)
+			assert.Equal(t, tt.late, aggregator.Late())
+			flushed := aggregator.Flush()
+			assert.Equal(t, tt.open, len(flushed))
+			assert.Equal(t, tt.flushed, flushed[0])
+			assert.Equal(t, 0, len(aggregator.Flush()))
+		})
+	}
+}
+
+func TestService(t *testing.T) {
+	service := NewService(newRegistry(), M1, M5)
+	subscription := service.Subscribe(model.BTC, M5)
+
+	// one trade every 30 seconds for 12 minutes
+	for i := 0; i < 24; i++ {
+		assert.NoError(t, service.Add(trade(model.BTC, float64(100+i), 1, model.Buy, time.Duration(i)*30*time.Second)))
+	}
+	assert.NoError(t, service.Add(trade(model.ETH, 10, 1, model.Sell, 0)))
+
+	candles, err := service.Query(model.BTC, M1, time.Time{}, time.Time{})
+	assert.NoError(t, err)
+	assert.Equal(t, 11, len(candles))
+	assert.Equal(t, 2, candles[0].Trades)
+	assert.Equal(t, 103.0, candles[1].Close)
+
+	candles, err = service.Query(model.BTC, M5, time.Time{}, time.Time{})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(candles))
+	assert.Equal(t, 10, candles[1].Trades)
+	assert.Equal(t, candles[0], <-subscription)
+
+	// the range is applied on the candle open time
+	candles, err = service.Query(model.BTC, M1, start.Add(2*time.Minute), start.Add(4*time.Minute))
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(candles))
+	assert.Equal(t, start.Add(2*time.Minute), candles[0].Time)
+
+	// closing does not persist the partial open candles
+	service.Close()
+	candles, err = service.Query(model.BTC, M1, time.Time{}, time.Time{})
+	assert.NoError(t, err)
+	assert.Equal(t, 11, len(candles))
+	candles, err = service.Query(model.ETH, M5, time.Time{}, time.Time{})
+	assert.NoError(t, err)
+	assert.Equal(t, 0, len(candles))
+}
+
+func TestService_Restart(t *testing.T) {
+	registry := newRegistry()
+
+	// one trade every 30 seconds for 12 minutes , then a restart
+	service := NewService(registry, M1, M5)
+	for i := 0; i < 24; i++ {
+		assert.NoError(t, service.Add(trade(model.BTC, float64(100+i), 1, model.Buy, time.Duration(i)*30*time.Second)))
+	}
+	service.Close()
+
+	// the restarted service replays the last 10 minutes , before going on with the live trades up to 20 minutes
+	service = NewService(registry, M1, M5)
+	subscription := service.Subscribe(model.BTC, M5)
+	for i := 4; i < 40; i++ {
+		assert.NoError(t, service.Add(trade(model.BTC, float64(100+i), 1, model.Buy, time.Duration(i)*30*time.Second)))
+	}
+
+	// there is one candle for each period , with all its trades
+	candles, err := service.Query(model.BTC, M1, time.Time{}, time.Time{})
+	assert.NoError(t, err)
+	assert.Equal(t, 19, len(candles))
+	for i, c := range candles {
+		assert.Equal(t, start.Add(time.Duration(i)*time.Minute), c.Time)
+		assert.Equal(t, 2, c.Trades)
+	}
+	candles, err = service.Query(model.BTC, M5, time.Time{}, time.Time{})
+	assert.NoError(t, err)
+	assert.Equal(t, 3, len(candles))
+	// the candle open on the restart is complete , the replayed trades make up for its first part
+	assert.Equal(t, 10, candles[2].Trades)
+	assert.Equal(t, 120.0, candles[2].Open)
+	assert.Equal(t, 129.0, candles[2].Close)
+	// the replayed candles are still published
+	assert.Equal(t, start, (<-subscription).Time)
+	assert.Equal(t, start.Add(5*time.Minute), (<-subscription).Time)
+
+	// the duplicates already in the store are ignored
+	assert.NoError(t, registry.Add(NewStore(registry).key(model.BTC, M1), Candle{Coin: model.BTC, Timeframe: M1, Time: start, Trades: 1}))
+	candles, err = service.Query(model.BTC, M1, time.Time{}, start.Add(time.Minute))
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(candles))
+	assert.Equal(t, 2, candles[0].Trades)
+}
+
+func TestSource(t *testing.T) {
+	service := NewService(newRegistry(), M1, M5)
+	for i := 0; i <= 20; i++ {
+		assert.NoError(t, service.Add(trade(model.BTC, float64(100+i), 1, model.Buy, time.Duration(i)*30*time.Second)))
+	}
+	service.Close()
+
+	source := NewSource(service.Store(), time.Time{}, start.Add(10*time.Minute)).
+		Subscribe(model.BTC, M1, M5)
+	process := make(chan api.Signal)
+	trades, err := source.Trades(process)
+	assert.NoError(t, err)
+
+	signals := make([]*model.TradeSignal, 0)
+	for trade := range trades {
+		signals = append(signals, trade)
+		process <- api.Signal{}
+	}
+	assert.Equal(t, 12, len(signals))
+	// the 5m candle closes after the 1m candle with the same close time
+	assert.Equal(t, time.Minute, signals[4].Meta.Interval)
+	assert.Equal(t, 5*time.Minute, signals[5].Meta.Interval)
+	assert.Equal(t, start.Add(5*time.Minute), signals[5].Tick.Time)
+	assert.Equal(t, 109.0, signals[5].Tick.Price)
+	assert.Equal(t, 10, signals[5].Meta.Size)
+	for i := 1; i < len(signals); i++ {
+		assert.False(t, signals[i].Tick.Time.Before(signals[i-1].Tick.Time))
+	}
+}
@@ -0,0 +1,146 @@
+package candle
+
+import (
+	"sync"
+	"time"
+
+	"github.com/drakos74/free-coin/internal/algo/processor"
+	"github.com/drakos74/free-coin/internal/api"
+	"github.com/drakos74/free-coin/internal/model"
+	"github.com/drakos74/free-coin/internal/storage"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// Name is the name of the candle processor.
+	Name = "candle"
+
+	subscriptionBuffer = 100
+)
+
+// Subscription identifies the candles of a coin for a timeframe.
+type Subscription struct {
+	Coin      model.Coin
+	Timeframe Timeframe
+}
+
+// Service builds the candles of the trade stream , persists them in the store ,
+// and publishes them to the subscribers as they close.
+// The candles of the periods already in the store are not persisted again ,
+// so that the trades replayed on a restart rebuild the candle that was open , without duplicating the closed ones.
+type Service struct {
+	lock        *sync.RWMutex
+	store       *Store
+	timeframes  []Timeframe
+	aggregators map[model.Coin]*Aggregator
+	subscribers map[Subscription][]chan Candle
+	stored      map[Subscription]time.Time
+}
+
+// NewService creates a new candle service on top of the registry , for all the timeframes if none are given.
+func NewService(registry storage.Registry, timeframes ...Timeframe) *Service {
+	if len(timeframes) == 0 {
+		timeframes = Timeframes
+	}
+	return &Service{
+		lock:        new(sync.RWMutex),
+		store:       NewStore(registry),
+		timeframes:  timeframes,
+		aggregators: make(map[model.Coin]*Aggregator),
+		subscribers: make(map[Subscription][]chan Candle),
+		stored:      make(map[Subscription]time.Time),
+	}
+}
+
+// Store returns the candle store of the service.
+func (s *Service) Store() *Store {
+	return s.store
+}
+
+// Subscribe returns the channel of the closed candles for the coin and timeframe.
+// Slow subscribers miss candles , rather than blocking the trade stream.
+func (s *Service) Subscribe(coin model.Coin, tf Timeframe) <-chan Candle {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	ch := make(chan Candle, subscriptionBuffer)
+	sub := Subscription{Coin: coin, Timeframe: tf}
+	s.subscribers[sub] = append(s.subscribers[sub], ch)
+	return ch
+}
+
+// Add adds the trade to the candles of its coin , and persists and publishes the closed candles.
+func (s *Service) Add(trade *model.TradeSignal) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	aggregator, ok := s.aggregators[trade.Coin]
+	if !ok {
+		aggregator = NewAggregator(trade.Coin, s.timeframes...)
+		s.aggregators[trade.Coin] = aggregator
+	}
+	return s.publish(aggregator.Add(trade.Tick))
+}
+
+// Query returns the stored candles of the coin and timeframe opening within [from , to).
+func (s *Service) Query(coin model.Coin, tf Timeframe, from, to time.Time) ([]Candle, error) {
+	return s.store.Query(coin, tf, from, to)
+}
+
+// Close closes the subscriptions.
+// The open candles are not persisted , as they are partial , the replayed trades of the next start rebuild them.
+func (s *Service) Close() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	for _, subscribers := range s.subscribers {
+		for _, ch := range subscribers {
+			close(ch)
+		}
+	}
+	s.subscribers = make(map[Subscription][]chan Candle)
+}
+
+func (s *Service) publish(candles []Candle) error {
+	var err error
+	for _, c := range candles {
+		sub := Subscription{Coin: c.Coin, Timeframe: c.Timeframe}
+		if e := s.persist(sub, c); e != nil {
+			err = e
+		}
+		for _, ch := range s.subscribers[sub] {
+			select {
+			case ch <- c:
+			default:
+				log.Warn().
+					Str("coin", string(c.Coin)).
+					Str("timeframe", c.Timeframe.String()).
+					Msg("candle subscriber is not keeping up")
+			}
+		}
+	}
+	return err
+}
+
+// persist stores the candle , unless its period is already in the store.
+func (s *Service) persist(sub Subscription, c Candle) error {
+	last, ok := s.stored[sub]
+	if !ok {
+		l, err := s.store.Last(c.Coin, c.Timeframe)
+		if err != nil {
+			return err
+		}
+		last = l
+	}
+	if !c.Time.After(last) {
+		s.stored[sub] = last
+		return nil
+	}
+	if err := s.store.Add(c); err != nil {
+		return err
+	}
+	s.stored[sub] = c.Time
+	return nil
+}
+
+// Processor feeds the trades to the candle service.
+func Processor(service *Service) api.Processor {
+	return processor.ProcessWithClose(Name, service.Add, service.Close)
+}
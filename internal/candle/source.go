@@ -0,0 +1,65 @@
+package candle
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/drakos74/free-coin/internal/api"
+	"github.com/drakos74/free-coin/internal/model"
+)
+
+// Source replays the stored candles as trade signals , so that strategies can run on several timeframes.
+// The candles of all the subscriptions are merged in close time order ,
+// and each one is emitted after the previous one has been processed.
+type Source struct {
+	store         *Store
+	from          time.Time
+	to            time.Time
+	subscriptions []Subscription
+}
+
+// NewSource creates a new candle source for the candles opening within [from , to).
+func NewSource(store *Store, from, to time.Time) *Source {
+	return &Source{
+		store:         store,
+		from:          from,
+		to:            to,
+		subscriptions: make([]Subscription, 0),
+	}
+}
+
+// Subscribe adds the timeframes of the coin to the replay.
+func (s *Source) Subscribe(coin model.Coin, timeframes ...Timeframe) *Source {
+	for _, tf := range timeframes {
+		s.subscriptions = append(s.subscriptions, Subscription{Coin: coin, Timeframe: tf})
+	}
+	return s
+}
+
+// Trades loads the candles and starts the replay.
+func (s *Source) Trades(process <-chan api.Signal) (model.TradeSource, error) {
+	candles := make([]Candle, 0)
+	for _, sub := range s.subscriptions {
+		cc, err := s.store.Query(sub.Coin, sub.Timeframe, s.from, s.to)
+		if err != nil {
+			return nil, fmt.Errorf("could not load candles: %w", err)
+		}
+		candles = append(candles, cc...)
+	}
+	sort.SliceStable(candles, func(i, j int) bool {
+		if !candles[i].End().Equal(candles[j].End()) {
+			return candles[i].End().Before(candles[j].End())
+		}
+		return candles[i].Timeframe < candles[j].Timeframe
+	})
+	trades := make(model.TradeSource)
+	go func() {
+		defer close(trades)
+		for _, c := range candles {
+			trades <- c.Signal()
+			<-process
+		}
+	}()
+	return trades, nil
+}
@@ -0,0 +1,83 @@
+package candle
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/drakos74/free-coin/internal/model"
+	"github.com/drakos74/free-coin/internal/storage"
+)
+
+const candlesPair = "candles"
+
+// Store persists the candles incrementally in the storage registry , one log per coin and timeframe.
+type Store struct {
+	registry storage.Registry
+}
+
+// NewStore creates a new candle store on top of the registry.
+func NewStore(registry storage.Registry) *Store {
+	return &Store{registry: registry}
+}
+
+func (s *Store) key(coin model.Coin, tf Timeframe) storage.K {
+	return storage.K{
+		Pair:  candlesPair,
+		Label: fmt.Sprintf("%s_%s", coin, tf),
+	}
+}
+
+// Add appends the candle to the store.
+func (s *Store) Add(candle Candle) error {
+	return s.registry.Add(s.key(candle.Coin, candle.Timeframe), candle)
+}
+
+// Last returns the open time of the latest stored candle of the coin and timeframe ,
+// or the zero time if there are none.
+func (s *Store) Last(coin model.Coin, tf Timeframe) (time.Time, error) {
+	candles, err := s.Query(coin, tf, time.Time{}, time.Time{})
+	if err != nil {
+		return time.Time{}, err
+	}
+	if len(candles) == 0 {
+		return time.Time{}, nil
+	}
+	return candles[len(candles)-1].Time, nil
+}
+
+// Query returns the candles of the coin and timeframe opening within [from , to) , ordered by time.
+// The zero times leave the range open.
+// There is one candle for each period , the one with the most trades , if the store holds more than one.
+func (s *Store) Query(coin model.Coin, tf Timeframe, from, to time.Time) ([]Candle, error) {
+	all := []Candle{{}}
+	err := s.registry.GetAll(s.key(coin, tf), &all)
+	if err != nil {
+		return nil, fmt.Errorf("could not load candles for %s %s: %w", coin, tf, err)
+	}
+	candles := make([]Candle, 0)
+	index := make(map[int64]int)
+	for _, c := range all {
+		if c.Trades == 0 {
+			continue
+		}
+		if !from.IsZero() && c.Time.Before(from) {
+			continue
+		}
+		if !to.IsZero() && !c.Time.Before(to) {
+			continue
+		}
+		if i, ok := index[c.Time.UnixNano()]; ok {
+			if c.Trades > candles[i].Trades {
+				candles[i] = c
+			}
+			continue
+		}
+		index[c.Time.UnixNano()] = len(candles)
+		candles = append(candles, c)
+	}
+	sort.SliceStable(candles, func(i, j int) bool {
+		return candles[i].Time.Before(candles[j].Time)
+	})
+	return candles, nil
+}
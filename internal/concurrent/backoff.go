@@ -0,0 +1,56 @@
+package concurrent
+
+import (
+	"math"
+	"math/rand"
+	"time"
+)
+
+// Backoff is an exponential backoff for retrying operations.
+// Min is the first delay , each consecutive delay is multiplied by Factor up to Max.
+// Jitter is the fraction of the delay that is randomised , in order to avoid synchronised retries.
+type Backoff struct {
+	Min     time.Duration
+	Max     time.Duration
+	Factor  float64
+	Jitter  float64
+	attempt int
+}
+
+// NewBackoff creates a new exponential backoff between the given min and max delays.
+func NewBackoff(min, max time.Duration) *Backoff {
+	return &Backoff{
+		Min:    min,
+		Max:    max,
+		Factor: 2,
+	}
+}
+
+// WithJitter adds a random jitter to the delays.
+func (b *Backoff) WithJitter(jitter float64) *Backoff {
+	b.Jitter = jitter
+	return b
+}
+
+// Next returns the next delay and increments the attempts.
+func (b *Backoff) Next() time.Duration {
+	d := float64(b.Min) * math.Pow(b.Factor, float64(b.attempt))
+	if d > float64(b.Max) || math.IsInf(d, 0) {
+		d = float64(b.Max)
+	}
+	b.attempt++
+	if b.Jitter > 0 {
+		d = d * (1 - b.Jitter*rand.Float64())
+	}
+	return time.Duration(d)
+}
+
+// Attempt returns the number of attempts since the last reset.
+func (b *Backoff) Attempt() int {
+	return b.attempt
+}
+
+// Reset resets the backoff to the initial delay.
+func (b *Backoff) Reset() {
+	b.attempt = 0
+}
@@ -0,0 +1,53 @@
+package concurrent
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBackoff_Next(t *testing.T) {
+
+	type test struct {
+		backoff *Backoff
+		delays  []time.Duration
+	}
+
+	tests := map[string]test{
+		"exponential": {
+			backoff: NewBackoff(time.Second, time.Minute),
+			delays:  []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 8 * time.Second},
+		},
+		"capped": {
+			backoff: NewBackoff(time.Second, 3*time.Second),
+			delays:  []time.Duration{time.Second, 2 * time.Second, 3 * time.Second, 3 * time.Second},
+		},
+		"factor": {
+			backoff: &Backoff{Min: time.Second, Max: time.Hour, Factor: 3},
+			delays:  []time.Duration{time.Second, 3 * time.Second, 9 * time.Second},
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			for i, d := range tt.delays {
+				assert.Equal(t, d, tt.backoff.Next())
+				assert.Equal(t, i+1, tt.backoff.Attempt())
+			}
+			tt.backoff.Reset()
+			assert.Equal(t, 0, tt.backoff.Attempt())
+			assert.Equal(t, tt.delays[0], tt.backoff.Next())
+		})
+	}
+}
+
+func TestBackoff_Jitter(t *testing.T) {
+	b := NewBackoff(time.Second, time.Minute).WithJitter(0.5)
+	for i := 0; i < 100; i++ {
+		b.Reset()
+		d := b.Next()
+		assert.True(t, d <= time.Second)
+		assert.True(t, d >= 500*time.Millisecond)
+	}
+}
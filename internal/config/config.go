@@ -0,0 +1,321 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/drakos74/free-coin/internal/account"
+	"github.com/drakos74/free-coin/internal/algo/processor/ml/feature"
+	mlmodel "github.com/drakos74/free-coin/internal/algo/processor/ml/model"
+	"github.com/drakos74/free-coin/internal/api"
+	"github.com/drakos74/free-coin/internal/model"
+	"github.com/drakos74/free-coin/internal/storage"
+	json_storage "github.com/drakos74/free-coin/internal/storage/file/json"
+	cointime "github.com/drakos74/free-coin/internal/time"
+	"github.com/drakos74/free-coin/internal/trader"
+)
+
+// Version is the current version of the config schema.
+const Version = 1
+
+const (
+	// JsonBackend stores everything in json files under the storage path.
+	JsonBackend = "json"
+	// MemoryBackend keeps the state in memory only.
+	MemoryBackend = "memory"
+	// VoidBackend does not store anything.
+	VoidBackend = "void"
+)
+
+// Config is the configuration for the whole bot.
+// Coins defines the coins to trade , and if the ml segments for each of them are live.
+// Segments overrides the default ml segments for specific coins.
+// Accounts are the exchange accounts to trade on , the first one is the default account.
+type Config struct {
+	Version  int                 `json:"version"`
+	Coins    map[model.Coin]bool `json:"coins"`
+	Segments []Segment           `json:"segments"`
+	Trader   Trader              `json:"trader"`
+	Risk     Risk                `json:"risk"`
+	Storage  Storage             `json:"storage"`
+	User     User                `json:"user"`
+	Accounts []account.Details   `json:"accounts"`
+	Source   Source              `json:"source"`
+	Option   Option              `json:"option"`
+	Buffer   Buffer              `json:"buffer"`
+}
+
+// Segment is the ml configuration for a coin and interval.
+type Segment struct {
+	Coin     model.Coin        `json:"coin"`
+	Duration cointime.Duration `json:"duration"`
+	Stats    mlmodel.Stats     `json:"stats"`
+	Trader   mlmodel.Trader    `json:"trader"`
+}
+
+// Trader defines the position sizing and closing thresholds.
+type Trader struct {
+	OpenValue  float64    `json:"open_value"`
+	StopLoss   float64    `json:"stop_loss"`
+	TakeProfit float64    `json:"take_profit"`
+	Tracking   []Tracking `json:"tracking"`
+}
+
+// Tracking defines the trend tracking for the open positions.
+type Tracking struct {
+	Duration  cointime.Duration `json:"duration"`
+	Samples   int               `json:"samples"`
+	Threshold []float64         `json:"threshold"`
+}
+
+// Risk defines the risk limits for the traders , zero values mean no limit.
+// MaxPositions is the max number of open positions.
+// MaxExposure is the max total value of the open positions.
+type Risk struct {
+	MaxPositions int     `json:"max_positions"`
+	MaxExposure  float64 `json:"max_exposure"`
+}
+
+// Storage defines the storage backend.
+type Storage struct {
+	Backend string `json:"backend"`
+	Path    string `json:"path"`
+}
+
+// User defines the user channel.
+type User struct {
+	Bot api.Index `json:"bot"`
+}
+
+// Source defines the trade source.
+// Book is the order book depth , zero means no book subscription.
+// WarmUp is the history to replay before going live.
+type Source struct {
+	Live   bool              `json:"live"`
+	Book   int               `json:"book"`
+	WarmUp cointime.Duration `json:"warm_up"`
+}
+
+// Option defines the running options.
+type Option struct {
+	Trace     []string `json:"trace"`
+	Log       bool     `json:"log"`
+	Debug     bool     `json:"debug"`
+	Benchmark bool     `json:"benchmark"`
+}
+
+// Buffer defines the aggregation intervals.
+type Buffer struct {
+	Interval cointime.Duration `json:"interval"`
+	Segment  cointime.Duration `json:"segment"`
+}
+
+// Load loads and validates the config from the given file.
+func Load(file string) (*Config, error) {
+	dat, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("could not read config file '%s': %w", file, err)
+	}
+	cfg, err := Parse(dat)
+	if err != nil {
+		return nil, fmt.Errorf("invalid config file '%s': %w", file, err)
+	}
+	return cfg, nil
+}
+
+// Parse parses and validates the config ,
+// after replacing the '${VAR}' references with the corresponding environment variables.
+func Parse(dat []byte) (*Config, error) {
+	dat, err := Expand(dat, os.LookupEnv)
+	if err != nil {
+		return nil, err
+	}
+	var cfg Config
+	decoder := json.NewDecoder(strings.NewReader(string(dat)))
+	decoder.DisallowUnknownFields()
+	err = decoder.Decode(&cfg)
+	if err != nil {
+		return nil, fmt.Errorf("could not decode config: %w", err)
+	}
+	err = cfg.Validate()
+	if err != nil {
+		return nil, err
+	}
+	return &cfg, nil
+}
+
+var envVar = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)}`)
+
+// Expand replaces the '${VAR}' references with the values from the lookup.
+// It fails if any of the variables is not defined.
+func Expand(dat []byte, lookup func(key string) (string, bool)) ([]byte, error) {
+	missing := make([]string, 0)
+	expanded := envVar.ReplaceAllFunc(dat, func(b []byte) []byte {
+		key := string(envVar.FindSubmatch(b)[1])
+		value, ok := lookup(key)
+		if !ok {
+			missing = append(missing, key)
+			return b
+		}
+		// make sure we dont break the json
+		quoted, _ := json.Marshal(value)
+		return quoted[1 : len(quoted)-1]
+	})
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("missing environment variables: %s", strings.Join(missing, ","))
+	}
+	return expanded, nil
+}
+
+// Validate checks the config and returns all the errors found.
+func (c *Config) Validate() error {
+	errs := make([]error, 0)
+	invalid := func(field string, format string, args ...interface{}) {
+		errs = append(errs, fmt.Errorf("%s: %s", field, fmt.Sprintf(format, args...)))
+	}
+	if c.Version != Version {
+		invalid("version", "unsupported version %d , expected %d", c.Version, Version)
+	}
+	if len(c.Coins) == 0 {
+		invalid("coins", "no coins defined")
+	}
+	for coin := range c.Coins {
+		if coin == model.NoCoin {
+			invalid("coins", "empty coin")
+		}
+	}
+	for i, s := range c.Segments {
+		field := fmt.Sprintf("segments[%d]", i)
+		if _, ok := c.Coins[s.Coin]; !ok {
+			invalid(field+".coin", "'%s' is not one of the coins", s.Coin)
+		}
+		if s.Duration.Duration <= 0 {
+			invalid(field+".duration", "must be positive")
+		}
+		if len(s.Stats.Model) == 0 {
+			invalid(field+".stats.model", "no models defined")
+		}
+		if len(s.Stats.In) > 0 {
+			if _, err := feature.NewPipeline(s.Stats, s.Stats.In...); err != nil {
+				invalid(field+".stats.in", "%v", err)
+			}
+		}
+		if len(s.Stats.Out) > 0 {
+			if _, err := feature.NewPipeline(s.Stats, s.Stats.Out...); err != nil {
+				invalid(field+".stats.out", "%v", err)
+			}
+		}
+	}
+	if c.Trader.OpenValue <= 0 {
+		invalid("trader.open_value", "must be positive")
+	}
+	if c.Trader.StopLoss < 0 {
+		invalid("trader.stop_loss", "must not be negative")
+	}
+	if c.Trader.TakeProfit < 0 {
+		invalid("trader.take_profit", "must not be negative")
+	}
+	for i, t := range c.Trader.Tracking {
+		if t.Duration.Duration <= 0 {
+			invalid(fmt.Sprintf("trader.tracking[%d].duration", i), "must be positive")
+		}
+	}
+	if c.Risk.MaxPositions < 0 {
+		invalid("risk.max_positions", "must not be negative")
+	}
+	if c.Risk.MaxExposure < 0 {
+		invalid("risk.max_exposure", "must not be negative")
+	}
+	switch c.Storage.Backend {
+	case JsonBackend, MemoryBackend, VoidBackend:
+	default:
+		invalid("storage.backend", "unknown backend '%s' , expected one of %s", c.Storage.Backend,
+			strings.Join([]string{JsonBackend, MemoryBackend, VoidBackend}, ","))
+	}
+	if c.User.Bot == "" {
+		invalid("user.bot", "no bot defined")
+	}
+	if len(c.Accounts) == 0 {
+		invalid("accounts", "no accounts defined")
+	}
+	if err := account.Validate(c.Accounts...); err != nil {
+		invalid("accounts", "%v", err)
+	}
+	if c.Source.Book < 0 {
+		invalid("source.book", "must not be negative")
+	}
+	if c.Buffer.Interval.Duration <= 0 {
+		invalid("buffer.interval", "must be positive")
+	}
+	if c.Buffer.Segment.Duration <= 0 {
+		invalid("buffer.segment", "must be positive")
+	}
+	return errors.Join(errs...)
+}
+
+// CoinList returns the configured coins in a stable order.
+func (c *Config) CoinList() []model.Coin {
+	coins := make([]model.Coin, 0, len(c.Coins))
+	for coin := range c.Coins {
+		coins = append(coins, coin)
+	}
+	sort.Slice(coins, func(i, j int) bool {
+		return coins[i] < coins[j]
+	})
+	return coins
+}
+
+// TrackingConfig returns the position tracking config.
+func (c *Config) TrackingConfig() []*model.TrackingConfig {
+	tracking := make([]*model.TrackingConfig, len(c.Trader.Tracking))
+	for i, t := range c.Trader.Tracking {
+		tracking[i] = &model.TrackingConfig{
+			Duration:  t.Duration.Duration,
+			Samples:   t.Samples,
+			Threshold: t.Threshold,
+		}
+	}
+	return tracking
+}
+
+// Settings returns the trader settings , including the risk limits.
+func (c *Config) Settings() trader.Settings {
+	return c.Risk.Apply(trader.Settings{
+		OpenValue:      c.Trader.OpenValue,
+		TakeProfit:     c.Trader.TakeProfit,
+		StopLoss:       c.Trader.StopLoss,
+		TrackingConfig: c.TrackingConfig(),
+	})
+}
+
+// Apply applies the risk limits to the given trader settings.
+func (r Risk) Apply(settings trader.Settings) trader.Settings {
+	settings.MaxPositions = r.MaxPositions
+	settings.MaxExposure = r.MaxExposure
+	return settings
+}
+
+// Shard returns the storage shard for the given table.
+func (c *Config) Shard(table string) storage.Shard {
+	switch c.Storage.Backend {
+	case MemoryBackend:
+		return json_storage.LocalShard()
+	case VoidBackend:
+		return storage.VoidShard(table)
+	}
+	return json_storage.BlobShard(table)
+}
+
+// Registry returns the event registry for the given path.
+func (c *Config) Registry(path string) storage.EventRegistry {
+	switch c.Storage.Backend {
+	case MemoryBackend, VoidBackend:
+		return storage.MockEventRegistry()
+	}
+	return json_storage.EventRegistry(path)
+}
@@ -0,0 +1,153 @@
+package config
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/drakos74/free-coin/internal/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoad(t *testing.T) {
+	t.Setenv("FREE_COIN_BOT", "free_coin")
+	t.Setenv("FREE_COIN_ACCOUNT", "drakos")
+
+	cfg, err := Load("../../infra/config/sample-config.json")
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, []model.Coin{model.BTC, model.ETH, model.SOL}, cfg.CoinList())
+	assert.False(t, cfg.Coins[model.SOL])
+	assert.Equal(t, 15*time.Minute, cfg.Segments[0].Duration.Duration)
+	assert.Equal(t, 2, len(cfg.Segments[0].Stats.Model))
+	assert.Equal(t, "free_coin", string(cfg.User.Bot))
+	assert.Equal(t, "drakos", string(cfg.Accounts[0].Name))
+	assert.Equal(t, 48*time.Hour, cfg.Source.WarmUp.Duration)
+
+	settings := cfg.Settings()
+	assert.Equal(t, 100.0, settings.OpenValue)
+	assert.Equal(t, 5, settings.MaxPositions)
+	assert.Equal(t, 30*time.Second, settings.TrackingConfig[0].Duration)
+
+	// the main config should also be valid
+	_, err = Load("../../free-coin.json")
+	assert.NoError(t, err)
+}
+
+func TestExpand(t *testing.T) {
+
+	type test struct {
+		in  string
+		env map[string]string
+		out string
+		err bool
+	}
+
+	tests := map[string]test{
+		"no-vars": {
+			in:  `{"bot":"free_coin"}`,
+			out: `{"bot":"free_coin"}`,
+		},
+		"replace": {
+			in:  `{"bot":"${BOT}","key":"${KEY}"}`,
+			env: map[string]string{"BOT": "free_coin", "KEY": "secret"},
+			out: `{"bot":"free_coin","key":"secret"}`,
+		},
+		"escape": {
+			in:  `{"key":"${KEY}"}`,
+			env: map[string]string{"KEY": `a"b`},
+			out: `{"key":"a\"b"}`,
+		},
+		"missing": {
+			in:  `{"bot":"${BOT}","key":"${KEY}"}`,
+			env: map[string]string{"BOT": "free_coin"},
+			err: true,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			out, err := Expand([]byte(tt.in), func(key string) (string, bool) {
+				v, ok := tt.env[key]
+				return v, ok
+			})
+			if tt.err {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.out, string(out))
+		})
+	}
+}
+
+func TestConfig_Validate(t *testing.T) {
+	dat, err := os.ReadFile("../../free-coin.json")
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	type test struct {
+		update func(cfg *Config)
+		errs   []string
+	}
+
+	tests := map[string]test{
+		"valid": {
+			update: func(cfg *Config) {},
+		},
+		"version": {
+			update: func(cfg *Config) {
+				cfg.Version = 0
+			},
+			errs: []string{"version: unsupported version 0"},
+		},
+		"segments": {
+			update: func(cfg *Config) {
+				cfg.Segments = []Segment{{Coin: model.KAVA}}
+			},
+			errs: []string{
+				"segments[0].coin: 'KAVA' is not one of the coins",
+				"segments[0].duration: must be positive",
+				"segments[0].stats.model: no models defined",
+			},
+		},
+		"trader-and-storage": {
+			update: func(cfg *Config) {
+				cfg.Trader.OpenValue = 0
+				cfg.Storage.Backend = "s3"
+			},
+			errs: []string{
+				"trader.open_value: must be positive",
+				"storage.backend: unknown backend 's3'",
+			},
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			cfg, err := Parse(dat)
+			if !assert.NoError(t, err) {
+				return
+			}
+			tt.update(cfg)
+			err = cfg.Validate()
+			if len(tt.errs) == 0 {
+				assert.NoError(t, err)
+				return
+			}
+			if !assert.Error(t, err) {
+				return
+			}
+			for _, e := range tt.errs {
+				assert.Contains(t, err.Error(), e)
+			}
+		})
+	}
+
+	_, err = Parse([]byte(`{"version":1,"unknown":true}`))
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "unknown")
+	}
+}
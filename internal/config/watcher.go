@@ -0,0 +1,202 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/drakos74/free-coin/internal/api"
+	"github.com/rs/zerolog/log"
+)
+
+const defaultWatchInterval = 30 * time.Second
+
+// Reload applies the new config to the running bot and returns a report of the applied changes.
+// It is also called with the arguments swapped , to roll back the new config if a later listener fails.
+type Reload func(current, next *Config) (string, error)
+
+// Check checks if the new config can be applied to the running bot , without applying it.
+type Check func(current, next *Config) error
+
+// Watcher watches the config file and applies the changes to the running bot ,
+// either when the file changes or on the user '?cfg reload' command.
+// Only the parts that can change at runtime are applied through the reload listeners ,
+// the rest is reported as requiring a restart.
+type Watcher struct {
+	lock     *sync.Mutex
+	file     string
+	interval time.Duration
+	modified time.Time
+	current  *Config
+	check    []Check
+	reload   []Reload
+}
+
+// NewWatcher creates a new watcher for the given file and the currently running config.
+func NewWatcher(file string, current *Config) *Watcher {
+	w := &Watcher{
+		lock:     new(sync.Mutex),
+		file:     file,
+		interval: defaultWatchInterval,
+		current:  current,
+		check:    make([]Check, 0),
+		reload:   make([]Reload, 0),
+	}
+	if info, err := os.Stat(file); err == nil {
+		w.modified = info.ModTime()
+	}
+	return w
+}
+
+// WithInterval sets the polling interval for the file changes.
+func (w *Watcher) WithInterval(interval time.Duration) *Watcher {
+	w.interval = interval
+	return w
+}
+
+// OnCheck adds a check , that has to pass before any of the reload listeners is notified.
+func (w *Watcher) OnCheck(check Check) *Watcher {
+	w.check = append(w.check, check)
+	return w
+}
+
+// OnReload adds a reload listener.
+func (w *Watcher) OnReload(reload Reload) *Watcher {
+	w.reload = append(w.reload, reload)
+	return w
+}
+
+// Current returns the currently running config.
+func (w *Watcher) Current() *Config {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	return w.current
+}
+
+// Reload loads the config file and applies it.
+// If the new config is not valid , or any of the checks fails , the running config is kept
+// and none of the reload listeners is notified.
+// If any of the reload listeners fails , the listeners before it roll back to the running config , which is kept.
+func (w *Watcher) Reload() (string, error) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	next, err := Load(w.file)
+	if err != nil {
+		return "", err
+	}
+	for _, check := range w.check {
+		if err := check(w.current, next); err != nil {
+			return "", fmt.Errorf("could not apply config: %w", err)
+		}
+	}
+	report := new(strings.Builder)
+	for i, reload := range w.reload {
+		r, err := reload(w.current, next)
+		if err != nil {
+			if rollbackErr := w.rollback(w.reload[:i], next); rollbackErr != nil {
+				return report.String(), fmt.Errorf("could not apply config: %w , could not roll back: %v", err, rollbackErr)
+			}
+			return "", fmt.Errorf("could not apply config: %w", err)
+		}
+		report.WriteString(r)
+	}
+	if sections := RestartRequired(w.current, next); len(sections) > 0 {
+		report.WriteString(fmt.Sprintf("\nrestart required for: %s", strings.Join(sections, ",")))
+	}
+	w.current = next
+	return report.String(), nil
+}
+
+// rollback re-applies the running config to the given listeners , in reverse order , after they applied the next one.
+func (w *Watcher) rollback(reloads []Reload, next *Config) error {
+	for i := len(reloads) - 1; i >= 0; i-- {
+		if _, err := reloads[i](next, w.current); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// changed checks if the config file has been modified since the last check.
+func (w *Watcher) changed() (bool, error) {
+	info, err := os.Stat(w.file)
+	if err != nil {
+		return false, fmt.Errorf("could not check config file '%s': %w", w.file, err)
+	}
+	if !info.ModTime().After(w.modified) {
+		return false, nil
+	}
+	w.modified = info.ModTime()
+	return true, nil
+}
+
+// Run starts watching the config file and listening for the user commands.
+func (w *Watcher) Run(ctx context.Context, index api.Index, u api.User) {
+	go w.listen(index, u)
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			changed, err := w.changed()
+			if err != nil {
+				log.Warn().Err(err).Msg("could not watch config")
+				continue
+			}
+			if changed {
+				report, err := w.Reload()
+				api.Reply(index, u, api.NewMessage(fmt.Sprintf("[cfg] %s changed\n%s", w.file, report)), err)
+			}
+		}
+	}
+}
+
+func (w *Watcher) listen(index api.Index, u api.User) {
+	for command := range u.Listen("cfg", "?cfg") {
+		var action string
+		_, err := command.Validate(
+			api.AnyUser(),
+			api.Contains("?cfg"),
+			api.OneOf(&action, "reload", ""),
+		)
+		if err != nil {
+			api.Reply(index, u, api.NewMessage("[cmd error]").ReplyTo(command.ID), err)
+			continue
+		}
+		switch action {
+		case "reload":
+			report, err := w.Reload()
+			api.Reply(index, u, api.NewMessage(fmt.Sprintf("[cfg] reload\n%s", report)).ReplyTo(command.ID), err)
+		default:
+			api.Reply(index, u, api.NewMessage(fmt.Sprintf("[cfg] %s\nversion:%d coins:%d segments:%d",
+				w.file,
+				w.Current().Version,
+				len(w.Current().Coins),
+				len(w.Current().Segments))).ReplyTo(command.ID), nil)
+		}
+	}
+}
+
+// RestartRequired returns the config sections that changed , but cannot be applied at runtime.
+func RestartRequired(current, next *Config) []string {
+	sections := make([]string, 0)
+	check := func(name string, a, b interface{}) {
+		if !reflect.DeepEqual(a, b) {
+			sections = append(sections, name)
+		}
+	}
+	check("trader", current.Trader, next.Trader)
+	check("risk", current.Risk, next.Risk)
+	check("storage", current.Storage, next.Storage)
+	check("user", current.User, next.User)
+	check("accounts", current.Accounts, next.Accounts)
+	check("source", current.Source, next.Source)
+	check("buffer", current.Buffer, next.Buffer)
+	return sections
+}
@@ -0,0 +1,162 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/drakos74/free-coin/internal/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWatcher_Reload(t *testing.T) {
+	dat, err := os.ReadFile("../../free-coin.json")
+	if !assert.NoError(t, err) {
+		return
+	}
+	file := filepath.Join(t.TempDir(), "config.json")
+	if !assert.NoError(t, os.WriteFile(file, dat, 0644)) {
+		return
+	}
+	current, err := Load(file)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	reloads := 0
+	var checkErr error
+	w := NewWatcher(file, current).OnCheck(func(current, next *Config) error {
+		return checkErr
+	}).OnReload(func(current, next *Config) (string, error) {
+		reloads++
+		return "applied", nil
+	})
+
+	changed, err := w.changed()
+	assert.NoError(t, err)
+	assert.False(t, changed)
+
+	// an invalid config is not applied
+	modified := time.Now().Add(time.Minute)
+	assert.NoError(t, os.WriteFile(file, []byte(`{"version":0}`), 0644))
+	assert.NoError(t, os.Chtimes(file, modified, modified))
+	changed, err = w.changed()
+	assert.NoError(t, err)
+	assert.True(t, changed)
+	_, err = w.Reload()
+	assert.Error(t, err)
+	assert.Equal(t, 0, reloads)
+	assert.Equal(t, current, w.Current())
+
+	// a valid config that fails the checks is not applied
+	assert.NoError(t, os.WriteFile(file, dat, 0644))
+	assert.NoError(t, os.Chtimes(file, modified, modified))
+	checkErr = fmt.Errorf("coin is not tradable")
+	_, err = w.Reload()
+	assert.Error(t, err)
+	assert.Equal(t, 0, reloads)
+	assert.Equal(t, current, w.Current())
+
+	// a valid config is applied
+	checkErr = nil
+	report, err := w.Reload()
+	assert.NoError(t, err)
+	assert.Equal(t, 1, reloads)
+	assert.Contains(t, report, "applied")
+	assert.NotContains(t, report, "restart required")
+
+	changed, err = w.changed()
+	assert.NoError(t, err)
+	assert.False(t, changed)
+}
+
+func TestWatcher_ReloadRollback(t *testing.T) {
+	dat, err := os.ReadFile("../../free-coin.json")
+	if !assert.NoError(t, err) {
+		return
+	}
+	file := filepath.Join(t.TempDir(), "config.json")
+	if !assert.NoError(t, os.WriteFile(file, dat, 0644)) {
+		return
+	}
+	current, err := Load(file)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	// the applied config of the first listener
+	var applied *Config
+	var reloadErr error
+	w := NewWatcher(file, current).OnReload(func(current, next *Config) (string, error) {
+		applied = next
+		return "applied", nil
+	}).OnReload(func(current, next *Config) (string, error) {
+		return "", reloadErr
+	})
+
+	// a failing listener rolls back the listeners before it
+	reloadErr = fmt.Errorf("could not set coins")
+	_, err = w.Reload()
+	assert.Error(t, err)
+	assert.Same(t, current, applied)
+	assert.Same(t, current, w.Current())
+
+	// the config is applied once all the listeners succeed
+	reloadErr = nil
+	report, err := w.Reload()
+	assert.NoError(t, err)
+	assert.Contains(t, report, "applied")
+	assert.NotSame(t, current, w.Current())
+	assert.Same(t, w.Current(), applied)
+}
+
+func TestRestartRequired(t *testing.T) {
+	dat, err := os.ReadFile("../../free-coin.json")
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	type test struct {
+		update   func(cfg *Config)
+		sections []string
+	}
+
+	tests := map[string]test{
+		"none": {
+			update:   func(cfg *Config) {},
+			sections: []string{},
+		},
+		"coins-segments-and-options": {
+			update: func(cfg *Config) {
+				cfg.Segments = nil
+				cfg.Option.Debug = !cfg.Option.Debug
+				cfg.Coins[model.KAVA] = true
+			},
+			sections: []string{},
+		},
+		"trader-and-storage": {
+			update: func(cfg *Config) {
+				cfg.Trader.OpenValue++
+				cfg.Storage.Backend = VoidBackend
+			},
+			sections: []string{"trader", "storage"},
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			current, err := Parse(dat)
+			if !assert.NoError(t, err) {
+				return
+			}
+			next, err := Parse(dat)
+			if !assert.NoError(t, err) {
+				return
+			}
+			tt.update(next)
+			assert.Equal(t, tt.sections, RestartRequired(current, next))
+		})
+	}
+}
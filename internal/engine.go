@@ -6,12 +6,15 @@ import (
 
 	"github.com/drakos74/free-coin/internal/api"
 	"github.com/drakos74/free-coin/internal/model"
+	cointime "github.com/drakos74/free-coin/internal/time"
 	"github.com/rs/zerolog/log"
 )
 
 type Engine struct {
 	source     api.Client
+	clock      cointime.Clock
 	processors []api.Processor
+	recall     chan api.Signal
 	count      map[model.Coin]int64
 	lost       map[model.Coin]int64
 }
@@ -19,20 +22,40 @@ type Engine struct {
 func NewEngine(client api.Client) (*Engine, error) {
 	return &Engine{
 		source:     client,
+		clock:      cointime.Current(),
 		processors: make([]api.Processor, 0),
+		recall:     make(chan api.Signal),
 		count:      make(map[model.Coin]int64),
 		lost:       make(map[model.Coin]int64),
 	}, nil
 }
 
+// WithClock sets the clock of the engine.
+// A simulated clock is advanced with the trade stream , so that the timers fire in the trade time.
+// The processors that should follow it need to be given the same clock e.g. through their config.
+func (e *Engine) WithClock(clock cointime.Clock) *Engine {
+	e.clock = clock
+	return e
+}
+
+// Clock returns the clock of the engine.
+func (e *Engine) Clock() cointime.Clock {
+	return e.clock
+}
+
 func (e *Engine) AddProcessor(processor api.Processor) *Engine {
 	e.processors = append(e.processors, processor)
 	return e
 }
 
+// Skip signals to the source that a trade dropped by a processor is done ,
+// as it will never reach the end of the pipeline.
+func (e *Engine) Skip(trade *model.TradeSignal) {
+	e.recall <- *api.NewSignal("engine-skipped").ForCoin(trade.Coin)
+}
+
 func (e *Engine) Run() error {
-	recall := make(chan api.Signal)
-	source, err := e.source.Trades(recall)
+	source, err := e.source.Trades(e.recall)
 	if err != nil {
 		return fmt.Errorf("could not start client: %w", err)
 	}
@@ -57,7 +80,7 @@ func (e *Engine) Run() error {
 		// TODO : add metrics for count and lost
 		//fmt.Printf("[%s] = [ %+v , %+v ] \n", trade.Coin, e.count[trade.Coin], e.lost[trade.Coin])
 		// signal to the source we are done processing this one
-		recall <- *api.NewSignal("engine-processed").ForCoin(trade.Coin)
+		e.recall <- *api.NewSignal("engine-processed").ForCoin(trade.Coin)
 	}
 
 	for coin := range e.count {
@@ -100,6 +123,10 @@ func (e *Engine) first() api.Processor {
 			}
 			e.count[trade.Coin] = c
 			e.lost[trade.Coin] = l
+			// move the simulated time along with the trades
+			if clock, ok := e.clock.(cointime.Simulator); ok {
+				clock.Advance(trade.Tick.Time)
+			}
 			// pass over to the next processor
 			out <- trade
 		}
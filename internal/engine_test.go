@@ -2,12 +2,19 @@ package coin
 
 import (
 	"log"
+	"os"
 	"testing"
 	"time"
 
 	"github.com/drakos74/free-coin/client/kraken"
+	clientlocal "github.com/drakos74/free-coin/client/local"
+	"github.com/drakos74/free-coin/internal/algo/processor/rule"
 	"github.com/drakos74/free-coin/internal/api"
 	"github.com/drakos74/free-coin/internal/model"
+	"github.com/drakos74/free-coin/internal/storage"
+	cointime "github.com/drakos74/free-coin/internal/time"
+	"github.com/drakos74/free-coin/user/local"
+	"github.com/stretchr/testify/assert"
 )
 
 func TestEngine_Run(t *testing.T) {
@@ -29,3 +36,99 @@ func TestEngine_Run(t *testing.T) {
 	}
 
 }
+
+// tradeClient emits the given trades , waiting for each one to be processed.
+type tradeClient struct {
+	trades []*model.TradeSignal
+}
+
+func (c tradeClient) Trades(process <-chan api.Signal) (model.TradeSource, error) {
+	source := make(model.TradeSource)
+	go func() {
+		for _, trade := range c.trades {
+			source <- trade
+			<-process
+		}
+		close(source)
+	}()
+	return source, nil
+}
+
+func TestEngine_RunWithSimClock(t *testing.T) {
+
+	start := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	trades := make([]*model.TradeSignal, 0)
+	for i := 0; i < 10; i++ {
+		trades = append(trades, &model.TradeSignal{
+			Coin: model.BTC,
+			Tick: model.Tick{Time: start.Add(time.Duration(i) * time.Hour)},
+		})
+	}
+
+	clock := cointime.NewSimClock(start)
+	engine, err := NewEngine(tradeClient{trades: trades})
+	if err != nil {
+		log.Fatalf("error creating engine: %s", err.Error())
+	}
+	engine.WithClock(clock)
+	ticker := clock.NewTicker(90 * time.Minute)
+
+	err = engine.Run()
+	if err != nil {
+		log.Fatalf("error running engine: %s", err.Error())
+	}
+	// the clock follows the trades , without waiting for the wall clock
+	assert.Equal(t, start.Add(9*time.Hour), clock.Now())
+	assert.Equal(t, clock, engine.Clock())
+	assert.Equal(t, 1, len(ticker.C()))
+}
+
+func TestEngine_BackTest(t *testing.T) {
+
+	start := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	trades := make([]*model.TradeSignal, 0)
+	for i := 0; i < 180; i++ {
+		tt := start.Add(time.Duration(i) * time.Minute)
+		trades = append(trades, &model.TradeSignal{
+			Coin: model.BTC,
+			Tick: model.NewTick(100+float64(i%20), 1, model.Buy, tt),
+			Meta: model.Meta{Time: tt},
+		})
+	}
+
+	clock := cointime.NewSimClock(start)
+	engine, err := NewEngine(tradeClient{trades: trades})
+	if err != nil {
+		log.Fatalf("error creating engine: %s", err.Error())
+	}
+	engine.WithClock(clock)
+
+	u, err := local.NewUser("")
+	if err != nil {
+		log.Fatalf("error creating user: %s", err.Error())
+	}
+	// the default strategy user and exchange log to local files
+	defer os.Remove(rule.Name)
+	defer os.Remove("temp.log")
+	config := rule.DefaultConfig(false, model.BTC).NoLive().WithClock(engine.Clock())
+	config.Position.Paper = true
+	engine.AddProcessor(NewStrategy(rule.Name).
+		ForUser(u).
+		ForExchange(clientlocal.NewPaper("backtest", 1000)).
+		WithProcessor(rule.Processor("backtest", storage.VoidShard(rule.Name), func(path string) (storage.Registry, error) {
+			return storage.NewVoidRegistry(), nil
+		}, config)).
+		Apply())
+
+	done := make(chan error)
+	go func() {
+		done <- engine.Run()
+	}()
+	select {
+	case err := <-done:
+		assert.NoError(t, err)
+	case <-time.After(10 * time.Second):
+		t.Fatal("back-test did not finish on the simulated clock")
+	}
+	assert.Equal(t, start.Add(179*time.Minute), clock.Now())
+}
@@ -0,0 +1,99 @@
+package drift
+
+import (
+	"math"
+)
+
+// Config defines the drift detection configuration.
+// Delta is the magnitude of changes that are tolerated , before accumulating as drift.
+// Threshold is the accumulated deviation from the mean , above which drift is detected.
+// Alpha is the forgetting factor for the accumulated deviation , 1 means no forgetting.
+// MinSamples is the number of samples needed before any drift can be detected.
+// Restart defines if the model should be restarted on drift , from the last checkpoint if the model saves its state.
+// Checkpoint is the number of samples without drift , after which the model state is saved.
+// Disabled turns the drift detection off , as an unset config falls back to the defaults.
+type Config struct {
+	Delta      float64 `json:"delta"`
+	Threshold  float64 `json:"threshold"`
+	Alpha      float64 `json:"alpha"`
+	MinSamples int     `json:"min_samples"`
+	Restart    bool    `json:"restart"`
+	Checkpoint int     `json:"checkpoint"`
+	Disabled   bool    `json:"disabled"`
+}
+
+// DefaultConfig is the default drift detection config for loss values in the range [0,1].
+func DefaultConfig() Config {
+	return Config{
+		Delta:      0.05,
+		Threshold:  5,
+		Alpha:      0.999,
+		MinSamples: 30,
+		Restart:    true,
+		Checkpoint: 50,
+	}
+}
+
+// OrDefault returns the default config , if the config is not set and not disabled.
+func (c Config) OrDefault() Config {
+	if c.Threshold == 0 && !c.Disabled {
+		return DefaultConfig()
+	}
+	return c
+}
+
+// PageHinkley is a streaming Page-Hinkley test for detecting an increase in the mean of the observed values.
+// It is meant to track the loss of a model , so that an increase signals the degradation of the model.
+type PageHinkley struct {
+	config Config
+	count  int
+	mean   float64
+	sum    float64
+	min    float64
+}
+
+// NewPageHinkley creates a new Page-Hinkley drift detector.
+func NewPageHinkley(config Config) *PageHinkley {
+	ph := &PageHinkley{config: config}
+	ph.Reset()
+	return ph
+}
+
+// Add adds a new observation and returns true if drift has been detected.
+// The detector is reset after a detection.
+func (ph *PageHinkley) Add(x float64) bool {
+	ph.count++
+	ph.mean += (x - ph.mean) / float64(ph.count)
+	alpha := ph.config.Alpha
+	if alpha == 0 {
+		alpha = 1
+	}
+	ph.sum = alpha*ph.sum + (x - ph.mean - ph.config.Delta)
+	ph.min = math.Min(ph.min, ph.sum)
+	if ph.count < ph.config.MinSamples {
+		return false
+	}
+	if ph.sum-ph.min > ph.config.Threshold {
+		ph.Reset()
+		return true
+	}
+	return false
+}
+
+// Ready returns true if the detector has seen enough samples to detect drift.
+func (ph *PageHinkley) Ready() bool {
+	return ph.count >= ph.config.MinSamples
+}
+
+// Mean returns the mean of the observed values since the last reset.
+func (ph *PageHinkley) Mean() float64 {
+	return ph.mean
+}
+
+// Reset resets the detector state.
+func (ph *PageHinkley) Reset() {
+	ph.count = 0
+	ph.mean = 0
+	ph.sum = 0
+	ph.min = math.MaxFloat64
+}
@@ -0,0 +1,99 @@
+package drift
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPageHinkley(t *testing.T) {
+
+	type test struct {
+		config Config
+		before float64
+		after  float64
+		noise  float64
+		drift  bool
+	}
+
+	tests := map[string]test{
+		"no-shift": {
+			config: DefaultConfig(),
+			before: 0.2,
+			after:  0.2,
+			noise:  0.1,
+		},
+		"loss-increase": {
+			config: DefaultConfig(),
+			before: 0.2,
+			after:  0.8,
+			noise:  0.1,
+			drift:  true,
+		},
+		"loss-decrease": {
+			config: DefaultConfig(),
+			before: 0.8,
+			after:  0.2,
+			noise:  0.1,
+		},
+		"small-increase-within-delta": {
+			config: DefaultConfig(),
+			before: 0.2,
+			after:  0.22,
+			noise:  0.01,
+		},
+		"high-threshold": {
+			config: Config{Delta: 0.05, Threshold: 1000, Alpha: 1, MinSamples: 30},
+			before: 0.2,
+			after:  0.8,
+			noise:  0.1,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			rnd := rand.New(rand.NewSource(1))
+			ph := NewPageHinkley(tt.config)
+			for i := 0; i < 200; i++ {
+				x := tt.before + tt.noise*(rnd.Float64()-0.5)
+				assert.False(t, ph.Add(x), "unexpected drift before the shift at %d", i)
+			}
+			var detected int
+			for i := 0; i < 200; i++ {
+				x := tt.after + tt.noise*(rnd.Float64()-0.5)
+				if ph.Add(x) {
+					detected = i + 1
+					break
+				}
+			}
+			if tt.drift {
+				assert.True(t, detected > 0, "drift not detected")
+				// should detect the shift reasonably fast
+				assert.True(t, detected < 20, "drift detected late at %d", detected)
+				// the detector should be reset
+				assert.False(t, ph.Ready())
+			} else {
+				assert.Equal(t, 0, detected)
+			}
+		})
+	}
+}
+
+func TestPageHinkley_MinSamples(t *testing.T) {
+	ph := NewPageHinkley(Config{Delta: 0, Threshold: 0.1, Alpha: 1, MinSamples: 10})
+	for i := 0; i < 9; i++ {
+		assert.False(t, ph.Add(float64(i)))
+		assert.False(t, ph.Ready())
+	}
+	assert.True(t, ph.Add(100))
+}
+
+func TestConfig_OrDefault(t *testing.T) {
+	assert.Equal(t, DefaultConfig(), Config{}.OrDefault())
+	cfg := Config{Threshold: 1}
+	assert.Equal(t, cfg, cfg.OrDefault())
+	// a disabled config is kept as is , instead of falling back to the defaults
+	disabled := Config{Disabled: true}
+	assert.Equal(t, disabled, disabled.OrDefault())
+}
@@ -0,0 +1,129 @@
+package indicator
+
+// SMA is the simple moving average of the close price.
+type SMA struct {
+	window *window
+	sum    float64
+}
+
+// NewSMA creates a new simple moving average for the given period.
+func NewSMA(period int) *SMA {
+	return &SMA{window: newWindow(period)}
+}
+
+// Add adds a bar to the indicator.
+func (sma *SMA) Add(bar Bar) {
+	sma.add(bar.Close)
+}
+
+func (sma *SMA) add(v float64) {
+	sma.sum += v
+	if evicted, ok := sma.window.push(v); ok {
+		sma.sum -= evicted
+	}
+}
+
+// Ready returns true if the period is filled.
+func (sma *SMA) Ready() bool {
+	return sma.window.full()
+}
+
+// Value returns the current average.
+func (sma *SMA) Value() float64 {
+	if len(sma.window.values) == 0 {
+		return 0
+	}
+	return sma.sum / float64(len(sma.window.values))
+}
+
+// Values returns the indicator values.
+func (sma *SMA) Values() map[string]float64 {
+	return map[string]float64{"": sma.Value()}
+}
+
+// EMA is the exponential moving average of the close price.
+// It is seeded with the simple moving average of the first period.
+type EMA struct {
+	period int
+	alpha  float64
+	count  int
+	sum    float64
+	value  float64
+}
+
+// NewEMA creates a new exponential moving average for the given period.
+func NewEMA(period int) *EMA {
+	return &EMA{
+		period: period,
+		alpha:  2 / float64(period+1),
+	}
+}
+
+// Add adds a bar to the indicator.
+func (ema *EMA) Add(bar Bar) {
+	ema.add(bar.Close)
+}
+
+func (ema *EMA) add(v float64) {
+	ema.count++
+	if ema.count <= ema.period {
+		ema.sum += v
+		ema.value = ema.sum / float64(ema.count)
+		return
+	}
+	ema.value = v*ema.alpha + ema.value*(1-ema.alpha)
+}
+
+// Ready returns true if the period is filled.
+func (ema *EMA) Ready() bool {
+	return ema.count >= ema.period
+}
+
+// Value returns the current average.
+func (ema *EMA) Value() float64 {
+	return ema.value
+}
+
+// Values returns the indicator values.
+func (ema *EMA) Values() map[string]float64 {
+	return map[string]float64{"": ema.value}
+}
+
+// WMA is the linearly weighted moving average of the close price.
+type WMA struct {
+	window *window
+}
+
+// NewWMA creates a new weighted moving average for the given period.
+func NewWMA(period int) *WMA {
+	return &WMA{window: newWindow(period)}
+}
+
+// Add adds a bar to the indicator.
+func (wma *WMA) Add(bar Bar) {
+	wma.window.push(bar.Close)
+}
+
+// Ready returns true if the period is filled.
+func (wma *WMA) Ready() bool {
+	return wma.window.full()
+}
+
+// Value returns the current average.
+func (wma *WMA) Value() float64 {
+	var sum, weights float64
+	for i, v := range wma.window.values {
+		w := float64(i + 1)
+		sum += w * v
+		weights += w
+	}
+	if weights == 0 {
+		return 0
+	}
+	return sum / weights
+}
+
+// Values returns the indicator values.
+func (wma *WMA) Values() map[string]float64 {
+	return map[string]float64{"": wma.Value()}
+}
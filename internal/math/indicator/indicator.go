@@ -0,0 +1,296 @@
+package indicator
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/drakos74/free-coin/internal/buffer"
+)
+
+// Bar is an open-high-low-close-volume representation of an interval bucket.
+type Bar struct {
+	Time   time.Time `json:"time"`
+	Open   float64   `json:"open"`
+	High   float64   `json:"high"`
+	Low    float64   `json:"low"`
+	Close  float64   `json:"close"`
+	Volume float64   `json:"volume"`
+}
+
+// FromBucket creates a bar from an IntervalWindow bucket.
+// It expects the price to be the first and the volume the second dimension of the window.
+func FromBucket(bucket buffer.StatsMessage) (Bar, bool) {
+	if !bucket.OK || len(bucket.Stats) < 2 || len(bucket.Data) < 2 {
+		return Bar{}, false
+	}
+	low, high := bucket.Stats[0].Range()
+	return Bar{
+		Time:   bucket.Last,
+		Open:   bucket.Data[0].First,
+		High:   high,
+		Low:    low,
+		Close:  bucket.Data[0].Last,
+		Volume: bucket.Stats[1].Sum(),
+	}, true
+}
+
+// Indicator is a streaming technical indicator.
+// Values returns the named values of the indicator,
+// single value indicators return their value under the empty name.
+type Indicator interface {
+	Add(bar Bar)
+	Ready() bool
+	Values() map[string]float64
+}
+
+// Constructor creates a new indicator based on the given parameters.
+type Constructor func(params ...float64) (Indicator, error)
+
+var registry = map[string]Constructor{
+	"sma": func(params ...float64) (Indicator, error) {
+		p, err := ints(params, 20)
+		if err != nil {
+			return nil, err
+		}
+		return NewSMA(p[0]), nil
+	},
+	"ema": func(params ...float64) (Indicator, error) {
+		p, err := ints(params, 20)
+		if err != nil {
+			return nil, err
+		}
+		return NewEMA(p[0]), nil
+	},
+	"wma": func(params ...float64) (Indicator, error) {
+		p, err := ints(params, 20)
+		if err != nil {
+			return nil, err
+		}
+		return NewWMA(p[0]), nil
+	},
+	"rsi": func(params ...float64) (Indicator, error) {
+		p, err := ints(params, 14)
+		if err != nil {
+			return nil, err
+		}
+		return NewRSI(p[0]), nil
+	},
+	"macd": func(params ...float64) (Indicator, error) {
+		p, err := ints(params, 12, 26, 9)
+		if err != nil {
+			return nil, err
+		}
+		return NewMACD(p[0], p[1], p[2]), nil
+	},
+	"stoch": func(params ...float64) (Indicator, error) {
+		p, err := ints(params, 14, 3)
+		if err != nil {
+			return nil, err
+		}
+		return NewStochastic(p[0], p[1]), nil
+	},
+	"bb": func(params ...float64) (Indicator, error) {
+		if len(params) > 2 {
+			return nil, fmt.Errorf("too many parameters: %v", params)
+		}
+		period, k := 20, 2.0
+		if len(params) > 0 {
+			period = int(params[0])
+		}
+		if len(params) > 1 {
+			k = params[1]
+		}
+		if period <= 0 || k <= 0 {
+			return nil, fmt.Errorf("invalid parameters: %v", params)
+		}
+		return NewBollinger(period, k), nil
+	},
+	"atr": func(params ...float64) (Indicator, error) {
+		p, err := ints(params, 14)
+		if err != nil {
+			return nil, err
+		}
+		return NewATR(p[0]), nil
+	},
+	"obv": func(params ...float64) (Indicator, error) {
+		if len(params) > 0 {
+			return nil, fmt.Errorf("too many parameters: %v", params)
+		}
+		return NewOBV(), nil
+	},
+	"vwap": func(params ...float64) (Indicator, error) {
+		if len(params) > 1 {
+			return nil, fmt.Errorf("too many parameters: %v", params)
+		}
+		// a zero period is the cumulative vwap
+		period := 0
+		if len(params) > 0 {
+			if params[0] < 0 || params[0] != float64(int(params[0])) {
+				return nil, fmt.Errorf("invalid parameter: %v", params[0])
+			}
+			period = int(params[0])
+		}
+		return NewVWAP(period), nil
+	},
+}
+
+// ints parses the given parameters as positive integers e.g. the periods, using the defaults for the missing ones.
+func ints(params []float64, defaults ...int) ([]int, error) {
+	if len(params) > len(defaults) {
+		return nil, fmt.Errorf("too many parameters: %v", params)
+	}
+	p := make([]int, len(defaults))
+	copy(p, defaults)
+	for i, param := range params {
+		if param <= 0 || param != float64(int(param)) {
+			return nil, fmt.Errorf("invalid parameter: %v", param)
+		}
+		p[i] = int(param)
+	}
+	return p, nil
+}
+
+// Types returns the names of the available indicator types.
+func Types() []string {
+	types := make([]string, 0, len(registry))
+	for t := range registry {
+		types = append(types, t)
+	}
+	sort.Strings(types)
+	return types
+}
+
+// New creates a new indicator of the given type.
+func New(t string, params ...float64) (Indicator, error) {
+	if constructor, ok := registry[t]; ok {
+		return constructor(params...)
+	}
+	return nil, fmt.Errorf("unknown indicator type '%s'", t)
+}
+
+// Spec defines an indicator instance.
+// Name is the name under which the indicator values are exposed, it defaults to the type.
+// Type is one of the registered indicator types.
+// Params are the indicator parameters e.g. the period, if omitted the defaults are used.
+type Spec struct {
+	Name   string    `json:"name"`
+	Type   string    `json:"type"`
+	Params []float64 `json:"params"`
+}
+
+// DefaultSpecs is the set of all indicators with their default parameters.
+func DefaultSpecs() []Spec {
+	types := Types()
+	specs := make([]Spec, len(types))
+	for i, t := range types {
+		specs[i] = Spec{Type: t}
+	}
+	return specs
+}
+
+// Set is a named collection of indicators, that are fed with the same bars.
+type Set struct {
+	names      []string
+	indicators map[string]Indicator
+	last       Bar
+	count      int
+}
+
+// NewSet creates a new indicator set based on the given specs.
+func NewSet(specs ...Spec) (*Set, error) {
+	set := &Set{
+		names:      make([]string, 0, len(specs)),
+		indicators: make(map[string]Indicator),
+	}
+	for _, spec := range specs {
+		name := spec.Name
+		if name == "" {
+			name = spec.Type
+		}
+		if _, ok := set.indicators[name]; ok {
+			return nil, fmt.Errorf("duplicate indicator name '%s'", name)
+		}
+		indicator, err := New(spec.Type, spec.Params...)
+		if err != nil {
+			return nil, fmt.Errorf("could not create indicator '%s': %w", name, err)
+		}
+		set.names = append(set.names, name)
+		set.indicators[name] = indicator
+	}
+	return set, nil
+}
+
+// Add adds the bar to all the indicators of the set.
+func (s *Set) Add(bar Bar) {
+	s.last = bar
+	s.count++
+	for _, indicator := range s.indicators {
+		indicator.Add(bar)
+	}
+}
+
+// Ready returns true if all the indicators of the set have enough data.
+func (s *Set) Ready() bool {
+	for _, indicator := range s.indicators {
+		if !indicator.Ready() {
+			return false
+		}
+	}
+	return s.count > 0
+}
+
+// Values returns the values of all indicators, along with the last bar values.
+// Multi-value indicators are exposed as '<name>.<value>' e.g. 'macd.signal'.
+func (s *Set) Values() map[string]float64 {
+	values := map[string]float64{
+		"open":   s.last.Open,
+		"high":   s.last.High,
+		"low":    s.last.Low,
+		"close":  s.last.Close,
+		"volume": s.last.Volume,
+	}
+	for name, indicator := range s.indicators {
+		for k, v := range indicator.Values() {
+			if k == "" {
+				values[name] = v
+			} else {
+				values[fmt.Sprintf("%s.%s", name, k)] = v
+			}
+		}
+	}
+	return values
+}
+
+// Names returns the names of the indicators in the set.
+func (s *Set) Names() []string {
+	return s.names
+}
+
+// window is a fixed size window of the latest values.
+type window struct {
+	size   int
+	values []float64
+}
+
+func newWindow(size int) *window {
+	return &window{
+		size:   size,
+		values: make([]float64, 0, size+1),
+	}
+}
+
+// push adds a value to the window and returns the value that was evicted if the window was full.
+func (w *window) push(v float64) (float64, bool) {
+	w.values = append(w.values, v)
+	if len(w.values) > w.size {
+		evicted := w.values[0]
+		w.values = w.values[1:]
+		return evicted, true
+	}
+	return 0, false
+}
+
+func (w *window) full() bool {
+	return len(w.values) == w.size
+}
@@ -0,0 +1,244 @@
+package indicator
+
+import (
+	"testing"
+	"time"
+
+	"github.com/drakos74/free-coin/internal/buffer"
+	"github.com/stretchr/testify/assert"
+)
+
+func closes(cc ...float64) []Bar {
+	bars := make([]Bar, len(cc))
+	for i, c := range cc {
+		bars[i] = Bar{
+			Time:   time.Unix(int64(i*60), 0),
+			Open:   c,
+			High:   c + 1,
+			Low:    c - 1,
+			Close:  c,
+			Volume: 10,
+		}
+	}
+	return bars
+}
+
+func TestIndicators(t *testing.T) {
+
+	type test struct {
+		indicator Indicator
+		bars      []Bar
+		ready     bool
+		values    map[string]float64
+	}
+
+	tests := map[string]test{
+		"sma": {
+			indicator: NewSMA(3),
+			bars:      closes(1, 2, 3, 4),
+			ready:     true,
+			values:    map[string]float64{"": 3},
+		},
+		"sma-not-ready": {
+			indicator: NewSMA(3),
+			bars:      closes(1, 2),
+			values:    map[string]float64{"": 1.5},
+		},
+		"ema": {
+			indicator: NewEMA(3),
+			bars:      closes(1, 2, 3, 4),
+			ready:     true,
+			// seeded with sma=2 , then 4*0.5 + 2*0.5
+			values: map[string]float64{"": 3},
+		},
+		"wma": {
+			indicator: NewWMA(3),
+			bars:      closes(1, 2, 3),
+			ready:     true,
+			// (1*1 + 2*2 + 3*3) / 6
+			values: map[string]float64{"": 14.0 / 6},
+		},
+		"rsi-up": {
+			indicator: NewRSI(3),
+			bars:      closes(1, 2, 3, 4),
+			ready:     true,
+			values:    map[string]float64{"": 100},
+		},
+		"rsi-mixed": {
+			indicator: NewRSI(2),
+			bars:      closes(1, 3, 2),
+			ready:     true,
+			// avg gain 1 , avg loss 0.5
+			values: map[string]float64{"": 100 - 100/3.0},
+		},
+		"macd-flat": {
+			indicator: NewMACD(2, 3, 2),
+			bars:      closes(5, 5, 5, 5, 5),
+			ready:     true,
+			values:    map[string]float64{"": 0, "signal": 0, "hist": 0},
+		},
+		"stoch": {
+			indicator: NewStochastic(3, 1),
+			bars:      closes(1, 2, 3),
+			ready:     true,
+			// highest high 4 , lowest low 0
+			values: map[string]float64{"k": 75, "d": 75},
+		},
+		"bollinger": {
+			indicator: NewBollinger(2, 2),
+			bars:      closes(1, 3),
+			ready:     true,
+			values:    map[string]float64{"upper": 4, "middle": 2, "lower": 0, "width": 2},
+		},
+		"atr": {
+			indicator: NewATR(2),
+			bars:      closes(1, 5),
+			ready:     true,
+			// first tr 2 , second max(2,|6-1|,|4-1|)=5
+			values: map[string]float64{"": 3.5},
+		},
+		"obv": {
+			indicator: NewOBV(),
+			bars:      closes(1, 2, 1, 1),
+			ready:     true,
+			values:    map[string]float64{"": 0},
+		},
+		"vwap-rolling": {
+			indicator: NewVWAP(2),
+			bars:      closes(1, 2, 3),
+			ready:     true,
+			values:    map[string]float64{"": 2.5},
+		},
+		"vwap-cumulative": {
+			indicator: NewVWAP(0),
+			bars:      closes(1, 2, 3),
+			ready:     true,
+			values:    map[string]float64{"": 2},
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			for _, bar := range tt.bars {
+				tt.indicator.Add(bar)
+			}
+			assert.Equal(t, tt.ready, tt.indicator.Ready())
+			values := tt.indicator.Values()
+			assert.Equal(t, len(tt.values), len(values))
+			for k, v := range tt.values {
+				assert.InDelta(t, v, values[k], 0.0001, k)
+			}
+		})
+	}
+}
+
+func TestNewSet(t *testing.T) {
+
+	type test struct {
+		specs  []Spec
+		err    bool
+		values []string
+	}
+
+	tests := map[string]test{
+		"default": {
+			specs:  DefaultSpecs(),
+			values: []string{"close", "sma", "ema", "wma", "rsi", "macd", "macd.signal", "macd.hist", "bb.upper", "bb.lower", "atr", "stoch.k", "stoch.d", "obv", "vwap"},
+		},
+		"named": {
+			specs:  []Spec{{Name: "fast", Type: "ema", Params: []float64{5}}, {Name: "slow", Type: "ema", Params: []float64{20}}},
+			values: []string{"fast", "slow"},
+		},
+		"unknown": {
+			specs: []Spec{{Type: "unknown"}},
+			err:   true,
+		},
+		"duplicate": {
+			specs: []Spec{{Type: "ema"}, {Type: "ema"}},
+			err:   true,
+		},
+		"invalid-params": {
+			specs: []Spec{{Type: "sma", Params: []float64{1, 2}}},
+			err:   true,
+		},
+		"negative-period": {
+			specs: []Spec{{Type: "ema", Params: []float64{-5}}},
+			err:   true,
+		},
+		"zero-sma": {
+			specs: []Spec{{Type: "sma", Params: []float64{0}}},
+			err:   true,
+		},
+		"zero-rsi": {
+			specs: []Spec{{Type: "rsi", Params: []float64{0}}},
+			err:   true,
+		},
+		"zero-stoch": {
+			specs: []Spec{{Type: "stoch", Params: []float64{0}}},
+			err:   true,
+		},
+		"zero-stoch-smoothing": {
+			specs: []Spec{{Type: "stoch", Params: []float64{14, 0}}},
+			err:   true,
+		},
+		"zero-macd-signal": {
+			specs: []Spec{{Type: "macd", Params: []float64{12, 26, 0}}},
+			err:   true,
+		},
+		"zero-atr": {
+			specs: []Spec{{Type: "atr", Params: []float64{0}}},
+			err:   true,
+		},
+		"cumulative-vwap": {
+			specs:  []Spec{{Type: "vwap", Params: []float64{0}}},
+			values: []string{"vwap"},
+		},
+		"negative-vwap": {
+			specs: []Spec{{Type: "vwap", Params: []float64{-1}}},
+			err:   true,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			set, err := NewSet(tt.specs...)
+			if tt.err {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			for _, bar := range closes(1, 2, 3) {
+				set.Add(bar)
+			}
+			values := set.Values()
+			for _, v := range tt.values {
+				assert.Contains(t, values, v)
+			}
+		})
+	}
+}
+
+func TestFromBucket(t *testing.T) {
+	iw, stats := buffer.NewIntervalWindow("test", 2, time.Minute)
+	iw = iw.WithInterval(60)
+	now := time.Unix(0, 0)
+	go func() {
+		for i, p := range []float64{10, 12, 8, 11} {
+			iw.Push(now.Add(time.Duration(i)*time.Second), p, 1)
+		}
+		iw.Flush()
+	}()
+	bar, ok := FromBucket(<-stats)
+	assert.True(t, ok)
+	assert.Equal(t, Bar{
+		Time:   now.Add(3 * time.Second),
+		Open:   10,
+		High:   12,
+		Low:    8,
+		Close:  11,
+		Volume: 4,
+	}, bar)
+
+	_, ok = FromBucket(buffer.StatsMessage{})
+	assert.False(t, ok)
+}
@@ -0,0 +1,154 @@
+package indicator
+
+import "math"
+
+// RSI is the relative strength index of the close price, based on Wilder's smoothing.
+type RSI struct {
+	period  int
+	count   int
+	prev    float64
+	avgGain float64
+	avgLoss float64
+}
+
+// NewRSI creates a new relative strength index for the given period.
+func NewRSI(period int) *RSI {
+	return &RSI{period: period}
+}
+
+// Add adds a bar to the indicator.
+func (rsi *RSI) Add(bar Bar) {
+	rsi.count++
+	if rsi.count == 1 {
+		rsi.prev = bar.Close
+		return
+	}
+	diff := bar.Close - rsi.prev
+	rsi.prev = bar.Close
+	gain := math.Max(diff, 0)
+	loss := math.Max(-diff, 0)
+	n := rsi.count - 1
+	if n <= rsi.period {
+		// simple average for the first period
+		rsi.avgGain += (gain - rsi.avgGain) / float64(n)
+		rsi.avgLoss += (loss - rsi.avgLoss) / float64(n)
+		return
+	}
+	p := float64(rsi.period)
+	rsi.avgGain = (rsi.avgGain*(p-1) + gain) / p
+	rsi.avgLoss = (rsi.avgLoss*(p-1) + loss) / p
+}
+
+// Ready returns true if the period is filled.
+func (rsi *RSI) Ready() bool {
+	return rsi.count > rsi.period
+}
+
+// Value returns the current index in the range [0,100].
+func (rsi *RSI) Value() float64 {
+	if rsi.avgLoss == 0 {
+		if rsi.avgGain == 0 {
+			return 50
+		}
+		return 100
+	}
+	rs := rsi.avgGain / rsi.avgLoss
+	return 100 - 100/(1+rs)
+}
+
+// Values returns the indicator values.
+func (rsi *RSI) Values() map[string]float64 {
+	return map[string]float64{"": rsi.Value()}
+}
+
+// MACD is the moving average convergence divergence of the close price.
+type MACD struct {
+	fast   *EMA
+	slow   *EMA
+	signal *EMA
+	line   float64
+}
+
+// NewMACD creates a new moving average convergence divergence for the given periods.
+func NewMACD(fast, slow, signal int) *MACD {
+	return &MACD{
+		fast:   NewEMA(fast),
+		slow:   NewEMA(slow),
+		signal: NewEMA(signal),
+	}
+}
+
+// Add adds a bar to the indicator.
+func (macd *MACD) Add(bar Bar) {
+	macd.fast.add(bar.Close)
+	macd.slow.add(bar.Close)
+	if macd.slow.Ready() && macd.fast.Ready() {
+		macd.line = macd.fast.Value() - macd.slow.Value()
+		macd.signal.add(macd.line)
+	}
+}
+
+// Ready returns true if the signal line is available.
+func (macd *MACD) Ready() bool {
+	return macd.signal.Ready()
+}
+
+// Values returns the macd line under the empty name, the signal line and the histogram.
+func (macd *MACD) Values() map[string]float64 {
+	return map[string]float64{
+		"":       macd.line,
+		"signal": macd.signal.Value(),
+		"hist":   macd.line - macd.signal.Value(),
+	}
+}
+
+// Stochastic is the stochastic oscillator with the %K and %D lines.
+type Stochastic struct {
+	high *window
+	low  *window
+	d    *SMA
+	k    float64
+}
+
+// NewStochastic creates a new stochastic oscillator for the given %K and %D periods.
+func NewStochastic(kPeriod, dPeriod int) *Stochastic {
+	return &Stochastic{
+		high: newWindow(kPeriod),
+		low:  newWindow(kPeriod),
+		d:    NewSMA(dPeriod),
+	}
+}
+
+// Add adds a bar to the indicator.
+func (s *Stochastic) Add(bar Bar) {
+	s.high.push(bar.High)
+	s.low.push(bar.Low)
+	if !s.high.full() {
+		return
+	}
+	highest := s.high.values[0]
+	lowest := s.low.values[0]
+	for i := range s.high.values {
+		highest = math.Max(highest, s.high.values[i])
+		lowest = math.Min(lowest, s.low.values[i])
+	}
+	if highest == lowest {
+		s.k = 50
+	} else {
+		s.k = 100 * (bar.Close - lowest) / (highest - lowest)
+	}
+	s.d.add(s.k)
+}
+
+// Ready returns true if the %D line is available.
+func (s *Stochastic) Ready() bool {
+	return s.d.Ready()
+}
+
+// Values returns the %K and %D lines.
+func (s *Stochastic) Values() map[string]float64 {
+	return map[string]float64{
+		"k": s.k,
+		"d": s.d.Value(),
+	}
+}
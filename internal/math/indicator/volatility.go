@@ -0,0 +1,89 @@
+package indicator
+
+import "math"
+
+// Bollinger are the bollinger bands around the simple moving average of the close price.
+type Bollinger struct {
+	sma *SMA
+	k   float64
+}
+
+// NewBollinger creates new bollinger bands for the given period and standard deviation multiplier.
+func NewBollinger(period int, k float64) *Bollinger {
+	return &Bollinger{
+		sma: NewSMA(period),
+		k:   k,
+	}
+}
+
+// Add adds a bar to the indicator.
+func (b *Bollinger) Add(bar Bar) {
+	b.sma.Add(bar)
+}
+
+// Ready returns true if the period is filled.
+func (b *Bollinger) Ready() bool {
+	return b.sma.Ready()
+}
+
+// Values returns the upper, middle and lower band, along with the relative band width.
+func (b *Bollinger) Values() map[string]float64 {
+	mean := b.sma.Value()
+	var variance float64
+	for _, v := range b.sma.window.values {
+		variance += (v - mean) * (v - mean)
+	}
+	if n := len(b.sma.window.values); n > 0 {
+		variance = variance / float64(n)
+	}
+	std := math.Sqrt(variance)
+	width := 0.0
+	if mean != 0 {
+		width = 2 * b.k * std / mean
+	}
+	return map[string]float64{
+		"upper":  mean + b.k*std,
+		"middle": mean,
+		"lower":  mean - b.k*std,
+		"width":  width,
+	}
+}
+
+// ATR is the average true range, based on Wilder's smoothing.
+type ATR struct {
+	period int
+	count  int
+	prev   float64
+	value  float64
+}
+
+// NewATR creates a new average true range for the given period.
+func NewATR(period int) *ATR {
+	return &ATR{period: period}
+}
+
+// Add adds a bar to the indicator.
+func (atr *ATR) Add(bar Bar) {
+	tr := bar.High - bar.Low
+	if atr.count > 0 {
+		tr = math.Max(tr, math.Max(math.Abs(bar.High-atr.prev), math.Abs(bar.Low-atr.prev)))
+	}
+	atr.prev = bar.Close
+	atr.count++
+	if atr.count <= atr.period {
+		atr.value += (tr - atr.value) / float64(atr.count)
+		return
+	}
+	p := float64(atr.period)
+	atr.value = (atr.value*(p-1) + tr) / p
+}
+
+// Ready returns true if the period is filled.
+func (atr *ATR) Ready() bool {
+	return atr.count >= atr.period
+}
+
+// Values returns the indicator values.
+func (atr *ATR) Values() map[string]float64 {
+	return map[string]float64{"": atr.value}
+}
@@ -0,0 +1,94 @@
+package indicator
+
+// OBV is the on-balance volume.
+type OBV struct {
+	count int
+	prev  float64
+	value float64
+}
+
+// NewOBV creates a new on-balance volume indicator.
+func NewOBV() *OBV {
+	return &OBV{}
+}
+
+// Add adds a bar to the indicator.
+func (obv *OBV) Add(bar Bar) {
+	if obv.count > 0 {
+		if bar.Close > obv.prev {
+			obv.value += bar.Volume
+		} else if bar.Close < obv.prev {
+			obv.value -= bar.Volume
+		}
+	}
+	obv.prev = bar.Close
+	obv.count++
+}
+
+// Ready returns true if there is a previous bar to compare against.
+func (obv *OBV) Ready() bool {
+	return obv.count > 1
+}
+
+// Values returns the indicator values.
+func (obv *OBV) Values() map[string]float64 {
+	return map[string]float64{"": obv.value}
+}
+
+// VWAP is the volume weighted average price, based on the typical price of the bars.
+// If a period is given it is calculated on a rolling window, otherwise it is cumulative.
+type VWAP struct {
+	pv     *window
+	volume *window
+	sumPV  float64
+	sumV   float64
+	count  int
+}
+
+// NewVWAP creates a new volume weighted average price for the given period.
+func NewVWAP(period int) *VWAP {
+	vwap := &VWAP{}
+	if period > 0 {
+		vwap.pv = newWindow(period)
+		vwap.volume = newWindow(period)
+	}
+	return vwap
+}
+
+// Add adds a bar to the indicator.
+func (vwap *VWAP) Add(bar Bar) {
+	typical := (bar.High + bar.Low + bar.Close) / 3
+	pv := typical * bar.Volume
+	vwap.sumPV += pv
+	vwap.sumV += bar.Volume
+	if vwap.pv != nil {
+		if evicted, ok := vwap.pv.push(pv); ok {
+			vwap.sumPV -= evicted
+		}
+		if evicted, ok := vwap.volume.push(bar.Volume); ok {
+			vwap.sumV -= evicted
+		}
+	}
+	vwap.count++
+}
+
+// Ready returns true if the period is filled.
+func (vwap *VWAP) Ready() bool {
+	if vwap.pv != nil {
+		return vwap.pv.full()
+	}
+	return vwap.count > 0
+}
+
+// Value returns the current average price.
+func (vwap *VWAP) Value() float64 {
+	if vwap.sumV == 0 {
+		return 0
+	}
+	return vwap.sumPV / vwap.sumV
+}
+
+// Values returns the indicator values.
+func (vwap *VWAP) Values() map[string]float64 {
+	return map[string]float64{"": vwap.Value()}
+}
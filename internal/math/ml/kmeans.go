@@ -2,6 +2,7 @@ package ml
 
 import (
 	"fmt"
+	"io/ioutil"
 	"math"
 	"sort"
 
@@ -50,6 +51,26 @@ func NewKMeans(pair string, dim int, iterations int) *KMeans {
 	return k
 }
 
+// NewInMemoryKMeans creates a k-means model for the given number of clusters ,
+// that is trained only on the data passed to Fit , without persisting them.
+func NewInMemoryKMeans(clusters int, iterations int) *KMeans {
+	return &KMeans{
+		dim:        clusters,
+		iterations: iterations,
+		stats:      make(map[int]*buffer.Stats, clusters),
+	}
+}
+
+// Fit trains the clusters on the given data , and returns the cluster of each sample.
+func (k *KMeans) Fit(data [][]float64) ([]int, error) {
+	k.model = cluster.NewKMeans(k.dim, k.iterations, data)
+	k.model.Output = ioutil.Discard
+	if err := k.model.Learn(); err != nil {
+		return nil, fmt.Errorf("could not train: %w", err)
+	}
+	return k.model.Guesses(), nil
+}
+
 func transform(stats map[int]*buffer.Stats) (map[int]Cluster, []float64) {
 	newStats := make(map[int]Cluster)
 	// keep limit
@@ -68,15 +89,14 @@ func transform(stats map[int]*buffer.Stats) (map[int]Cluster, []float64) {
 func (k *KMeans) train(data [][]float64, results []float64, metadata Metadata) (Metadata, error) {
 	// train either on demand or based on intervals
 	if len(data) >= k.dim {
-		k.model = cluster.NewKMeans(k.dim, k.iterations, data)
-		if err := k.model.Learn(); err != nil {
+		guesses, err := k.Fit(data)
+		if err != nil {
 			log.Error().
 				Err(err).
 				Str("key", fmt.Sprintf("%+v", k.dataKey)).
 				Msg("error during training on k-means")
-			return metadata, fmt.Errorf("could not train: %w", err)
+			return metadata, err
 		}
-		guesses := k.model.Guesses()
 		if len(guesses) != len(results) {
 			return metadata, fmt.Errorf("could not align results with data [ %d | %d | %d ]", len(results), len(guesses), len(data))
 		}
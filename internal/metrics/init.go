@@ -33,6 +33,9 @@ func init() {
 	prometheus.MustRegister(Observer.prometheus.Duration)
 	prometheus.MustRegister(Observer.prometheus.Calls)
 	prometheus.MustRegister(Observer.prometheus.Errors)
+	prometheus.MustRegister(Observer.prometheus.Level)
+	prometheus.MustRegister(Observer.prometheus.Wait)
+	prometheus.MustRegister(Observer.prometheus.Exceeded)
 
 	go func() {
 		http.Handle("/metrics", promhttp.Handler())
@@ -38,3 +38,15 @@ func (m *Metrics) IncrementErrors(labels ...string) {
 func (m *Metrics) IncrementCalls(labels ...string) {
 	m.prometheus.Calls.WithLabelValues(labels...).Inc()
 }
+
+func (m *Metrics) NoteLevel(f float64, labels ...string) {
+	m.prometheus.Level.WithLabelValues(labels...).Set(f)
+}
+
+func (m *Metrics) TrackWait(f float64, labels ...string) {
+	m.prometheus.Wait.WithLabelValues(labels...).Observe(f)
+}
+
+func (m *Metrics) IncrementExceeded(labels ...string) {
+	m.prometheus.Exceeded.WithLabelValues(labels...).Inc()
+}
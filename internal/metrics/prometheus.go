@@ -9,6 +9,9 @@ type prometheusMetrics struct {
 	Duration *prometheus.HistogramVec
 	Calls    *prometheus.CounterVec
 	Errors   *prometheus.CounterVec
+	Level    *prometheus.GaugeVec
+	Wait     *prometheus.HistogramVec
+	Exceeded *prometheus.CounterVec
 }
 
 func newPrometheusMetrics() prometheusMetrics {
@@ -48,5 +51,22 @@ func newPrometheusMetrics() prometheusMetrics {
 				Name:      "processor",
 			}, []string{"coin", "process"},
 		),
+		Level: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Namespace: "limit",
+				Name:      "level",
+			}, []string{"exchange"}),
+		Wait: prometheus.NewHistogramVec(
+			prometheus.HistogramOpts{
+				Namespace: "limit",
+				Name:      "wait",
+				Buckets:   []float64{0, .1, .5, 1, 5, 10, 30, 60},
+			}, []string{"exchange", "priority"}),
+		Exceeded: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace: "limit",
+				Name:      "exceeded",
+			}, []string{"exchange"},
+		),
 	}
 }
@@ -0,0 +1,34 @@
+package model
+
+import "time"
+
+// DefaultBookDepth defines the default distances from the mid price , in basis points , for the order book depth.
+var DefaultBookDepth = []float64{10, 50, 100}
+
+// OrderBook defines the derived features of the level 2 order book.
+// Spread is the relative spread with respect to the mid price.
+// Microprice is the mid price weighted by the volume at the top of the book.
+// Imbalance is the relative difference between the bid and ask volume in the book.
+// Pressure is the imbalance , weighted by the distance of each level from the mid price.
+// Depth is the bid and ask volume within the given distances from the mid price.
+type OrderBook struct {
+	Time       time.Time   `json:"time"`
+	Mid        float64     `json:"mid"`
+	Spread     float64     `json:"spread"`
+	Microprice float64     `json:"microprice"`
+	Imbalance  float64     `json:"imbalance"`
+	Pressure   float64     `json:"pressure"`
+	Depth      []BookDepth `json:"depth"`
+}
+
+// BookDepth defines the bid and ask volume within the given basis points from the mid price.
+type BookDepth struct {
+	Bps float64 `json:"bps"`
+	Bid float64 `json:"bid"`
+	Ask float64 `json:"ask"`
+}
+
+// Ready checks if the order book features have been set.
+func (ob OrderBook) Ready() bool {
+	return ob.Mid > 0
+}
@@ -91,7 +91,7 @@ var Coins = map[string]Coin{
 	"XMR":   XMR,
 	"XTZ":   XTZ,
 	"FLOW":  FLOW,
-	"SC":    SOL,
+	"SC":    SC,
 	"KEEP":  KEEP,
 	"REP":   REP,
 }
@@ -2,6 +2,7 @@ package model
 
 import (
 	"fmt"
+	"hash/fnv"
 	"time"
 
 	"github.com/google/uuid"
@@ -60,7 +61,16 @@ type Audit struct {
 	Fills  int    `json:"fills"`
 }
 
+// Fill defines the execution of an order , as reported by the exchange.
+// Fees are denominated in the quote currency.
+type Fill struct {
+	Price  float64 `json:"price"`
+	Volume float64 `json:"volume"`
+	Fees   float64 `json:"fees"`
+}
+
 // TrackedOrder is an order decorated with metadata information.
+// Fill is set if the exchange reported the execution of the order.
 type TrackedOrder struct {
 	Order
 	Key    Key
@@ -71,6 +81,16 @@ type TrackedOrder struct {
 	TxIDs  []string  `json:"txIds"`
 	Audit  Audit     `json:"audit"`
 	Reason string    `json:"reason"`
+	Fill   *Fill     `json:"fill,omitempty"`
+}
+
+// Filled returns the price and fees of the order execution ,
+// falling back to the order price and the estimated fees if the exchange did not report them.
+func (to TrackedOrder) Filled() (price float64, fees float64) {
+	if to.Fill != nil && to.Fill.Price > 0 {
+		return to.Fill.Price, to.Fill.Fees
+	}
+	return to.Price, to.Price * to.Volume * Fees / 100
 }
 
 func (to TrackedOrder) IsClosing() bool {
@@ -99,8 +119,10 @@ func NewTrackedOrder(key Key, time time.Time, reason string, order Order) *Track
 }
 
 // Order defines an order
+// CID is the client order id , that the exchange carries along with the order.
 type Order struct {
 	ID       string    `json:"id"`
+	CID      string    `json:"cid"`
 	Coin     Coin      `json:"coin"`
 	Type     Type      `json:"type"`
 	OType    OrderType `json:"order_type"`
@@ -122,6 +144,18 @@ func FromPosition(position Position, close bool) Order {
 	return order.Create()
 }
 
+// NewClientID creates a deterministic client order id from the parts describing the order intent ,
+// so that a repeated submission of the same intent carries the same id.
+// The id is 16 hex characters , within the client id limits of the exchanges.
+func NewClientID(parts ...string) string {
+	h := fnv.New64a()
+	for _, p := range parts {
+		h.Write([]byte(p))
+		h.Write([]byte{0})
+	}
+	return fmt.Sprintf("%016x", h.Sum64())
+}
+
 // NewOrder creates a new order for the given coin.
 func NewOrder(coin Coin) *Order {
 	return &Order{
@@ -193,6 +227,12 @@ func (o *Order) TakeProfit() *Order {
 	return o
 }
 
+// WithCID defines the client order id for this order.
+func (o *Order) WithCID(cid string) *Order {
+	o.CID = cid
+	return o
+}
+
 // WithLeverage defines the leverage amount for this order.
 func (o *Order) WithLeverage(l Leverage) *Order {
 	o.mustBeEmpty(int(o.Leverage))
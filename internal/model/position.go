@@ -8,6 +8,7 @@ import (
 	"github.com/rs/zerolog/log"
 
 	"github.com/drakos74/free-coin/internal/buffer"
+	cointime "github.com/drakos74/free-coin/internal/time"
 	"github.com/google/uuid"
 )
 
@@ -55,20 +56,24 @@ type Data struct {
 }
 
 // MetaData contains position related metadata
+// OpenFees are the fees paid for the opening order , while Fees are the estimated ones for the whole position.
 type MetaData struct {
 	OpenTime    time.Time `json:"open_time"`
 	CurrentTime time.Time `json:"current_time"`
+	OpenFees    float64   `json:"open_fees"`
 	Fees        float64   `json:"fees"`
 	Cost        float64   `json:"cost"`
 	Net         float64   `json:"net"`
 }
 
 // Stats contains position stats, these are calculated by processors on the fly
+// MAE is the max adverse excursion , the lowest pnl of the position so far.
 type Stats struct {
 	HasUpdate bool                      `json:"-"`
 	Trend     map[time.Duration]Trend   `json:"-"`
 	Profit    map[time.Duration]*Profit `json:"-"`
 	PnL       float64                   `json:"pnl"`
+	MAE       float64                   `json:"mae"`
 	Value     float64                   `json:"value"`
 	Key       Key                       `json:"key"`
 }
@@ -173,9 +178,14 @@ type Position struct {
 	Volume       float64   `json:"volume"`
 }
 
+// Sync updates the position with the upstream one , and returns true if anything changed.
 func (p *Position) Sync(pos Position) (Position, bool) {
 	update := false
 
+	if pos.Type != p.Type {
+		update = true
+		p.Type = pos.Type
+	}
 	if pos.OpenPrice != p.OpenPrice {
 		update = true
 		p.OpenPrice = pos.OpenPrice
@@ -199,6 +209,9 @@ func (p *Position) Update(trace bool, trade Tick, cfg []*TrackingConfig) Positio
 	p.PnL = pnl
 	p.Value = value
 	p.Fees = fees
+	if pnl < p.MAE {
+		p.MAE = pnl
+	}
 
 	p.HasUpdate = false
 
@@ -264,7 +277,7 @@ func (p *Position) Update(trace bool, trade Tick, cfg []*TrackingConfig) Positio
 			if _, ok := profit.Window.Push(trade.Time, p.PnL); ok {
 				p.HasUpdate = true
 				trend := p.Trend[k]
-				trend.Stamp = time.Now()
+				trend.Stamp = cointime.Now()
 				trend.Live = true
 				s, xx, yy, err := profit.Window.Polynomial(0, func(b buffer.TimeWindowView) float64 {
 					return 100 * buffer.Avg(b)
@@ -397,6 +410,7 @@ func OpenPosition(order *TrackedOrder, trackingConfig []*TrackingConfig) Positio
 			profit[cfg.Duration] = NewProfit(cfg)
 		}
 	}
+	price, fees := order.Filled()
 	return Position{
 		Data: Data{
 			ID:      uuid.New().String(),
@@ -404,6 +418,7 @@ func OpenPosition(order *TrackedOrder, trackingConfig []*TrackingConfig) Positio
 		},
 		MetaData: MetaData{
 			OpenTime: order.Time,
+			OpenFees: fees,
 		},
 		Stats: Stats{
 			Trend:  make(map[time.Duration]Trend),
@@ -413,6 +428,6 @@ func OpenPosition(order *TrackedOrder, trackingConfig []*TrackingConfig) Positio
 		Coin:      order.Coin,
 		Type:      order.Type,
 		Volume:    order.Volume,
-		OpenPrice: order.Price,
+		OpenPrice: price,
 	}
 }
@@ -0,0 +1,29 @@
+package model
+
+// Regime defines the market regime of a coin.
+type Regime string
+
+const (
+	// NoRegime defines a missing regime classification.
+	NoRegime Regime = ""
+	// Trending defines a market moving consistently in one direction.
+	Trending Regime = "trending"
+	// Ranging defines a market moving sideways within a range.
+	Ranging Regime = "ranging"
+	// Volatile defines a market with big price swings.
+	Volatile Regime = "volatile"
+)
+
+// AnyRegime checks if the given regime is part of the regimes.
+// An empty set of regimes, or a missing regime classification, matches any regime.
+func AnyRegime(regime Regime, regimes ...Regime) bool {
+	if len(regimes) == 0 || regime == NoRegime {
+		return true
+	}
+	for _, r := range regimes {
+		if r == regime {
+			return true
+		}
+	}
+	return false
+}
@@ -105,6 +105,12 @@ type Meta struct {
 	Size     int       `json:"size"`
 	Live     bool      `json:"live"`
 	Exchange string    `json:"exchange"`
+	Regime   Regime    `json:"regime"`
+	// Interval is the aggregation interval of the signal , zero for the raw trades.
+	Interval time.Duration `json:"interval"`
+	// Anomalies is the number of data quality anomalies of the trade ,
+	// or of the trades within the interval for the aggregated signals.
+	Anomalies int `json:"anomalies"`
 }
 
 type Book struct {
@@ -116,11 +122,12 @@ type Book struct {
 
 // TradeSignal defines a generic trade signal
 type TradeSignal struct {
-	Coin   Coin   `json:"coin"`
-	Meta   Meta   `json:"meta"`
-	Tick   Tick   `json:"tick"`
-	Book   Book   `json:"-"`
-	Spread Spread `json:"-"`
+	Coin      Coin      `json:"coin"`
+	Meta      Meta      `json:"meta"`
+	Tick      Tick      `json:"tick"`
+	Book      Book      `json:"-"`
+	OrderBook OrderBook `json:"order_book"`
+	Spread    Spread    `json:"-"`
 }
 
 //// Trade represents a trade object with all necessary details.
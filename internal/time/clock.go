@@ -0,0 +1,218 @@
+package time
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// Clock provides the current time and the timers ,
+// so that the time can be simulated e.g. for running back-tests at the speed of the trade stream.
+type Clock interface {
+	Now() time.Time
+	Since(t time.Time) time.Duration
+	After(d time.Duration) <-chan time.Time
+	NewTicker(d time.Duration) Ticker
+}
+
+// Ticker delivers the ticks of a clock at the given interval.
+type Ticker interface {
+	C() <-chan time.Time
+	Stop()
+}
+
+// Simulator is a clock that is advanced explicitly , instead of following the wall clock.
+type Simulator interface {
+	Clock
+	Advance(t time.Time)
+}
+
+type systemClock struct{}
+
+// System is the wall clock.
+var System Clock = systemClock{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+func (systemClock) Since(t time.Time) time.Duration {
+	return time.Since(t)
+}
+
+func (systemClock) After(d time.Duration) <-chan time.Time {
+	return time.After(d)
+}
+
+func (systemClock) NewTicker(d time.Duration) Ticker {
+	return systemTicker{ticker: time.NewTicker(d)}
+}
+
+type systemTicker struct {
+	ticker *time.Ticker
+}
+
+func (t systemTicker) C() <-chan time.Time {
+	return t.ticker.C
+}
+
+func (t systemTicker) Stop() {
+	t.ticker.Stop()
+}
+
+var current = struct {
+	lock  *sync.RWMutex
+	clock Clock
+}{
+	lock:  new(sync.RWMutex),
+	clock: System,
+}
+
+// SetClock sets the clock for the components that are not given one explicitly e.g. the api messages.
+func SetClock(clock Clock) {
+	current.lock.Lock()
+	defer current.lock.Unlock()
+	if clock == nil {
+		clock = System
+	}
+	current.clock = clock
+}
+
+// Current returns the clock set with SetClock , the System clock by default.
+func Current() Clock {
+	current.lock.RLock()
+	defer current.lock.RUnlock()
+	return current.clock
+}
+
+// Now returns the current time of the current clock.
+func Now() time.Time {
+	return Current().Now()
+}
+
+// SimClock is a simulated clock , it only moves when advanced ,
+// and fires the timers that are due in simulated time order.
+// Like the wall clock tickers , a simulated ticker drops the ticks that its consumer is not ready for.
+type SimClock struct {
+	lock   *sync.Mutex
+	now    time.Time
+	timers []*simTimer
+}
+
+type simTimer struct {
+	at      time.Time
+	period  time.Duration
+	c       chan time.Time
+	stopped bool
+}
+
+// NewSimClock creates a new simulated clock starting at the given time.
+func NewSimClock(start time.Time) *SimClock {
+	return &SimClock{
+		lock:   new(sync.Mutex),
+		now:    start,
+		timers: make([]*simTimer, 0),
+	}
+}
+
+// Now returns the simulated time.
+func (c *SimClock) Now() time.Time {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.now
+}
+
+// Since returns the simulated time passed since t.
+func (c *SimClock) Since(t time.Time) time.Duration {
+	return c.Now().Sub(t)
+}
+
+// After returns a channel that receives the simulated time , once the clock is advanced by d.
+func (c *SimClock) After(d time.Duration) <-chan time.Time {
+	return c.add(d, 0).c
+}
+
+// NewTicker creates a ticker that ticks every d of simulated time.
+func (c *SimClock) NewTicker(d time.Duration) Ticker {
+	if d <= 0 {
+		panic("non-positive interval for NewTicker")
+	}
+	return &simTicker{clock: c, timer: c.add(d, d)}
+}
+
+func (c *SimClock) add(d time.Duration, period time.Duration) *simTimer {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	timer := &simTimer{
+		at:     c.now.Add(d),
+		period: period,
+		c:      make(chan time.Time, 1),
+	}
+	if d <= 0 {
+		timer.c <- c.now
+		return timer
+	}
+	c.timers = append(c.timers, timer)
+	return timer
+}
+
+// Advance moves the clock to the given time , firing the due timers in order.
+// The clock never moves backwards.
+func (c *SimClock) Advance(t time.Time) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	for {
+		active := c.timers[:0]
+		for _, timer := range c.timers {
+			if !timer.stopped {
+				active = append(active, timer)
+			}
+		}
+		c.timers = active
+		if len(c.timers) == 0 {
+			break
+		}
+		sort.SliceStable(c.timers, func(i, j int) bool {
+			return c.timers[i].at.Before(c.timers[j].at)
+		})
+		timer := c.timers[0]
+		if timer.at.After(t) {
+			break
+		}
+		if timer.at.After(c.now) {
+			c.now = timer.at
+		}
+		select {
+		case timer.c <- timer.at:
+		default:
+		}
+		if timer.period > 0 {
+			timer.at = timer.at.Add(timer.period)
+		} else {
+			timer.stopped = true
+		}
+	}
+	if t.After(c.now) {
+		c.now = t
+	}
+}
+
+// Add advances the clock by the given duration.
+func (c *SimClock) Add(d time.Duration) {
+	c.Advance(c.Now().Add(d))
+}
+
+type simTicker struct {
+	clock *SimClock
+	timer *simTimer
+}
+
+func (t *simTicker) C() <-chan time.Time {
+	return t.timer.c
+}
+
+func (t *simTicker) Stop() {
+	t.clock.lock.Lock()
+	defer t.clock.lock.Unlock()
+	t.timer.stopped = true
+}
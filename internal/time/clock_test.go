@@ -0,0 +1,53 @@
+package time
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSimClock(t *testing.T) {
+	start := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	clock := NewSimClock(start)
+
+	after := clock.After(time.Minute)
+	ticker := clock.NewTicker(10 * time.Second)
+
+	clock.Advance(start.Add(5 * time.Second))
+	assert.Equal(t, start.Add(5*time.Second), clock.Now())
+	assert.Equal(t, 0, len(after))
+	assert.Equal(t, 0, len(ticker.C()))
+
+	clock.Advance(start.Add(15 * time.Second))
+	assert.Equal(t, start.Add(10*time.Second), <-ticker.C())
+
+	// the clock does not go back
+	clock.Advance(start)
+	assert.Equal(t, start.Add(15*time.Second), clock.Now())
+	assert.Equal(t, 10*time.Second, clock.Since(start.Add(5*time.Second)))
+
+	// ticks are dropped if not consumed , like the wall clock tickers
+	clock.Add(time.Hour)
+	assert.Equal(t, start.Add(time.Minute), <-after)
+	assert.Equal(t, start.Add(20*time.Second), <-ticker.C())
+	assert.Equal(t, 0, len(ticker.C()))
+
+	ticker.Stop()
+	clock.Add(time.Hour)
+	assert.Equal(t, 0, len(ticker.C()))
+	assert.Equal(t, start.Add(2*time.Hour+15*time.Second), clock.Now())
+}
+
+func TestSetClock(t *testing.T) {
+	start := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	clock := NewSimClock(start)
+	SetClock(clock)
+	defer SetClock(nil)
+
+	assert.Equal(t, start, Now())
+	assert.True(t, IsValidTime(start.Add(30*time.Second)))
+	assert.False(t, IsValidTime(time.Now()))
+	clock.Add(24 * time.Hour)
+	assert.Equal(t, start.Unix(), ThisDay())
+}
@@ -15,11 +15,12 @@ func ToString(t time.Time) string {
 }
 
 func ToNow(t time.Time) float64 {
-	return t.Sub(time.Now()).Seconds()
+	return t.Sub(Now()).Seconds()
 }
 
+// IsValidTime checks if the given time is within a minute of the current clock time.
 func IsValidTime(t time.Time) bool {
-	now := time.Now()
+	now := Now()
 	ms := t.Unix() - now.Unix()
 	d := time.Second * time.Duration(math.Abs(float64(ms)))
 	if d > time.Minute {
@@ -69,22 +70,22 @@ func ToMilli(t time.Time) int64 {
 
 // ThisWeek returns the unix time in seconds for the last 7 days.
 func ThisWeek() int64 {
-	return time.Now().AddDate(0, 0, -7).Unix()
+	return Now().AddDate(0, 0, -7).Unix()
 }
 
 // ThisDay returns the unix time in seconds for the last 24 hours.
 func ThisDay() int64 {
-	return time.Now().Add(-24 * time.Hour).Unix()
+	return Now().Add(-24 * time.Hour).Unix()
 }
 
 // LastXHours returns the time x hours before the current time in nanoseconds.
 func LastXHours(h int) int64 {
-	return time.Now().Add(-1*time.Duration(h)*time.Hour).Unix() * time.Second.Nanoseconds()
+	return Now().Add(-1*time.Duration(h)*time.Hour).Unix() * time.Second.Nanoseconds()
 }
 
 // ThisInstant returns the current time in nanoseconds.
 func ThisInstant() int64 {
-	return time.Now().Unix() * time.Second.Nanoseconds()
+	return Now().Unix() * time.Second.Nanoseconds()
 }
 
 func At(year, month, day, hour int) int64 {
@@ -145,7 +146,7 @@ func (d *Duration) UnmarshalJSON(b []byte) error {
 
 // Execute executes the given function at the specified interval providing also a shutdown hook.
 func Execute(stop <-chan struct{}, interval time.Duration, exec func() error, shutdown func()) {
-	ticker := time.NewTicker(interval)
+	ticker := Current().NewTicker(interval)
 	go func() {
 		err := exec()
 		if err != nil {
@@ -154,7 +155,7 @@ func Execute(stop <-chan struct{}, interval time.Duration, exec func() error, sh
 		defer shutdown()
 		for {
 			select {
-			case <-ticker.C:
+			case <-ticker.C():
 				err := exec()
 				if err != nil {
 					log.Warn().Err(err).Msg("ERROR")
@@ -0,0 +1,73 @@
+package trader
+
+import (
+	"fmt"
+
+	"github.com/drakos74/free-coin/internal/account"
+	"github.com/drakos74/free-coin/internal/api"
+	"github.com/drakos74/free-coin/internal/storage"
+)
+
+// ExchangeProvider creates the exchange for the given account.
+type ExchangeProvider func(details account.Details) (api.Exchange, error)
+
+// Account is an exchange trader for a specific user account.
+type Account struct {
+	Details  account.Details
+	Exchange api.Exchange
+	*ExchangeTrader
+}
+
+// NewAccounts creates an exchange trader for each of the given accounts.
+// Each account keeps its own positions and pnl , the open value is scaled by the account multiplier
+// and the orders are submitted without leverage , unless it is a margin account.
+// Paper accounts submit all orders to their virtual exchange.
+func NewAccounts(id string, shard storage.Shard, registry storage.EventRegistry, settings Settings, exchange ExchangeProvider, u api.User, details ...account.Details) ([]*Account, error) {
+	err := account.Validate(details...)
+	if err != nil {
+		return nil, fmt.Errorf("invalid accounts: %w", err)
+	}
+	accounts := make([]*Account, len(details))
+	for i, d := range details {
+		e, err := exchange(d)
+		if err != nil {
+			return nil, fmt.Errorf("could not create exchange for account '%s': %w", d.Name, err)
+		}
+		s := settings
+		s.OpenValue = d.Scale(settings.OpenValue)
+		s.Spot = !d.Exchange.Margin
+		s.Paper = d.Exchange.Paper
+		t, err := SimpleTrader(fmt.Sprintf("%s-%s", id, d.Name), shard, registry, s, e, u)
+		if err != nil {
+			return nil, fmt.Errorf("could not create trader for account '%s': %w", d.Name, err)
+		}
+		accounts[i] = &Account{
+			Details:        d,
+			Exchange:       e,
+			ExchangeTrader: t,
+		}
+	}
+	return accounts, nil
+}
+
+// Live returns if an order meant to be live is submitted for the account ,
+// the orders of the accounts that are not live are only tracked.
+func (a *Account) Live(live bool) bool {
+	return live && a.Details.Live
+}
+
+// Capital returns the capital of the account for the performance analytics.
+func (a *Account) Capital() float64 {
+	if a.Details.Exchange.Paper {
+		return a.Details.Exchange.Balance
+	}
+	return a.Settings().OpenValue
+}
+
+// Index returns the user channel for the account , or the given default one.
+func (a *Account) Index(index api.Index) api.Index {
+	if a.Details.User.Index != "" {
+		return a.Details.User.Index
+	}
+	return index
+}
@@ -0,0 +1,63 @@
+package trader
+
+import (
+	"testing"
+	"time"
+
+	"github.com/drakos74/free-coin/internal/account"
+	"github.com/drakos74/free-coin/internal/api"
+	"github.com/drakos74/free-coin/internal/model"
+	"github.com/drakos74/free-coin/internal/storage"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewAccounts(t *testing.T) {
+
+	type test struct {
+		details   account.Details
+		openValue float64
+		submitted bool
+	}
+
+	tests := map[string]test{
+		"live": {
+			details:   account.Details{Name: "live", Live: true, Exchange: account.ExchangeDetails{Margin: true}},
+			openValue: 100,
+			submitted: true,
+		},
+		"tracked": {
+			details:   account.Details{Name: "tracked", Exchange: account.ExchangeDetails{Margin: true}},
+			openValue: 100,
+		},
+		"scaled": {
+			details:   account.Details{Name: "scaled", Multiplier: 2, Live: true, Exchange: account.ExchangeDetails{Margin: true}},
+			openValue: 200,
+			submitted: true,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			upstream := newNetExchange()
+			accounts, err := NewAccounts("test", storage.VoidShard(""), storage.MockEventRegistry(), Settings{OpenValue: 100},
+				func(details account.Details) (api.Exchange, error) {
+					return upstream, nil
+				}, nil, tt.details)
+			assert.NoError(t, err)
+			assert.Equal(t, 1, len(accounts))
+			acc := accounts[0]
+			assert.Equal(t, tt.openValue, acc.Settings().OpenValue)
+			assert.Equal(t, tt.openValue, acc.Capital())
+
+			_, ok, _, err := acc.CreateOrder(model.Key{Coin: model.BTC, Duration: time.Minute}, time.Now(), 100, model.Buy, true, 0, SignalReason, acc.Live(true), nil)
+			assert.NoError(t, err)
+			assert.True(t, ok)
+			// the position is tracked for all the accounts , but only submitted for the live ones
+			_, positions := acc.CurrentPositions(model.BTC)
+			assert.Equal(t, 1, len(positions))
+			upstream.lock.Lock()
+			assert.Equal(t, tt.submitted, upstream.volumes[model.BTC] > 0)
+			upstream.lock.Unlock()
+		})
+	}
+}
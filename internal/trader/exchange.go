@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"math"
+	"strconv"
 	"time"
 
 	"github.com/drakos74/free-coin/internal/emoji"
@@ -12,6 +13,7 @@ import (
 
 	"github.com/drakos74/free-coin/internal/api"
 	"github.com/drakos74/free-coin/internal/model"
+	cointime "github.com/drakos74/free-coin/internal/time"
 	"github.com/rs/zerolog/log"
 )
 
@@ -82,7 +84,22 @@ type ExchangeTrader struct {
 	settings Settings
 	tracker  *tracker
 	log      *Log
+	journal  *Journal
 	user     api.User
+	clock    cointime.Clock
+	missing  map[model.Coin]int
+	outbox   *outbox
+	retry    Retry
+}
+
+// Retry defines how many times a failed order is retried ,
+// and the backoff before the first retry , which doubles for every next one.
+// Timeout bounds the calls to the exchange for an order , including its retries , and for the recovery of the pending orders.
+// The backoff and the timeout are on the wall clock , as they follow the exchange and not the trade time.
+type Retry struct {
+	Attempts int
+	Backoff  time.Duration
+	Timeout  time.Duration
 }
 
 // SimpleTrader is a simple exchange trader
@@ -103,51 +120,60 @@ func NewExchangeTrader(trader *trader, exchange api.Exchange, registry storage.R
 		settings: settings,
 		tracker:  newTracker(),
 		log:      NewEventLog(registry),
+		journal:  NewJournal(trader.account, registry),
 		user:     u,
+		clock:    clock(settings),
+		missing:  make(map[model.Coin]int),
+		outbox:   newOutbox(trader.account, trader.storage),
+		retry: Retry{
+			Attempts: 3,
+			Backoff:  time.Second,
+			Timeout:  30 * time.Second,
+		},
 	}
+	ctx, cancel := exTrader.retry.context()
+	defer cancel()
+	exTrader.recover(ctx)
 	exTrader.sync()
 	return exTrader
 }
 
+// clock returns the clock of the settings , or the current one if none is given.
+func clock(settings Settings) cointime.Clock {
+	if settings.Clock != nil {
+		return settings.Clock
+	}
+	return cointime.Current()
+}
+
+// WithRetry sets the retries for the failed orders.
+func (xt *ExchangeTrader) WithRetry(attempts int, backoff time.Duration) *ExchangeTrader {
+	xt.retry.Attempts = attempts
+	xt.retry.Backoff = backoff
+	return xt
+}
+
+// context creates the context for the calls to the exchange , bounded by the retry timeout.
+func (r Retry) context() (context.Context, context.CancelFunc) {
+	if r.Timeout <= 0 {
+		return context.WithCancel(context.Background())
+	}
+	return context.WithTimeout(context.Background(), r.Timeout)
+}
+
+// sync syncs the positions with the upstream exchange , at the interval of the trader clock.
 func (xt *ExchangeTrader) sync() {
-	ticker := time.NewTicker(5 * time.Minute)
+	ticker := xt.clock.NewTicker(5 * time.Minute)
 	quit := make(chan struct{})
 	go func() {
 		for {
 			select {
-			case <-ticker.C:
-				pp, err := xt.UpstreamPositions(context.Background())
-				if err != nil {
-					if xt.user != nil {
-						xt.user.Send(api.Index(xt.trader.account), api.NewMessage(fmt.Sprintf("err-get-pos ... %s", err.Error())), nil)
-					}
-					continue
-				}
-				_, positions := xt.CurrentPositions(model.AllCoins)
-				for _, xp := range pp {
-					found := false
-					for k, pos := range positions {
-						if xp.Coin == pos.Coin {
-							found = true
-							newPos, update := pos.Sync(xp)
-							if update {
-								xt.trader.positions[k] = newPos
-								err = xt.trader.save()
-								if xt.user != nil {
-									xt.user.Send(api.Index(xt.trader.account), api.NewMessage(fmt.Sprintf(" synced with upstream %s | %v", formatPos(newPos), err)), nil)
-								}
-							}
-						}
-					}
-					if !found {
-						// open new position
-						xp.Live = true
-						xt.trader.positions[model.Key{Coin: xp.Coin}] = xp
-						err = xt.trader.save()
-						if xt.user != nil {
-							xt.user.Send(api.Index(xt.trader.account), api.NewMessage(fmt.Sprintf(" synced with upstream %s | %v", formatPos(xp), err)), nil)
-						}
-					}
+			case <-ticker.C():
+				ctx, cancel := xt.retry.context()
+				err := xt.reconcile(ctx)
+				cancel()
+				if err != nil && xt.user != nil {
+					xt.user.Send(api.Index(xt.trader.account), api.NewMessage(fmt.Sprintf("err-get-pos ... %s", err.Error())), nil)
 				}
 			case <-quit:
 				ticker.Stop()
@@ -157,6 +183,140 @@ func (xt *ExchangeTrader) sync() {
 	}()
 }
 
+// reconcile aligns the live positions with the upstream exchange , which is the source of truth.
+// The pending orders are resolved first , so that their positions are in place before the comparison.
+// The positions are compared by their net volume for each coin , as the exchange does not know about the strategy keys.
+// A live position missing upstream is only closed if it is missing on consecutive syncs ,
+// so that a partial upstream response does not close it.
+// The positions closed or replaced are recorded in the journal at their last price.
+// Only the margin accounts are reconciled , as the upstream positions are the margin ones ,
+// the spot and paper positions are never reported by the exchange.
+// The trader must be the only one on the exchange account , so the processors trading an account share its trader.
+func (xt *ExchangeTrader) reconcile(ctx context.Context) error {
+	xt.recover(ctx)
+	if xt.settings.Spot || xt.settings.Paper {
+		return nil
+	}
+	pp, err := xt.UpstreamPositions(ctx)
+	if err != nil {
+		return err
+	}
+	upstream := make(map[model.Coin][]model.Position)
+	for _, xp := range pp {
+		upstream[xp.Coin] = append(upstream[xp.Coin], xp)
+	}
+
+	xt.trader.lock.Lock()
+	live := make(map[model.Coin][]model.Key)
+	for k, pos := range xt.trader.positions {
+		if pos.Live {
+			live[pos.Coin] = append(live[pos.Coin], k)
+		}
+	}
+	updates := make([]string, 0)
+	closed := make([]model.Position, 0)
+	for coin, positions := range upstream {
+		delete(xt.missing, coin)
+		xp, ok := net(positions)
+		keys := live[coin]
+		switch {
+		case !ok:
+			// the upstream positions cancel out
+			for _, k := range keys {
+				closed = append(closed, xt.trader.positions[k])
+				delete(xt.trader.positions, k)
+			}
+			if len(keys) > 0 {
+				updates = append(updates, fmt.Sprintf(" closed with upstream %s", coin))
+			}
+		case len(keys) == 1:
+			pos := xt.trader.positions[keys[0]]
+			if newPos, update := pos.Sync(xp); update {
+				xt.trader.positions[keys[0]] = newPos
+				updates = append(updates, fmt.Sprintf(" synced with upstream %s", formatPos(newPos)))
+			}
+		case len(keys) > 1 && math.Abs(netVolume(xt.trader.positions, keys)-xp.Type.Sign()*xp.Volume) < 1e-9:
+			// the strategy positions add up to the upstream one
+		default:
+			// open new position , replacing the ones that do not add up to the upstream
+			for _, k := range keys {
+				closed = append(closed, xt.trader.positions[k])
+				delete(xt.trader.positions, k)
+			}
+			xp.Live = true
+			xt.trader.positions[model.Key{Coin: coin}] = xp
+			updates = append(updates, fmt.Sprintf(" synced with upstream %s", formatPos(xp)))
+		}
+	}
+	for coin := range xt.missing {
+		if _, ok := live[coin]; !ok {
+			delete(xt.missing, coin)
+		}
+	}
+	for coin, keys := range live {
+		if _, ok := upstream[coin]; ok {
+			continue
+		}
+		xt.missing[coin]++
+		if xt.missing[coin] < 2 {
+			continue
+		}
+		delete(xt.missing, coin)
+		for _, k := range keys {
+			closed = append(closed, xt.trader.positions[k])
+			delete(xt.trader.positions, k)
+		}
+		updates = append(updates, fmt.Sprintf(" closed missing upstream %s", coin))
+	}
+	if len(updates) > 0 {
+		err = xt.trader.save()
+	}
+	xt.trader.lock.Unlock()
+
+	now := xt.clock.Now()
+	for _, position := range closed {
+		xt.record(closedTrade(position, now, ReconcileReason))
+	}
+
+	if xt.user != nil {
+		for _, update := range updates {
+			xt.user.Send(api.Index(xt.trader.account), api.NewMessage(fmt.Sprintf("%s | %v", update, err)), nil)
+		}
+	}
+	return err
+}
+
+// net nets the positions of a coin into one , at the average open price.
+// It returns false if the positions cancel out.
+func net(positions []model.Position) (model.Position, bool) {
+	xp := positions[0]
+	volume := 0.0
+	total := 0.0
+	value := 0.0
+	for _, p := range positions {
+		volume += p.Type.Sign() * p.Volume
+		total += p.Volume
+		value += p.OpenPrice * p.Volume
+	}
+	if math.Abs(volume) < 1e-9 {
+		return xp, false
+	}
+	if len(positions) > 1 {
+		xp.Type = model.SignedType(volume)
+		xp.Volume = math.Abs(volume)
+		xp.OpenPrice = value / total
+	}
+	return xp, true
+}
+
+func netVolume(positions map[model.Key]model.Position, keys []model.Key) float64 {
+	volume := 0.0
+	for _, k := range keys {
+		volume += positions[k].Type.Sign() * positions[k].Volume
+	}
+	return volume
+}
+
 func formatPos(pos model.Position) string {
 	return fmt.Sprintf("%s %s at %.2f * %.2f",
 		pos.Coin, emoji.MapType(pos.Type), pos.OpenPrice, pos.Volume)
@@ -349,15 +509,24 @@ func (xt *ExchangeTrader) CreateOrder(key model.Key, time time.Time, price float
 			action = xt.track(key, action)
 			// we intended to close the position , but we dont have anything to close
 			return nil, false, action, fmt.Errorf("ignoring close signal '%s' no open position for '%v'", openType.String(), key)
+		} else if err := xt.checkRisk(price * volume); err != nil {
+			action.Reason = VoidReasonRisk
+			xt.log.append(action)
+			action = xt.track(key, action)
+			return nil, false, action, err
 		} else {
 			xt.log.append(action)
 		}
 	}
+	leverage := model.L_5
+	if xt.settings.Spot {
+		leverage = model.NoLeverage
+	}
 	order := model.NewOrder(key.Coin).
 		Market().
 		WithType(t).
 		WithVolume(volume).
-		WithLeverage(model.L_5).
+		WithLeverage(leverage).
 		CreateTracked(model.Key{
 			Coin:     key.Coin,
 			Duration: key.Duration,
@@ -366,9 +535,20 @@ func (xt *ExchangeTrader) CreateOrder(key model.Key, time time.Time, price float
 		}, time, fmt.Sprintf("%+v", action))
 	order.RefID = close
 	order.Price = price
+	// the client ids are deterministic for the intent , so that a retried order is not executed twice
+	intent := model.NewClientID(xt.trader.account, key.ToString(), strconv.FormatInt(time.UnixNano(), 10), openType.String())
+	order.CID = model.NewClientID(intent, "initial")
 	var err error = nil
-	if live {
-		order, _, err = xt.exchange.OpenOrder(order)
+	// a paper exchange gets all the orders , so that we can compare it against the live one
+	submit := live || xt.settings.Paper
+	if submit {
+		err = xt.submit(Intent{
+			Order:    *order,
+			Close:    close != "",
+			Live:     live,
+			Reason:   reason,
+			Decision: decision,
+		}, order)
 		if err != nil {
 			return nil, false, action, fmt.Errorf("could not send initial order: %w", err)
 		}
@@ -376,17 +556,29 @@ func (xt *ExchangeTrader) CreateOrder(key model.Key, time time.Time, price float
 	if close == "" {
 		err = xt.trader.add(order, live, decision)
 	} else {
+		xt.record(NewTrade(position, order, reason))
 		err = xt.trader.close(key)
+	}
+	xt.resolve(order.CID)
+	if close != "" && open {
 		// and ... open a new one ...
-		if open {
-			if live {
-				_, _, err = xt.exchange.OpenOrder(order)
-				if err != nil {
-					return nil, false, action, fmt.Errorf("could not send reverse order: %w", err)
-				}
+		reverse := *order
+		reverse.CID = model.NewClientID(intent, "reverse")
+		reverse.TxIDs = nil
+		reverse.Fill = nil
+		if submit {
+			err = xt.submit(Intent{
+				Order:    reverse,
+				Live:     live,
+				Reason:   reason,
+				Decision: decision,
+			}, &reverse)
+			if err != nil {
+				return nil, false, action, fmt.Errorf("could not send reverse order: %w", err)
 			}
-			err = xt.trader.add(order, live, decision)
 		}
+		err = xt.trader.add(&reverse, live, decision)
+		xt.resolve(reverse.CID)
 	}
 	if err != nil {
 		log.Error().Err(err).Msg("could not store position")
@@ -396,6 +588,127 @@ func (xt *ExchangeTrader) CreateOrder(key model.Key, time time.Time, price float
 	return order, true, action, err
 }
 
+// submit submits the order of the intent , and retries on errors.
+// Before each retry the exchange is asked for the client order id , so that an order that went through is not submitted again.
+// The intent stays in the outbox until it is resolved , as the outcome of a failed order is unknown.
+// The retries stop at the retry timeout , so that a failing exchange does not hold the trade stream.
+func (xt *ExchangeTrader) submit(intent Intent, order *model.TrackedOrder) error {
+	if err := xt.outbox.add(intent); err != nil {
+		log.Error().Err(err).Str("cid", order.CID).Msg("could not store intent")
+	}
+	defer xt.outbox.done(order.CID)
+	ctx, cancel := xt.retry.context()
+	defer cancel()
+	filled, txIDs, err := xt.exchange.OpenOrder(order)
+	tracker, ok := xt.exchange.(api.OrderTracker)
+	backoff := xt.retry.Backoff
+	for attempt := 0; err != nil && ok && attempt < xt.retry.Attempts; attempt++ {
+		log.Warn().Err(err).
+			Str("cid", order.CID).
+			Int("attempt", attempt).
+			Msg("retrying order")
+		timer := time.NewTimer(backoff)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return fmt.Errorf("could not submit order within %v: %w", xt.retry.Timeout, err)
+		}
+		backoff *= 2
+		found, exists, qErr := tracker.QueryOrder(ctx, order)
+		if qErr != nil {
+			err = fmt.Errorf("could not get order status: %w", qErr)
+			continue
+		}
+		if exists {
+			filled = found
+			txIDs = found.TxIDs
+			err = nil
+			break
+		}
+		filled, txIDs, err = xt.exchange.OpenOrder(order)
+	}
+	if err != nil {
+		return err
+	}
+	order.TxIDs = txIDs
+	if filled != nil && filled.Fill != nil {
+		order.Fill = filled.Fill
+	}
+	return nil
+}
+
+// resolve removes the intent from the outbox , after its order is applied to the positions.
+func (xt *ExchangeTrader) resolve(cid string) {
+	if err := xt.outbox.remove(cid); err != nil {
+		log.Error().Err(err).Str("cid", cid).Msg("could not remove intent")
+	}
+}
+
+// recover resolves the pending intents of the outbox , by asking the exchange for their orders.
+// The orders that went through are applied to the positions , and the ones that did not are dropped.
+// Without a way to look up the orders , the intents are dropped and the positions are synced with the exchange.
+func (xt *ExchangeTrader) recover(ctx context.Context) {
+	tracker, ok := xt.exchange.(api.OrderTracker)
+	for _, intent := range xt.outbox.list() {
+		order := intent.Order
+		if ctx.Err() != nil {
+			// the rest of the intents are left for the next sync
+			log.Warn().Err(ctx.Err()).Str("cid", order.CID).Msg("could not recover pending order")
+			return
+		}
+		if !ok {
+			log.Warn().Str("cid", order.CID).Msg("dropping pending order")
+			xt.resolve(order.CID)
+			continue
+		}
+		found, exists, err := tracker.QueryOrder(ctx, &order)
+		if err != nil {
+			log.Error().Err(err).Str("cid", order.CID).Msg("could not get pending order status")
+			continue
+		}
+		if exists {
+			order.TxIDs = found.TxIDs
+			if err := xt.apply(intent, &order); err != nil {
+				log.Error().Err(err).Str("cid", order.CID).Msg("could not apply pending order")
+			}
+		}
+		xt.resolve(order.CID)
+	}
+}
+
+// apply applies an order that went through to the positions.
+func (xt *ExchangeTrader) apply(intent Intent, order *model.TrackedOrder) error {
+	if !intent.Close {
+		return xt.trader.add(order, intent.Live, intent.Decision)
+	}
+	position, ok, _ := xt.trader.check(order.Key)
+	if !ok {
+		return fmt.Errorf("no position to close for '%s'", order.Key.ToString())
+	}
+	xt.record(NewTrade(position, order, intent.Reason))
+	return xt.trader.close(order.Key)
+}
+
+// checkRisk checks if a new position of the given value is within the risk limits.
+func (xt *ExchangeTrader) checkRisk(value float64) error {
+	if xt.settings.MaxPositions == 0 && xt.settings.MaxExposure == 0 {
+		return nil
+	}
+	_, positions := xt.trader.getAll(model.AllCoins)
+	if xt.settings.MaxPositions > 0 && len(positions) >= xt.settings.MaxPositions {
+		return fmt.Errorf("max positions reached: %d", len(positions))
+	}
+	exposure := value
+	for _, p := range positions {
+		exposure += p.OpenPrice * p.Volume
+	}
+	if xt.settings.MaxExposure > 0 && exposure > xt.settings.MaxExposure {
+		return fmt.Errorf("max exposure exceeded: %.2f > %.2f", exposure, xt.settings.MaxExposure)
+	}
+	return nil
+}
+
 func (xt *ExchangeTrader) track(key model.Key, action Event) Event {
 	action.Coin = xt.tracker.PnLPerCoin[key.Coin]
 	action.Global = xt.tracker.Stats
@@ -403,11 +716,28 @@ func (xt *ExchangeTrader) track(key model.Key, action Event) Event {
 	return action
 }
 
+// Reset drops the positions of the given coins , and records them in the journal at their last price.
 func (xt *ExchangeTrader) Reset(coins ...model.Coin) (int, error) {
-	pp, err := xt.trader.reset(coins...)
+	pp, removed, err := xt.trader.reset(coins...)
+	now := xt.clock.Now()
+	for _, position := range removed {
+		xt.record(closedTrade(position, now, ForceResetReason))
+	}
 	return len(pp), err
 }
 
+// record adds the trade to the journal.
+func (xt *ExchangeTrader) record(trade Trade) {
+	if err := xt.journal.add(trade); err != nil {
+		log.Error().Err(err).Str("key", trade.Key.ToString()).Msg("could not record trade")
+	}
+}
+
+// Journal returns the journal of the round-trip trades.
+func (xt *ExchangeTrader) Journal() *Journal {
+	return xt.journal
+}
+
 // Actions returns the exchange actions so far
 func (xt *ExchangeTrader) Actions() map[model.Coin][]Event {
 	return xt.log.Events
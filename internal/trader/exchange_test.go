@@ -1,12 +1,12 @@
 package trader
 
 import (
+	"context"
 	"testing"
 	"time"
 
 	"github.com/drakos74/free-coin/client/local"
 	"github.com/drakos74/free-coin/internal/model"
-	"github.com/drakos74/free-coin/internal/storage/file/json"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -158,16 +158,14 @@ func TestExchangeTrader_CreateOrder(t *testing.T) {
 
 	for name, tt := range tests {
 		t.Run(name, func(t *testing.T) {
-			exchange := local.NewExchange("")
-			trd, err := newTrader("test", json.LocalShard(), make(map[model.Coin]map[time.Duration]Settings))
-			assert.NoError(t, err)
-			trader := NewExchangeTrader(trd, exchange, nil)
+			exchange := local.NewExchange(local.VoidLog)
+			trader := newTestTrader(t, exchange)
 
 			for _, signal := range tt.signals {
 				o, ok, _, err := trader.CreateOrder(model.Key{
 					Coin:     signal.c,
 					Duration: signal.d,
-				}, time.Now(), signal.p, signal.t, true, 1)
+				}, time.Now(), signal.p, signal.t, true, 1, SignalReason, true, nil)
 				if !assert.NoError(t, err) {
 					return
 				}
@@ -181,7 +179,7 @@ func TestExchangeTrader_CreateOrder(t *testing.T) {
 			orders := exchange.Orders()
 			sum := 0.0
 			for _, o := range orders {
-				s := o.Volume * o.OpenPrice
+				s := o.Volume * o.Price
 				switch o.Type {
 				case model.Buy:
 					sum -= s
@@ -193,9 +191,10 @@ func TestExchangeTrader_CreateOrder(t *testing.T) {
 			}
 			assert.Equal(t, tt.wallet, sum)
 
-			assert.Equal(t, tt.open, len(trd.positions))
+			_, positions := trader.CurrentPositions(model.AllCoins)
+			assert.Equal(t, tt.open, len(positions))
 			assert.Equal(t, tt.total, len(orders))
-			wallet := exchange.Gather(true)
+			wallet := exchange.Gather(false)
 			assert.Equal(t, tt.total, wallet[model.BTC].Buy+wallet[model.BTC].Sell)
 		})
 	}
@@ -205,10 +204,61 @@ func TestExchangeTrader_CreateOrder(t *testing.T) {
 // TODO: actually create this test
 func TestExchangeTrader_Update(t *testing.T) {
 
-	exchange := local.NewExchange("")
-	trd, err := newTrader("test", json.LocalShard(), make(map[model.Coin]map[time.Duration]Settings))
-	assert.NoError(t, err)
-	trader := NewExchangeTrader(trd, exchange, nil)
+	exchange := local.NewExchange(local.VoidLog)
+	trader := newTestTrader(t, exchange)
 	trader.Actions()
 
 }
+
+func TestExchangeTrader_Journal(t *testing.T) {
+	upstream := newNetExchange()
+	xt := newTestTrader(t, upstream)
+
+	// the positions closed by a reset are journaled at their last price
+	_, ok, _, err := xt.CreateOrder(model.Key{Coin: model.ETH, Duration: time.Minute}, time.Now(), 10, model.Sell, true, 2, SignalReason, true, nil)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	_, err = xt.Reset(model.ETH)
+	assert.NoError(t, err)
+	trades := xt.Journal().Trades(Query{Coin: model.ETH})
+	assert.Equal(t, 1, len(trades))
+	assert.Equal(t, ForceResetReason, trades[0].Reason)
+
+	// the positions closed upstream are journaled on the sync that closes them
+	_, ok, _, err = xt.CreateOrder(model.Key{Coin: model.BTC, Duration: time.Minute}, time.Now(), 100, model.Buy, true, 1, SignalReason, true, nil)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	upstream.volumes[model.BTC] = 0
+	assert.NoError(t, xt.reconcile(context.Background()))
+	assert.Equal(t, 0, len(xt.Journal().Trades(Query{Coin: model.BTC})))
+	assert.NoError(t, xt.reconcile(context.Background()))
+	trades = xt.Journal().Trades(Query{Coin: model.BTC})
+	assert.Equal(t, 1, len(trades))
+	assert.Equal(t, ReconcileReason, trades[0].Reason)
+}
+
+func TestExchangeTrader_JournalFill(t *testing.T) {
+	paper := local.NewPaper("paper", 1000).WithFee(0.1)
+	xt := newTestTrader(t, paper)
+	key := model.Key{Coin: model.BTC, Duration: time.Minute}
+
+	_, ok, _, err := xt.CreateOrder(key, time.Now(), 100, model.Buy, true, 1, SignalReason, true, nil)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	// the order fills at the price of the stream , not at the signal price
+	paper.Process(&model.TradeSignal{
+		Coin: model.BTC,
+		Tick: model.Tick{
+			Level: model.Level{Price: 110},
+		},
+	})
+	_, ok, _, err = xt.CreateOrder(key, time.Now(), 105, model.Sell, false, 0, TakeProfitReason, true, nil)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+
+	trades := xt.Journal().Trades(Query{Coin: model.BTC})
+	assert.Equal(t, 1, len(trades))
+	assert.Equal(t, 110.0, trades[0].ClosePrice)
+	assert.InDelta(t, 0.1+0.11, trades[0].Fees, 1e-9)
+	assert.InDelta(t, 10-0.21, trades[0].PnL, 1e-9)
+}
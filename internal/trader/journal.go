@@ -0,0 +1,276 @@
+package trader
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/drakos74/free-coin/internal/model"
+	"github.com/drakos74/free-coin/internal/storage"
+	"github.com/rs/zerolog/log"
+)
+
+const journalPair = "journal"
+
+// Trade is a round-trip trade , from the order opening the position to the one closing it.
+// PnL and Fees are denominated in EUR , Return and MAE are fractions of the position open value.
+// MAE is the max adverse excursion i.e. the worst pnl of the position while it was open.
+type Trade struct {
+	Key        model.Key       `json:"key"`
+	Coin       model.Coin      `json:"coin"`
+	Type       model.Type      `json:"type"`
+	OpenID     string          `json:"open_id"`
+	CloseID    string          `json:"close_id"`
+	OpenTime   time.Time       `json:"open_time"`
+	CloseTime  time.Time       `json:"close_time"`
+	OpenPrice  float64         `json:"open_price"`
+	ClosePrice float64         `json:"close_price"`
+	Volume     float64         `json:"volume"`
+	Fees       float64         `json:"fees"`
+	PnL        float64         `json:"pnl"`
+	Return     float64         `json:"return"`
+	MAE        float64         `json:"mae"`
+	Reason     Reason          `json:"reason"`
+	Decision   *model.Decision `json:"decision"`
+	Live       bool            `json:"live"`
+}
+
+// Hold returns the holding time of the trade.
+func (t Trade) Hold() time.Duration {
+	return t.CloseTime.Sub(t.OpenTime)
+}
+
+// NewTrade creates the round-trip trade for the position closed by the given order.
+// The prices and fees are the ones reported by the exchange for the opening and closing orders ,
+// or the estimated ones if the exchange did not report them.
+func NewTrade(position model.Position, order *model.TrackedOrder, reason Reason) Trade {
+	price, fees := order.Filled()
+	return newTrade(position, order.ID, order.Time, price, fees, reason)
+}
+
+// closedTrade creates the round-trip trade for a position closed without an order e.g. on reset or by the exchange ,
+// at the last price of the position.
+func closedTrade(position model.Position, t time.Time, reason Reason) Trade {
+	price := position.CurrentPrice
+	if price == 0 {
+		price = position.OpenPrice
+	}
+	return newTrade(position, "", t, price, price*position.Volume*model.Fees/100, reason)
+}
+
+func newTrade(position model.Position, closeID string, closeTime time.Time, price float64, closeFees float64, reason Reason) Trade {
+	net := 0.0
+	switch position.Type {
+	case model.Buy:
+		net = price - position.OpenPrice
+	case model.Sell:
+		net = position.OpenPrice - price
+	}
+	volume := position.Volume
+	openFees := position.OpenFees
+	if openFees == 0 {
+		openFees = position.OpenPrice * volume * model.Fees / 100
+	}
+	fees := openFees + closeFees
+	pnl := net*volume - fees
+	ret := 0.0
+	if value := position.OpenPrice * volume; value > 0 {
+		ret = pnl / value
+	}
+	return Trade{
+		Key:        position.Stats.Key,
+		Coin:       position.Coin,
+		Type:       position.Type,
+		OpenID:     position.OrderID,
+		CloseID:    closeID,
+		OpenTime:   position.OpenTime,
+		CloseTime:  closeTime,
+		OpenPrice:  position.OpenPrice,
+		ClosePrice: price,
+		Volume:     volume,
+		Fees:       fees,
+		PnL:        pnl,
+		Return:     ret,
+		MAE:        math.Min(position.MAE, ret),
+		Reason:     reason,
+		Decision:   position.Decision,
+		Live:       position.Live,
+	}
+}
+
+// Query filters the journal trades , the zero values match all trades.
+// From and To apply to the close time of the trades.
+type Query struct {
+	Coin   model.Coin
+	Key    model.Key
+	From   time.Time
+	To     time.Time
+	Reason Reason
+}
+
+func (q Query) match(t Trade) bool {
+	if q.Coin != "" && q.Coin != model.AllCoins && q.Coin != t.Coin {
+		return false
+	}
+	if q.Key != (model.Key{}) && q.Key != t.Key {
+		return false
+	}
+	if !q.From.IsZero() && t.CloseTime.Before(q.From) {
+		return false
+	}
+	if !q.To.IsZero() && !t.CloseTime.Before(q.To) {
+		return false
+	}
+	if q.Reason != "" && q.Reason != t.Reason {
+		return false
+	}
+	return true
+}
+
+// Summary summarises the performance of the journal trades.
+// Expectancy is the average pnl per trade and MaxAdverse the worst max adverse excursion.
+type Summary struct {
+	Trades     int
+	Wins       int
+	Losses     int
+	WinRate    float64
+	PnL        float64
+	Fees       float64
+	Expectancy float64
+	AvgHold    time.Duration
+	MaxAdverse float64
+}
+
+// Journal records the round-trip trades of an account.
+type Journal struct {
+	lock     *sync.RWMutex
+	account  string
+	registry storage.Registry
+	trades   []Trade
+}
+
+// NewJournal creates a new journal for the account , loading the trades already in the registry.
+func NewJournal(account string, registry storage.Registry) *Journal {
+	j := &Journal{
+		lock:     new(sync.RWMutex),
+		account:  account,
+		registry: registry,
+		trades:   make([]Trade, 0),
+	}
+	if registry != nil {
+		trades := []Trade{{}}
+		err := registry.GetAll(j.key(), &trades)
+		if err != nil {
+			log.Warn().Err(err).Str("account", account).Msg("could not load journal")
+		}
+		for _, t := range trades {
+			if !t.CloseTime.IsZero() {
+				j.trades = append(j.trades, t)
+			}
+		}
+	}
+	return j
+}
+
+func (j *Journal) key() storage.K {
+	return storage.K{
+		Pair:  journalPair,
+		Label: j.account,
+	}
+}
+
+func (j *Journal) add(trade Trade) error {
+	j.lock.Lock()
+	defer j.lock.Unlock()
+	j.trades = append(j.trades, trade)
+	if j.registry == nil {
+		return nil
+	}
+	return j.registry.Add(j.key(), trade)
+}
+
+// Trades returns the trades matching the query , ordered by close time.
+func (j *Journal) Trades(query Query) []Trade {
+	j.lock.RLock()
+	defer j.lock.RUnlock()
+	trades := make([]Trade, 0)
+	for _, t := range j.trades {
+		if query.match(t) {
+			trades = append(trades, t)
+		}
+	}
+	sort.SliceStable(trades, func(i, k int) bool {
+		return trades[i].CloseTime.Before(trades[k].CloseTime)
+	})
+	return trades
+}
+
+// Summary summarises the trades matching the query.
+func (j *Journal) Summary(query Query) Summary {
+	trades := j.Trades(query)
+	s := Summary{Trades: len(trades)}
+	if len(trades) == 0 {
+		return s
+	}
+	var hold time.Duration
+	for _, t := range trades {
+		if t.PnL > 0 {
+			s.Wins++
+		} else {
+			s.Losses++
+		}
+		s.PnL += t.PnL
+		s.Fees += t.Fees
+		hold += t.Hold()
+		if t.MAE < s.MaxAdverse {
+			s.MaxAdverse = t.MAE
+		}
+	}
+	n := float64(len(trades))
+	s.WinRate = float64(s.Wins) / n
+	s.Expectancy = s.PnL / n
+	s.AvgHold = hold / time.Duration(len(trades))
+	return s
+}
+
+var csvHeader = []string{
+	"open_time", "close_time", "coin", "key", "type", "volume",
+	"open_price", "close_price", "fees_eur", "pnl_eur", "return", "hold", "mae", "reason", "live",
+}
+
+// WriteCSV exports the trades matching the query in csv format.
+func (j *Journal) WriteCSV(w io.Writer, query Query) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(csvHeader); err != nil {
+		return fmt.Errorf("could not write csv header: %w", err)
+	}
+	for _, t := range j.Trades(query) {
+		err := writer.Write([]string{
+			t.OpenTime.Format(time.RFC3339),
+			t.CloseTime.Format(time.RFC3339),
+			string(t.Coin),
+			t.Key.ToString(),
+			t.Type.String(),
+			strconv.FormatFloat(t.Volume, 'f', -1, 64),
+			strconv.FormatFloat(t.OpenPrice, 'f', -1, 64),
+			strconv.FormatFloat(t.ClosePrice, 'f', -1, 64),
+			strconv.FormatFloat(t.Fees, 'f', 2, 64),
+			strconv.FormatFloat(t.PnL, 'f', 2, 64),
+			strconv.FormatFloat(t.Return, 'f', 4, 64),
+			t.Hold().String(),
+			strconv.FormatFloat(t.MAE, 'f', 4, 64),
+			string(t.Reason),
+			strconv.FormatBool(t.Live),
+		})
+		if err != nil {
+			return fmt.Errorf("could not write csv trade: %w", err)
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
@@ -0,0 +1,185 @@
+package trader
+
+import (
+	"bytes"
+	"encoding/csv"
+	"testing"
+	"time"
+
+	"github.com/drakos74/free-coin/internal/model"
+	"github.com/drakos74/free-coin/internal/storage"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewTrade(t *testing.T) {
+
+	type test struct {
+		position model.Position
+		price    float64
+		fill     *model.Fill
+		pnl      float64
+		fees     float64
+	}
+
+	open := time.Date(2022, 1, 1, 10, 0, 0, 0, time.UTC)
+
+	tests := map[string]test{
+		"buy-profit": {
+			position: model.Position{Coin: model.BTC, Type: model.Buy, OpenPrice: 100, Volume: 2},
+			price:    110,
+			pnl:      20 - 0.84,
+			fees:     0.84,
+		},
+		"sell-profit": {
+			position: model.Position{Coin: model.BTC, Type: model.Sell, OpenPrice: 100, Volume: 2},
+			price:    90,
+			pnl:      20 - 0.76,
+			fees:     0.76,
+		},
+		"buy-loss": {
+			position: model.Position{Coin: model.BTC, Type: model.Buy, OpenPrice: 100, Volume: 2},
+			price:    90,
+			pnl:      -20 - 0.76,
+			fees:     0.76,
+		},
+		"buy-filled": {
+			position: model.Position{Coin: model.BTC, Type: model.Buy, OpenPrice: 100, Volume: 2,
+				MetaData: model.MetaData{OpenFees: 0.1}},
+			price: 110,
+			fill:  &model.Fill{Price: 111, Volume: 2, Fees: 0.2},
+			pnl:   22 - 0.3,
+			fees:  0.3,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			tt.position.OpenTime = open
+			order := model.NewOrder(model.BTC).
+				Market().
+				WithType(tt.position.Type.Inv()).
+				WithVolume(tt.position.Volume).
+				CreateTracked(model.Key{Coin: model.BTC}, open.Add(time.Hour), "")
+			order.Price = tt.price
+			order.Fill = tt.fill
+			trade := NewTrade(tt.position, order, TakeProfitReason)
+			assert.InDelta(t, tt.pnl, trade.PnL, 1e-9)
+			assert.InDelta(t, tt.fees, trade.Fees, 1e-9)
+			assert.InDelta(t, tt.pnl/200, trade.Return, 1e-9)
+			assert.Equal(t, time.Hour, trade.Hold())
+			assert.Equal(t, order.ID, trade.CloseID)
+		})
+	}
+}
+
+func TestJournal(t *testing.T) {
+	registry := storage.NewMockRegistry()
+	journal := NewJournal("test", registry)
+
+	start := time.Date(2022, 1, 1, 10, 0, 0, 0, time.UTC)
+	trade := func(coin model.Coin, pnl float64, mae float64, hold time.Duration, reason Reason, at int) Trade {
+		return Trade{
+			Key:       model.Key{Coin: coin, Duration: time.Minute},
+			Coin:      coin,
+			Type:      model.Buy,
+			OpenTime:  start.Add(time.Duration(at)*time.Hour - hold),
+			CloseTime: start.Add(time.Duration(at) * time.Hour),
+			PnL:       pnl,
+			Fees:      1,
+			MAE:       mae,
+			Reason:    reason,
+		}
+	}
+	assert.NoError(t, journal.add(trade(model.BTC, 10, -0.01, time.Hour, TakeProfitReason, 3)))
+	assert.NoError(t, journal.add(trade(model.ETH, -5, -0.05, 3*time.Hour, StopLossReason, 1)))
+	assert.NoError(t, journal.add(trade(model.BTC, -2, -0.02, 2*time.Hour, StopLossReason, 2)))
+	assert.Equal(t, 3, len(registry.Events[journal.key()]))
+
+	type test struct {
+		query   Query
+		summary Summary
+	}
+
+	tests := map[string]test{
+		"all": {
+			query: Query{},
+			summary: Summary{
+				Trades:     3,
+				Wins:       1,
+				Losses:     2,
+				WinRate:    1.0 / 3,
+				PnL:        3,
+				Fees:       3,
+				Expectancy: 1,
+				AvgHold:    2 * time.Hour,
+				MaxAdverse: -0.05,
+			},
+		},
+		"coin": {
+			query: Query{Coin: model.BTC},
+			summary: Summary{
+				Trades:     2,
+				Wins:       1,
+				Losses:     1,
+				WinRate:    0.5,
+				PnL:        8,
+				Fees:       2,
+				Expectancy: 4,
+				AvgHold:    90 * time.Minute,
+				MaxAdverse: -0.02,
+			},
+		},
+		"time-and-reason": {
+			query: Query{From: start.Add(90 * time.Minute), Reason: StopLossReason},
+			summary: Summary{
+				Trades:     1,
+				Losses:     1,
+				PnL:        -2,
+				Fees:       1,
+				Expectancy: -2,
+				AvgHold:    2 * time.Hour,
+				MaxAdverse: -0.02,
+			},
+		},
+		"key": {
+			query: Query{Key: model.Key{Coin: model.ETH, Duration: time.Minute}},
+			summary: Summary{
+				Trades:     1,
+				Losses:     1,
+				PnL:        -5,
+				Fees:       1,
+				Expectancy: -5,
+				AvgHold:    3 * time.Hour,
+				MaxAdverse: -0.05,
+			},
+		},
+		"none": {
+			query:   Query{To: start},
+			summary: Summary{},
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			summary := journal.Summary(tt.query)
+			assert.InDelta(t, tt.summary.WinRate, summary.WinRate, 1e-9)
+			summary.WinRate = tt.summary.WinRate
+			assert.Equal(t, tt.summary, summary)
+		})
+	}
+
+	// the trades are ordered by close time
+	trades := journal.Trades(Query{})
+	assert.Equal(t, model.ETH, trades[0].Coin)
+	assert.Equal(t, -2.0, trades[1].PnL)
+
+	buffer := new(bytes.Buffer)
+	assert.NoError(t, journal.WriteCSV(buffer, Query{Coin: model.BTC}))
+	records, err := csv.NewReader(buffer).ReadAll()
+	assert.NoError(t, err)
+	assert.Equal(t, 3, len(records))
+	assert.Equal(t, csvHeader, records[0])
+	assert.Equal(t, "-2.00", records[1][9])
+	assert.Equal(t, "2h0m0s", records[1][11])
+	assert.Equal(t, string(TakeProfitReason), records[2][13])
+}
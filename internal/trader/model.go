@@ -11,6 +11,7 @@ import (
 	"github.com/rs/zerolog/log"
 
 	"github.com/drakos74/free-coin/internal/model"
+	cointime "github.com/drakos74/free-coin/internal/time"
 )
 
 const (
@@ -23,11 +24,21 @@ type State struct {
 	Positions map[string]model.Position `json:"positions"`
 }
 
+// Settings are the trader settings.
+// Spot defines that the orders are submitted without leverage.
+// Paper defines that all orders are submitted , regardless of the live flag , as the exchange is a virtual one.
+// MaxPositions and MaxExposure are the risk limits for opening new positions , zero means no limit.
+// Clock drives the syncs and the retries of the trader , if nil the current clock is used.
 type Settings struct {
 	OpenValue      float64
 	TakeProfit     float64
 	StopLoss       float64
 	TrackingConfig []*model.TrackingConfig
+	Spot           bool
+	Paper          bool
+	MaxPositions   int
+	MaxExposure    float64
+	Clock          cointime.Clock
 }
 
 type config struct {
@@ -77,7 +88,9 @@ const (
 	VoidHistoryReasonType Reason = "void-history"
 	VoidReasonClose       Reason = "void-close"
 	VoidReasonReverse     Reason = "void-reverse"
+	VoidReasonRisk        Reason = "void-risk"
 	ForceResetReason      Reason = "reset"
+	ReconcileReason       Reason = "reconcile"
 )
 
 // Event defines a trading action for reference and debugging.
@@ -0,0 +1,104 @@
+package trader
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/drakos74/free-coin/internal/model"
+	"github.com/drakos74/free-coin/internal/storage"
+	"github.com/rs/zerolog/log"
+)
+
+// Intent is an order submitted to the exchange , along with what it means for the positions.
+// Close defines that the order closes the position of the order key , otherwise it opens one.
+type Intent struct {
+	Order    model.TrackedOrder `json:"order"`
+	Close    bool               `json:"close"`
+	Live     bool               `json:"live"`
+	Reason   Reason             `json:"reason"`
+	Decision *model.Decision    `json:"decision"`
+}
+
+// outbox keeps the intents until their outcome on the exchange is applied to the positions.
+// It is persisted , so that the intents with an unknown outcome are reconciled after a restart.
+type outbox struct {
+	lock     *sync.Mutex
+	storage  storage.Persistence
+	account  string
+	pending  map[string]Intent
+	inflight map[string]bool
+}
+
+func newOutbox(account string, st storage.Persistence) *outbox {
+	pending := make(map[string]Intent)
+	err := st.Load(outboxKey(account), &pending)
+	if err != nil {
+		log.Debug().Err(err).Str("account", account).Msg("no pending orders")
+	}
+	if pending == nil {
+		pending = make(map[string]Intent)
+	}
+	return &outbox{
+		lock:     new(sync.Mutex),
+		storage:  st,
+		account:  account,
+		pending:  pending,
+		inflight: make(map[string]bool),
+	}
+}
+
+// add adds the intent before it is submitted.
+func (o *outbox) add(intent Intent) error {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+	o.pending[intent.Order.CID] = intent
+	o.inflight[intent.Order.CID] = true
+	return o.save()
+}
+
+// done marks the end of the submission , the intent stays pending if its outcome is unknown.
+func (o *outbox) done(cid string) {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+	delete(o.inflight, cid)
+}
+
+// remove removes the intent , once its outcome is applied to the positions.
+func (o *outbox) remove(cid string) error {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+	delete(o.inflight, cid)
+	if _, ok := o.pending[cid]; !ok {
+		return nil
+	}
+	delete(o.pending, cid)
+	return o.save()
+}
+
+// list returns the pending intents , that are not being submitted.
+func (o *outbox) list() []Intent {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+	intents := make([]Intent, 0)
+	for cid, intent := range o.pending {
+		if !o.inflight[cid] {
+			intents = append(intents, intent)
+		}
+	}
+	return intents
+}
+
+func (o *outbox) save() error {
+	if err := o.storage.Store(outboxKey(o.account), o.pending); err != nil {
+		return fmt.Errorf("could not store outbox: %w", err)
+	}
+	return nil
+}
+
+func outboxKey(account string) storage.Key {
+	return storage.Key{
+		Pair:  "outbox",
+		Hash:  0,
+		Label: account,
+	}
+}
@@ -0,0 +1,152 @@
+package trader
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/drakos74/free-coin/client/fault"
+	"github.com/drakos74/free-coin/internal/model"
+	"github.com/drakos74/free-coin/internal/storage"
+	"github.com/drakos74/free-coin/internal/storage/file/json"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExchangeTrader_Retry(t *testing.T) {
+
+	type test struct {
+		faults  map[string]fault.Fault
+		err     bool
+		orders  int
+		volume  float64
+		pending int
+	}
+
+	tests := map[string]test{
+		"no-fault": {
+			orders: 1,
+			volume: 1,
+		},
+		"dropped-response": {
+			faults: map[string]fault.Fault{
+				fault.OpenOrder: {Drop: 1},
+			},
+			// the order went through , so it is not submitted again
+			orders: 1,
+			volume: 1,
+		},
+		"error": {
+			faults: map[string]fault.Fault{
+				fault.OpenOrder: {Error: 1},
+			},
+			err:     true,
+			pending: 1,
+		},
+		"unknown-status": {
+			faults: map[string]fault.Fault{
+				fault.OpenOrder:  {Drop: 1},
+				fault.QueryOrder: {Error: 1},
+			},
+			err:     true,
+			orders:  1,
+			pending: 1,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			upstream := newNetExchange()
+			exchange := fault.NewExchange(upstream, 1)
+			for method, f := range tt.faults {
+				exchange.With(method, f)
+			}
+			xt := newTestTrader(t, exchange)
+			order, ok, _, err := xt.CreateOrder(model.Key{Coin: model.BTC, Duration: time.Minute},
+				time.Now(), 100, model.Buy, true, 1, SignalReason, true, nil)
+			if tt.err {
+				assert.Error(t, err)
+				assert.False(t, ok)
+			} else {
+				assert.NoError(t, err)
+				assert.True(t, ok)
+				assert.Equal(t, 16, len(order.CID))
+			}
+			assert.Equal(t, tt.orders, len(upstream.orders))
+			for _, n := range upstream.orders {
+				assert.Equal(t, 1, n)
+			}
+			assert.Equal(t, tt.volume, volume(xt, model.BTC))
+			assert.Equal(t, tt.pending, len(xt.outbox.list()))
+		})
+	}
+}
+
+func TestExchangeTrader_ClientID(t *testing.T) {
+	now := time.Now()
+	key := model.Key{Coin: model.BTC, Duration: time.Minute}
+	submit := func() []string {
+		upstream := newNetExchange()
+		xt := newTestTrader(t, upstream)
+		_, _, _, err := xt.CreateOrder(key, now, 100, model.Buy, true, 1, SignalReason, true, nil)
+		assert.NoError(t, err)
+		// reverse the position
+		_, _, _, err = xt.CreateOrder(key, now.Add(time.Minute), 100, model.Sell, true, 0, SignalReason, true, nil)
+		assert.NoError(t, err)
+		cids := make([]string, 0)
+		for cid := range upstream.orders {
+			cids = append(cids, cid)
+		}
+		return cids
+	}
+	first := submit()
+	// the initial , the close and the reverse orders have their own id
+	assert.Equal(t, 3, len(first))
+	// the same intents give the same ids
+	assert.ElementsMatch(t, first, submit())
+}
+
+func TestExchangeTrader_Recover(t *testing.T) {
+	upstream := newNetExchange()
+	exchange := fault.NewExchange(upstream, 1).
+		With(fault.OpenOrder, fault.Fault{Drop: 1}).
+		With(fault.QueryOrder, fault.Fault{Error: 1})
+
+	// the storage is shared , as after a restart
+	st, err := json.LocalShard()("test")
+	assert.NoError(t, err)
+	shard := func(shard string) (storage.Persistence, error) {
+		return st, nil
+	}
+	trd, err := newTrader("test", shard, nil)
+	assert.NoError(t, err)
+	xt := NewExchangeTrader(trd, exchange, storage.NewVoidRegistry(), Settings{OpenValue: 100}, nil).
+		WithRetry(1, time.Millisecond)
+
+	decision := &model.Decision{Confidence: 0.5}
+	_, _, _, err = xt.CreateOrder(model.Key{Coin: model.BTC, Duration: time.Minute},
+		time.Now(), 100, model.Buy, true, 1, SignalReason, true, decision)
+	assert.Error(t, err)
+	// the second order never reaches the exchange
+	exchange.With(fault.OpenOrder, fault.Fault{Error: 1})
+	_, _, _, err = xt.CreateOrder(model.Key{Coin: model.ETH, Duration: time.Minute},
+		time.Now(), 10, model.Buy, true, 1, SignalReason, true, nil)
+	assert.Error(t, err)
+	assert.Equal(t, 0.0, volume(xt, model.BTC))
+	assert.Equal(t, 2, len(xt.outbox.list()))
+
+	// the pending orders are resolved at start-up
+	exchange.With(fault.QueryOrder, fault.Fault{})
+	exchange.With(fault.OpenOrder, fault.Fault{})
+	trd, err = newTrader("test", shard, nil)
+	assert.NoError(t, err)
+	xt = NewExchangeTrader(trd, exchange, storage.NewVoidRegistry(), Settings{OpenValue: 100}, nil)
+	assert.Equal(t, 0, len(xt.outbox.list()))
+	assert.Equal(t, 1.0, volume(xt, model.BTC))
+	assert.Equal(t, 0.0, volume(xt, model.ETH))
+	_, positions := xt.CurrentPositions(model.BTC)
+	for _, p := range positions {
+		assert.Equal(t, decision.Confidence, p.Decision.Confidence)
+	}
+	assert.NoError(t, xt.reconcile(context.Background()))
+	assert.Equal(t, 1.0, volume(xt, model.BTC))
+}
@@ -0,0 +1,326 @@
+package trader
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"math/rand"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/drakos74/free-coin/client/fault"
+	"github.com/drakos74/free-coin/internal/api"
+	"github.com/drakos74/free-coin/internal/model"
+	"github.com/drakos74/free-coin/internal/storage"
+	cointime "github.com/drakos74/free-coin/internal/time"
+	"github.com/stretchr/testify/assert"
+)
+
+// netExchange is an exchange keeping one net position for each coin.
+type netExchange struct {
+	lock    *sync.Mutex
+	volumes map[model.Coin]float64
+	prices  map[model.Coin]float64
+	orders  map[string]int
+}
+
+func newNetExchange() *netExchange {
+	return &netExchange{
+		lock:    new(sync.Mutex),
+		volumes: make(map[model.Coin]float64),
+		prices:  make(map[model.Coin]float64),
+		orders:  make(map[string]int),
+	}
+}
+
+func (n *netExchange) OpenPositions(ctx context.Context) (*model.PositionBatch, error) {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+	positions := make([]model.Position, 0)
+	for coin, volume := range n.volumes {
+		if math.Abs(volume) < 1e-9 {
+			continue
+		}
+		positions = append(positions, model.Position{
+			Coin:      coin,
+			Type:      model.SignedType(volume),
+			Volume:    math.Abs(volume),
+			OpenPrice: n.prices[coin],
+		})
+	}
+	return &model.PositionBatch{Positions: positions}, nil
+}
+
+func (n *netExchange) OpenOrder(order *model.TrackedOrder) (*model.TrackedOrder, []string, error) {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+	n.volumes[order.Coin] += order.Type.Sign() * order.Volume
+	n.prices[order.Coin] = order.Price
+	n.orders[order.CID]++
+	return order, []string{order.ID}, nil
+}
+
+func (n *netExchange) QueryOrder(ctx context.Context, order *model.TrackedOrder) (*model.TrackedOrder, bool, error) {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+	if n.orders[order.CID] == 0 {
+		return nil, false, nil
+	}
+	return order, true, nil
+}
+
+func (n *netExchange) Balance(ctx context.Context, priceMap map[model.Coin]model.CurrentPrice) (map[model.Coin]model.Balance, error) {
+	return map[model.Coin]model.Balance{}, nil
+}
+
+func (n *netExchange) Pairs(ctx context.Context) map[string]api.Pair {
+	return map[string]api.Pair{}
+}
+
+func (n *netExchange) CurrentPrice(ctx context.Context) (map[model.Coin]model.CurrentPrice, error) {
+	return map[model.Coin]model.CurrentPrice{}, nil
+}
+
+// volume returns the net volume of the live positions for the coin.
+func volume(xt *ExchangeTrader, coin model.Coin) float64 {
+	_, positions := xt.CurrentPositions(coin)
+	v := 0.0
+	for _, p := range positions {
+		if p.Live {
+			v += p.Type.Sign() * p.Volume
+		}
+	}
+	return v
+}
+
+func newTestTrader(t *testing.T, exchange api.Exchange) *ExchangeTrader {
+	trd, err := newTrader("test", storage.VoidShard(""), nil)
+	assert.NoError(t, err)
+	return NewExchangeTrader(trd, exchange, storage.NewVoidRegistry(), Settings{OpenValue: 100}, nil).
+		WithRetry(3, time.Millisecond)
+}
+
+func TestExchangeTrader_Faults(t *testing.T) {
+
+	type test struct {
+		faults map[string]fault.Fault
+	}
+
+	tests := map[string]test{
+		"errors": {
+			faults: map[string]fault.Fault{
+				fault.OpenOrder:     {Error: 0.3},
+				fault.OpenPositions: {Error: 0.3},
+			},
+		},
+		"dropped-responses": {
+			faults: map[string]fault.Fault{
+				fault.OpenOrder:     {Drop: 0.3},
+				fault.OpenPositions: {Drop: 0.3},
+			},
+		},
+		"duplicated-fills": {
+			faults: map[string]fault.Fault{
+				fault.OpenOrder: {Duplicate: 0.3},
+			},
+		},
+		"partial-and-stale-positions": {
+			faults: map[string]fault.Fault{
+				fault.OpenOrder:     {Drop: 0.1},
+				fault.OpenPositions: {Partial: 0.3, Stale: 0.3},
+			},
+		},
+		"all": {
+			faults: map[string]fault.Fault{
+				fault.OpenOrder:     {Error: 0.1, Drop: 0.1, Duplicate: 0.1, Latency: time.Millisecond},
+				fault.OpenPositions: {Error: 0.1, Drop: 0.1, Partial: 0.1, Stale: 0.1, Latency: time.Millisecond},
+			},
+		},
+	}
+
+	coins := []model.Coin{model.BTC, model.ETH}
+	for name, tt := range tests {
+		for seed := int64(1); seed <= 5; seed++ {
+			t.Run(fmt.Sprintf("%s-%d", name, seed), func(t *testing.T) {
+				upstream := newNetExchange()
+				exchange := fault.NewExchange(upstream, seed)
+				for method, f := range tt.faults {
+					exchange.With(method, f)
+				}
+				xt := newTestTrader(t, exchange)
+
+				rnd := rand.New(rand.NewSource(seed))
+				for i := 0; i < 200; i++ {
+					coin := coins[rnd.Intn(len(coins))]
+					tp := model.Buy
+					if rnd.Float64() < 0.5 {
+						tp = model.Sell
+					}
+					// the errors are expected , the positions must still end up in sync
+					_, _, _, _ = xt.CreateOrder(model.Key{Coin: coin, Duration: time.Minute},
+						time.Now(), 100+float64(i), tp, true, 1, SignalReason, true, nil)
+					if i%10 == 0 {
+						_ = xt.reconcile(context.Background())
+					}
+				}
+				for method := range tt.faults {
+					exchange.With(method, fault.Fault{})
+				}
+				// a missing position is closed on the second sync
+				assert.NoError(t, xt.reconcile(context.Background()))
+				assert.NoError(t, xt.reconcile(context.Background()))
+
+				injected := 0
+				for method := range tt.faults {
+					c := exchange.Counts(method)
+					injected += c.Errors + c.Drops + c.Duplicates + c.Partials + c.Stale
+				}
+				assert.True(t, injected > 0)
+				for _, coin := range coins {
+					assert.InDelta(t, upstream.volumes[coin], volume(xt, coin), 1e-9, string(coin))
+				}
+			})
+		}
+	}
+}
+
+func TestExchangeTrader_Reconcile(t *testing.T) {
+	upstream := newNetExchange()
+	exchange := fault.NewExchange(upstream, 1)
+	xt := newTestTrader(t, exchange)
+	key := model.Key{Coin: model.BTC, Duration: time.Minute}
+
+	_, ok, _, err := xt.CreateOrder(key, time.Now(), 100, model.Buy, true, 1, SignalReason, true, nil)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+
+	// a timeout keeps the positions as they are
+	exchange.With(fault.OpenPositions, fault.Fault{Latency: time.Hour})
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	assert.Error(t, xt.reconcile(ctx))
+	assert.Equal(t, 1.0, volume(xt, model.BTC))
+
+	// a partial response does not close the position
+	exchange.With(fault.OpenPositions, fault.Fault{Partial: 1})
+	assert.NoError(t, xt.reconcile(context.Background()))
+	exchange.With(fault.OpenPositions, fault.Fault{})
+	assert.NoError(t, xt.reconcile(context.Background()))
+	_, positions := xt.CurrentPositions(model.BTC)
+	assert.Equal(t, 1, len(positions))
+	assert.Equal(t, key, positions[key].Key)
+
+	// a duplicated fill is synced on the strategy position
+	exchange.With(fault.OpenOrder, fault.Fault{Duplicate: 1})
+	_, ok, _, err = xt.CreateOrder(model.Key{Coin: model.ETH, Duration: time.Minute}, time.Now(), 10, model.Sell, true, 2, SignalReason, true, nil)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, -2.0, volume(xt, model.ETH))
+	assert.NoError(t, xt.reconcile(context.Background()))
+	assert.Equal(t, -4.0, volume(xt, model.ETH))
+
+	// a dropped close with an unknown status leaves the position open until the sync
+	exchange.With(fault.OpenOrder, fault.Fault{Drop: 1})
+	exchange.With(fault.QueryOrder, fault.Fault{Error: 1})
+	_, _, _, err = xt.CreateOrder(key, time.Now(), 110, model.Sell, false, 0, TakeProfitReason, true, nil)
+	assert.Error(t, err)
+	assert.Equal(t, 1.0, volume(xt, model.BTC))
+	assert.NoError(t, xt.reconcile(context.Background()))
+	assert.NoError(t, xt.reconcile(context.Background()))
+	assert.Equal(t, 0.0, volume(xt, model.BTC))
+}
+
+func TestExchangeTrader_ReconcileAccounts(t *testing.T) {
+
+	type test struct {
+		settings Settings
+		volume   float64
+	}
+
+	tests := map[string]test{
+		"margin": {
+			settings: Settings{OpenValue: 100},
+			volume:   0,
+		},
+		"spot": {
+			settings: Settings{OpenValue: 100, Spot: true},
+			volume:   1,
+		},
+		"paper": {
+			settings: Settings{OpenValue: 100, Paper: true},
+			volume:   1,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			upstream := newNetExchange()
+			trd, err := newTrader("test", storage.VoidShard(""), nil)
+			assert.NoError(t, err)
+			xt := NewExchangeTrader(trd, upstream, storage.NewVoidRegistry(), tt.settings, nil).
+				WithRetry(3, time.Millisecond)
+
+			_, ok, _, err := xt.CreateOrder(model.Key{Coin: model.BTC, Duration: time.Minute}, time.Now(), 100, model.Buy, true, 1, SignalReason, true, nil)
+			assert.NoError(t, err)
+			assert.True(t, ok)
+
+			// the exchange reports only the margin positions
+			upstream.lock.Lock()
+			upstream.volumes = make(map[model.Coin]float64)
+			upstream.lock.Unlock()
+			assert.NoError(t, xt.reconcile(context.Background()))
+			assert.NoError(t, xt.reconcile(context.Background()))
+			assert.Equal(t, tt.volume, volume(xt, model.BTC))
+		})
+	}
+}
+
+func TestExchangeTrader_RetryTimeout(t *testing.T) {
+
+	type test struct {
+		attempts int
+		backoff  time.Duration
+		timeout  time.Duration
+	}
+
+	tests := map[string]test{
+		"sim-clock": {
+			attempts: 3,
+			backoff:  time.Millisecond,
+			timeout:  time.Minute,
+		},
+		"timeout": {
+			attempts: 3,
+			backoff:  time.Hour,
+			timeout:  10 * time.Millisecond,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			exchange := fault.NewExchange(newNetExchange(), 1).
+				With(fault.OpenOrder, fault.Fault{Error: 1})
+			trd, err := newTrader("test", storage.VoidShard(""), nil)
+			assert.NoError(t, err)
+			// the simulated clock never moves , while the order is retried
+			settings := Settings{OpenValue: 100, Clock: cointime.NewSimClock(time.Now())}
+			xt := NewExchangeTrader(trd, exchange, storage.NewVoidRegistry(), settings, nil).
+				WithRetry(tt.attempts, tt.backoff)
+			xt.retry.Timeout = tt.timeout
+
+			done := make(chan error)
+			go func() {
+				_, _, _, err := xt.CreateOrder(model.Key{Coin: model.BTC, Duration: time.Minute}, time.Now(), 100, model.Buy, true, 1, SignalReason, true, nil)
+				done <- err
+			}()
+			select {
+			case err := <-done:
+				assert.Error(t, err)
+			case <-time.After(5 * time.Second):
+				t.Fatal("order retries did not finish")
+			}
+			assert.Equal(t, 0.0, volume(xt, model.BTC))
+		})
+	}
+}
@@ -104,26 +104,22 @@ func (t *trader) update(trace map[string]bool, trade *model.TradeSignal, cfg []*
 	return positions
 }
 
-func (t *trader) reset(coins ...model.Coin) (map[model.Key]model.Position, error) {
-	positions := t.positions
-	if len(coins) == 1 && coins[0] == model.AllCoins {
-		positions = make(map[model.Key]model.Position)
-	} else {
+// reset removes the positions of the given coins , and returns the remaining and the removed ones.
+func (t *trader) reset(coins ...model.Coin) (map[model.Key]model.Position, map[model.Key]model.Position, error) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	all := len(coins) == 1 && coins[0] == model.AllCoins
+	removed := make(map[model.Key]model.Position)
+	for k, position := range t.positions {
 		for _, coin := range coins {
-			if coin == model.NoCoin {
-				continue
+			if all || (coin != model.NoCoin && position.Coin == coin) {
+				removed[k] = position
+				delete(t.positions, k)
+				break
 			}
-			newPositions := make(map[model.Key]model.Position)
-			for k, position := range positions {
-				if position.Coin != coin {
-					newPositions[k] = position
-				}
-			}
-			positions = newPositions
 		}
 	}
-	t.positions = positions
-	return t.positions, t.save()
+	return t.positions, removed, t.save()
 }
 
 func (t *trader) getAll(coins ...model.Coin) ([]model.Key, map[model.Key]model.Position /*, map[model.Coin]model.CurrentPrice*/) {
@@ -163,18 +159,18 @@ func (t *trader) check(key model.Key) (model.Position, bool, map[model.Key]model
 }
 
 func (t *trader) close(key model.Key) error {
-	t.lock.RLock()
-	defer t.lock.RUnlock()
+	t.lock.Lock()
+	defer t.lock.Unlock()
 	if _, ok := t.positions[key]; !ok {
-		return fmt.Errorf("cannot find position to close for key: %s", key)
+		return fmt.Errorf("cannot find position to close for key: %s", key.ToString())
 	}
 	delete(t.positions, key)
 	return t.save()
 }
 
 func (t *trader) add(order *model.TrackedOrder, live bool, decision *model.Decision) error {
-	t.lock.RLock()
-	defer t.lock.RUnlock()
+	t.lock.Lock()
+	defer t.lock.Unlock()
 	key := order.Key
 	// we need to be careful here and add the position ...
 	position := model.OpenPosition(order, t.config)
@@ -182,12 +178,13 @@ func (t *trader) add(order *model.TrackedOrder, live bool, decision *model.Decis
 	position.Live = live
 	if p, ok := t.positions[key]; ok {
 		if position.Coin != p.Coin {
-			return fmt.Errorf("different coin found for key: %s [%s vs %s]", key, p.Coin, position.Coin)
+			return fmt.Errorf("different coin found for key: %s [%s vs %s]", key.ToString(), p.Coin, position.Coin)
 		}
 		if position.Type != p.Type {
-			return fmt.Errorf("different type found for key: %s [%s vs %s]", key, p.Type.String(), position.Type.String())
+			return fmt.Errorf("different type found for key: %s [%s vs %s]", key.ToString(), p.Type.String(), position.Type.String())
 		}
 		position.Volume += p.Volume
+		position.OpenFees += p.OpenFees
 		log.Warn().
 			Str("account", t.account).
 			Str("key", fmt.Sprintf("%+v", key)).
@@ -5,15 +5,15 @@ import (
 	"time"
 
 	"github.com/drakos74/free-coin/internal/model"
-	"github.com/drakos74/free-coin/internal/storage/file/json"
+	"github.com/drakos74/free-coin/internal/storage"
 	"github.com/stretchr/testify/assert"
 )
 
 func TestTrader_Add(t *testing.T) {
 
 	type test struct {
-		openKey       Key
-		checkKey      Key
+		openKey       model.Key
+		checkKey      model.Key
 		openOrder     *model.Order
 		openPosition  bool
 		openPositions int
@@ -22,17 +22,17 @@ func TestTrader_Add(t *testing.T) {
 
 	tests := map[string]test{
 		"no-position": {
-			checkKey: Key{
+			checkKey: model.Key{
 				Coin:     model.BTC,
 				Duration: 5 * time.Minute,
 			},
 		},
 		"open-position": {
-			openKey: Key{
+			openKey: model.Key{
 				Coin:     model.BTC,
 				Duration: 5 * time.Minute,
 			},
-			checkKey: Key{
+			checkKey: model.Key{
 				Coin:     model.BTC,
 				Duration: 5 * time.Minute,
 			},
@@ -40,11 +40,11 @@ func TestTrader_Add(t *testing.T) {
 			openPosition: true,
 		},
 		"open-position-double": {
-			openKey: Key{
+			openKey: model.Key{
 				Coin:     model.BTC,
 				Duration: 5 * time.Minute,
 			},
-			checkKey: Key{
+			checkKey: model.Key{
 				Coin:     model.BTC,
 				Duration: 5 * time.Minute,
 			},
@@ -52,11 +52,11 @@ func TestTrader_Add(t *testing.T) {
 			openPosition: true,
 		},
 		"open-position-with-close": {
-			openKey: Key{
+			openKey: model.Key{
 				Coin:     model.BTC,
 				Duration: 5 * time.Minute,
 			},
-			checkKey: Key{
+			checkKey: model.Key{
 				Coin:     model.BTC,
 				Duration: 5 * time.Minute,
 			},
@@ -65,11 +65,11 @@ func TestTrader_Add(t *testing.T) {
 			close:        true,
 		},
 		"other-open-position": {
-			openKey: Key{
+			openKey: model.Key{
 				Coin:     model.BTC,
 				Duration: 5 * time.Minute,
 			},
-			checkKey: Key{
+			checkKey: model.Key{
 				Coin:     model.BTC,
 				Duration: 10 * time.Minute,
 			},
@@ -77,11 +77,11 @@ func TestTrader_Add(t *testing.T) {
 			openPositions: 1,
 		},
 		"other-coin-position": {
-			openKey: Key{
+			openKey: model.Key{
 				Coin:     model.BTC,
 				Duration: 5 * time.Minute,
 			},
-			checkKey: Key{
+			checkKey: model.Key{
 				Coin:     model.ETH,
 				Duration: 10 * time.Minute,
 			},
@@ -91,15 +91,12 @@ func TestTrader_Add(t *testing.T) {
 
 	for name, tt := range tests {
 		t.Run(name, func(t *testing.T) {
-			trader, err := newTrader("id", json.LocalShard(), make(map[model.Coin]map[time.Duration]Settings))
+			trader, err := newTrader("id", storage.VoidShard(""), nil)
 			assert.NoError(t, err)
 
 			if tt.openOrder != nil {
 				// lets add an order
-				err = trader.add(tt.openKey, model.NewTrackedOrder(model.Key{
-					Coin:     tt.openKey.Coin,
-					Duration: tt.openKey.Duration,
-				}, time.Now(), tt.openOrder.Create()))
+				err = trader.add(model.NewTrackedOrder(tt.openKey, time.Now(), "", tt.openOrder.Create()), false, nil)
 				assert.NoError(t, err)
 			}
 
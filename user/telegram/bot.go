@@ -11,6 +11,7 @@ import (
 	"github.com/drakos74/free-coin/internal/account"
 
 	"github.com/drakos74/free-coin/internal/api"
+	cointime "github.com/drakos74/free-coin/internal/time"
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api"
 	"github.com/rs/zerolog/log"
 )
@@ -45,6 +46,7 @@ type Bot struct {
 	blockedTriggers map[string]time.Time
 	consumers       map[api.ConsumerKey]chan api.Command
 	lock            *sync.Mutex
+	clock           cointime.Clock
 }
 
 // NewBot creates a new telegram bot implementing the coinapi.User api.
@@ -79,9 +81,16 @@ func NewBot(idxs ...api.Index) (*Bot, error) {
 		blockedTriggers: make(map[string]time.Time),
 		consumers:       make(map[api.ConsumerKey]chan api.Command),
 		lock:            new(sync.Mutex),
+		clock:           cointime.Current(),
 	}, nil
 }
 
+// WithClock sets the clock for the trigger timeouts.
+func (b *Bot) WithClock(clock cointime.Clock) *Bot {
+	b.clock = clock
+	return b
+}
+
 // Run starts the Bot and polls for updates from telegram.
 func (b *Bot) Run(ctx context.Context) error {
 	u := tgbotapi.NewUpdate(0)
@@ -146,7 +155,7 @@ func (b *Bot) checkIfBlocked(trigger *api.Trigger) (string, bool) {
 	if trigger != nil {
 		if blockedTime, ok := b.blockedTriggers[trigger.ID]; ok {
 			// trigger has been blocked
-			blocked := time.Since(blockedTime)
+			blocked := b.clock.Since(blockedTime)
 			if blocked > blockTimeout {
 				// unblock ...
 				delete(b.blockedTriggers, trigger.ID)
@@ -12,6 +12,7 @@ import (
 	"time"
 
 	"github.com/drakos74/free-coin/internal/api"
+	cointime "github.com/drakos74/free-coin/internal/time"
 
 	"github.com/stretchr/testify/assert"
 
@@ -24,6 +25,7 @@ func newMockBot(input chan tgbotapi.Update, output chan tgbotapi.MessageConfig)
 		triggers:        make(map[string]*api.Trigger),
 		blockedTriggers: make(map[string]time.Time),
 		consumers:       make(map[api.ConsumerKey]chan api.Command),
+		clock:           cointime.System,
 	}
 }
 